@@ -0,0 +1,495 @@
+package ibd2schema
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+* How new names are made up
+ */
+type AnonymizeMode string
+
+const (
+	/** prefix and the first hex digits of the HMAC-SHA256 of the name, the
+	same names get the same new names in every run with the same salt */
+	ANONYMIZE_HASH AnonymizeMode = "hash"
+	/** prefix and a sequence number, e.g. t_1, t_2 */
+	ANONYMIZE_SEQUENTIAL AnonymizeMode = "sequential"
+)
+
+const ANONYMIZE_HASH_LEN = 12
+
+/*
+* Kind of a renamed object, the value is the prefix of the new names
+ */
+type anonymizeKind string
+
+const (
+	ANONYMIZE_SCHEMA     anonymizeKind = "s"
+	ANONYMIZE_TABLE      anonymizeKind = "t"
+	ANONYMIZE_COLUMN     anonymizeKind = "c"
+	ANONYMIZE_INDEX      anonymizeKind = "k"
+	ANONYMIZE_CONSTRAINT anonymizeKind = "fk"
+	ANONYMIZE_CHECK      anonymizeKind = "chk"
+	ANONYMIZE_PARTITION  anonymizeKind = "p"
+)
+
+/*
+* New names by original names of each kind of object. Columns, indexes,
+constraints and partitions are mapped by name regardless of their table, so
+a column keeps its new name in all tables and in foreign key references.
+*/
+type AnonymizeMapping struct {
+	Mode AnonymizeMode `json:"mode"`
+	/** secret key of the hashes, random by default */
+	Salt        string            `json:"salt,omitempty"`
+	Schemas     map[string]string `json:"schemas"`
+	Tables      map[string]string `json:"tables"`
+	Columns     map[string]string `json:"columns"`
+	Indexes     map[string]string `json:"indexes"`
+	Constraints map[string]string `json:"constraints"`
+	Partitions  map[string]string `json:"partitions"`
+}
+
+/*
+* Create an empty mapping
+@param[in]	mode	how new names are made up
+@param[in]	salt	secret key of the hashes, a random key if empty
+*/
+func NewAnonymizeMapping(mode AnonymizeMode, salt string) (*AnonymizeMapping, error) {
+	if mode != ANONYMIZE_HASH && mode != ANONYMIZE_SEQUENTIAL {
+		return nil, fmt.Errorf("unknown anonymize mode %s", mode)
+	}
+	if mode == ANONYMIZE_HASH && salt == "" {
+		key := make([]byte, 16)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generate salt failed, err:%v", err)
+		}
+		salt = hex.EncodeToString(key)
+	}
+	mapping := &AnonymizeMapping{Mode: mode, Salt: salt}
+	mapping.init()
+	return mapping, nil
+}
+
+func (m *AnonymizeMapping) init() {
+	for _, names := range []*map[string]string{&m.Schemas, &m.Tables, &m.Columns, &m.Indexes,
+		&m.Constraints, &m.Partitions} {
+		if *names == nil {
+			*names = make(map[string]string)
+		}
+	}
+}
+
+/*
+* Parse a mapping saved by DumpJson
+ */
+func ParseAnonymizeMapping(data []byte) (*AnonymizeMapping, error) {
+	mapping := &AnonymizeMapping{}
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("parse anonymize mapping failed, err:%v", err)
+	}
+	if mapping.Mode != ANONYMIZE_HASH && mapping.Mode != ANONYMIZE_SEQUENTIAL {
+		return nil, fmt.Errorf("unknown anonymize mode %s", mapping.Mode)
+	}
+	mapping.init()
+	return mapping, nil
+}
+
+func (m *AnonymizeMapping) DumpJson() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+/*
+* Get the mapping from the new names back to the original names
+ */
+func (m *AnonymizeMapping) Reverse() (*AnonymizeMapping, error) {
+	reversed := &AnonymizeMapping{Mode: m.Mode}
+	reversed.init()
+	pairs := [][2]map[string]string{
+		{m.Schemas, reversed.Schemas}, {m.Tables, reversed.Tables}, {m.Columns, reversed.Columns},
+		{m.Indexes, reversed.Indexes}, {m.Constraints, reversed.Constraints},
+		{m.Partitions, reversed.Partitions},
+	}
+	for _, pair := range pairs {
+		for name, newName := range pair[0] {
+			if previous, ok := pair[1][newName]; ok {
+				return nil, fmt.Errorf("%s and %s have the same new name %s", previous, name, newName)
+			}
+			pair[1][newName] = name
+		}
+	}
+	return reversed, nil
+}
+
+/*
+* Get the names of a kind of object
+ */
+func (m *AnonymizeMapping) getNames(kind anonymizeKind) map[string]string {
+	switch kind {
+	case ANONYMIZE_SCHEMA:
+		return m.Schemas
+	case ANONYMIZE_TABLE:
+		return m.Tables
+	case ANONYMIZE_COLUMN:
+		return m.Columns
+	case ANONYMIZE_INDEX:
+		return m.Indexes
+	case ANONYMIZE_PARTITION:
+		return m.Partitions
+	}
+	return m.Constraints
+}
+
+type AnonymizeOptions struct {
+	/** keep the schema names, e.g. if they are generic like shard names */
+	KeepSchemaNames bool
+	/** keep the comments of tables, columns, indexes and partitions */
+	KeepComments bool
+}
+
+func NewAnonymizeOptions() *AnonymizeOptions {
+	return &AnonymizeOptions{}
+}
+
+/*
+* Renames the objects of SDIs consistently. Names of the mapping are reused,
+new names are added to it.
+*/
+type Anonymizer struct {
+	Options *AnonymizeOptions
+	Mapping *AnonymizeMapping
+	/** only rename the names of the mapping, used to reverse */
+	mappedOnly bool
+	/** new names in use of each kind */
+	used map[anonymizeKind]map[string]bool
+}
+
+func NewAnonymizer(opts *AnonymizeOptions, mapping *AnonymizeMapping) *Anonymizer {
+	a := &Anonymizer{
+		Options: opts,
+		Mapping: mapping,
+		used:    make(map[anonymizeKind]map[string]bool),
+	}
+	for _, kind := range []anonymizeKind{ANONYMIZE_SCHEMA, ANONYMIZE_TABLE, ANONYMIZE_COLUMN,
+		ANONYMIZE_INDEX, ANONYMIZE_CONSTRAINT, ANONYMIZE_PARTITION} {
+		a.used[kind] = make(map[string]bool)
+		for _, newName := range mapping.getNames(kind) {
+			a.used[kind][newName] = true
+		}
+	}
+	a.used[ANONYMIZE_CHECK] = a.used[ANONYMIZE_CONSTRAINT]
+	return a
+}
+
+/*
+* Create an anonymizer that renames anonymized SDIs back to the original
+names of the mapping. Comments that were stripped are not restored.
+*/
+func NewReverseAnonymizer(mapping *AnonymizeMapping) (*Anonymizer, error) {
+	reversed, err := mapping.Reverse()
+	if err != nil {
+		return nil, err
+	}
+	a := NewAnonymizer(&AnonymizeOptions{KeepComments: true}, reversed)
+	a.mappedOnly = true
+	return a, nil
+}
+
+/*
+* Get the new name of an object, making it up if the name is not mapped yet
+ */
+func (a *Anonymizer) rename(kind anonymizeKind, name string) string {
+	if name == "" || (kind == ANONYMIZE_SCHEMA && a.Options.KeepSchemaNames) {
+		return name
+	}
+	names := a.Mapping.getNames(kind)
+	if newName, ok := names[name]; ok {
+		return newName
+	}
+	if a.mappedOnly {
+		return name
+	}
+	var newName string
+	for n := len(names) + 1; newName == "" || a.used[kind][newName]; n++ {
+		if a.Mapping.Mode == ANONYMIZE_SEQUENTIAL {
+			newName = fmt.Sprintf("%s_%d", kind, n)
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(a.Mapping.Salt))
+		fmt.Fprintf(mac, "%s\x00%s", kind, name)
+		newName = fmt.Sprintf("%s_%s", kind, hex.EncodeToString(mac.Sum(nil))[:ANONYMIZE_HASH_LEN])
+		if a.used[kind][newName] {
+			/* collision of truncated hashes */
+			newName = fmt.Sprintf("%s_%d", newName, n)
+		}
+	}
+	names[name] = newName
+	a.used[kind][newName] = true
+	return newName
+}
+
+/*
+* Replace the quoted identifiers of an expression, the other tokens are kept
+as they are.
+@param[in]	expression	expression of SDI JSON, e.g. "(`a` + `b`)"
+@param[in]	replace		replacement of an identifier including its quotes
+@return expression with the replaced identifiers
+*/
+func rewriteQuotedIdentifiers(expression string, replace func(name string) string) (string, error) {
+	tokens, err := tokenizeDDL(expression)
+	if err != nil {
+		return "", fmt.Errorf("tokenize expression %s failed, err:%v", expression, err)
+	}
+	var b strings.Builder
+	last := 0
+	for _, token := range tokens {
+		if token.kind != DDL_TOKEN_QUOTED_IDENT {
+			continue
+		}
+		/* find the closing backtick, doubled backticks are escaped ones */
+		end := token.pos + 1
+		for end < len(expression) {
+			if expression[end] == '`' {
+				if end+1 < len(expression) && expression[end+1] == '`' {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		b.WriteString(expression[last:token.pos])
+		b.WriteString(replace(token.text))
+		last = end + 1
+	}
+	b.WriteString(expression[last:])
+	return b.String(), nil
+}
+
+/*
+* Anonymize an SDI: rename the schema, table, columns, indexes, constraints
+and partitions, strip the comments and rewrite the generation expressions,
+default expressions, CHECK clauses and partition expressions with the new
+column names. Tablespace SDIs get the new schema, table and partition names
+in their tablespace and file names.
+@param[in]	sdi	SDI to anonymize, it is not modified
+@return anonymized SDI with its table schema
+*/
+func (a *Anonymizer) AnonymizeSDI(sdi *SDI) (*SDI, error) {
+	decoder := json.NewDecoder(bytes.NewReader(sdi.UncompressedData))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("decode SDI %d failed, err:%v", sdi.ID, err)
+	}
+	ddObject, ok := object[`dd_object`].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("dd_object of SDI %d not found", sdi.ID)
+	}
+	var err error
+	switch object[`dd_object_type`] {
+	case `Table`:
+		err = a.anonymizeTable(ddObject)
+	case `Tablespace`:
+		a.anonymizeTablespace(ddObject)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("anonymize SDI %d failed, err:%v", sdi.ID, err)
+	}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	result := &SDI{
+		Type:                sdi.Type,
+		ID:                  sdi.ID,
+		UncompressedData:    data,
+		UncompressedDataLen: uint64(len(data)),
+	}
+	if err = result.DumpTableSchema(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (a *Anonymizer) stripComment(object map[string]interface{}) {
+	if _, ok := object[`comment`]; ok && !a.Options.KeepComments {
+		object[`comment`] = ""
+	}
+}
+
+/*
+* Rename a string member of a dd object
+ */
+func (a *Anonymizer) renameMember(object map[string]interface{}, member string, kind anonymizeKind) {
+	if name, ok := object[member].(string); ok {
+		object[member] = a.rename(kind, name)
+	}
+}
+
+/*
+* Rewrite string members of a dd object that hold expressions
+ */
+func (a *Anonymizer) rewriteMembers(object map[string]interface{}, replace func(string) string,
+	members ...string) error {
+	for _, member := range members {
+		expression, ok := object[member].(string)
+		if !ok || expression == "" {
+			continue
+		}
+		rewritten, err := rewriteQuotedIdentifiers(expression, replace)
+		if err != nil {
+			return err
+		}
+		object[member] = rewritten
+	}
+	return nil
+}
+
+func getObjectArray(object map[string]interface{}, member string) []map[string]interface{} {
+	items, _ := object[member].([]interface{})
+	objects := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if o, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, o)
+		}
+	}
+	return objects
+}
+
+func getObjectInt(object map[string]interface{}, member string) int64 {
+	n, _ := object[member].(json.Number).Int64()
+	return n
+}
+
+/*
+* Check if an index is internal to InnoDB and keeps its name
+ */
+func isInternalIndexName(name string) bool {
+	return name == "PRIMARY" || name == "GEN_CLUST_INDEX" || name == "FTS_DOC_ID_INDEX"
+}
+
+func (a *Anonymizer) anonymizeTable(table map[string]interface{}) error {
+	a.renameMember(table, `schema_ref`, ANONYMIZE_SCHEMA)
+	a.renameMember(table, `name`, ANONYMIZE_TABLE)
+	a.stripComment(table)
+	columns := getObjectArray(table, `columns`)
+	/* names of the columns by lower case name, identifiers of expressions
+	are case-insensitive */
+	columnNames := make(map[string]string, len(columns))
+	for _, column := range columns {
+		name, _ := column[`name`].(string)
+		columnNames[strings.ToLower(name)] = name
+	}
+	replaceColumn := func(name string) string {
+		if original, ok := columnNames[strings.ToLower(name)]; ok {
+			name = a.rename(ANONYMIZE_COLUMN, original)
+		}
+		return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+	}
+	indexes := getObjectArray(table, `indexes`)
+	for _, index := range indexes {
+		if name, _ := index[`name`].(string); !isInternalIndexName(name) {
+			index[`name`] = a.rename(ANONYMIZE_INDEX, name)
+		}
+		a.stripComment(index)
+	}
+	for _, column := range columns {
+		name, _ := column[`name`].(string)
+		switch HiddenType(getObjectInt(column, `hidden`)) {
+		case HT_HIDDEN_SE:
+			/* system columns like DB_TRX_ID */
+		case HT_HIDDEN_SQL:
+			/* column of a functional key part, !hidden!<index>!<key part>!<n> */
+			if parts := strings.SplitN(name, "!", 4); len(parts) == 4 && parts[1] == "hidden" {
+				parts[2] = a.rename(ANONYMIZE_INDEX, parts[2])
+				column[`name`] = strings.Join(parts, "!")
+			}
+		default:
+			options, _ := column[`options`].(string)
+			if gipk := ParseKeyValueList(options)["gipk"]; name != "FTS_DOC_ID" && (gipk == "" || gipk == "0") {
+				column[`name`] = a.rename(ANONYMIZE_COLUMN, name)
+			}
+		}
+		a.stripComment(column)
+		if err := a.rewriteMembers(column, replaceColumn, `generation_expression`,
+			`generation_expression_utf8`); err != nil {
+			return err
+		}
+		if option, _ := column[`default_option`].(string); strings.HasPrefix(option, "(") {
+			if err := a.rewriteMembers(column, replaceColumn, `default_option`); err != nil {
+				return err
+			}
+		}
+	}
+	for _, fk := range getObjectArray(table, `foreign_keys`) {
+		a.renameMember(fk, `name`, ANONYMIZE_CONSTRAINT)
+		a.renameMember(fk, `referenced_table_schema_name`, ANONYMIZE_SCHEMA)
+		a.renameMember(fk, `referenced_table_name`, ANONYMIZE_TABLE)
+		if name, ok := fk[`unique_constraint_name`].(string); ok && !isInternalIndexName(name) {
+			fk[`unique_constraint_name`] = a.rename(ANONYMIZE_INDEX, name)
+		}
+		for _, element := range getObjectArray(fk, `elements`) {
+			a.renameMember(element, `referenced_column_name`, ANONYMIZE_COLUMN)
+		}
+	}
+	for _, check := range getObjectArray(table, `check_constraints`) {
+		a.renameMember(check, `name`, ANONYMIZE_CHECK)
+		if err := a.rewriteMembers(check, replaceColumn, `check_clause`, `check_clause_utf8`); err != nil {
+			return err
+		}
+	}
+	if err := a.rewriteMembers(table, replaceColumn, `partition_expression`, `partition_expression_utf8`,
+		`subpartition_expression`, `subpartition_expression_utf8`); err != nil {
+		return err
+	}
+	for _, partition := range getObjectArray(table, `partitions`) {
+		a.renameMember(partition, `name`, ANONYMIZE_PARTITION)
+		a.stripComment(partition)
+		for _, subpartition := range getObjectArray(partition, `subpartitions`) {
+			a.renameMember(subpartition, `name`, ANONYMIZE_PARTITION)
+			a.stripComment(subpartition)
+		}
+	}
+	return nil
+}
+
+/*
+* Rename a file-per-table tablespace name like "db/t#p#p0#sp#p0sp0"
+ */
+func (a *Anonymizer) renameTablespaceName(name string) string {
+	schemaName, tableName, ok := strings.Cut(name, "/")
+	if !ok {
+		return name
+	}
+	parts := strings.Split(tableName, "#")
+	parts[0] = a.rename(ANONYMIZE_TABLE, parts[0])
+	for n := 2; n < len(parts); n += 2 {
+		if kind := strings.ToLower(parts[n-1]); kind == "p" || kind == "sp" {
+			parts[n] = a.rename(ANONYMIZE_PARTITION, parts[n])
+		}
+	}
+	return a.rename(ANONYMIZE_SCHEMA, schemaName) + "/" + strings.Join(parts, "#")
+}
+
+func (a *Anonymizer) anonymizeTablespace(tablespace map[string]interface{}) {
+	name, _ := tablespace[`name`].(string)
+	tablespace[`name`] = a.renameTablespaceName(name)
+	a.stripComment(tablespace)
+	for _, file := range getObjectArray(tablespace, `files`) {
+		filename, _ := file[`filename`].(string)
+		dir, ibd, ok := strings.Cut(strings.TrimPrefix(filename, "./"), "/")
+		if !ok || !strings.HasSuffix(ibd, ".ibd") {
+			continue
+		}
+		file[`filename`] = "./" + a.renameTablespaceName(dir+"/"+strings.TrimSuffix(ibd, ".ibd")) + ".ibd"
+	}
+}
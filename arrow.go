@@ -0,0 +1,605 @@
+package ibd2schema
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/decimal128"
+	"github.com/apache/arrow/go/v17/arrow/decimal256"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+)
+
+const (
+	/** default number of rows in a record batch */
+	ARROW_DEFAULT_BATCH_ROWS = 64 * 1024
+	/** key of the table DDL in the metadata of the schema */
+	ARROW_DDL_METADATA_KEY = "ibd2schema.ddl"
+	/** key of the column type in the metadata of a field, e.g. "int unsigned" */
+	ARROW_COLUMN_TYPE_METADATA_KEY = "ibd2schema.column_type"
+	/** key of the canonical extension type in the metadata of a field */
+	ARROW_EXTENSION_NAME_KEY = "ARROW:extension:name"
+	/** decimal precision that fits in a decimal128 */
+	ARROW_DECIMAL128_MAX_PRECISION = 38
+)
+
+type ArrowOptions struct {
+	/** number of rows of a record batch */
+	BatchRows int64
+	/** write the IPC streaming format instead of the file format */
+	Stream bool
+	/** write zero or invalid dates as NULL, the temporal columns become
+	nullable; otherwise they fail the export */
+	ZeroDateAsNull bool
+	/** allocator of the record batches, memory.DefaultAllocator if nil */
+	Allocator memory.Allocator
+	/** rows and columns to export, nil for the whole table */
+	Scan *ScanOptions
+}
+
+/*
+* Get the default Arrow options: ARROW_DEFAULT_BATCH_ROWS rows per record
+batch in the IPC file format.
+*/
+func NewArrowOptions() *ArrowOptions {
+	return &ArrowOptions{
+		BatchRows: ARROW_DEFAULT_BATCH_ROWS,
+		Allocator: memory.DefaultAllocator,
+	}
+}
+
+/*
+* Arrow field of a column and the conversion of its decoded values.
+ */
+type arrowColumn struct {
+	column *Column
+	field  arrow.Field
+	/** append a decoded value that is not NULL to the builder */
+	append func(b array.Builder, value interface{}) error
+}
+
+/*
+* Map a column to an Arrow field. Integers keep their width and sign,
+decimals become decimal128 or decimal256, ENUM a dictionary of its elements,
+TIME a duration since values may exceed a day.
+@param[in]	column	table column
+@param[in]	opts	Arrow options
+@return Arrow column
+*/
+func newArrowColumn(column *Column, opts *ArrowOptions) (ac *arrowColumn, err error) {
+	ac = &arrowColumn{column: column}
+	metadata := map[string]string{ARROW_COLUMN_TYPE_METADATA_KEY: column.ColumnTypeUTF8}
+	nullable := column.IsNullable
+	var dataType arrow.DataType
+	/* handle the zero dates of temporal columns */
+	checkDate := func(b array.Builder, d Date, value interface{}) (bool, error) {
+		if !isInvalidDate(d) {
+			return true, nil
+		}
+		if opts.ZeroDateAsNull {
+			b.AppendNull()
+			return false, nil
+		}
+		return false, fmt.Errorf("column %s has invalid date %v", column.Name, value)
+	}
+	switch column.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG:
+		signed := map[ColumnType]arrow.DataType{
+			CT_TINY:     arrow.PrimitiveTypes.Int8,
+			CT_SHORT:    arrow.PrimitiveTypes.Int16,
+			CT_INT24:    arrow.PrimitiveTypes.Int32,
+			CT_LONG:     arrow.PrimitiveTypes.Int32,
+			CT_LONGLONG: arrow.PrimitiveTypes.Int64,
+		}
+		unsigned := map[ColumnType]arrow.DataType{
+			CT_TINY:     arrow.PrimitiveTypes.Uint8,
+			CT_SHORT:    arrow.PrimitiveTypes.Uint16,
+			CT_INT24:    arrow.PrimitiveTypes.Uint32,
+			CT_LONG:     arrow.PrimitiveTypes.Uint32,
+			CT_LONGLONG: arrow.PrimitiveTypes.Uint64,
+		}
+		dataType = signed[column.Type]
+		if column.IsUnsigned {
+			dataType = unsigned[column.Type]
+		}
+		ac.append = func(b array.Builder, value interface{}) error {
+			var i int64
+			var u uint64
+			switch v := value.(type) {
+			case int64:
+				i, u = v, uint64(v)
+			case uint64:
+				i, u = int64(v), v
+			default:
+				return fmt.Errorf("unexpected %T", value)
+			}
+			switch b := b.(type) {
+			case *array.Int8Builder:
+				b.Append(int8(i))
+			case *array.Int16Builder:
+				b.Append(int16(i))
+			case *array.Int32Builder:
+				b.Append(int32(i))
+			case *array.Int64Builder:
+				b.Append(i)
+			case *array.Uint8Builder:
+				b.Append(uint8(u))
+			case *array.Uint16Builder:
+				b.Append(uint16(u))
+			case *array.Uint32Builder:
+				b.Append(uint32(u))
+			case *array.Uint64Builder:
+				b.Append(u)
+			}
+			return nil
+		}
+	case CT_YEAR:
+		dataType = arrow.PrimitiveTypes.Uint16
+		ac.append = func(b array.Builder, value interface{}) error {
+			b.(*array.Uint16Builder).Append(uint16(value.(int64)))
+			return nil
+		}
+	case CT_BIT:
+		dataType = arrow.PrimitiveTypes.Uint64
+		ac.append = func(b array.Builder, value interface{}) error {
+			b.(*array.Uint64Builder).Append(value.(uint64))
+			return nil
+		}
+	case CT_FLOAT:
+		dataType = arrow.PrimitiveTypes.Float32
+		ac.append = func(b array.Builder, value interface{}) error {
+			b.(*array.Float32Builder).Append(value.(float32))
+			return nil
+		}
+	case CT_DOUBLE:
+		dataType = arrow.PrimitiveTypes.Float64
+		ac.append = func(b array.Builder, value interface{}) error {
+			b.(*array.Float64Builder).Append(value.(float64))
+			return nil
+		}
+	case CT_NEWDECIMAL:
+		precision, scale := int32(column.NumericPrecision), int32(column.NumericScale)
+		dataType = &arrow.Decimal128Type{Precision: precision, Scale: scale}
+		if precision > ARROW_DECIMAL128_MAX_PRECISION {
+			dataType = &arrow.Decimal256Type{Precision: precision, Scale: scale}
+		}
+		ac.append = func(b array.Builder, value interface{}) error {
+			unscaled, err := decimalUnscaled(value.(string), uint32(scale))
+			if err != nil {
+				return err
+			}
+			switch b := b.(type) {
+			case *array.Decimal128Builder:
+				b.Append(decimal128.FromBigInt(unscaled))
+			case *array.Decimal256Builder:
+				b.Append(decimal256.FromBigInt(unscaled))
+			}
+			return nil
+		}
+	case CT_DATE, CT_NEWDATE:
+		dataType = arrow.FixedWidthTypes.Date32
+		nullable = nullable || opts.ZeroDateAsNull
+		ac.append = func(b array.Builder, value interface{}) error {
+			d := value.(Date)
+			if ok, err := checkDate(b, d, value); !ok {
+				return err
+			}
+			t := time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
+			b.(*array.Date32Builder).Append(arrow.Date32FromTime(t))
+			return nil
+		}
+	case CT_DATETIME, CT_DATETIME2:
+		/* no time zone, a wall clock time like DATETIME */
+		dataType = &arrow.TimestampType{Unit: arrow.Microsecond}
+		nullable = nullable || opts.ZeroDateAsNull
+		ac.append = func(b array.Builder, value interface{}) error {
+			dt := value.(Datetime)
+			if ok, err := checkDate(b, dt.Date, value); !ok {
+				return err
+			}
+			t := time.Date(dt.Year, time.Month(dt.Month), dt.Day, dt.Hour, dt.Minute, dt.Second,
+				dt.Microsecond*1000, time.UTC)
+			b.(*array.TimestampBuilder).Append(arrow.Timestamp(t.UnixMicro()))
+			return nil
+		}
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		dataType = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+		nullable = nullable || opts.ZeroDateAsNull
+		ac.append = func(b array.Builder, value interface{}) error {
+			t := value.(time.Time)
+			/* '0000-00-00 00:00:00' is stored as 0 */
+			if t.Unix() == 0 && t.Nanosecond() == 0 {
+				if opts.ZeroDateAsNull {
+					b.AppendNull()
+					return nil
+				}
+				return fmt.Errorf("column %s has zero timestamp", column.Name)
+			}
+			b.(*array.TimestampBuilder).Append(arrow.Timestamp(t.UnixMicro()))
+			return nil
+		}
+	case CT_TIME, CT_TIME2:
+		dataType = &arrow.DurationType{Unit: arrow.Microsecond}
+		ac.append = func(b array.Builder, value interface{}) error {
+			d := value.(Time).Duration() / time.Microsecond
+			b.(*array.DurationBuilder).Append(arrow.Duration(d))
+			return nil
+		}
+	case CT_ENUM:
+		/* the dictionary holds the elements in order, so the indexes are the
+		ENUM values minus 1 */
+		indexType := arrow.PrimitiveTypes.Uint8
+		if len(column.Elements) > 255 {
+			indexType = arrow.PrimitiveTypes.Uint16
+		}
+		dataType = &arrow.DictionaryType{IndexType: indexType, ValueType: arrow.BinaryTypes.String}
+		ac.append = func(b array.Builder, value interface{}) error {
+			return b.(*array.BinaryDictionaryBuilder).AppendString(value.(string))
+		}
+	case CT_JSON:
+		dataType = arrow.BinaryTypes.String
+		metadata[ARROW_EXTENSION_NAME_KEY] = "arrow.json"
+	case CT_SET, CT_STRING, CT_VARCHAR, CT_VAR_STRING, CT_TINY_BLOB, CT_MEDIUM_BLOB,
+		CT_LONG_BLOB, CT_BLOB:
+		dataType = arrow.BinaryTypes.Binary
+		if column.Collation.CharsetName != "binary" {
+			dataType = arrow.BinaryTypes.String
+		}
+	case CT_GEOMETRY:
+		/* SRID followed by WKB, as stored by MySQL */
+		dataType = arrow.BinaryTypes.Binary
+	default:
+		return nil, fmt.Errorf("unsupported type %d of column %s", column.Type, column.Name)
+	}
+	if ac.append == nil {
+		ac.append = func(b array.Builder, value interface{}) error {
+			var data []byte
+			switch v := value.(type) {
+			case string:
+				data = []byte(v)
+			case []byte:
+				data = v
+			default:
+				return fmt.Errorf("unexpected %T", value)
+			}
+			switch b := b.(type) {
+			case *array.StringBuilder:
+				b.BinaryBuilder.Append(data)
+			case *array.BinaryBuilder:
+				b.Append(data)
+			}
+			return nil
+		}
+	}
+	ac.field = arrow.Field{
+		Name:     column.Name,
+		Type:     dataType,
+		Nullable: nullable,
+		Metadata: arrow.MetadataFrom(metadata),
+	}
+	return ac, nil
+}
+
+/*
+* Create the builder of a column, the dictionary of an ENUM is filled with
+its elements.
+*/
+func (ac *arrowColumn) newBuilder(mem memory.Allocator) array.Builder {
+	dt, ok := ac.field.Type.(*arrow.DictionaryType)
+	if !ok {
+		return array.NewBuilder(mem, ac.field.Type)
+	}
+	sb := array.NewStringBuilder(mem)
+	defer sb.Release()
+	sb.AppendValues(ac.column.Elements, nil)
+	elements := sb.NewArray()
+	defer elements.Release()
+	return array.NewDictionaryBuilderWithDict(mem, dt, elements)
+}
+
+/*
+* Append a decoded value to the builder of the column.
+ */
+func (ac *arrowColumn) appendValue(b array.Builder, value interface{}) error {
+	if value == nil {
+		if !ac.field.Nullable {
+			return fmt.Errorf("NULL value of NOT NULL column %s", ac.column.Name)
+		}
+		b.AppendNull()
+		return nil
+	}
+	if err := ac.append(b, value); err != nil {
+		return fmt.Errorf("convert value of column %s failed, err:%v", ac.column.Name, err)
+	}
+	return nil
+}
+
+/*
+* Map columns to Arrow columns.
+@return Arrow columns and their schema
+*/
+func newArrowColumns(columns []*Column, opts *ArrowOptions, metadata map[string]string) (
+	acs []*arrowColumn, sc *arrow.Schema, err error) {
+	fields := make([]arrow.Field, 0, len(columns))
+	for _, column := range columns {
+		ac, err := newArrowColumn(column, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		acs = append(acs, ac)
+		fields = append(fields, ac.field)
+	}
+	md := arrow.MetadataFrom(metadata)
+	return acs, arrow.NewSchema(fields, &md), nil
+}
+
+/*
+* Get the Arrow schema of the columns.
+@param[in]	columns	columns in file order
+@param[in]	opts	Arrow options, nil for the defaults
+@param[in]	metadata	metadata of the schema
+@return Arrow schema
+*/
+func NewArrowSchema(columns []*Column, opts *ArrowOptions, metadata map[string]string) (
+	*arrow.Schema, error) {
+	if opts == nil {
+		opts = NewArrowOptions()
+	}
+	_, sc, err := newArrowColumns(columns, opts, metadata)
+	return sc, err
+}
+
+/*
+* Builder of Arrow record batches from decoded rows.
+ */
+type ArrowRecordBuilder struct {
+	schema   *arrow.Schema
+	columns  []*arrowColumn
+	builders []array.Builder
+	/** number of rows appended since the last record */
+	NumRows int64
+}
+
+/*
+* Create an Arrow record builder.
+@param[in]	columns	columns of the rows, see Row.Columns
+@param[in]	opts	Arrow options, nil for the defaults
+@param[in]	metadata	metadata of the schema
+@return Arrow record builder, Release it when done
+*/
+func NewArrowRecordBuilder(columns []*Column, opts *ArrowOptions, metadata map[string]string) (
+	rb *ArrowRecordBuilder, err error) {
+	if opts == nil {
+		opts = NewArrowOptions()
+	}
+	mem := opts.Allocator
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	rb = &ArrowRecordBuilder{}
+	rb.columns, rb.schema, err = newArrowColumns(columns, opts, metadata)
+	if err != nil {
+		return nil, err
+	}
+	for _, ac := range rb.columns {
+		rb.builders = append(rb.builders, ac.newBuilder(mem))
+	}
+	return rb, nil
+}
+
+/*
+* Get the schema of the records.
+ */
+func (rb *ArrowRecordBuilder) Schema() *arrow.Schema {
+	return rb.schema
+}
+
+/*
+* Append a row.
+@param[in]	values	decoded values in the order of the builder columns
+*/
+func (rb *ArrowRecordBuilder) AppendRow(values []interface{}) (err error) {
+	if len(values) != len(rb.columns) {
+		return fmt.Errorf("row has %d values, expected %d", len(values), len(rb.columns))
+	}
+	for n, ac := range rb.columns {
+		if err = ac.appendValue(rb.builders[n], values[n]); err != nil {
+			return err
+		}
+	}
+	rb.NumRows++
+	return nil
+}
+
+/*
+* Create a record batch of the appended rows and reset the builder.
+@return record batch, Release it when done
+*/
+func (rb *ArrowRecordBuilder) NewRecord() arrow.Record {
+	arrays := make([]arrow.Array, len(rb.builders))
+	for n, b := range rb.builders {
+		arrays[n] = b.NewArray()
+		defer arrays[n].Release()
+	}
+	record := array.NewRecord(rb.schema, arrays, rb.NumRows)
+	rb.NumRows = 0
+	return record
+}
+
+/*
+* Release the builders.
+ */
+func (rb *ArrowRecordBuilder) Release() {
+	for _, b := range rb.builders {
+		b.Release()
+	}
+	rb.builders = nil
+}
+
+/*
+* Writer that tracks its offset for the IPC file writer, which only seeks to
+get the current offset, so files can be written to pipes.
+*/
+type arrowOffsetWriter struct {
+	w      io.Writer
+	offset int64
+}
+
+func (ow *arrowOffsetWriter) Write(p []byte) (n int, err error) {
+	n, err = ow.w.Write(p)
+	ow.offset += int64(n)
+	return n, err
+}
+
+func (ow *arrowOffsetWriter) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekCurrent {
+		return 0, fmt.Errorf("arrow writer only gets the current offset")
+	}
+	return ow.offset, nil
+}
+
+/*
+* Writer of decoded rows to an Arrow IPC file or stream, rows are buffered
+and written in record batches.
+*/
+type ArrowWriter struct {
+	builder *ArrowRecordBuilder
+	writer  interface {
+		Write(rec arrow.Record) error
+		Close() error
+	}
+	batchRows int64
+	NumRows   uint64
+}
+
+/*
+* Create an Arrow IPC writer.
+@param[in]	w	destination of the file or stream
+@param[in]	columns	columns of the rows, see Row.Columns
+@param[in]	opts	Arrow options, nil for the defaults
+@param[in]	metadata	metadata of the schema
+@return Arrow writer
+*/
+func NewArrowWriter(w io.Writer, columns []*Column, opts *ArrowOptions,
+	metadata map[string]string) (aw *ArrowWriter, err error) {
+	if opts == nil {
+		opts = NewArrowOptions()
+	}
+	aw = &ArrowWriter{batchRows: opts.BatchRows}
+	if aw.batchRows <= 0 {
+		aw.batchRows = ARROW_DEFAULT_BATCH_ROWS
+	}
+	aw.builder, err = NewArrowRecordBuilder(columns, opts, metadata)
+	if err != nil {
+		return nil, err
+	}
+	ipcOpts := []ipc.Option{ipc.WithSchema(aw.builder.Schema())}
+	if opts.Allocator != nil {
+		ipcOpts = append(ipcOpts, ipc.WithAllocator(opts.Allocator))
+	}
+	if opts.Stream {
+		aw.writer = ipc.NewWriter(w, ipcOpts...)
+		return aw, nil
+	}
+	ws, ok := w.(io.WriteSeeker)
+	if ok {
+		/* e.g. os.Stdout on a pipe */
+		_, err = ws.Seek(0, io.SeekCurrent)
+		ok = err == nil
+	}
+	if !ok {
+		ws = &arrowOffsetWriter{w: w}
+	}
+	aw.writer, err = ipc.NewFileWriter(ws, ipcOpts...)
+	if err != nil {
+		aw.builder.Release()
+		return nil, fmt.Errorf("create arrow file writer failed, err:%v", err)
+	}
+	return aw, nil
+}
+
+/*
+* Buffer a row, the record batch is written when it is full.
+@param[in]	values	decoded values in the order of the writer columns
+*/
+func (aw *ArrowWriter) WriteRow(values []interface{}) (err error) {
+	if err = aw.builder.AppendRow(values); err != nil {
+		return err
+	}
+	aw.NumRows++
+	if aw.builder.NumRows >= aw.batchRows {
+		return aw.Flush()
+	}
+	return nil
+}
+
+/*
+* Write the buffered rows as a record batch.
+ */
+func (aw *ArrowWriter) Flush() (err error) {
+	if aw.builder.NumRows == 0 {
+		return nil
+	}
+	record := aw.builder.NewRecord()
+	defer record.Release()
+	if err = aw.writer.Write(record); err != nil {
+		return fmt.Errorf("write record batch failed, err:%v", err)
+	}
+	return nil
+}
+
+/*
+* Write the buffered rows and the end of the file or stream.
+ */
+func (aw *ArrowWriter) Close() (err error) {
+	defer aw.builder.Release()
+	if err = aw.Flush(); err != nil {
+		return err
+	}
+	return aw.writer.Close()
+}
+
+/*
+* Export the rows of a table to an Arrow IPC file or stream. The schema is
+derived from the SDI columns and the table DDL is saved in the schema
+metadata.
+@param[in]	ctx		context to cancel the export
+@param[in]	tableSchema	table schema from the SDI
+@param[in]	w		destination of the file or stream
+@param[in]	opts		Arrow options, nil for the defaults
+@return number of exported rows
+*/
+func (ts *TableSpace) ExportArrow(ctx context.Context, tableSchema *TableSchema, w io.Writer,
+	opts *ArrowOptions) (rows uint64, err error) {
+	if opts == nil {
+		opts = NewArrowOptions()
+	}
+	it, err := ts.Scan(ctx, tableSchema, opts.Scan)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+	aw, err := NewArrowWriter(w, it.Columns(), opts,
+		map[string]string{ARROW_DDL_METADATA_KEY: tableSchema.DDL})
+	if err != nil {
+		return 0, err
+	}
+	for it.Next() {
+		if err = aw.WriteRow(it.Row().Values); err != nil {
+			aw.builder.Release()
+			return aw.NumRows, err
+		}
+	}
+	if err = it.Err(); err != nil {
+		aw.builder.Release()
+		return aw.NumRows, err
+	}
+	if err = aw.Close(); err != nil {
+		return aw.NumRows, err
+	}
+	return aw.NumRows, nil
+}
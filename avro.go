@@ -0,0 +1,411 @@
+package ibd2schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* Name of a Debezium semantic type of a schema, kept by the Avro converter of
+Kafka Connect
+*/
+const AVRO_CONNECT_NAME = "connect.name"
+
+/*
+* Namespace and name of the shared geometry record
+ */
+const AVRO_GEOMETRY_NAMESPACE = "io.debezium.data.geometry"
+const AVRO_GEOMETRY_NAME = "Geometry"
+
+var avroNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type AvroOptions struct {
+	/** first part of the record namespaces, the topic prefix of the connector,
+	e.g. "mysql" gives mysql.db.table.Value */
+	TopicPrefix string
+}
+
+func NewAvroOptions() *AvroOptions {
+	return &AvroOptions{}
+}
+
+/*
+* Avro record schema of the rows of a table, like the value schema of
+Debezium without the envelope
+*/
+type AvroSchema struct {
+	Type        string       `json:"type"`
+	Name        string       `json:"name"`
+	Namespace   string       `json:"namespace,omitempty"`
+	Doc         string       `json:"doc,omitempty"`
+	Fields      []*AvroField `json:"fields"`
+	ConnectName string       `json:"connect.name,omitempty"`
+}
+
+type AvroField struct {
+	Name string `json:"name"`
+	/** primitive type name, named type or schema object, or a union */
+	Type interface{} `json:"type"`
+	Doc  string      `json:"doc,omitempty"`
+	/** JSON of the default value, empty if none */
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+/*
+* Sanitize a name for Avro, invalid characters become underscores as with
+field.name.adjustment.mode=avro of Debezium
+*/
+func sanitizeAvroName(name string) string {
+	if avroNameRegexp.MatchString(name) {
+		return name
+	}
+	var b strings.Builder
+	for n, r := range name {
+		switch {
+		case r == '_' || (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z'):
+			b.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if n == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+/*
+* Parse a BIT default value of SDI, e.g. b'101'
+ */
+func parseBitLiteral(value string) (uint64, bool) {
+	bits, err := strconv.ParseUint(strings.Trim(strings.TrimPrefix(value, "b"), "'"), 2, 64)
+	return bits, err == nil
+}
+
+/*
+* Get the bytes of a BIT value in little-endian order as in io.debezium.data.Bits
+ */
+func getBitBytes(bits uint64, precision uint32) []byte {
+	data := make([]byte, (precision+7)/8)
+	for n := range data {
+		data[n] = byte(bits >> (8 * n))
+	}
+	return data
+}
+
+/*
+* Get a byte string as Avro JSON, each byte is a code point
+ */
+func getAvroBytesJSON(data []byte) json.RawMessage {
+	runes := make([]rune, len(data))
+	for n, b := range data {
+		runes[n] = rune(b)
+	}
+	encoded, _ := json.Marshal(string(runes))
+	return encoded
+}
+
+/*
+* Get the unscaled value of a decimal as big-endian two's complement bytes
+@param[in]	value	decimal, e.g. "-1.5"
+@param[in]	scale	scale of the column
+@return bytes, false if the value is not a decimal
+*/
+func getDecimalBytes(value string, scale uint32) ([]byte, bool) {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	integer, fraction, _ := strings.Cut(value, ".")
+	if uint32(len(fraction)) > scale {
+		return nil, false
+	}
+	fraction += strings.Repeat("0", int(scale)-len(fraction))
+	unscaled, ok := new(big.Int).SetString(integer+fraction, 10)
+	if !ok {
+		return nil, false
+	}
+	if negative {
+		unscaled.Neg(unscaled)
+	}
+	size := unscaled.BitLen()/8 + 1
+	if unscaled.Sign() >= 0 {
+		data := unscaled.FillBytes(make([]byte, size))
+		return data, true
+	}
+	/* two's complement of a negative value */
+	modulus := new(big.Int).Lsh(big.NewInt(1), uint(8*size))
+	return new(big.Int).Add(modulus, unscaled).FillBytes(make([]byte, size)), true
+}
+
+/*
+* Parse the time of a TIME default value
+@return microseconds, false if the value is not a time
+*/
+func parseTimeMicros(value string) (int64, bool) {
+	negative := strings.HasPrefix(value, "-")
+	parts := strings.Split(strings.TrimPrefix(value, "-"), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	hours, err1 := strconv.ParseInt(parts[0], 10, 64)
+	minutes, err2 := strconv.ParseInt(parts[1], 10, 64)
+	seconds, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, false
+	}
+	micros := (hours*3600+minutes*60)*1000000 + int64(seconds*1000000+0.5)
+	if negative {
+		micros = -micros
+	}
+	return micros, true
+}
+
+/*
+* State of generating the Avro schema of one table
+ */
+type avroGenerator struct {
+	namespace string
+	/** full names of the named types already defined */
+	defined map[string]bool
+}
+
+/*
+* Get a schema object with a Debezium semantic type
+ */
+func getAvroConnectType(avroType, connectName string, parameters map[string]string) map[string]interface{} {
+	schema := map[string]interface{}{"type": avroType, AVRO_CONNECT_NAME: connectName}
+	if len(parameters) != 0 {
+		schema["connect.parameters"] = parameters
+	}
+	return schema
+}
+
+/*
+* Get the Avro type of a column, without nullability
+ */
+func (g *avroGenerator) getColumnType(c *Column) interface{} {
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24:
+		return "int"
+	case CT_LONG:
+		if c.IsUnsigned {
+			return "long"
+		}
+		return "int"
+	case CT_LONGLONG:
+		return "long"
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		return map[string]interface{}{
+			"type":        "bytes",
+			"logicalType": "decimal",
+			"precision":   c.NumericPrecision,
+			"scale":       c.NumericScale,
+		}
+	case CT_FLOAT:
+		return "float"
+	case CT_DOUBLE:
+		return "double"
+	case CT_BIT:
+		if c.NumericPrecision == 1 {
+			return "boolean"
+		}
+		return getAvroConnectType("bytes", "io.debezium.data.Bits",
+			map[string]string{"length": fmt.Sprint(c.NumericPrecision)})
+	case CT_YEAR:
+		return getAvroConnectType("int", "io.debezium.time.Year", nil)
+	case CT_DATE, CT_NEWDATE:
+		return map[string]interface{}{"type": "int", "logicalType": "date"}
+	case CT_TIME, CT_TIME2:
+		return map[string]interface{}{"type": "long", "logicalType": "time-micros"}
+	case CT_DATETIME, CT_DATETIME2:
+		if c.DatetimePrecision <= 3 {
+			return map[string]interface{}{"type": "long", "logicalType": "timestamp-millis"}
+		}
+		return map[string]interface{}{"type": "long", "logicalType": "timestamp-micros"}
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		return getAvroConnectType("string", "io.debezium.time.ZonedTimestamp", nil)
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return "bytes"
+		}
+		return "string"
+	case CT_ENUM:
+		for _, element := range c.Elements {
+			if !avroNameRegexp.MatchString(element) {
+				return getAvroConnectType("string", "io.debezium.data.Enum",
+					map[string]string{"allowed": strings.Join(c.Elements, ",")})
+			}
+		}
+		name := sanitizeAvroName(c.Name)
+		return map[string]interface{}{"type": "enum", "name": name, "symbols": c.Elements}
+	case CT_SET:
+		return getAvroConnectType("string", "io.debezium.data.EnumSet",
+			map[string]string{"allowed": strings.Join(c.Elements, ",")})
+	case CT_JSON:
+		return getAvroConnectType("string", "io.debezium.data.Json", nil)
+	case CT_GEOMETRY:
+		fullName := AVRO_GEOMETRY_NAMESPACE + "." + AVRO_GEOMETRY_NAME
+		if g.defined[fullName] {
+			return fullName
+		}
+		g.defined[fullName] = true
+		return map[string]interface{}{
+			"type":      "record",
+			"name":      AVRO_GEOMETRY_NAME,
+			"namespace": AVRO_GEOMETRY_NAMESPACE,
+			"fields": []*AvroField{
+				{Name: "wkb", Type: "bytes"},
+				{Name: "srid", Type: []interface{}{"null", "int"}, Default: json.RawMessage("null")},
+			},
+			AVRO_CONNECT_NAME: fullName,
+		}
+	}
+	return "string"
+}
+
+/*
+* Get the Avro JSON of the constant default value of a column
+@return JSON, nil if the column has no constant default or it is not
+converted
+*/
+func (g *avroGenerator) getDefault(c *Column) json.RawMessage {
+	value, ok := c.getLiteralDefault()
+	if !ok {
+		return nil
+	}
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG, CT_FLOAT, CT_DOUBLE, CT_YEAR:
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		if data, ok := getDecimalBytes(value, c.NumericScale); ok {
+			return getAvroBytesJSON(data)
+		}
+	case CT_BIT:
+		if bits, ok := parseBitLiteral(value); ok {
+			if c.NumericPrecision == 1 {
+				return json.RawMessage(fmt.Sprint(bits != 0))
+			}
+			return getAvroBytesJSON(getBitBytes(bits, c.NumericPrecision))
+		}
+	case CT_DATE, CT_NEWDATE:
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			return json.RawMessage(fmt.Sprint(t.Unix() / 86400))
+		}
+	case CT_TIME, CT_TIME2:
+		if micros, ok := parseTimeMicros(value); ok {
+			return json.RawMessage(fmt.Sprint(micros))
+		}
+	case CT_DATETIME, CT_DATETIME2:
+		if t, err := time.Parse("2006-01-02 15:04:05.999999", value); err == nil {
+			if c.DatetimePrecision <= 3 {
+				return json.RawMessage(fmt.Sprint(t.UnixMilli()))
+			}
+			return json.RawMessage(fmt.Sprint(t.UnixMicro()))
+		}
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return getAvroBytesJSON([]byte(value))
+		}
+		data, _ := json.Marshal(value)
+		return data
+	case CT_ENUM, CT_SET:
+		data, _ := json.Marshal(value)
+		return data
+	}
+	return nil
+}
+
+/*
+* Get the field of a column: nullable columns are unions with null, which
+comes first if the default is NULL
+*/
+func (g *avroGenerator) getField(c *Column) *AvroField {
+	field := &AvroField{
+		Name:    sanitizeAvroName(c.Name),
+		Type:    g.getColumnType(c),
+		Doc:     c.Comment,
+		Default: g.getDefault(c),
+	}
+	if !c.IsNullable {
+		return field
+	}
+	if field.Default != nil {
+		field.Type = []interface{}{field.Type, "null"}
+		return field
+	}
+	field.Type = []interface{}{"null", field.Type}
+	field.Default = json.RawMessage("null")
+	return field
+}
+
+/*
+* Generate the Avro record schema of the rows of a table with the logical
+types of Debezium: decimal, date, time-micros, timestamp-millis or
+timestamp-micros by the precision of DATETIME, and the Debezium semantic
+types of TIMESTAMP, YEAR, BIT, SET and JSON. ENUM columns are Avro enums if
+their values are valid Avro names. Defaults are converted if they are
+constants.
+@param[in]	table	table schema
+@param[in]	opts	options, default NewAvroOptions
+@return record schema
+*/
+func GenerateAvroSchema(table *TableSchema, opts *AvroOptions) *AvroSchema {
+	if opts == nil {
+		opts = NewAvroOptions()
+	}
+	parts := make([]string, 0, 3)
+	if opts.TopicPrefix != "" {
+		parts = append(parts, opts.TopicPrefix)
+	}
+	parts = append(parts, sanitizeAvroName(table.SchemaName), sanitizeAvroName(table.Name))
+	g := &avroGenerator{
+		namespace: strings.Join(parts, "."),
+		defined:   make(map[string]bool),
+	}
+	schema := &AvroSchema{
+		Type:        "record",
+		Name:        "Value",
+		Namespace:   g.namespace,
+		Doc:         table.Comment,
+		Fields:      make([]*AvroField, 0),
+		ConnectName: g.namespace + ".Value",
+	}
+	for _, column := range table.GetUserColumns() {
+		schema.Fields = append(schema.Fields, g.getField(column))
+	}
+	return schema
+}
+
+/*
+* Renderer of a JSON array of the Avro record schemas of the tables
+ */
+type AvroRenderer struct {
+	/** Avro options, default NewAvroOptions */
+	Options *AvroOptions
+}
+
+func (r *AvroRenderer) Name() string {
+	return "avro"
+}
+
+func (r *AvroRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	schemas := make([]*AvroSchema, len(tables))
+	for n, table := range tables {
+		schemas[n] = GenerateAvroSchema(table, r.Options)
+	}
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
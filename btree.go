@@ -0,0 +1,147 @@
+package ibd2schema
+
+import (
+	"fmt"
+)
+
+/*
+* Fetch a B-tree page and check that it belongs to the index.
+@param[in]	pageNum	page number
+@param[in]	indexID	id of the index
+@return index page
+*/
+func (ts *TableSpace) FetchIndexPage(pageNum uint32, indexID uint64) (page *Page, err error) {
+	if ts.PageSize.IsCompressed {
+		return nil, fmt.Errorf("reading records of compressed tablespace is not supported")
+	}
+	page, err = ts.FetchPage(pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("fetch index page failed, err:%v", err)
+	}
+	page.GetPageType()
+	if page.PageType != FIL_PAGE_INDEX {
+		return nil, fmt.Errorf("page %d type %d is not FIL_PAGE_INDEX", pageNum, page.PageType)
+	}
+	page.GetIndexID()
+	if page.IndexID != indexID {
+		return nil, fmt.Errorf("page %d belongs to index id %d, expected %d",
+			pageNum, page.IndexID, indexID)
+	}
+	page.GetPageLevel()
+	page.GetNextPageNum()
+	page.GetPrevPageNum()
+	page.GetNRecs()
+	return page, nil
+}
+
+/*
+* Get the next record in the record list of a page.
+@param[in]	recOffset	offset of the current record
+@return offset of the next record, PAGE_NEW_SUPREMUM at the end of the list
+*/
+func (p *Page) GetNextRec(recOffset uint32) (nextRecOffset uint32, err error) {
+	next, err := p.RecGetNextOffs(uint16(recOffset))
+	if err != nil {
+		return 0, err
+	}
+	if next == 0 {
+		return 0, fmt.Errorf("record list of page %d ends before supremum", p.PageNum)
+	}
+	if uint32(next) < PAGE_NEW_SUPREMUM || uint32(next) >= p.Logical-PAGE_DIR {
+		return 0, fmt.Errorf("next record offset %d out of page %d", next, p.PageNum)
+	}
+	return uint32(next), nil
+}
+
+/*
+* Descend from the root page to the leftmost page of a B-tree level.
+@param[in]	index		index to descend
+@param[in]	columnCache	columns of the table
+@param[in]	level		level to reach, 0 for the leaf level
+@return leftmost page of the level
+*/
+func (ts *TableSpace) FetchLeftmostPage(index *Index, columnCache ColumnCache, level uint16) (
+	page *Page, err error) {
+	if index.RootPageNum == FIL_NULL {
+		return nil, fmt.Errorf("root page of index %s not found", index.Name)
+	}
+	fields, err := index.GetFields(columnCache)
+	if err != nil {
+		return nil, err
+	}
+	nUniq, err := index.GetNUniqInTree(columnCache)
+	if err != nil {
+		return nil, err
+	}
+	page, err = ts.FetchIndexPage(index.RootPageNum, index.ID)
+	if err != nil {
+		return nil, err
+	}
+	if page.PageLevel < level {
+		return nil, fmt.Errorf("index %s has no level %d", index.Name, level)
+	}
+	for page.PageLevel > level {
+		recOffset, err := page.GetNextRec(PAGE_NEW_INFIMUM)
+		if err != nil {
+			return nil, err
+		}
+		if recOffset == PAGE_NEW_SUPREMUM {
+			return nil, fmt.Errorf("non-leaf page %d of index %s is empty", page.PageNum, index.Name)
+		}
+		rec, err := page.ParseRecord(recOffset, fields, nUniq)
+		if err != nil {
+			return nil, err
+		}
+		if rec.Status != REC_STATUS_NODE_PTR {
+			return nil, fmt.Errorf("record at %d of non-leaf page %d is not a node pointer",
+				recOffset, page.PageNum)
+		}
+		parentLevel := page.PageLevel
+		page, err = ts.FetchIndexPage(rec.ChildPageNum, index.ID)
+		if err != nil {
+			return nil, err
+		}
+		if page.PageLevel+1 != parentLevel {
+			return nil, fmt.Errorf("page level not match, parentLevel:%d, childLevel:%d",
+				parentLevel, page.PageLevel)
+		}
+	}
+	if page.PrevPageNum != FIL_NULL {
+		return nil, fmt.Errorf("leftmost page %d of index %s has previous page %d",
+			page.PageNum, index.Name, page.PrevPageNum)
+	}
+	return page, nil
+}
+
+/*
+* Walk all pages of a B-tree level from left to right.
+@param[in]	index		index to walk
+@param[in]	columnCache	columns of the table
+@param[in]	level		level to walk, 0 for the leaf level
+@param[in]	fn		called for each page, stop walking if it returns false
+*/
+func (ts *TableSpace) WalkLevel(index *Index, columnCache ColumnCache, level uint16,
+	fn func(page *Page) (bool, error)) (err error) {
+	page, err := ts.FetchLeftmostPage(index, columnCache, level)
+	if err != nil {
+		return err
+	}
+	for {
+		next, err := fn(page)
+		if err != nil {
+			return err
+		}
+		if !next || page.NextPageNum == FIL_NULL {
+			return nil
+		}
+		prevPageNum := page.PageNum
+		page, err = ts.FetchIndexPage(page.NextPageNum, index.ID)
+		if err != nil {
+			return err
+		}
+		if page.PageLevel != level || page.PrevPageNum != prevPageNum {
+			return fmt.Errorf("page %d is not linked to page %d on level %d",
+				page.PageNum, prevPageNum, level)
+		}
+	}
+}
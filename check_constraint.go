@@ -0,0 +1,70 @@
+package ibd2schema
+
+import (
+	"fmt"
+
+	"github.com/tidwall/gjson"
+)
+
+var CheckConstraintMembers = []string{
+	`name`,
+	`state`,
+	`check_clause_utf8`,
+}
+
+type CheckConstraint struct {
+	Name            string
+	State           CheckConstraintState
+	CheckClauseUTF8 string
+	GJson           gjson.Result
+}
+
+func NewCheckConstraint(cc gjson.Result) *CheckConstraint {
+	return &CheckConstraint{
+		Name:            cc.Get(`name`).String(),
+		State:           CheckConstraintState(cc.Get(`state`).Int()),
+		CheckClauseUTF8: cc.Get(`check_clause_utf8`).String(),
+		GJson:           cc,
+	}
+}
+
+func CheckCheckConstraintMembers(cc gjson.Result) error {
+	if !cc.IsObject() {
+		return fmt.Errorf("check constraint is not an object")
+	}
+	for _, member := range CheckConstraintMembers {
+		if err := CheckMember(cc, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+* Parse the check constraints section of SDI JSON, which is missing before
+MySQL 8.0.16
+@param[in]	ddObject	Data Dictionary JSON object
+@return parsed check constraints
+*/
+func ParseCheckConstraints(ddObject gjson.Result) (ccList []*CheckConstraint, err error) {
+	ccList = make([]*CheckConstraint, 0)
+	for _, cc := range ddObject.Get(`check_constraints`).Array() {
+		err = CheckCheckConstraintMembers(cc)
+		if err != nil {
+			return nil, err
+		}
+		ccList = append(ccList, NewCheckConstraint(cc))
+	}
+	return ccList, nil
+}
+
+/*
+* Get the definition of the check constraint as in CREATE TABLE
+ */
+func (cc *CheckConstraint) GetDefinition() string {
+	definition := fmt.Sprintf("CONSTRAINT %s CHECK (%s)", quoteMySQLIdentifier(cc.Name), cc.CheckClauseUTF8)
+	if cc.State == CC_NOT_ENFORCED {
+		definition += " /*!80016 NOT ENFORCED */"
+	}
+	return definition
+}
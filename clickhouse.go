@@ -0,0 +1,294 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type ClickHouseOptions struct {
+	/** the tables are created in databases named like the MySQL schemas if
+	IncludeSchemaName is set, else in the current database */
+	RenderOptions
+	/** table engine, e.g. ReplacingMergeTree(version) for change data capture */
+	Engine string
+	/** use LowCardinality(String) for ENUM columns, else Enum8 or Enum16 with
+	the MySQL enum values */
+	LowCardinalityEnums bool
+	/** wrap the types of nullable columns in Nullable */
+	Nullable bool
+}
+
+func NewClickHouseOptions() *ClickHouseOptions {
+	return &ClickHouseOptions{
+		RenderOptions:       *NewRenderOptions(),
+		Engine:              "MergeTree",
+		LowCardinalityEnums: true,
+		Nullable:            true,
+	}
+}
+
+/*
+* State of rendering one table for ClickHouse
+ */
+type clickhouseRenderer struct {
+	table  *TableSchema
+	opts   *ClickHouseOptions
+	result *WarehouseTable
+}
+
+func (r *clickhouseRenderer) qualify(schemaName, name string) string {
+	if r.opts.IncludeSchemaName && schemaName != "" {
+		return quoteBacktickIdentifier(schemaName) + "." + quoteBacktickIdentifier(name)
+	}
+	return quoteBacktickIdentifier(name)
+}
+
+/*
+* Get the ENUM type of the values of an ENUM column, e.g. Enum8('a' = 1)
+ */
+func (r *clickhouseRenderer) getEnumType(c *Column) string {
+	values := make([]string, len(c.Elements))
+	for n, element := range c.Elements {
+		values[n] = fmt.Sprintf("%s = %d", quoteBackslashString(element), n+1)
+	}
+	if len(values) > 127 {
+		return fmt.Sprintf("Enum16(%s)", strings.Join(values, ", "))
+	}
+	return fmt.Sprintf("Enum8(%s)", strings.Join(values, ", "))
+}
+
+/*
+* Get the ClickHouse type of a column without the Nullable wrapper
+ */
+func (r *clickhouseRenderer) getColumnType(c *Column) string {
+	integer := func(bits int) string {
+		if c.IsUnsigned {
+			return fmt.Sprintf("UInt%d", bits)
+		}
+		return fmt.Sprintf("Int%d", bits)
+	}
+	switch c.Type {
+	case CT_TINY:
+		return integer(8)
+	case CT_SHORT:
+		return integer(16)
+	case CT_INT24, CT_LONG:
+		return integer(32)
+	case CT_LONGLONG:
+		return integer(64)
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		return fmt.Sprintf("Decimal(%d, %d)", c.NumericPrecision, c.NumericScale)
+	case CT_FLOAT:
+		return "Float32"
+	case CT_DOUBLE:
+		return "Float64"
+	case CT_BIT:
+		return "UInt64"
+	case CT_YEAR:
+		return "UInt16"
+	case CT_DATE, CT_NEWDATE:
+		return "Date32"
+	case CT_TIME, CT_TIME2:
+		r.result.warn("time column %s is String", c.Name)
+		return "String"
+	case CT_DATETIME, CT_DATETIME2:
+		return fmt.Sprintf("DateTime64(%d)", c.DatetimePrecision)
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		if c.DatetimePrecision == 0 {
+			return "DateTime('UTC')"
+		}
+		return fmt.Sprintf("DateTime64(%d, 'UTC')", c.DatetimePrecision)
+	case CT_STRING:
+		if !c.isText() {
+			return fmt.Sprintf("FixedString(%d)", c.Size)
+		}
+		return "String"
+	case CT_VARCHAR, CT_VAR_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB, CT_JSON, CT_SET:
+		return "String"
+	case CT_ENUM:
+		if r.opts.LowCardinalityEnums {
+			return "LowCardinality(String)"
+		}
+		return r.getEnumType(c)
+	case CT_GEOMETRY:
+		r.result.warn("geometry column %s is String of the MySQL internal format", c.Name)
+		return "String"
+	}
+	r.result.warn("column %s of type %s is String", c.Name, c.ColumnTypeUTF8)
+	return "String"
+}
+
+/*
+* Get the column definition of CREATE TABLE
+ */
+func (r *clickhouseRenderer) getColumnDefinition(c *Column) string {
+	columnType := r.getColumnType(c)
+	if c.IsNullable {
+		switch {
+		case !r.opts.Nullable:
+			r.result.warn("NULL values of column %s become the default value of its type", c.Name)
+		case strings.HasPrefix(columnType, "LowCardinality("):
+			columnType = "LowCardinality(Nullable(String))"
+		default:
+			columnType = fmt.Sprintf("Nullable(%s)", columnType)
+		}
+	}
+	if c.GenerationExpressionUTF8 != "" {
+		r.result.warn("generated column %s is a plain column", c.Name)
+	}
+	definition := quoteBacktickIdentifier(c.Name) + " " + columnType
+	definition += getWarehouseDefault(r.result, c, func(precision uint32) string {
+		if precision == 0 {
+			return "now()"
+		}
+		return fmt.Sprintf("now64(%d)", precision)
+	})
+	if c.Comment != "" {
+		definition += " COMMENT " + quoteBackslashString(c.Comment)
+	}
+	return definition
+}
+
+/*
+* Get the sorting key from the primary key, else from the first unique index
+of NOT NULL columns, which InnoDB clusters the rows by
+*/
+func (r *clickhouseRenderer) getOrderBy() string {
+	var key *Index
+	for _, index := range r.table.GetUserIndexes() {
+		if index.Type == IT_UNIQUE && key == nil {
+			usable := true
+			for _, keyPart := range index.KeyParts {
+				if keyPart.GetExpression() != "" || keyPart.Column.IsNullable {
+					usable = false
+				}
+			}
+			if usable {
+				key = index
+			}
+		}
+		if index.Type == IT_PRIMARY {
+			key = index
+			break
+		}
+	}
+	for _, index := range r.table.GetUserIndexes() {
+		if index.Type == IT_UNIQUE && index != key {
+			r.result.warn("unique index %s is not enforced", index.Name)
+		}
+	}
+	if key == nil {
+		r.result.warn("table has no primary key, the rows are not sorted")
+		return "tuple()"
+	}
+	names := make([]string, len(key.KeyParts))
+	for n, keyPart := range key.KeyParts {
+		names[n] = quoteBacktickIdentifier(keyPart.Column.Name)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return fmt.Sprintf("(%s)", strings.Join(names, ", "))
+}
+
+/*
+* Render a table for ClickHouse: a MergeTree table sorted by the primary
+key, with Nullable types for nullable columns. Secondary indexes,
+constraints, partitioning and auto increment are dropped, the lossy
+translations are listed in the warnings.
+@param[in]	table	table schema
+@param[in]	opts	options, default NewClickHouseOptions
+@return statements of the table
+*/
+func RenderClickHouse(table *TableSchema, opts *ClickHouseOptions) *WarehouseTable {
+	if opts == nil {
+		opts = NewClickHouseOptions()
+	}
+	r := &clickhouseRenderer{
+		table: table,
+		opts:  opts,
+		result: &WarehouseTable{
+			SchemaName: table.SchemaName,
+			Name:       table.Name,
+			Statements: make([]string, 0),
+		},
+	}
+	definitions := make([]string, 0)
+	for _, column := range table.GetUserColumns() {
+		definitions = append(definitions, r.getColumnDefinition(column))
+	}
+	orderBy := r.getOrderBy()
+	if len(table.ForeignKeys) != 0 {
+		r.result.warn("foreign keys are dropped")
+	}
+	for _, check := range table.CheckConstraints {
+		r.result.warn("check constraint %s is dropped", check.Name)
+	}
+	if table.Partitioning != nil {
+		r.result.warn("partitioning is not translated, the table is not partitioned")
+	}
+	createTable := fmt.Sprintf("CREATE TABLE %s%s\n(\n  %s\n)\nENGINE = %s\nORDER BY %s",
+		opts.getIfNotExists(), r.qualify(table.SchemaName, table.Name), strings.Join(definitions, ",\n  "),
+		opts.Engine, orderBy)
+	if table.Comment != "" {
+		createTable += "\nCOMMENT " + quoteBackslashString(table.Comment)
+	}
+	r.result.Statements = append(r.result.Statements, createTable)
+	return r.result
+}
+
+/*
+* Render tables as a ClickHouse script: the databases and then the tables
+@param[in]	tables	table schemas
+@param[in]	opts	options, default NewClickHouseOptions
+@return script
+*/
+func RenderClickHouseScript(tables []*TableSchema, opts *ClickHouseOptions) string {
+	if opts == nil {
+		opts = NewClickHouseOptions()
+	}
+	var b strings.Builder
+	if opts.CreateSchemas && opts.IncludeSchemaName {
+		for _, name := range getSchemaNames(tables) {
+			fmt.Fprintf(&b, "CREATE DATABASE IF NOT EXISTS %s%s\n", quoteBacktickIdentifier(name), opts.Terminator)
+		}
+		b.WriteString("\n")
+	}
+	for _, table := range tables {
+		chTable := RenderClickHouse(table, opts)
+		for _, warning := range chTable.Warnings {
+			fmt.Fprintf(&b, "-- WARNING: %s\n", warning)
+		}
+		for _, statement := range chTable.Statements {
+			b.WriteString(statement + opts.Terminator + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+/*
+* Renderer of ClickHouse statements, see RenderClickHouseScript
+ */
+type ClickHouseRenderer struct {
+	/** ClickHouse options, default NewClickHouseOptions, the shared options
+	are replaced by those passed to Render */
+	Options *ClickHouseOptions
+}
+
+func (r *ClickHouseRenderer) Name() string {
+	return "clickhouse"
+}
+
+func (r *ClickHouseRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	chOpts := NewClickHouseOptions()
+	if r.Options != nil {
+		*chOpts = *r.Options
+	}
+	if opts != nil {
+		chOpts.RenderOptions = *opts
+	}
+	_, err := io.WriteString(w, RenderClickHouseScript(tables, chOpts))
+	return err
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/tidwall/pretty"
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runAnonymize(args []string) error {
+	opts := ibd2schema.NewAnonymizeOptions()
+	flags := flag.NewFlagSet("anonymize", flag.ContinueOnError)
+	mappingPath := flags.String("mapping", "", "JSON file of the name mapping, read if it exists and written with the new names")
+	mode := flags.String("mode", string(ibd2schema.ANONYMIZE_HASH), "new names of a new mapping: hash or sequential")
+	salt := flags.String("salt", "", "secret key of hashed names of a new mapping, random by default")
+	format := flags.String("format", "sql", "output format: sql for CREATE statements, sdi for SDI JSON")
+	reverse := flags.Bool("reverse", false, "rename anonymized SDIs back to the names of -mapping")
+	flags.BoolVar(&opts.KeepSchemaNames, "keep-schema-names", false, "do not rename schemas")
+	flags.BoolVar(&opts.KeepComments, "keep-comments", false, "do not strip comments")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: anonymize -mapping file [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *mappingPath == "" || flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected -mapping and at least 1 path")
+	}
+	var mapping *ibd2schema.AnonymizeMapping
+	data, err := os.ReadFile(*mappingPath)
+	switch {
+	case err == nil:
+		if mapping, err = ibd2schema.ParseAnonymizeMapping(data); err != nil {
+			return err
+		}
+	case errors.Is(err, fs.ErrNotExist) && !*reverse:
+		if mapping, err = ibd2schema.NewAnonymizeMapping(ibd2schema.AnonymizeMode(*mode), *salt); err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+	anonymizer := ibd2schema.NewAnonymizer(opts, mapping)
+	if *reverse {
+		if anonymizer, err = ibd2schema.NewReverseAnonymizer(mapping); err != nil {
+			return err
+		}
+	}
+	sdis := make([]*ibd2schema.SDI, 0)
+	for _, path := range flags.Args() {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return err
+		} else if info.IsDir() {
+			if files, err = ibd2schema.ListTablespaceFiles(path); err != nil {
+				return err
+			}
+		}
+		for _, file := range files {
+			fileSDIs, err := loadSDIs(file)
+			if err != nil {
+				return err
+			}
+			for _, sdi := range fileSDIs {
+				anonymized, err := anonymizer.AnonymizeSDI(sdi)
+				if err != nil {
+					return fmt.Errorf("anonymize %s failed, err:%v", file, err)
+				}
+				sdis = append(sdis, anonymized)
+			}
+		}
+	}
+	switch *format {
+	case "sql":
+		tables := make([]*ibd2schema.TableSchema, 0)
+		for _, sdi := range sdis {
+			if sdi.TableSchema != nil {
+				tables = append(tables, sdi.TableSchema)
+			}
+		}
+		fmt.Print(ibd2schema.NewFKGraph(tables).GenerateRestoreScript(ibd2schema.NewRestoreOptions()))
+	case "sdi":
+		results := [][]byte{[]byte(`["ibd2sdi"`)}
+		for _, sdi := range sdis {
+			results = append(results, sdi.DumpJson())
+		}
+		fmt.Println(string(pretty.Pretty(append(bytes.Join(results, []byte(",")), ']'))))
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	if *reverse {
+		return nil
+	}
+	if data, err = mapping.DumpJson(); err != nil {
+		return err
+	}
+	return os.WriteFile(*mappingPath, data, 0600)
+}
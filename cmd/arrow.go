@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runArrow(args []string) error {
+	opts := ibd2schema.NewArrowOptions()
+	flags := flag.NewFlagSet("arrow", flag.ContinueOnError)
+	out := flags.String("o", "", "file to write to, else stdout")
+	flags.BoolVar(&opts.Stream, "stream", false, "write the IPC streaming format instead of the file format")
+	flags.Int64Var(&opts.BatchRows, "batch-rows", ibd2schema.ARROW_DEFAULT_BATCH_ROWS, "number of rows of a record batch")
+	flags.BoolVar(&opts.ZeroDateAsNull, "zero-date-null", false, "write zero or invalid dates as NULL")
+	columns := flags.String("columns", "", "comma separated names of the columns to export, else all")
+	schemaOnly := flags.Bool("schema", false, "print the Arrow schema instead of exporting the rows")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: arrow [options] <ibd>\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected 1 ibd file")
+	}
+	if *columns != "" {
+		opts.Scan = &ibd2schema.ScanOptions{Columns: strings.Split(*columns, ",")}
+	}
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	ts, err := ibd2schema.NewTableSpace(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("open tablespace %s failed, err:%v", flags.Arg(0), err)
+	}
+	if err = ts.DumpSchemas(); err != nil {
+		return fmt.Errorf("dump schemas of %s failed, err:%v", flags.Arg(0), err)
+	}
+	for _, tableSchema := range ts.TableSchemas {
+		if *schemaOnly {
+			it, err := ts.Scan(context.Background(), tableSchema, opts.Scan)
+			if err != nil {
+				return err
+			}
+			defer it.Close()
+			sc, err := ibd2schema.NewArrowSchema(it.Columns(), opts, nil)
+			if err != nil {
+				return err
+			}
+			fmt.Println(sc)
+			return nil
+		}
+		var w io.Writer = os.Stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		rows, err := ts.ExportArrow(context.Background(), tableSchema, w, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "exported %d rows of %s.%s\n", rows, tableSchema.SchemaName, tableSchema.Name)
+		return nil
+	}
+	return fmt.Errorf("no table in %s", flags.Arg(0))
+}
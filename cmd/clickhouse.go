@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runClickHouse(args []string) error {
+	opts := ibd2schema.NewClickHouseOptions()
+	flags := flag.NewFlagSet("clickhouse", flag.ContinueOnError)
+	flags.StringVar(&opts.Engine, "engine", opts.Engine, "table engine, e.g. ReplacingMergeTree(version)")
+	noDatabases := flags.Bool("no-databases", false, "create the tables in the current database instead of databases named like the MySQL schemas")
+	enums := flags.Bool("enums", false, "use Enum8 or Enum16 for ENUM columns instead of LowCardinality(String)")
+	noNullable := flags.Bool("no-nullable", false, "do not wrap the types of nullable columns in Nullable")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: clickhouse [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	opts.IncludeSchemaName = !*noDatabases
+	opts.CreateSchemas = !*noDatabases
+	opts.LowCardinalityEnums = !*enums
+	opts.Nullable = !*noNullable
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	fmt.Print(ibd2schema.RenderClickHouseScript(tables, opts))
+	return nil
+}
@@ -8,8 +8,48 @@ import (
 	ibd2schema "github.com/zing22845/go-ibd2schema"
 )
 
+/*
+* Subcommands by name, the arguments exclude the subcommand name
+ */
+var commands = map[string]func(args []string) error{
+	"diff":        runDiff,
+	"migrate":     runMigrate,
+	"compare":     runCompare,
+	"fingerprint": runFingerprint,
+	"drift":       runDrift,
+	"fkgraph":     runFKGraph,
+	"history":     runHistory,
+	"lint":        runLint,
+	"redundant":   runRedundant,
+	"anonymize":   runAnonymize,
+	"postgres":    runPostgres,
+	"clickhouse":  runClickHouse,
+	"warehouse":   runWarehouse,
+	"render":      runRender,
+	"eventschema": runEventSchema,
+	"codegen":     runCodegen,
+	"arrow":       runArrow,
+	"dump":        runDump,
+	"docs":        runDocs,
+	"json":        runJSON,
+}
+
 func main() {
-	filePath := os.Args[1]
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s <ibd file> | <command> [arguments]\n", os.Args[0])
+		os.Exit(2)
+	}
+	if run, ok := commands[os.Args[1]]; ok {
+		if err := run(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+	dumpFile(os.Args[1])
+}
+
+func dumpFile(filePath string) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runCodegen(args []string) error {
+	goOpts := ibd2schema.NewGoStructOptions()
+	protoOpts := ibd2schema.NewProtoOptions()
+	flags := flag.NewFlagSet("codegen", flag.ContinueOnError)
+	lang := flags.String("lang", "go", "language: go or proto")
+	pkg := flags.String("package", "models", "package of the generated file")
+	nullMode := flags.String("nullable", string(ibd2schema.GO_NULL_SQL), "Go types of nullable columns: sql or pointer")
+	noJSON := flags.Bool("no-json-tags", false, "only add db tags to the Go struct fields")
+	flags.StringVar(&protoOpts.GoPackage, "go-package", "", "go_package option of the proto file")
+	out := flags.String("o", "", "file to write to, else print")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: codegen [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	goOpts.NullMode = ibd2schema.GoNullMode(*nullMode)
+	if goOpts.NullMode != ibd2schema.GO_NULL_SQL && goOpts.NullMode != ibd2schema.GO_NULL_POINTER {
+		return fmt.Errorf("unknown nullable mode %s", *nullMode)
+	}
+	goOpts.Package = *pkg
+	goOpts.JSONTags = !*noJSON
+	protoOpts.Package = *pkg
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	var source []byte
+	switch *lang {
+	case "go":
+		source, err = ibd2schema.GenerateGoStructs(tables, goOpts)
+		if err != nil {
+			return err
+		}
+	case "proto":
+		source = ibd2schema.GenerateProto(tables, protoOpts)
+	default:
+		return fmt.Errorf("unknown language %s", *lang)
+	}
+	if *out == "" {
+		fmt.Print(string(source))
+		return nil
+	}
+	return os.WriteFile(*out, source, 0644)
+}
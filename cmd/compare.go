@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runCompare(args []string) error {
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	schema := flags.String("schema", "", "schema of unqualified tables in the SQL file, default the schema of the .ibd tables")
+	collation := flags.String("collation", ibd2schema.DDL_DEFAULT_COLLATION, "collation of tables without CHARSET or COLLATE")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: compare [-schema name] [-collation name] [-format text|json] <ibd or sdi> <sql file>\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected 2 files, got %d", flags.NArg())
+	}
+	actualTables, err := loadTableSchemas(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	script, err := os.ReadFile(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	opts := ibd2schema.NewDDLParseOptions()
+	opts.SchemaName = *schema
+	opts.DefaultCollation = *collation
+	if opts.SchemaName == "" && len(actualTables) > 0 {
+		opts.SchemaName = actualTables[0].SchemaName
+	}
+	expectedTables, err := ibd2schema.ParseDDLScript(string(script), opts)
+	if err != nil {
+		return fmt.Errorf("parse %s failed, err:%v", flags.Arg(1), err)
+	}
+	diffs := make([]*ibd2schema.TableDiff, 0)
+	for _, diff := range ibd2schema.DiffTableSchemaLists(expectedTables, actualTables) {
+		if diff.HasChanges() {
+			diffs = append(diffs, diff)
+		}
+	}
+	switch *format {
+	case "json":
+		data, err := ibd2schema.DumpTableDiffsJson(diffs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		if len(diffs) == 0 {
+			fmt.Println("equivalent")
+		}
+		for _, diff := range diffs {
+			fmt.Print(diff.String())
+		}
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	if len(diffs) != 0 {
+		return fmt.Errorf("%d tables differ", len(diffs))
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+/*
+* Load the SDIs of an .ibd file or of an SDI JSON document as written by
+ibd2sdi or by this tool, with their table schemas.
+*/
+func loadSDIs(filePath string) ([]*ibd2schema.SDI, error) {
+	return loadSDIsWithIndexStats(filePath, false)
+}
+
+/*
+* Load the SDIs like loadSDIs, with the index statistics of the table schemas
+of an .ibd file if indexStats is set.
+*/
+func loadSDIsWithIndexStats(filePath string, indexStats bool) ([]*ibd2schema.SDI, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	if first := peekFirstNonSpace(reader); first == '[' || first == '{' {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("read %s failed, err:%v", filePath, err)
+		}
+		sdis, err := ibd2schema.ParseSDIDocument(bytes.TrimSpace(data))
+		if err != nil {
+			return nil, fmt.Errorf("parse SDI document %s failed, err:%v", filePath, err)
+		}
+		return sdis, nil
+	}
+	ts, err := ibd2schema.NewTableSpace(reader)
+	if err != nil {
+		return nil, fmt.Errorf("open tablespace %s failed, err:%v", filePath, err)
+	}
+	if err = ts.DumpSchemas(); err != nil {
+		return nil, fmt.Errorf("dump schemas of %s failed, err:%v", filePath, err)
+	}
+	if indexStats {
+		if err = ts.DumpIndexStats(); err != nil {
+			return nil, fmt.Errorf("dump index stats of %s failed, err:%v", filePath, err)
+		}
+	}
+	return ts.SDIs, nil
+}
+
+/*
+* Get the first byte that is not white space without consuming it, 0 if
+there is none within the buffer of the reader
+*/
+func peekFirstNonSpace(reader *bufio.Reader) byte {
+	for n := 1; ; n++ {
+		data, err := reader.Peek(n)
+		if len(data) < n || err != nil {
+			return 0
+		}
+		switch c := data[n-1]; c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return c
+		}
+	}
+}
+
+/*
+* Load the table schemas of an .ibd file or of an SDI JSON document, see
+loadSDIs.
+*/
+func loadTableSchemas(filePath string) ([]*ibd2schema.TableSchema, error) {
+	sdis, err := loadSDIs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]*ibd2schema.TableSchema, 0)
+	for _, sdi := range sdis {
+		if sdi.TableSchema != nil {
+			tables = append(tables, sdi.TableSchema)
+		}
+	}
+	return tables, nil
+}
+
+func runDiff(args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: diff [-format text|json] <old ibd or sdi> <new ibd or sdi>\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected 2 files, got %d", flags.NArg())
+	}
+	oldTables, err := loadTableSchemas(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newTables, err := loadTableSchemas(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	diffs := ibd2schema.DiffTableSchemaLists(oldTables, newTables)
+	switch *format {
+	case "json":
+		data, err := ibd2schema.DumpTableDiffsJson(diffs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		for _, diff := range diffs {
+			fmt.Print(diff.String())
+		}
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	return nil
+}
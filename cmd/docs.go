@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runDocs(args []string) error {
+	opts := ibd2schema.NewDocOptions()
+	flags := flag.NewFlagSet("docs", flag.ContinueOnError)
+	format := flags.String("format", string(ibd2schema.DOC_MARKDOWN), "document format: markdown or html")
+	diagram := flags.String("diagram", string(ibd2schema.DIAGRAM_MERMAID), "ER diagram: mermaid, graphviz or none")
+	flags.StringVar(&opts.Title, "title", opts.Title, "title of the document")
+	diagramOnly := flags.Bool("diagram-only", false, "print only the source of the ER diagram")
+	out := flags.String("o", "", "file to write to, else print")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: docs [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	opts.Format = ibd2schema.DocFormat(*format)
+	opts.Diagram = ibd2schema.DiagramFormat(*diagram)
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	var doc string
+	if *diagramOnly {
+		doc, err = ibd2schema.GenerateERDiagram(tables, opts.Diagram)
+	} else {
+		doc, err = ibd2schema.GenerateDataDictionary(tables, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Print(doc)
+		return nil
+	}
+	return os.WriteFile(*out, []byte(doc), 0644)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runDrift(args []string) error {
+	opts := ibd2schema.NewDriftOptions()
+	flags := flag.NewFlagSet("drift", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text or json")
+	flags.IntVar(&opts.Parallelism, "parallel", opts.Parallelism, "number of files parsed in parallel")
+	flags.BoolVar(&opts.IncludePhysical, "physical", false, "compare physical options like ROW_FORMAT too")
+	flags.BoolVar(&opts.IgnoreSchemaNames, "ignore-schema-names", false, "identify tables by name only")
+	flags.StringVar(&opts.TempDir, "tmpdir", "", "directory of files extracted from xbstream archives")
+	exclude := flags.String("exclude-schemas", strings.Join(opts.ExcludeSchemas, ","), "comma separated schemas to skip")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: drift [options] <datadir, backup dir, xbstream or ibd>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 2 sources, got %d", flags.NArg())
+	}
+	opts.ExcludeSchemas = nil
+	if *exclude != "" {
+		opts.ExcludeSchemas = strings.Split(*exclude, ",")
+	}
+	report := ibd2schema.ScanDrift(flags.Args(), opts)
+	switch *format {
+	case "json":
+		data, err := report.DumpJson()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		fmt.Print(report.String())
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	if len(report.Errors) != 0 {
+		return fmt.Errorf("%d files could not be scanned", len(report.Errors))
+	}
+	if report.HasDrift() {
+		return fmt.Errorf("%d tables drifted across %d source variants",
+			len(report.Drifts), len(report.SourceVariants))
+	}
+	return nil
+}
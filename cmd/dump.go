@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runDump(args []string) error {
+	opts := ibd2schema.NewDumpOptions()
+	flags := flag.NewFlagSet("dump", flag.ContinueOnError)
+	noDropTable := flags.Bool("no-drop-table", false, "do not add DROP TABLE IF EXISTS before each table")
+	noCreateDatabase := flags.Bool("no-create-db", false, "do not add CREATE DATABASE and USE statements")
+	skipDumpDate := flags.Bool("skip-dump-date", false, "do not add the date to the last line")
+	target := flags.String("target", "", "server version to restore into, "+
+		"e.g. 5.7, 8.0.13, 8.4, mariadb-10.6 or mariadb-11.4")
+	out := flags.String("o", "", "file to write to, else print")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: dump [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	opts.AddDropTable = !*noDropTable
+	opts.CreateDatabases = !*noCreateDatabase
+	opts.Source = strings.Join(flags.Args(), " ")
+	if *target != "" {
+		version, err := ibd2schema.ParseTargetVersion(*target)
+		if err != nil {
+			return err
+		}
+		opts.Target = version
+	}
+	if !*skipDumpDate {
+		opts.DumpDate = time.Now()
+	}
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	script := ibd2schema.GenerateDumpScript(tables, opts)
+	if *out == "" {
+		fmt.Print(script)
+		return nil
+	}
+	return os.WriteFile(*out, []byte(script), 0644)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runEventSchema(args []string) error {
+	opts := ibd2schema.NewAvroOptions()
+	flags := flag.NewFlagSet("eventschema", flag.ContinueOnError)
+	format := flags.String("format", "avro", "schema format: avro or jsonschema")
+	flags.StringVar(&opts.TopicPrefix, "topic-prefix", "", "topic prefix of the connector, first part of the Avro namespaces")
+	outDir := flags.String("out", "", "directory to write a file per table to, <schema>.<table>.avsc or .json, else print a JSON array")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: eventschema [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	schemas := make([]interface{}, len(tables))
+	extension := ".avsc"
+	for n, table := range tables {
+		switch *format {
+		case "avro":
+			schemas[n] = ibd2schema.GenerateAvroSchema(table, opts)
+		case "jsonschema":
+			schemas[n] = ibd2schema.GenerateJSONSchema(table)
+			extension = ".json"
+		default:
+			return fmt.Errorf("unknown format %s", *format)
+		}
+	}
+	if *outDir == "" {
+		data, err := json.MarshalIndent(schemas, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if err = os.MkdirAll(*outDir, 0755); err != nil {
+		return err
+	}
+	for n, table := range tables {
+		data, err := json.MarshalIndent(schemas[n], "", "  ")
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(*outDir, fmt.Sprintf("%s.%s%s", table.SchemaName, table.Name, extension))
+		if err = os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runFingerprint(args []string) error {
+	flags := flag.NewFlagSet("fingerprint", flag.ContinueOnError)
+	physical := flags.Bool("physical", false, "include physical options like ROW_FORMAT and KEY_BLOCK_SIZE")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: fingerprint [-physical] <ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 file")
+	}
+	for _, filePath := range flags.Args() {
+		tables, err := loadTableSchemas(filePath)
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			if table.Hidden != ibd2schema.HT_VISIBLE {
+				continue
+			}
+			fmt.Printf("%s  `%s`.`%s`  %s\n", table.GetFingerprint(*physical),
+				table.SchemaName, table.Name, filePath)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+/*
+* Load the table schemas of data directories, .ibd files and SDI documents.
+Each partition file of a partitioned table holds the schema of the whole
+table, so a table is only loaded once.
+*/
+func loadTableSchemasFromPaths(paths []string) ([]*ibd2schema.TableSchema, error) {
+	tables := make([]*ibd2schema.TableSchema, 0)
+	loaded := make(map[string]bool)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		files := []string{path}
+		if info.IsDir() {
+			if files, err = ibd2schema.ListTablespaceFiles(path); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			fileTables, err := loadTableSchemas(file)
+			if err != nil {
+				return nil, err
+			}
+			for _, table := range fileTables {
+				key := fmt.Sprintf("`%s`.`%s`", table.SchemaName, table.Name)
+				if !loaded[key] {
+					loaded[key] = true
+					tables = append(tables, table)
+				}
+			}
+		}
+	}
+	return tables, nil
+}
+
+func runFKGraph(args []string) error {
+	opts := ibd2schema.NewRestoreOptions()
+	flags := flag.NewFlagSet("fkgraph", flag.ContinueOnError)
+	format := flags.String("format", "sql", "output format: sql for the restore script, text for the graph")
+	flags.BoolVar(&opts.SplitForeignKeys, "split", false, "add all foreign keys with ALTER TABLE after the tables")
+	noCreateDatabase := flags.Bool("no-create-db", false, "do not add CREATE DATABASE statements")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: fkgraph [-format sql|text] [-split] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	opts.CreateDatabases = !*noCreateDatabase
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	graph := ibd2schema.NewFKGraph(tables)
+	switch *format {
+	case "sql":
+		fmt.Print(graph.GenerateRestoreScript(opts))
+	case "text":
+		fmt.Print(graph.String())
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	return nil
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+/*
+* Subcommands of history by name
+ */
+var historyCommands = map[string]func(catalog *ibd2schema.HistoryCatalog, args []string) error{
+	"ingest":    runHistoryIngest,
+	"snapshots": runHistorySnapshots,
+	"log":       runHistoryLog,
+	"show":      runHistoryShow,
+	"diff":      runHistoryDiff,
+}
+
+const historyUsage = `usage: history [-db catalog.db] <command> [arguments]
+  ingest -instance name [-time backup time] <datadir, ibd or sdi>...
+  snapshots [-instance name]
+  log -instance name [-all] [-physical] <schema>.<table>
+  show -instance name [-time backup time] <schema>.<table>
+  diff [-format text|json] -instance name <old backup time> <new backup time>
+Backup times are RFC 3339 times or dates, a time selects the latest backup at or before it.
+`
+
+func runHistory(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	dbPath := flags.String("db", "ibd2schema-history.db", "file of the history catalog")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), historyUsage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected a command")
+	}
+	run, ok := historyCommands[flags.Arg(0)]
+	if !ok {
+		flags.Usage()
+		return fmt.Errorf("unknown command %s", flags.Arg(0))
+	}
+	catalog, err := ibd2schema.OpenHistoryCatalog(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer catalog.Close()
+	return run(catalog, flags.Args()[1:])
+}
+
+/*
+* Parse a backup time, an RFC 3339 time or a date in UTC
+ */
+func parseBackupTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "2006-01-02" {
+				/* the backups of the whole day */
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid backup time %s", value)
+}
+
+/*
+* Parse a table name like schema.table
+ */
+func parseTableName(value string) (schemaName, name string, err error) {
+	schemaName, name, ok := strings.Cut(strings.ReplaceAll(value, "`", ""), ".")
+	if !ok {
+		return "", "", fmt.Errorf("expected <schema>.<table>, got %s", value)
+	}
+	return schemaName, name, nil
+}
+
+func formatBackupTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func runHistoryIngest(catalog *ibd2schema.HistoryCatalog, args []string) error {
+	flags := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	instance := flags.String("instance", "", "name of the instance")
+	backupTime := flags.String("time", "", "time of the backup, default now")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *instance == "" || flags.NArg() == 0 {
+		return fmt.Errorf("expected -instance and at least 1 path")
+	}
+	t := time.Now()
+	if *backupTime != "" {
+		var err error
+		if t, err = time.Parse(time.RFC3339Nano, *backupTime); err != nil {
+			return fmt.Errorf("invalid backup time %s, expected RFC 3339", *backupTime)
+		}
+	}
+	sdis := make([]*ibd2schema.SDI, 0)
+	for _, path := range flags.Args() {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return err
+		} else if info.IsDir() {
+			if files, err = ibd2schema.ListTablespaceFiles(path); err != nil {
+				return err
+			}
+		}
+		for _, file := range files {
+			fileSDIs, err := loadSDIs(file)
+			if err != nil {
+				return err
+			}
+			sdis = append(sdis, fileSDIs...)
+		}
+	}
+	snapshot, err := catalog.Ingest(*instance, t, strings.Join(flags.Args(), ","), sdis)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("ingested %d tables of %s at %s\n", snapshot.Tables, snapshot.Instance,
+		formatBackupTime(snapshot.BackupTime))
+	return nil
+}
+
+func runHistorySnapshots(catalog *ibd2schema.HistoryCatalog, args []string) error {
+	flags := flag.NewFlagSet("snapshots", flag.ContinueOnError)
+	instance := flags.String("instance", "", "name of the instance, default all instances")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	snapshots, err := catalog.GetSnapshots(*instance)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		fmt.Printf("%s  %s  %d tables  %s\n", snapshot.Instance, formatBackupTime(snapshot.BackupTime),
+			snapshot.Tables, snapshot.Source)
+	}
+	return nil
+}
+
+func runHistoryLog(catalog *ibd2schema.HistoryCatalog, args []string) error {
+	flags := flag.NewFlagSet("log", flag.ContinueOnError)
+	instance := flags.String("instance", "", "name of the instance")
+	all := flags.Bool("all", false, "show unchanged snapshots too")
+	physical := flags.Bool("physical", false, "compare physical options like ROW_FORMAT too")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *instance == "" || flags.NArg() != 1 {
+		return fmt.Errorf("expected -instance and <schema>.<table>")
+	}
+	schemaName, name, err := parseTableName(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	entries, err := catalog.GetTableHistory(*instance, schemaName, name, !*all, *physical)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("table `%s`.`%s` not found in the snapshots of %s", schemaName, name, *instance)
+	}
+	var previous *ibd2schema.HistoryTable
+	for _, entry := range entries {
+		fmt.Printf("%s  %s", formatBackupTime(entry.BackupTime), entry.Action)
+		if entry.Table != nil {
+			fmt.Printf("  %.12s", entry.Table.Fingerprint)
+		}
+		fmt.Println()
+		if entry.Action == ibd2schema.HISTORY_CHANGED && previous != nil {
+			diff, err := diffHistoryTables(catalog, previous, entry.Table)
+			if err != nil {
+				return err
+			}
+			lines := strings.Split(strings.TrimRight(diff.String(), "\n"), "\n")
+			for _, line := range lines[1:] {
+				fmt.Println(line)
+			}
+		}
+		if entry.Table != nil {
+			previous = entry.Table
+		}
+	}
+	return nil
+}
+
+func diffHistoryTables(catalog *ibd2schema.HistoryCatalog, oldTable, newTable *ibd2schema.HistoryTable) (
+	*ibd2schema.TableDiff, error) {
+	oldSchema, err := catalog.LoadTableSchema(oldTable)
+	if err != nil {
+		return nil, err
+	}
+	newSchema, err := catalog.LoadTableSchema(newTable)
+	if err != nil {
+		return nil, err
+	}
+	return ibd2schema.DiffTableSchemas(oldSchema, newSchema), nil
+}
+
+func runHistoryShow(catalog *ibd2schema.HistoryCatalog, args []string) error {
+	flags := flag.NewFlagSet("show", flag.ContinueOnError)
+	instance := flags.String("instance", "", "name of the instance")
+	backupTime := flags.String("time", "", "backup time, default the latest backup")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *instance == "" || flags.NArg() != 1 {
+		return fmt.Errorf("expected -instance and <schema>.<table>")
+	}
+	schemaName, name, err := parseTableName(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	t := time.Now()
+	if *backupTime != "" {
+		if t, err = parseBackupTime(*backupTime); err != nil {
+			return err
+		}
+	}
+	snapshot, err := catalog.FindSnapshot(*instance, t)
+	if err != nil {
+		return err
+	}
+	tables, err := catalog.GetSnapshotTables(*instance, snapshot.BackupTime)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if table.SchemaName == schemaName && table.Name == name {
+			fmt.Printf("-- %s at %s\n%s;\n", *instance, formatBackupTime(snapshot.BackupTime), table.DDL)
+			return nil
+		}
+	}
+	return fmt.Errorf("table `%s`.`%s` not found in the snapshot of %s at %s", schemaName, name,
+		*instance, formatBackupTime(snapshot.BackupTime))
+}
+
+func runHistoryDiff(catalog *ibd2schema.HistoryCatalog, args []string) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	instance := flags.String("instance", "", "name of the instance")
+	format := flags.String("format", "text", "output format: text or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *instance == "" || flags.NArg() != 2 {
+		return fmt.Errorf("expected -instance and 2 backup times")
+	}
+	oldTime, err := parseBackupTime(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newTime, err := parseBackupTime(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	diffs, err := catalog.DiffSnapshots(*instance, oldTime, newTime)
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "json":
+		data, err := ibd2schema.DumpTableDiffsJson(diffs)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "text":
+		if len(diffs) == 0 {
+			fmt.Println("no changes")
+		}
+		for _, diff := range diffs {
+			fmt.Print(diff.String())
+		}
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runJSON(args []string) error {
+	flags := flag.NewFlagSet("json", flag.ContinueOnError)
+	printSchema := flags.Bool("schema", false, "print the JSON Schema of the format instead")
+	out := flags.String("o", "", "file to write to, else print")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: json [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	data := ibd2schema.SchemaDocumentJSONSchema
+	if !*printSchema {
+		if flags.NArg() == 0 {
+			flags.Usage()
+			return fmt.Errorf("expected at least 1 path")
+		}
+		tables, err := loadTableSchemasFromPaths(flags.Args())
+		if err != nil {
+			return err
+		}
+		data, err = json.MarshalIndent(ibd2schema.GenerateSchemaDocument(tables), "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	if *out == "" {
+		fmt.Print(string(data))
+		return nil
+	}
+	return os.WriteFile(*out, data, 0644)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+/*
+* Levels from the most severe, see -fail-on
+ */
+var lintLevels = []ibd2schema.LintLevel{ibd2schema.LINT_ERROR, ibd2schema.LINT_WARNING, ibd2schema.LINT_NOTE}
+
+func runLint(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text, json or sarif")
+	disable := flags.String("disable", "", "comma separated ids of the rules to skip")
+	allowGipk := flags.Bool("allow-gipk", false, "accept generated invisible primary keys")
+	maxIndexBytes := flags.Uint64("max-index-bytes", 767, "longest VARCHAR or CHAR key part in bytes")
+	failOn := flags.String("fail-on", "error", "exit non-zero on findings at this level or above: error, warning, note or none")
+	listRules := flags.Bool("rules", false, "list the rules and exit")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: lint [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	linter := ibd2schema.NewLinter()
+	for _, rule := range linter.Rules {
+		switch r := rule.(type) {
+		case *ibd2schema.NoPrimaryKeyRule:
+			r.AllowGipk = *allowGipk
+		case *ibd2schema.LongIndexColumnRule:
+			r.MaxBytes = *maxIndexBytes
+		}
+	}
+	if *disable != "" {
+		if err := linter.Disable(strings.Split(*disable, ",")...); err != nil {
+			return err
+		}
+	}
+	if *listRules {
+		for _, rule := range linter.Rules {
+			fmt.Printf("%-20s %-8s %s\n", rule.ID(), rule.DefaultLevel(), rule.Description())
+		}
+		return nil
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	for _, path := range flags.Args() {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return err
+		} else if info.IsDir() {
+			if files, err = ibd2schema.ListTablespaceFiles(path); err != nil {
+				return err
+			}
+		}
+		for _, file := range files {
+			tables, err := loadTableSchemas(file)
+			if err != nil {
+				return err
+			}
+			linter.Lint(file, tables)
+		}
+	}
+	switch *format {
+	case "text":
+		fmt.Print(linter.String())
+	case "json", "sarif":
+		dump := linter.DumpJson
+		if *format == "sarif" {
+			dump = linter.DumpSarif
+		}
+		data, err := dump()
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	if *failOn == "none" {
+		return nil
+	}
+	for _, level := range lintLevels {
+		if n := linter.CountFindings(level); n != 0 {
+			return fmt.Errorf("%d findings at level %s", n, level)
+		}
+		if string(level) == *failOn {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level %s", *failOn)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	format := flags.String("format", "sql", "output format: sql or json")
+	serverVersion := flags.Uint("server-version", 0,
+		"version of the server that runs the statements, e.g. 80034")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(),
+			"usage: migrate [-format sql|json] [-server-version N] <source ibd or sdi> <target ibd or sdi>\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("expected 2 files, got %d", flags.NArg())
+	}
+	oldTables, err := loadTableSchemas(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	newTables, err := loadTableSchemas(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	opts := ibd2schema.NewMigrationOptions()
+	opts.ServerVersion = uint32(*serverVersion)
+	migrations := ibd2schema.GenerateMigrations(oldTables, newTables, opts)
+	switch *format {
+	case "json":
+		data, err := ibd2schema.DumpMigrationsJson(migrations)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "sql":
+		for _, migration := range migrations {
+			fmt.Print(migration.String())
+		}
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runPostgres(args []string) error {
+	opts := ibd2schema.NewPostgresOptions()
+	flags := flag.NewFlagSet("postgres", flag.ContinueOnError)
+	noSchemas := flags.Bool("no-schemas", false, "create the tables in the search path instead of schemas named like the MySQL schemas")
+	noUnsignedChecks := flags.Bool("no-unsigned-checks", false, "do not add CHECK constraints to unsigned columns")
+	noEnumTypes := flags.Bool("no-enum-types", false, "use text with a CHECK constraint for ENUM columns instead of enum types")
+	flags.StringVar(&opts.TextSearchConfig, "text-search-config", opts.TextSearchConfig, "text search configuration of FULLTEXT indexes")
+	flags.StringVar(&opts.CaseInsensitiveCollation, "ci-collation", "", "collation of columns with case-insensitive MySQL collations")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: postgres [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	opts.IncludeSchemaName = !*noSchemas
+	opts.CreateSchemas = !*noSchemas
+	opts.UnsignedChecks = !*noUnsignedChecks
+	opts.EnumTypes = !*noEnumTypes
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	fmt.Print(ibd2schema.RenderPostgresScript(tables, opts))
+	return nil
+}
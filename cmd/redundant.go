@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runRedundant(args []string) error {
+	flags := flag.NewFlagSet("redundant", flag.ContinueOnError)
+	format := flags.String("format", "text", "output format: text, json or sql")
+	stats := flags.Bool("stats", true, "read the index sizes from the pages of .ibd files")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: redundant [-format text|json|sql] [-stats=false] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	redundant := make([]*ibd2schema.RedundantIndex, 0)
+	for _, path := range flags.Args() {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return err
+		} else if info.IsDir() {
+			if files, err = ibd2schema.ListTablespaceFiles(path); err != nil {
+				return err
+			}
+		}
+		for _, file := range files {
+			sdis, err := loadSDIsWithIndexStats(file, *stats)
+			if err != nil {
+				return err
+			}
+			for _, sdi := range sdis {
+				if sdi.TableSchema != nil {
+					redundant = append(redundant, ibd2schema.FindRedundantIndexes(sdi.TableSchema)...)
+				}
+			}
+		}
+	}
+	switch *format {
+	case "text":
+		var pages uint32
+		var size uint64
+		for _, ri := range redundant {
+			fmt.Printf("%s\n  %s\n", ri.String(), ri.DropStatement)
+			pages += ri.Pages
+			size += ri.Size
+		}
+		fmt.Printf("%d redundant indexes, %d pages, %d bytes\n", len(redundant), pages, size)
+	case "json":
+		data, err := ibd2schema.DumpRedundantIndexesJson(redundant)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	case "sql":
+		fmt.Print(ibd2schema.GenerateDropIndexScript(redundant))
+	default:
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	return nil
+}
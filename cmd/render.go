@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runRender(args []string) error {
+	opts := ibd2schema.NewRenderOptions()
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	format := flags.String("format", "mysql", "renderer: "+strings.Join(ibd2schema.GetRendererNames(), ", "))
+	flags.BoolVar(&opts.IfNotExists, "if-not-exists", false, "add IF NOT EXISTS to CREATE statements")
+	noSchemaName := flags.Bool("no-schema-name", false, "do not qualify the table names with the schema name")
+	noCreateSchemas := flags.Bool("no-create-schemas", false, "do not create the schemas of the tables")
+	flags.StringVar(&opts.Terminator, "terminator", opts.Terminator, "terminator of the statements")
+	target := flags.String("target", "", "mysql format only, server version to render for, "+
+		"e.g. 5.7, 8.0.13, 8.4, mariadb-10.6 or mariadb-11.4")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: render [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	opts.IncludeSchemaName = !*noSchemaName
+	opts.CreateSchemas = !*noCreateSchemas
+	renderer, err := ibd2schema.GetRenderer(*format)
+	if err != nil {
+		return err
+	}
+	if *target != "" {
+		if *format != "mysql" {
+			return fmt.Errorf("-target is only supported by the mysql format")
+		}
+		version, err := ibd2schema.ParseTargetVersion(*target)
+		if err != nil {
+			return err
+		}
+		renderer = &ibd2schema.MySQLRenderer{Target: version}
+	}
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	return renderer.Render(os.Stdout, tables, opts)
+}
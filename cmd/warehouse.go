@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	ibd2schema "github.com/zing22845/go-ibd2schema"
+)
+
+func runWarehouse(args []string) error {
+	flags := flag.NewFlagSet("warehouse", flag.ContinueOnError)
+	dialect := flags.String("dialect", string(ibd2schema.WAREHOUSE_BIGQUERY), "SQL dialect: bigquery or snowflake")
+	noSchemas := flags.Bool("no-schemas", false, "create the tables in the default dataset or schema instead of ones named like the MySQL schemas")
+	noConstraints := flags.Bool("no-constraints", false, "do not add the informational primary key, unique and foreign key constraints")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: warehouse [options] <datadir, ibd or sdi>...\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("expected at least 1 path")
+	}
+	switch ibd2schema.WarehouseDialect(*dialect) {
+	case ibd2schema.WAREHOUSE_BIGQUERY, ibd2schema.WAREHOUSE_SNOWFLAKE:
+	default:
+		return fmt.Errorf("unknown dialect %s", *dialect)
+	}
+	opts := ibd2schema.NewWarehouseOptions(ibd2schema.WarehouseDialect(*dialect))
+	opts.IncludeSchemaName = !*noSchemas
+	opts.CreateSchemas = !*noSchemas
+	opts.Constraints = !*noConstraints
+	tables, err := loadTableSchemasFromPaths(flags.Args())
+	if err != nil {
+		return err
+	}
+	fmt.Print(ibd2schema.RenderWarehouseScript(tables, opts))
+	return nil
+}
@@ -0,0 +1,442 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/*
+* Go types of nullable columns
+ */
+type GoNullMode string
+
+const (
+	/** sql.NullString, sql.NullInt64 and so on, pointers where database/sql
+	has no null type */
+	GO_NULL_SQL GoNullMode = "sql"
+	/** pointers, e.g. *string */
+	GO_NULL_POINTER GoNullMode = "pointer"
+)
+
+/*
+* First line of generated files, see https://go.dev/s/generatedcode
+ */
+const CODEGEN_HEADER = "Code generated by ibd2schema. DO NOT EDIT."
+
+/*
+* Initialisms that are upper case in Go names, e.g. UserID
+ */
+var goInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"QPS": true, "RAM": true, "RPC": true, "SKU": true, "SLA": true, "SMTP": true, "SQL": true,
+	"SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true,
+	"UUID": true, "URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true,
+}
+
+type GoStructOptions struct {
+	/** package name of the generated file */
+	Package  string
+	NullMode GoNullMode
+	/** add json tags besides db tags */
+	JSONTags bool
+}
+
+func NewGoStructOptions() *GoStructOptions {
+	return &GoStructOptions{
+		Package:  "models",
+		NullMode: GO_NULL_SQL,
+		JSONTags: true,
+	}
+}
+
+type ProtoOptions struct {
+	/** protobuf package of the generated file */
+	Package string
+	/** go_package option, none if empty */
+	GoPackage string
+}
+
+func NewProtoOptions() *ProtoOptions {
+	return &ProtoOptions{Package: "models"}
+}
+
+/*
+* Get the exported Go name of a table or column name, e.g. "user_id" gives
+"UserID"
+*/
+func getGoName(name string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if upper := strings.ToUpper(word); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	goName := b.String()
+	if goName == "" || !unicode.IsUpper([]rune(goName)[0]) {
+		goName = "X" + goName
+	}
+	return goName
+}
+
+/*
+* Get the protobuf field name of a column name, e.g. "userId" gives "userid"
+ */
+func getProtoFieldName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	fieldName := b.String()
+	if fieldName == "" || (fieldName[0] >= '0' && fieldName[0] <= '9') {
+		fieldName = "f_" + fieldName
+	}
+	return fieldName
+}
+
+/*
+* Make names unique by adding a number to repeated names
+ */
+type uniqueNames map[string]bool
+
+func (u uniqueNames) get(name string) string {
+	unique := name
+	for n := 2; u[unique]; n++ {
+		unique = fmt.Sprintf("%s%d", name, n)
+	}
+	u[unique] = true
+	return unique
+}
+
+/*
+* Sort tables by schema and table name, so the generated code does not depend
+on the order of the files
+*/
+func sortTables(tables []*TableSchema) []*TableSchema {
+	sorted := append([]*TableSchema{}, tables...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].SchemaName != sorted[j].SchemaName {
+			return sorted[i].SchemaName < sorted[j].SchemaName
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+/*
+* Get the type names of tables, the table names in Go or protobuf case,
+prefixed with the schema name if a table name is in several schemas
+*/
+func getTypeNames(tables []*TableSchema) map[*TableSchema]string {
+	count := make(map[string]int)
+	for _, table := range tables {
+		count[getGoName(table.Name)]++
+	}
+	names := make(map[*TableSchema]string)
+	unique := make(uniqueNames)
+	for _, table := range tables {
+		name := getGoName(table.Name)
+		if count[name] > 1 {
+			name = getGoName(table.SchemaName) + name
+		}
+		names[table] = unique.get(name)
+	}
+	return names
+}
+
+/*
+* Get the Go type of a column and the package it needs
+@return type and import path, empty if none
+*/
+func (c *Column) getGoType(nullMode GoNullMode) (string, string) {
+	var goType, sqlType, pkg string
+	switch c.Type {
+	case CT_TINY:
+		switch {
+		case c.isBoolean():
+			goType, sqlType = "bool", "sql.NullBool"
+		case c.IsUnsigned:
+			goType, sqlType = "uint8", "sql.NullByte"
+		default:
+			goType, sqlType = "int8", "sql.NullInt16"
+		}
+	case CT_SHORT:
+		goType, sqlType = "int16", "sql.NullInt16"
+		if c.IsUnsigned {
+			goType, sqlType = "uint16", "sql.NullInt32"
+		}
+	case CT_INT24, CT_LONG:
+		goType, sqlType = "int32", "sql.NullInt32"
+		if c.IsUnsigned {
+			goType, sqlType = "uint32", "sql.NullInt64"
+		}
+	case CT_LONGLONG:
+		goType, sqlType = "int64", "sql.NullInt64"
+		if c.IsUnsigned {
+			/* sql.NullInt64 overflows above 2^63-1, pointer instead */
+			goType, sqlType = "uint64", ""
+		}
+	case CT_FLOAT:
+		goType, sqlType = "float32", "sql.NullFloat64"
+	case CT_DOUBLE:
+		goType, sqlType = "float64", "sql.NullFloat64"
+	case CT_YEAR:
+		goType, sqlType = "int16", "sql.NullInt16"
+	case CT_DATE, CT_NEWDATE, CT_DATETIME, CT_DATETIME2, CT_TIMESTAMP, CT_TIMESTAMP2:
+		goType, sqlType, pkg = "time.Time", "sql.NullTime", "time"
+	case CT_BIT, CT_GEOMETRY:
+		/* nil for NULL */
+		return "[]byte", ""
+	case CT_JSON:
+		return "json.RawMessage", "encoding/json"
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return "[]byte", ""
+		}
+		goType, sqlType = "string", "sql.NullString"
+	default:
+		/* DECIMAL as string keeps its precision, TIME may exceed 24 hours */
+		goType, sqlType = "string", "sql.NullString"
+	}
+	if !c.IsNullable {
+		return goType, pkg
+	}
+	if nullMode == GO_NULL_SQL && sqlType != "" {
+		return sqlType, "database/sql"
+	}
+	return "*" + goType, pkg
+}
+
+/*
+* Write a comment, one line per line of the text
+ */
+func writeComment(b *strings.Builder, indent, prefix, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(prefix+text), "\n") {
+		fmt.Fprintf(b, "%s// %s\n", indent, strings.TrimSpace(line))
+	}
+}
+
+/*
+* Generate a Go file with a struct per table, fields have db tags with the
+column names. Tables are sorted by schema and name and the file is gofmt'ed,
+so the same tables give the same file.
+@param[in]	tables	table schemas
+@param[in]	opts	options, default NewGoStructOptions
+@return Go source
+*/
+func GenerateGoStructs(tables []*TableSchema, opts *GoStructOptions) ([]byte, error) {
+	if opts == nil {
+		opts = NewGoStructOptions()
+	}
+	tables = sortTables(tables)
+	typeNames := getTypeNames(tables)
+	imports := make(map[string]bool)
+	var body strings.Builder
+	for _, table := range tables {
+		typeName := typeNames[table]
+		body.WriteString("\n")
+		writeComment(&body, "", fmt.Sprintf("%s is a row of `%s`.`%s`", typeName, table.SchemaName, table.Name),
+			"")
+		if table.Comment != "" {
+			writeComment(&body, "", "", table.Comment)
+		}
+		fmt.Fprintf(&body, "type %s struct {\n", typeName)
+		/* the TableName method below */
+		fieldNames := uniqueNames{"TableName": true}
+		for _, column := range table.GetUserColumns() {
+			goType, pkg := column.getGoType(opts.NullMode)
+			if pkg != "" {
+				imports[pkg] = true
+			}
+			if column.Comment != "" {
+				writeComment(&body, "\t", "", column.Comment)
+			}
+			tag := fmt.Sprintf("db:%q", column.Name)
+			if opts.JSONTags {
+				tag += fmt.Sprintf(" json:%q", column.Name)
+			}
+			fmt.Fprintf(&body, "\t%s %s `%s`\n", fieldNames.get(getGoName(column.Name)), goType, tag)
+		}
+		body.WriteString("}\n")
+		fmt.Fprintf(&body, "\n// TableName returns the name of the table of %s\n", typeName)
+		fmt.Fprintf(&body, "func (%s) TableName() string {\n\treturn %q\n}\n", typeName, table.Name)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n\npackage %s\n", CODEGEN_HEADER, opts.Package)
+	if len(imports) != 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		b.WriteString("\nimport (\n")
+		for _, path := range paths {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+		b.WriteString(")\n")
+	}
+	b.WriteString(body.String())
+	source, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("format Go source failed, err:%v", err)
+	}
+	return source, nil
+}
+
+/*
+* Get the protobuf type of a column
+@return type and the file it needs to import, empty if none
+*/
+func (c *Column) getProtoType() (string, string) {
+	switch c.Type {
+	case CT_TINY:
+		if c.isBoolean() {
+			return "bool", ""
+		}
+		fallthrough
+	case CT_SHORT, CT_INT24, CT_LONG:
+		if c.IsUnsigned {
+			return "uint32", ""
+		}
+		return "int32", ""
+	case CT_LONGLONG:
+		if c.IsUnsigned {
+			return "uint64", ""
+		}
+		return "int64", ""
+	case CT_FLOAT:
+		return "float", ""
+	case CT_DOUBLE:
+		return "double", ""
+	case CT_YEAR:
+		return "int32", ""
+	case CT_DATETIME, CT_DATETIME2, CT_TIMESTAMP, CT_TIMESTAMP2:
+		return "google.protobuf.Timestamp", "google/protobuf/timestamp.proto"
+	case CT_BIT, CT_GEOMETRY:
+		return "bytes", ""
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return "bytes", ""
+		}
+	}
+	/* DECIMAL, DATE, TIME, ENUM, SET and JSON as text */
+	return "string", ""
+}
+
+/*
+* Generate a proto3 file with a message per table. Field numbers follow the
+column order, nullable columns are optional fields. Tables are sorted by
+schema and name, so the same tables give the same file.
+@param[in]	tables	table schemas
+@param[in]	opts	options, default NewProtoOptions
+@return proto source
+*/
+func GenerateProto(tables []*TableSchema, opts *ProtoOptions) []byte {
+	if opts == nil {
+		opts = NewProtoOptions()
+	}
+	tables = sortTables(tables)
+	typeNames := getTypeNames(tables)
+	imports := make(map[string]bool)
+	var body strings.Builder
+	for _, table := range tables {
+		body.WriteString("\n")
+		writeComment(&body, "", fmt.Sprintf("Row of `%s`.`%s`", table.SchemaName, table.Name), "")
+		if table.Comment != "" {
+			writeComment(&body, "", "", table.Comment)
+		}
+		fmt.Fprintf(&body, "message %s {\n", typeNames[table])
+		/* the TableName method below */
+		fieldNames := uniqueNames{"TableName": true}
+		for n, column := range table.GetUserColumns() {
+			protoType, file := column.getProtoType()
+			if file != "" {
+				imports[file] = true
+			}
+			if column.Comment != "" {
+				writeComment(&body, "  ", "", column.Comment)
+			}
+			if column.Type == CT_ENUM || column.Type == CT_SET {
+				writeComment(&body, "  ", "values: ", strings.Join(column.Elements, ", "))
+			}
+			label := ""
+			if column.IsNullable {
+				label = "optional "
+			}
+			fmt.Fprintf(&body, "  %s%s %s = %d;\n", label, protoType,
+				fieldNames.get(getProtoFieldName(column.Name)), n+1)
+		}
+		body.WriteString("}\n")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s\n\nsyntax = \"proto3\";\n\npackage %s;\n", CODEGEN_HEADER, opts.Package)
+	if len(imports) != 0 {
+		files := make([]string, 0, len(imports))
+		for file := range imports {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		b.WriteString("\n")
+		for _, file := range files {
+			fmt.Fprintf(&b, "import %q;\n", file)
+		}
+	}
+	if opts.GoPackage != "" {
+		fmt.Fprintf(&b, "\noption go_package = %q;\n", opts.GoPackage)
+	}
+	b.WriteString(body.String())
+	return []byte(b.String())
+}
+
+/*
+* Renderer of Go structs, see GenerateGoStructs
+ */
+type GoStructRenderer struct {
+	/** Go options, default NewGoStructOptions */
+	Options *GoStructOptions
+}
+
+func (r *GoStructRenderer) Name() string {
+	return "go"
+}
+
+func (r *GoStructRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	source, err := GenerateGoStructs(tables, r.Options)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+/*
+* Renderer of protobuf messages, see GenerateProto
+ */
+type ProtoRenderer struct {
+	/** protobuf options, default NewProtoOptions */
+	Options *ProtoOptions
+}
+
+func (r *ProtoRenderer) Name() string {
+	return "proto"
+}
+
+func (r *ProtoRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	_, err := w.Write(GenerateProto(tables, r.Options))
+	return err
+}
@@ -2,8 +2,7 @@ package ibd2schema
 
 import (
 	"fmt"
-
-	"github.com/tidwall/gjson"
+	"strings"
 )
 
 type Collation struct {
@@ -314,29 +313,38 @@ func GetCollationByID(id int) (*Collation, error) {
 }
 
 /*
-* Parse Collation
-@param[in]	    collation	  Table collation object
-@param[in,out]	ddl         DDL string
-@return False in case of errors
+* Get a collation by name, utf8 is accepted as an alias of utf8mb3.
+@param[in]	name	collation name, case-insensitive
+@return collation
 */
-func ParseCollation(ddObject gjson.Result) (ddl string, err error) {
-	// table collation
-	collationID := ddObject.Get(`collation_id`)
-	if !collationID.Exists() {
-		return "", fmt.Errorf(`table collation id not found`)
+func GetCollationByName(name string) (*Collation, error) {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "utf8_") {
+		name = "utf8mb3_" + name[len("utf8_"):]
+	}
+	for _, collation := range collationsIDMap {
+		if collation.Name == name {
+			return collation, nil
+		}
 	}
-	collation, err := GetCollationByID(int(collationID.Uint()))
-	if err != nil {
-		return "", err
+	return nil, fmt.Errorf("unknown collation %s", name)
+}
+
+/*
+* Get the default collation of a character set, utf8 is accepted as an
+alias of utf8mb3.
+@param[in]	charset	character set name, case-insensitive
+@return default collation of the character set
+*/
+func GetDefaultCollation(charset string) (*Collation, error) {
+	charset = strings.ToLower(charset)
+	if charset == "utf8" {
+		charset = "utf8mb3"
 	}
-	ddl += " DEFAULT CHARSET="
-	ddl += collation.CharsetName
-	/*
-		if collation.IsDefault {
-			return ddl, nil
+	for _, collation := range collationsIDMap {
+		if collation.CharsetName == charset && collation.IsDefault {
+			return collation, nil
 		}
-	*/
-	ddl += " COLLATE="
-	ddl += collation.Name
-	return ddl, nil
+	}
+	return nil, fmt.Errorf("unknown character set %s", charset)
 }
@@ -1,6 +1,7 @@
 package ibd2schema
 
 import (
+	"encoding/base64"
 	"fmt"
 	"strconv"
 	"strings"
@@ -54,9 +55,38 @@ type Column struct {
 	GenerationExpression string
 	Hidden               HiddenType
 	Size                 uint64
-	Collation            *Collation
-	GJson                gjson.Result
-	DDL                  string
+	IsNullable           bool
+	IsUnsigned           bool
+	IsVirtual            bool
+	NumericPrecision     uint32
+	NumericScale         uint32
+	DatetimePrecision    uint32
+	IsZerofill           bool
+	IsAutoIncrement      bool
+	/** full column type, e.g. "varchar(64)" or "int unsigned" */
+	ColumnTypeUTF8 string
+	/** true if the column has no DEFAULT clause */
+	HasNoDefault bool
+	/** true if the default value is NULL */
+	DefaultValueNull bool
+	/** true if DefaultValueUTF8 is not set */
+	DefaultValueUTF8Null bool
+	DefaultValueUTF8     string
+	/** default function or expression, e.g. CURRENT_TIMESTAMP */
+	DefaultOption string
+	/** ON UPDATE function, e.g. CURRENT_TIMESTAMP */
+	UpdateOption             string
+	Comment                  string
+	GenerationExpressionUTF8 string
+	/** spatial reference system id, nil if not set */
+	SRSID               *uint32
+	IsExplicitCollation bool
+	Options             map[string]string
+	SEPrivateData       map[string]string
+	/** names of ENUM or SET elements in definition order */
+	Elements  []string
+	Collation *Collation
+	GJson     gjson.Result
 }
 
 func NewColumn(c gjson.Result) (*Column, error) {
@@ -64,16 +94,50 @@ func NewColumn(c gjson.Result) (*Column, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Column{
-		OrdinalPosition:      int(c.Get(`ordinal_position`).Int()) - 1,
-		Name:                 c.Get(`name`).String(),
-		GenerationExpression: c.Get(`generation_expression`).String(),
-		Hidden:               HiddenType(c.Get(`hidden`).Int()),
-		Type:                 ColumnType(c.Get(`type`).Int()),
-		Size:                 uint64(c.Get(`char_length`).Uint()),
-		Collation:            collation,
-		GJson:                c,
-	}, nil
+	column := &Column{
+		OrdinalPosition:          int(c.Get(`ordinal_position`).Int()) - 1,
+		Name:                     c.Get(`name`).String(),
+		GenerationExpression:     c.Get(`generation_expression`).String(),
+		Hidden:                   HiddenType(c.Get(`hidden`).Int()),
+		Type:                     ColumnType(c.Get(`type`).Int()),
+		Size:                     uint64(c.Get(`char_length`).Uint()),
+		IsNullable:               c.Get(`is_nullable`).Bool(),
+		IsUnsigned:               c.Get(`is_unsigned`).Bool(),
+		IsVirtual:                c.Get(`is_virtual`).Bool(),
+		NumericPrecision:         uint32(c.Get(`numeric_precision`).Uint()),
+		NumericScale:             uint32(c.Get(`numeric_scale`).Uint()),
+		DatetimePrecision:        uint32(c.Get(`datetime_precision`).Uint()),
+		IsZerofill:               c.Get(`is_zerofill`).Bool(),
+		IsAutoIncrement:          c.Get(`is_auto_increment`).Bool(),
+		ColumnTypeUTF8:           c.Get(`column_type_utf8`).String(),
+		HasNoDefault:             c.Get(`has_no_default`).Bool(),
+		DefaultValueNull:         c.Get(`default_value_null`).Bool(),
+		DefaultValueUTF8Null:     c.Get(`default_value_utf8_null`).Bool(),
+		DefaultValueUTF8:         c.Get(`default_value_utf8`).String(),
+		DefaultOption:            c.Get(`default_option`).String(),
+		UpdateOption:             c.Get(`update_option`).String(),
+		Comment:                  c.Get(`comment`).String(),
+		GenerationExpressionUTF8: c.Get(`generation_expression_utf8`).String(),
+		IsExplicitCollation:      c.Get(`is_explicit_collation`).Bool(),
+		Options:                  ParseKeyValueList(c.Get(`options`).String()),
+		SEPrivateData:            ParseKeyValueList(c.Get(`se_private_data`).String()),
+		Elements:                 make([]string, 0),
+		Collation:                collation,
+		GJson:                    c,
+	}
+	if !c.Get(`srs_id_null`).Bool() {
+		srsID := uint32(c.Get(`srs_id`).Uint())
+		column.SRSID = &srsID
+	}
+	for _, e := range c.Get(`elements`).Array() {
+		name, err := base64.StdEncoding.DecodeString(e.Get(`name`).String())
+		if err != nil {
+			return nil, fmt.Errorf("decode element name of column %s failed, err:%v",
+				column.Name, err)
+		}
+		column.Elements = append(column.Elements, string(name))
+	}
+	return column, nil
 }
 
 /*
@@ -132,155 +196,236 @@ func (c *Column) isGipk() bool {
 	start := pos + 5
 	end := strings.Index(options[start:], ";")
 	if end == -1 {
-		end = len(options)
+		end = len(options) - start
 	}
-	valueStr := options[start:end]
+	valueStr := options[start : start+end]
 	valueStr = strings.Trim(valueStr, " ")
 	// convert valueStr to integer
 	value, _ := strconv.Atoi(valueStr)
 	return value != 0
 }
 
-func (c *Column) parseName() {
-	c.DDL += fmt.Sprintf("  `%s`", c.Name)
-}
+type ColumnCache map[int]*Column
 
-func (c *Column) parseStringAttribute(attribute string) {
-	c.DDL += fmt.Sprintf(" %s", c.GJson.Get(attribute).String())
+func (cc ColumnCache) AddColumn(c gjson.Result) (column *Column, err error) {
+	column, err = NewColumn(c)
+	if err != nil {
+		return nil, err
+	}
+	cc[column.OrdinalPosition] = column
+	return column, nil
 }
 
-func (c *Column) parseCharset() {
-	if c.GJson.Get("is_explicit_collation").Bool() {
-		if c.skipCharset() {
-			return
+func CheckColumnMembers(column gjson.Result) error {
+	if !column.IsObject() {
+		return fmt.Errorf("column is not an object")
+	}
+	for _, member := range ColumnMembers {
+		if err := CheckMember(column, member); err != nil {
+			return err
 		}
-		c.DDL += fmt.Sprintf(
-			" CHARACTER SET %s COLLATE %s",
-			c.Collation.CharsetName, c.Collation.Name)
 	}
+	return nil
 }
 
-func (c *Column) parseGenerationExpression() {
-	if c.GenerationExpression != "" {
-		c.DDL += fmt.Sprintf(
-			" GENERATED ALWAYS AS (%s)",
-			c.GenerationExpression)
-		if c.GJson.Get("is_virtual").Bool() {
-			c.DDL += " VIRTUAL"
-		} else {
-			c.DDL += " STORED"
+/*
+* Parse the columns section of SDI JSON
+@param[in]	ddObject	Data Dictionary JSON object
+@return all columns of the table by position, including hidden ones
+*/
+func ParseColumns(ddObject gjson.Result) (columnCache ColumnCache, err error) {
+	columns := ddObject.Get(`columns`)
+	if !columns.Exists() {
+		return nil, fmt.Errorf(`table columns not found`)
+	}
+	columnCache = make(ColumnCache)
+	for _, c := range columns.Array() {
+		err = CheckColumnMembers(c)
+		if err != nil {
+			return nil, err
+		}
+		_, err = columnCache.AddColumn(c)
+		if err != nil {
+			return nil, err
 		}
 	}
+	return columnCache, nil
 }
 
-func (c *Column) parseIsNullable() {
-	if !c.GJson.Get("is_nullable").Bool() {
-		c.DDL += " NOT NULL"
-	} else if c.Type == CT_TIMESTAMP2 || c.Type == CT_TIMESTAMP {
-		c.DDL += " NULL"
-	}
+/*
+* Check if the column is a system column added by InnoDB, like DB_ROW_ID,
+DB_TRX_ID and DB_ROLL_PTR.
+*/
+func (c *Column) isSystemColumn() bool {
+	return c.Hidden == HT_HIDDEN_SE &&
+		(c.Name == "DB_ROW_ID" || c.Name == "DB_TRX_ID" || c.Name == "DB_ROLL_PTR")
 }
 
-func (c *Column) parseDefaultValueNull() {
-	/* skip default if is generated */
-	if c.GenerationExpression != "" {
-		return
+/*
+* Check if a CHAR column is stored with fixed length, which is only the case
+for fixed width charsets.
+*/
+func (c *Column) isFixedWidthCharset() bool {
+	return c.Collation.Maxlen == 1 ||
+		c.Collation.CharsetName == "ucs2" ||
+		c.Collation.CharsetName == "utf32"
+}
+
+/*
+* Get the stored size of a fixed length column.
+@return size in bytes, 0 if the column is stored with variable length
+*/
+func (c *Column) GetFixedSize() uint32 {
+	if c.isSystemColumn() {
+		return uint32(c.Size)
 	}
-	defaultValueUTF8Null := c.GJson.Get("default_value_utf8_null").Bool()
-	defaultValueUTF8 := c.GJson.Get("default_value_utf8").String()
-	if c.GJson.Get("default_value_null").Bool() && defaultValueUTF8Null {
-		c.DDL += " DEFAULT NULL"
-	} else if !defaultValueUTF8Null {
-		defaultOption := c.GJson.Get("default_option").String()
-		if defaultOption == "" {
-			c.DDL += fmt.Sprintf(" DEFAULT '%s'", defaultValueUTF8)
-		} else {
-			c.DDL += fmt.Sprintf(" DEFAULT %s", defaultOption)
+	switch c.Type {
+	case CT_TINY, CT_YEAR:
+		return 1
+	case CT_SHORT:
+		return 2
+	case CT_INT24, CT_DATE, CT_NEWDATE, CT_TIME:
+		return 3
+	case CT_LONG, CT_FLOAT, CT_TIMESTAMP:
+		return 4
+	case CT_LONGLONG, CT_DOUBLE, CT_DATETIME:
+		return 8
+	case CT_TIME2:
+		return 3 + (c.DatetimePrecision+1)/2
+	case CT_TIMESTAMP2:
+		return 4 + (c.DatetimePrecision+1)/2
+	case CT_DATETIME2:
+		return 5 + (c.DatetimePrecision+1)/2
+	case CT_NEWDECIMAL:
+		return DecimalBinSize(c.NumericPrecision, c.NumericScale)
+	case CT_BIT:
+		return (c.NumericPrecision + 7) / 8
+	case CT_ENUM:
+		if len(c.Elements) < 256 {
+			return 1
+		}
+		return 2
+	case CT_SET:
+		size := (uint32(len(c.Elements)) + 7) / 8
+		if size > 4 {
+			return 8
+		}
+		if size == 3 {
+			return 4
+		}
+		return size
+	case CT_STRING:
+		if c.isFixedWidthCharset() {
+			return uint32(c.Size)
 		}
 	}
-	updateOption := c.GJson.Get("update_option")
-	if updateOption.Exists() && updateOption.String() != "" {
-		c.DDL += fmt.Sprintf(" ON UPDATE %s", updateOption.String())
-	}
+	return 0
 }
 
-func (c *Column) parseIsAutoIncrement() {
-	if c.GJson.Get("is_auto_increment").Bool() {
-		c.DDL += " AUTO_INCREMENT"
-	}
+/*
+* Check if the column is stored as BLOB in InnoDB, which may be stored
+off-page.
+*/
+func (c *Column) isBlob() bool {
+	return c.Type == CT_TINY_BLOB ||
+		c.Type == CT_MEDIUM_BLOB ||
+		c.Type == CT_LONG_BLOB ||
+		c.Type == CT_BLOB ||
+		c.Type == CT_JSON ||
+		c.Type == CT_GEOMETRY
 }
 
-func (c *Column) parseIsGipk() {
-	if c.isGipk() {
-		c.DDL += " /*!80023 INVISIBLE */"
-	}
+/*
+* Check if the length of the column may take 2 bytes in the record header.
+ */
+func (c *Column) isBigCol() bool {
+	return c.Size > 255 || c.isBlob()
 }
 
-func (c *Column) parseComment() {
-	comment := c.GJson.Get("comment").String()
-	if comment != "" {
-		c.DDL += fmt.Sprintf(" COMMENT '%s'", comment)
+/*
+* Check if the default of a column is an expression, other than
+CURRENT_TIMESTAMP which all targets support
+*/
+func (c *Column) hasExpressionDefault() bool {
+	if c.DefaultOption == "" || c.DefaultValueUTF8Null && c.DefaultValueNull {
+		return false
 	}
+	option := strings.ToUpper(c.DefaultOption)
+	return !strings.HasPrefix(option, "CURRENT_TIMESTAMP") && !strings.HasPrefix(option, "NOW(")
 }
 
-func (c *Column) parseDDL() {
-	// parse ddl from attribues
-	c.parseName()
-	c.parseStringAttribute("column_type_utf8")
-	c.parseCharset()
-	c.parseGenerationExpression()
-	c.parseIsNullable()
-	c.parseDefaultValueNull()
-	c.parseIsAutoIncrement()
-	c.parseIsGipk()
-	c.parseComment()
+/*
+* Options of a column definition, see TargetVersion
+ */
+type columnDefinitionOptions struct {
+	/** collation of an explicit CHARACTER SET clause, nil for the collation
+	of the column */
+	collation *Collation
+	/** clause of an invisible column, empty to render it visible */
+	invisible string
+	/** leave out a default that is an expression */
+	noExpressionDefault bool
 }
 
-type ColumnCache map[int]*Column
-
-func (cc ColumnCache) AddColumn(c gjson.Result) (column *Column, err error) {
-	column, err = NewColumn(c)
-	if err != nil {
-		return nil, err
-	}
-	cc[column.OrdinalPosition] = column
-	return column, nil
+/*
+* Get the definition of the column as in CREATE TABLE, without indentation.
+ */
+func (c *Column) GetDefinition() string {
+	return c.getDefinition(&columnDefinitionOptions{invisible: " /*!80023 INVISIBLE */"})
 }
 
-func CheckColumnMembers(column gjson.Result) error {
-	if !column.IsObject() {
-		return fmt.Errorf("column is not an object")
+/*
+* Render the definition of the column from its attributes in the order of
+SHOW CREATE TABLE
+*/
+func (c *Column) getDefinition(opts *columnDefinitionOptions) string {
+	definition := quoteMySQLIdentifier(c.Name) + " " + c.ColumnTypeUTF8
+	if c.IsExplicitCollation && !c.skipCharset() {
+		collation := c.Collation
+		if opts.collation != nil {
+			collation = opts.collation
+		}
+		definition += fmt.Sprintf(" CHARACTER SET %s COLLATE %s", collation.CharsetName, collation.Name)
 	}
-	for _, member := range ColumnMembers {
-		if err := CheckMember(column, member); err != nil {
-			return err
+	if c.GenerationExpression != "" {
+		definition += fmt.Sprintf(" GENERATED ALWAYS AS (%s)", c.GenerationExpression)
+		if c.IsVirtual {
+			definition += " VIRTUAL"
+		} else {
+			definition += " STORED"
 		}
 	}
-	return nil
-}
-
-func ParseColumns(ddObject gjson.Result) (
-	ddl string, columnCache ColumnCache, err error) {
-	columns := ddObject.Get(`columns`)
-	if !columns.Exists() {
-		return "", nil, fmt.Errorf(`table columns not found`)
+	if !c.IsNullable {
+		definition += " NOT NULL"
+	} else if c.Type == CT_TIMESTAMP2 || c.Type == CT_TIMESTAMP {
+		definition += " NULL"
 	}
-	columnCache = make(ColumnCache)
-	for _, c := range columns.Array() {
-		err = CheckColumnMembers(c)
-		if err != nil {
-			return "", nil, err
-		}
-		column, err := columnCache.AddColumn(c)
-		if err != nil {
-			return "", nil, err
+	/* generated columns have no default */
+	if c.GenerationExpression == "" {
+		switch {
+		case c.DefaultValueNull && c.DefaultValueUTF8Null:
+			definition += " DEFAULT NULL"
+		case c.DefaultValueUTF8Null:
+		case c.DefaultOption == "" && c.Type == CT_BIT && strings.HasPrefix(c.DefaultValueUTF8, "b'"):
+			/* bit literal like b'101' */
+			definition += " DEFAULT " + c.DefaultValueUTF8
+		case c.DefaultOption == "":
+			definition += " DEFAULT " + quoteMySQLString(c.DefaultValueUTF8)
+		case !opts.noExpressionDefault || !c.hasExpressionDefault():
+			definition += fmt.Sprintf(" DEFAULT %s", c.DefaultOption)
 		}
-		if !column.isHiddenUser() && column.isHidden() {
-			continue
+		if c.UpdateOption != "" {
+			definition += fmt.Sprintf(" ON UPDATE %s", c.UpdateOption)
 		}
-		// parse ddl
-		column.parseDDL()
-		ddl += fmt.Sprintf("%s,\n", column.DDL)
 	}
-	return ddl, columnCache, nil
+	if c.IsAutoIncrement {
+		definition += " AUTO_INCREMENT"
+	}
+	if c.isHiddenUser() {
+		definition += opts.invisible
+	}
+	if c.Comment != "" {
+		definition += " COMMENT " + quoteMySQLString(c.Comment)
+	}
+	return definition
 }
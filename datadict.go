@@ -0,0 +1,644 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+/*
+* Format of the data dictionary
+ */
+type DocFormat string
+
+const (
+	DOC_MARKDOWN DocFormat = "markdown"
+	/** standalone HTML page */
+	DOC_HTML DocFormat = "html"
+)
+
+/*
+* Format of the ER diagram
+ */
+type DiagramFormat string
+
+const (
+	DIAGRAM_MERMAID  DiagramFormat = "mermaid"
+	DIAGRAM_GRAPHVIZ DiagramFormat = "graphviz"
+	DIAGRAM_NONE     DiagramFormat = "none"
+)
+
+/*
+* Script that renders the Mermaid diagrams of an HTML data dictionary
+ */
+const MERMAID_SCRIPT_URL = "https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs"
+
+type DocOptions struct {
+	Format  DocFormat
+	Diagram DiagramFormat
+	/** title of the document */
+	Title string
+}
+
+func NewDocOptions() *DocOptions {
+	return &DocOptions{
+		Format:  DOC_MARKDOWN,
+		Diagram: DIAGRAM_MERMAID,
+		Title:   "Data dictionary",
+	}
+}
+
+/*
+* Get an identifier of a name that keeps letters, digits and underscores,
+e.g. for Mermaid entities and HTML ids
+*/
+func getDiagramID(name string) string {
+	var b strings.Builder
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		b.WriteRune('_')
+	}
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+/*
+* Get the base type of a column for a diagram, e.g. "varchar" for
+"varchar(64)"
+*/
+func (c *Column) getBaseType() string {
+	baseType, _, _ := strings.Cut(c.ColumnTypeUTF8, "(")
+	baseType, _, _ = strings.Cut(baseType, " ")
+	return baseType
+}
+
+/*
+* Get the keys of the columns of a table for a diagram
+@return "PK", "UK" and "FK" by column name
+*/
+func getColumnKeys(table *TableSchema) map[string][]string {
+	keys := make(map[string][]string)
+	add := func(name, key string) {
+		for _, k := range keys[name] {
+			if k == key {
+				return
+			}
+		}
+		keys[name] = append(keys[name], key)
+	}
+	for _, index := range table.GetUserIndexes() {
+		if index.Type != IT_PRIMARY && index.Type != IT_UNIQUE {
+			continue
+		}
+		key := "UK"
+		if index.Type == IT_PRIMARY {
+			key = "PK"
+		}
+		for _, keyPart := range index.KeyParts {
+			add(keyPart.Column.Name, key)
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		for _, name := range fk.ColumnNames {
+			add(name, "FK")
+		}
+	}
+	return keys
+}
+
+/*
+* Check if the columns of a foreign key are unique in their table, so a row
+is referenced by at most one row
+*/
+func isForeignKeyUnique(table *TableSchema, fk *ForeignKey) bool {
+	for _, index := range table.GetUserIndexes() {
+		if (index.Type != IT_PRIMARY && index.Type != IT_UNIQUE) || len(index.KeyParts) > len(fk.ColumnNames) {
+			continue
+		}
+		unique := true
+		for _, keyPart := range index.KeyParts {
+			found := false
+			for _, name := range fk.ColumnNames {
+				found = found || strings.EqualFold(name, keyPart.Column.Name)
+			}
+			unique = unique && found && keyPart.Length == 0
+		}
+		if unique {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+* Check if a column of a foreign key is nullable, so a row may reference no
+row
+*/
+func isForeignKeyOptional(table *TableSchema, fk *ForeignKey) bool {
+	for _, column := range table.GetUserColumns() {
+		for _, name := range fk.ColumnNames {
+			if strings.EqualFold(name, column.Name) && column.IsNullable {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+* Generate the Mermaid ER diagram of tables
+ */
+func generateMermaidDiagram(g *FKGraph) string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, table := range g.Tables {
+		fmt.Fprintf(&b, "    %s[\"%s.%s\"] {\n", getDiagramID(table.SchemaName+"."+table.Name),
+			strings.ReplaceAll(table.SchemaName, `"`, "'"), strings.ReplaceAll(table.Name, `"`, "'"))
+		keys := getColumnKeys(table)
+		for _, column := range table.GetUserColumns() {
+			fmt.Fprintf(&b, "        %s %s", getDiagramID(column.getBaseType()), getDiagramID(column.Name))
+			if len(keys[column.Name]) != 0 {
+				fmt.Fprintf(&b, " %s", strings.Join(keys[column.Name], ", "))
+			}
+			if column.Comment != "" {
+				comment := strings.ReplaceAll(strings.ReplaceAll(column.Comment, `"`, "'"), "\n", " ")
+				fmt.Fprintf(&b, " \"%s\"", comment)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, edge := range g.Edges {
+		referenced := "||"
+		if isForeignKeyOptional(edge.Table, edge.ForeignKey) {
+			referenced = "|o"
+		}
+		referencing := "o{"
+		if isForeignKeyUnique(edge.Table, edge.ForeignKey) {
+			referencing = "o|"
+		}
+		fmt.Fprintf(&b, "    %s %s--%s %s : \"%s\"\n",
+			getDiagramID(edge.Referenced.SchemaName+"."+edge.Referenced.Name), referenced, referencing,
+			getDiagramID(edge.Table.SchemaName+"."+edge.Table.Name), strings.ReplaceAll(edge.ForeignKey.Name, `"`, "'"))
+	}
+	return b.String()
+}
+
+/*
+* Get the position of a column in the user columns of a table, for a port of
+a Graphviz node
+@return position from 1, 0 if not found
+*/
+func getColumnPort(table *TableSchema, name string) int {
+	for n, column := range table.GetUserColumns() {
+		if strings.EqualFold(column.Name, name) {
+			return n + 1
+		}
+	}
+	return 0
+}
+
+/*
+* Generate the Graphviz ER diagram of tables, edges go from the referencing
+columns to the referenced columns
+*/
+func generateGraphvizDiagram(g *FKGraph) string {
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+	}
+	var b strings.Builder
+	b.WriteString("digraph er {\n  rankdir=LR;\n  node [shape=plaintext];\n")
+	for _, table := range g.Tables {
+		name := table.SchemaName + "." + table.Name
+		fmt.Fprintf(&b, "  %s [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\">", quote(name))
+		fmt.Fprintf(&b, "<tr><td bgcolor=\"lightgrey\"><b>%s</b></td></tr>", html.EscapeString(name))
+		keys := getColumnKeys(table)
+		for n, column := range table.GetUserColumns() {
+			text := html.EscapeString(fmt.Sprintf("%s : %s", column.Name, column.ColumnTypeUTF8))
+			if len(keys[column.Name]) != 0 {
+				text += " " + strings.Join(keys[column.Name], ",")
+			}
+			fmt.Fprintf(&b, "<tr><td port=\"c%d\" align=\"left\">%s</td></tr>", n+1, text)
+		}
+		b.WriteString("</table>>];\n")
+	}
+	for _, edge := range g.Edges {
+		from := quote(edge.Table.SchemaName + "." + edge.Table.Name)
+		if port := getColumnPort(edge.Table, edge.ForeignKey.ColumnNames[0]); port != 0 {
+			from += fmt.Sprintf(":c%d", port)
+		}
+		to := quote(edge.Referenced.SchemaName + "." + edge.Referenced.Name)
+		if len(edge.ForeignKey.ReferenceNames) != 0 {
+			if port := getColumnPort(edge.Referenced, edge.ForeignKey.ReferenceNames[0]); port != 0 {
+				to += fmt.Sprintf(":c%d", port)
+			}
+		}
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", from, to, quote(edge.ForeignKey.Name))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+/*
+* Generate the ER diagram of tables from their foreign keys. Foreign keys to
+tables that are not given are left out.
+@param[in]	tables	table schemas, hidden tables are ignored
+@param[in]	diagram	DIAGRAM_MERMAID or DIAGRAM_GRAPHVIZ
+@return diagram source
+*/
+func GenerateERDiagram(tables []*TableSchema, diagram DiagramFormat) (string, error) {
+	switch diagram {
+	case DIAGRAM_MERMAID:
+		return generateMermaidDiagram(NewFKGraph(tables)), nil
+	case DIAGRAM_GRAPHVIZ:
+		return generateGraphvizDiagram(NewFKGraph(tables)), nil
+	}
+	return "", fmt.Errorf("unknown diagram format %s", diagram)
+}
+
+/*
+* Writer of the data dictionary in Markdown or HTML. The block methods take
+markup, see text, code and link.
+*/
+type docWriter struct {
+	format DocFormat
+	b      strings.Builder
+}
+
+/*
+* Escape text
+ */
+func (d *docWriter) text(s string) string {
+	if d.format == DOC_HTML {
+		return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+	}
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '`', '*', '_', '[', ']', '<', '>', '|', '#':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString("<br>")
+		case '\r':
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+/*
+* Get text as inline code
+ */
+func (d *docWriter) code(s string) string {
+	if d.format == DOC_HTML {
+		return "<code>" + d.text(s) + "</code>"
+	}
+	s = strings.ReplaceAll(strings.ReplaceAll(s, "\n", " "), "|", `\|`)
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		return fence + " " + s + " " + fence
+	}
+	return fence + s + fence
+}
+
+/*
+* Get text in bold
+ */
+func (d *docWriter) bold(s string) string {
+	if d.format == DOC_HTML {
+		return "<b>" + d.text(s) + "</b>"
+	}
+	return "**" + d.text(s) + "**"
+}
+
+/*
+* Get a link to an anchor of the document
+@param[in]	markup	markup of the link text
+@param[in]	id	id of the anchor
+*/
+func (d *docWriter) link(markup, id string) string {
+	if d.format == DOC_HTML {
+		return fmt.Sprintf(`<a href="#%s">%s</a>`, id, markup)
+	}
+	return fmt.Sprintf("[%s](#%s)", markup, id)
+}
+
+func (d *docWriter) heading(level int, id, markup string) {
+	if d.format == DOC_HTML {
+		if id != "" {
+			fmt.Fprintf(&d.b, "<h%d id=\"%s\">%s</h%d>\n", level, id, markup, level)
+		} else {
+			fmt.Fprintf(&d.b, "<h%d>%s</h%d>\n", level, markup, level)
+		}
+		return
+	}
+	if id != "" {
+		markup = fmt.Sprintf(`<a id="%s"></a>%s`, id, markup)
+	}
+	fmt.Fprintf(&d.b, "%s %s\n\n", strings.Repeat("#", level), markup)
+}
+
+func (d *docWriter) paragraph(markup string) {
+	if d.format == DOC_HTML {
+		fmt.Fprintf(&d.b, "<p>%s</p>\n", markup)
+		return
+	}
+	fmt.Fprintf(&d.b, "%s\n\n", markup)
+}
+
+func (d *docWriter) list(items []string) {
+	if d.format == DOC_HTML {
+		d.b.WriteString("<ul>\n")
+		for _, item := range items {
+			fmt.Fprintf(&d.b, "<li>%s</li>\n", item)
+		}
+		d.b.WriteString("</ul>\n")
+		return
+	}
+	for _, item := range items {
+		fmt.Fprintf(&d.b, "- %s\n", item)
+	}
+	d.b.WriteString("\n")
+}
+
+func (d *docWriter) table(headers []string, rows [][]string) {
+	if d.format == DOC_HTML {
+		d.b.WriteString("<table>\n<tr>")
+		for _, header := range headers {
+			fmt.Fprintf(&d.b, "<th>%s</th>", header)
+		}
+		d.b.WriteString("</tr>\n")
+		for _, row := range rows {
+			d.b.WriteString("<tr>")
+			for _, cell := range row {
+				fmt.Fprintf(&d.b, "<td>%s</td>", cell)
+			}
+			d.b.WriteString("</tr>\n")
+		}
+		d.b.WriteString("</table>\n")
+		return
+	}
+	fmt.Fprintf(&d.b, "| %s |\n|%s\n", strings.Join(headers, " | "), strings.Repeat("---|", len(headers)))
+	for _, row := range rows {
+		fmt.Fprintf(&d.b, "| %s |\n", strings.Join(row, " | "))
+	}
+	d.b.WriteString("\n")
+}
+
+/*
+* Write a block of source, e.g. of a diagram or of SQL
+@param[in]	language	language of the source, "mermaid" is rendered
+*/
+func (d *docWriter) source(language, source string) {
+	if d.format == DOC_HTML {
+		class := ""
+		if language == "mermaid" {
+			class = ` class="mermaid"`
+		}
+		fmt.Fprintf(&d.b, "<pre%s>\n%s</pre>\n", class, html.EscapeString(strings.TrimRight(source, "\n")+"\n"))
+		return
+	}
+	fence := "```"
+	for strings.Contains(source, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(&d.b, "%s%s\n%s\n%s\n\n", fence, language, strings.TrimRight(source, "\n"), fence)
+}
+
+/*
+* Get the id of the section of a table
+ */
+func getTableDocID(schemaName, name string) string {
+	return "table-" + getDiagramID(schemaName+"-"+name)
+}
+
+/*
+* Get the default of a column as in the DDL, empty if none
+ */
+func (c *Column) getDocDefault() string {
+	switch {
+	case c.GenerationExpressionUTF8 != "":
+		return ""
+	case c.DefaultValueNull && c.DefaultValueUTF8Null:
+		return "NULL"
+	case c.DefaultValueUTF8Null:
+		return ""
+	case c.DefaultOption != "":
+		return c.DefaultOption
+	case c.Type == CT_BIT && strings.HasPrefix(c.DefaultValueUTF8, "b'"):
+		return c.DefaultValueUTF8
+	}
+	return quoteMySQLString(c.DefaultValueUTF8)
+}
+
+/*
+* Get the attributes of a column that are not in the other fields of the
+data dictionary, e.g. "auto_increment"
+*/
+func (c *Column) getDocExtra() []string {
+	extra := make([]string, 0)
+	if c.IsAutoIncrement {
+		extra = append(extra, "auto_increment")
+	}
+	if c.UpdateOption != "" {
+		extra = append(extra, "on update "+c.UpdateOption)
+	}
+	if c.GenerationExpressionUTF8 != "" {
+		generated := "stored generated"
+		if c.IsVirtual {
+			generated = "virtual generated"
+		}
+		extra = append(extra, fmt.Sprintf("%s (%s)", generated, c.GenerationExpressionUTF8))
+	}
+	if c.isHiddenUser() {
+		extra = append(extra, "invisible")
+	}
+	return extra
+}
+
+/*
+* Write the section of a table
+ */
+func (d *docWriter) writeTable(g *FKGraph, table *TableSchema) {
+	d.heading(3, getTableDocID(table.SchemaName, table.Name), d.code(table.Name))
+	if table.Comment != "" {
+		d.paragraph(d.text(table.Comment))
+	}
+	rows := make([][]string, 0)
+	for _, column := range table.GetUserColumns() {
+		nullable := "NO"
+		if column.IsNullable {
+			nullable = "YES"
+		}
+		defaultValue := column.getDocDefault()
+		if defaultValue != "" {
+			defaultValue = d.code(defaultValue)
+		}
+		extra := column.getDocExtra()
+		for n := range extra {
+			extra[n] = d.text(extra[n])
+		}
+		rows = append(rows, []string{d.code(column.Name), d.code(column.ColumnTypeUTF8), nullable,
+			defaultValue, strings.Join(extra, ", "), d.text(column.Comment)})
+	}
+	d.paragraph(d.bold("Columns"))
+	d.table([]string{"Column", "Type", "Nullable", "Default", "Extra", "Comment"}, rows)
+	if indexes := table.GetUserIndexes(); len(indexes) != 0 {
+		rows = make([][]string, 0, len(indexes))
+		for _, index := range indexes {
+			keyParts := make([]string, len(index.KeyParts))
+			for n, keyPart := range index.KeyParts {
+				keyParts[n] = keyPart.Column.Name
+				if expression := keyPart.GetExpression(); expression != "" {
+					keyParts[n] = expression
+				}
+				if keyPart.Length != 0 {
+					keyParts[n] += fmt.Sprintf("(%d)", keyPart.Length)
+				}
+				if keyPart.Descending {
+					keyParts[n] += " DESC"
+				}
+				keyParts[n] = d.code(keyParts[n])
+			}
+			indexType := strings.ToUpper(index.Type.String())
+			if index.Type == IT_MULTIPLE {
+				indexType = "INDEX"
+			}
+			if !index.IsVisible && index.Type != IT_PRIMARY {
+				indexType += ", INVISIBLE"
+			}
+			rows = append(rows, []string{d.code(index.Name), indexType, strings.Join(keyParts, ", "),
+				d.text(index.Comment)})
+		}
+		d.paragraph(d.bold("Indexes"))
+		d.table([]string{"Index", "Type", "Columns", "Comment"}, rows)
+	}
+	references := make(map[*ForeignKey]*TableSchema)
+	referencedBy := make([]string, 0)
+	for _, edge := range g.Edges {
+		if edge.Table == table {
+			references[edge.ForeignKey] = edge.Referenced
+		}
+		if edge.Referenced == table {
+			name := fmt.Sprintf("%s.%s", edge.Table.SchemaName, edge.Table.Name)
+			referencedBy = append(referencedBy, fmt.Sprintf("%s (%s)",
+				d.link(d.code(name), getTableDocID(edge.Table.SchemaName, edge.Table.Name)),
+				d.code(edge.ForeignKey.Name)))
+		}
+	}
+	if len(table.ForeignKeys) != 0 {
+		rows = make([][]string, 0, len(table.ForeignKeys))
+		for _, fk := range table.ForeignKeys {
+			columns := make([]string, len(fk.ColumnNames))
+			for n, name := range fk.ColumnNames {
+				columns[n] = d.code(name)
+			}
+			referencedName := fmt.Sprintf("%s.%s(%s)", fk.ReferencedTableSchemaName, fk.ReferencedTableName,
+				strings.Join(fk.ReferenceNames, ", "))
+			reference := d.code(referencedName)
+			if referenced := references[fk]; referenced != nil {
+				reference = d.link(reference, getTableDocID(referenced.SchemaName, referenced.Name))
+			}
+			rows = append(rows, []string{d.code(fk.Name), strings.Join(columns, ", "), reference,
+				fk.UpdateRule.String(), fk.DeleteRule.String()})
+		}
+		d.paragraph(d.bold("Foreign keys"))
+		d.table([]string{"Foreign key", "Columns", "References", "On update", "On delete"}, rows)
+	}
+	if len(referencedBy) != 0 {
+		d.paragraph(d.bold("Referenced by"))
+		d.list(referencedBy)
+	}
+	if len(table.CheckConstraints) != 0 {
+		items := make([]string, len(table.CheckConstraints))
+		for n, cc := range table.CheckConstraints {
+			items[n] = d.code(cc.GetDefinition())
+		}
+		d.paragraph(d.bold("Check constraints"))
+		d.list(items)
+	}
+	if table.Partitioning != nil {
+		d.paragraph(d.bold("Partitioning"))
+		d.source("sql", table.Partitioning.GetDefinition())
+	}
+}
+
+/*
+* Generate the data dictionary of tables: an ER diagram, a table of
+contents, and for each schema and table its columns, indexes, foreign keys
+with links to the referenced tables, the tables that reference it, check
+constraints and partitioning.
+@param[in]	tables	table schemas, hidden tables are ignored
+@param[in]	opts	options, nil for defaults
+@return Markdown or HTML document
+*/
+func GenerateDataDictionary(tables []*TableSchema, opts *DocOptions) (string, error) {
+	if opts == nil {
+		opts = NewDocOptions()
+	}
+	if opts.Format != DOC_MARKDOWN && opts.Format != DOC_HTML {
+		return "", fmt.Errorf("unknown document format %s", opts.Format)
+	}
+	g := NewFKGraph(tables)
+	d := &docWriter{format: opts.Format}
+	d.heading(1, "", d.text(opts.Title))
+	if opts.Diagram != DIAGRAM_NONE && opts.Diagram != "" {
+		diagram, err := GenerateERDiagram(g.Tables, opts.Diagram)
+		if err != nil {
+			return "", err
+		}
+		language := "mermaid"
+		if opts.Diagram == DIAGRAM_GRAPHVIZ {
+			language = "dot"
+		}
+		d.heading(2, "diagram", "Diagram")
+		d.source(language, diagram)
+	}
+	d.heading(2, "tables", "Tables")
+	items := make([]string, 0, len(g.Tables))
+	for _, table := range g.Tables {
+		name := fmt.Sprintf("%s.%s", table.SchemaName, table.Name)
+		item := d.link(d.code(name), getTableDocID(table.SchemaName, table.Name))
+		if table.Comment != "" {
+			item += " - " + d.text(strings.SplitN(table.Comment, "\n", 2)[0])
+		}
+		items = append(items, item)
+	}
+	d.list(items)
+	currentSchema := ""
+	for n, table := range g.Tables {
+		if n == 0 || table.SchemaName != currentSchema {
+			d.heading(2, "schema-"+getDiagramID(table.SchemaName), d.code(table.SchemaName))
+			currentSchema = table.SchemaName
+		}
+		d.writeTable(g, table)
+	}
+	if opts.Format == DOC_MARKDOWN {
+		return d.b.String(), nil
+	}
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(opts.Title))
+	b.WriteString("<style>\nbody { font-family: sans-serif; margin: 2em; }\n" +
+		"table { border-collapse: collapse; margin-bottom: 1em; }\n" +
+		"th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }\n" +
+		"th { background: #f4f4f4; }\n</style>\n")
+	if opts.Diagram == DIAGRAM_MERMAID {
+		fmt.Fprintf(&b, "<script type=\"module\">\nimport mermaid from \"%s\";\n"+
+			"mermaid.initialize({ startOnLoad: true });\n</script>\n", MERMAID_SCRIPT_URL)
+	}
+	b.WriteString("</head>\n<body>\n")
+	b.WriteString(d.b.String())
+	b.WriteString("</body>\n</html>\n")
+	return b.String(), nil
+}
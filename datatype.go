@@ -1,8 +1,27 @@
 package ibd2schema
 
 const (
+	/** Row ID type size in bytes. */
+	DATA_ROW_ID_LEN uint32 = 6
 	/** Transaction ID type size in bytes. */
 	DATA_TRX_ID_LEN uint32 = 6
 	/** Rollback data pointer type size in bytes. */
 	DATA_ROLL_PTR_LEN uint32 = 7
+	/** Number of decimal digits packed in 4 bytes of a binary DECIMAL */
+	DIG_PER_DEC1 = 9
 )
+
+/* Bytes needed to store the leftover digits of a binary DECIMAL */
+var dig2bytes = [DIG_PER_DEC1 + 1]uint32{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+
+/*
+* Size of a DECIMAL value in the binary format
+@param[in]	precision	total number of digits
+@param[in]	scale		number of digits after the decimal point
+@return size in bytes
+*/
+func DecimalBinSize(precision, scale uint32) uint32 {
+	intg := precision - scale
+	return (intg/DIG_PER_DEC1)*4 + dig2bytes[intg%DIG_PER_DEC1] +
+		(scale/DIG_PER_DEC1)*4 + dig2bytes[scale%DIG_PER_DEC1]
+}
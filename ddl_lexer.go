@@ -0,0 +1,445 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+type ddlTokenKind int
+
+const (
+	DDL_TOKEN_EOF ddlTokenKind = iota
+	/** unquoted identifier or keyword */
+	DDL_TOKEN_WORD
+	/** identifier quoted with backticks */
+	DDL_TOKEN_QUOTED_IDENT
+	DDL_TOKEN_STRING
+	DDL_TOKEN_NUMBER
+	/** hexadecimal literal, x'1F' or 0x1F */
+	DDL_TOKEN_HEX
+	/** bit literal, b'101' or 0b101 */
+	DDL_TOKEN_BIT
+	DDL_TOKEN_SYMBOL
+)
+
+type ddlToken struct {
+	kind ddlTokenKind
+	/** unescaped value of strings and quoted identifiers, digits of hex and bit literals */
+	text string
+	/** offset in the statement */
+	pos int
+	/** true if the token follows white space or a comment */
+	spaceBefore bool
+}
+
+/*
+* Check if the token is the keyword, case-insensitively
+ */
+func (t *ddlToken) is(keyword string) bool {
+	return t.kind == DDL_TOKEN_WORD && strings.EqualFold(t.text, keyword)
+}
+
+func (t *ddlToken) isSymbol(symbol string) bool {
+	return t.kind == DDL_TOKEN_SYMBOL && t.text == symbol
+}
+
+/*
+* Check if the token is an identifier, a quoted identifier or a word
+ */
+func (t *ddlToken) isIdent() bool {
+	return t.kind == DDL_TOKEN_WORD || t.kind == DDL_TOKEN_QUOTED_IDENT
+}
+
+var ddlSymbols = []string{
+	"<=>", "<<", ">>", "<=", ">=", "<>", "!=", "||", "&&", ":=", "->>", "->",
+	"(", ")", ",", ";", ".", "=", "<", ">", "+", "-", "*", "/", "%", "^", "~", "!", "|", "&", "@",
+}
+
+func isDDLWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= '0' && c <= '9') ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDDLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+/*
+* Split MySQL DDL into tokens. Comments are skipped, the content of
+versioned comments like / *!80023 INVISIBLE * / is tokenized as if it was
+not commented out.
+@param[in]	sql	SQL text
+@return tokens, ending with DDL_TOKEN_EOF
+*/
+func tokenizeDDL(sql string) (tokens []*ddlToken, err error) {
+	versionedComments := 0
+	space := false
+	for pos := 0; pos < len(sql); {
+		c := sql[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			pos++
+			space = true
+			continue
+		case c == '#' || (strings.HasPrefix(sql[pos:], "--") &&
+			(pos+2 == len(sql) || strings.ContainsRune(" \t\r\n", rune(sql[pos+2])))):
+			end := strings.IndexByte(sql[pos:], '\n')
+			if end == -1 {
+				end = len(sql) - pos
+			}
+			pos += end
+			space = true
+			continue
+		case strings.HasPrefix(sql[pos:], "/*!") || strings.HasPrefix(sql[pos:], "/*+"):
+			pos += 3
+			for pos < len(sql) && isDDLDigit(sql[pos]) {
+				pos++
+			}
+			versionedComments++
+			space = true
+			continue
+		case strings.HasPrefix(sql[pos:], "/*"):
+			end := strings.Index(sql[pos+2:], "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", pos)
+			}
+			pos += end + 4
+			space = true
+			continue
+		case versionedComments > 0 && strings.HasPrefix(sql[pos:], "*/"):
+			versionedComments--
+			pos += 2
+			space = true
+			continue
+		}
+		token := &ddlToken{pos: pos, spaceBefore: space}
+		space = false
+		switch {
+		case c == '`':
+			var b strings.Builder
+			for pos++; ; pos++ {
+				if pos >= len(sql) {
+					return nil, fmt.Errorf("unterminated quoted identifier at offset %d", token.pos)
+				}
+				if sql[pos] == '`' {
+					if pos+1 < len(sql) && sql[pos+1] == '`' {
+						b.WriteByte('`')
+						pos++
+						continue
+					}
+					pos++
+					break
+				}
+				b.WriteByte(sql[pos])
+			}
+			token.kind, token.text = DDL_TOKEN_QUOTED_IDENT, b.String()
+		case c == '\'' || c == '"':
+			token.kind = DDL_TOKEN_STRING
+			token.text, pos, err = scanDDLString(sql, pos)
+			if err != nil {
+				return nil, err
+			}
+		case (c == 'x' || c == 'X' || c == 'b' || c == 'B') && pos+1 < len(sql) && sql[pos+1] == '\'':
+			end := strings.IndexByte(sql[pos+2:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated literal at offset %d", pos)
+			}
+			token.kind = DDL_TOKEN_HEX
+			if c == 'b' || c == 'B' {
+				token.kind = DDL_TOKEN_BIT
+			}
+			token.text = sql[pos+2 : pos+2+end]
+			pos += end + 3
+		case c == '0' && pos+2 < len(sql) && (sql[pos+1] == 'x' || sql[pos+1] == 'b'):
+			end := pos + 2
+			for end < len(sql) && isDDLWordChar(sql[end]) {
+				end++
+			}
+			token.kind = DDL_TOKEN_HEX
+			if sql[pos+1] == 'b' {
+				token.kind = DDL_TOKEN_BIT
+			}
+			token.text = sql[pos+2 : end]
+			pos = end
+		case isDDLDigit(c) || (c == '.' && pos+1 < len(sql) && isDDLDigit(sql[pos+1])):
+			end := pos
+			for end < len(sql) && (isDDLDigit(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			if end < len(sql) && (sql[end] == 'e' || sql[end] == 'E') {
+				exp := end + 1
+				if exp < len(sql) && (sql[exp] == '+' || sql[exp] == '-') {
+					exp++
+				}
+				if exp < len(sql) && isDDLDigit(sql[exp]) {
+					for end = exp; end < len(sql) && isDDLDigit(sql[end]); end++ {
+					}
+				}
+			}
+			if end < len(sql) && isDDLWordChar(sql[end]) {
+				/* identifiers may start with digits */
+				for end < len(sql) && isDDLWordChar(sql[end]) {
+					end++
+				}
+				token.kind = DDL_TOKEN_WORD
+			} else {
+				token.kind = DDL_TOKEN_NUMBER
+			}
+			token.text = sql[pos:end]
+			pos = end
+		case isDDLWordChar(c):
+			end := pos
+			for end < len(sql) && isDDLWordChar(sql[end]) {
+				end++
+			}
+			token.kind, token.text = DDL_TOKEN_WORD, sql[pos:end]
+			pos = end
+		default:
+			for _, symbol := range ddlSymbols {
+				if strings.HasPrefix(sql[pos:], symbol) {
+					token.kind, token.text = DDL_TOKEN_SYMBOL, symbol
+					break
+				}
+			}
+			if token.kind != DDL_TOKEN_SYMBOL {
+				return nil, fmt.Errorf("unexpected character '%c' at offset %d", c, pos)
+			}
+			pos += len(token.text)
+		}
+		tokens = append(tokens, token)
+	}
+	return append(tokens, &ddlToken{kind: DDL_TOKEN_EOF, pos: len(sql)}), nil
+}
+
+/*
+* Scan a quoted string starting at pos, adjacent strings are concatenated
+by the caller.
+@return unescaped string and the offset after the closing quote
+*/
+func scanDDLString(sql string, pos int) (s string, end int, err error) {
+	quote := sql[pos]
+	var b strings.Builder
+	for pos++; pos < len(sql); pos++ {
+		c := sql[pos]
+		switch {
+		case c == quote && pos+1 < len(sql) && sql[pos+1] == quote:
+			b.WriteByte(quote)
+			pos++
+		case c == quote:
+			return b.String(), pos + 1, nil
+		case c == '\\' && pos+1 < len(sql):
+			pos++
+			switch sql[pos] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'Z':
+				b.WriteByte(0x1a)
+			case '%', '_':
+				/* kept escaped for LIKE patterns */
+				b.WriteByte('\\')
+				b.WriteByte(sql[pos])
+			default:
+				b.WriteByte(sql[pos])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string at offset %d", pos)
+}
+
+/*
+* Words of expressions that are keywords rather than column names
+ */
+var ddlExpressionKeywords = map[string]bool{
+	"and": true, "or": true, "xor": true, "not": true, "is": true, "null": true,
+	"true": true, "false": true, "unknown": true, "like": true, "rlike": true,
+	"regexp": true, "escape": true, "in": true, "between": true, "div": true,
+	"mod": true, "case": true, "when": true, "then": true, "else": true, "end": true,
+	"interval": true, "as": true, "binary": true, "collate": true, "distinct": true,
+	"using": true, "separator": true, "charset": true, "character": true, "set": true,
+	"signed": true, "unsigned": true, "integer": true, "char": true, "nchar": true,
+	"decimal": true, "date": true, "datetime": true, "time": true, "json": true,
+	"double": true, "float": true, "real": true, "year": true, "array": true,
+	"maxvalue": true, "current_timestamp": true, "current_date": true,
+	"current_time": true, "current_user": true, "localtime": true,
+	"localtimestamp": true, "utc_date": true, "utc_time": true, "utc_timestamp": true,
+	"microsecond": true, "second": true, "minute": true, "hour": true, "day": true,
+	"week": true, "month": true, "quarter": true, "second_microsecond": true,
+	"minute_microsecond": true, "minute_second": true, "hour_microsecond": true,
+	"hour_second": true, "hour_minute": true, "day_microsecond": true,
+	"day_second": true, "day_minute": true, "day_hour": true, "year_month": true,
+	"both": true, "leading": true, "trailing": true, "from": true, "for": true,
+	"sounds": true, "member": true, "of": true, "any": true, "all": true,
+}
+
+/*
+* Keywords that are followed by a space before an opening parenthesis
+ */
+var ddlOperatorKeywords = map[string]bool{
+	"and": true, "or": true, "xor": true, "not": true, "in": true, "is": true,
+	"like": true, "regexp": true, "rlike": true, "between": true, "case": true,
+	"when": true, "then": true, "else": true, "as": true, "interval": true,
+	"div": true, "mod": true, "escape": true,
+}
+
+/*
+* Format expression tokens in a canonical way: keywords and function names
+in lower case, column names quoted with backticks, strings quoted with
+single quotes, single spaces between operands and operators, and grouping
+parentheses removed. Two expressions that only differ by formatting or
+redundant parentheses give the same result, like the expression that the
+server prints and the one that was written in the CREATE TABLE statement.
+The result is only used to compare expressions, removing the parentheses
+may make different expressions compare equal.
+@param[in]	tokens	expression tokens, without DDL_TOKEN_EOF
+@return canonical expression
+*/
+func formatExpressionTokens(tokens []*ddlToken) string {
+	/* find the grouping parentheses */
+	skip := make([]bool, len(tokens))
+	stack := make([]int, 0)
+	for n, token := range tokens {
+		switch {
+		case token.isSymbol("("):
+			stack = append(stack, n)
+		case token.isSymbol(")") && len(stack) != 0:
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			/* function calls keep their arguments and IN its list */
+			grouping := open == 0
+			if open > 0 {
+				before := tokens[open-1]
+				lower := strings.ToLower(before.text)
+				grouping = (before.kind == DDL_TOKEN_SYMBOL && before.text != ")") ||
+					(before.kind == DDL_TOKEN_WORD && ddlOperatorKeywords[lower] && lower != "in")
+			}
+			if grouping {
+				/* a list like (1,2) is not a grouping */
+				depth := 0
+				for _, t := range tokens[open+1 : n] {
+					switch {
+					case t.isSymbol("("):
+						depth++
+					case t.isSymbol(")"):
+						depth--
+					case t.isSymbol(",") && depth == 0:
+						grouping = false
+					}
+				}
+			}
+			if grouping {
+				skip[open], skip[n] = true, true
+			}
+		}
+	}
+	var b strings.Builder
+	var previous *ddlToken
+	unary := false
+	for n, token := range tokens {
+		if skip[n] {
+			continue
+		}
+		var text string
+		switch token.kind {
+		case DDL_TOKEN_WORD:
+			lower := strings.ToLower(token.text)
+			next := nextUnskipped(tokens, skip, n)
+			isCall := next != nil && next.isSymbol("(")
+			switch {
+			case isCall || ddlExpressionKeywords[lower]:
+				text = lower
+			case strings.HasPrefix(token.text, "_") && next != nil && next.kind == DDL_TOKEN_STRING:
+				/* character set introducer */
+				text = lower
+			default:
+				text = "`" + token.text + "`"
+			}
+		case DDL_TOKEN_QUOTED_IDENT:
+			text = "`" + strings.ReplaceAll(token.text, "`", "``") + "`"
+		case DDL_TOKEN_STRING:
+			text = "'" + strings.ReplaceAll(strings.ReplaceAll(token.text, `\`, `\\`), "'", `\'`) + "'"
+		case DDL_TOKEN_HEX:
+			text = "0x" + strings.ToLower(token.text)
+		case DDL_TOKEN_BIT:
+			text = "0b" + token.text
+		default:
+			text = strings.ToLower(token.text)
+		}
+		if previous != nil && !unary && needsSpace(previous, token) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(text)
+		unary = isUnaryOperator(previous, token)
+		previous = token
+	}
+	return b.String()
+}
+
+func nextUnskipped(tokens []*ddlToken, skip []bool, n int) *ddlToken {
+	for n++; n < len(tokens); n++ {
+		if !skip[n] {
+			return tokens[n]
+		}
+	}
+	return nil
+}
+
+/*
+* Check if a space separates two tokens of a canonical expression
+ */
+func needsSpace(previous, token *ddlToken) bool {
+	switch {
+	case token.isSymbol(")") || token.isSymbol(",") || token.isSymbol("."):
+		return false
+	case previous.isSymbol("(") || previous.isSymbol(",") || previous.isSymbol("."):
+		return false
+	case token.isSymbol("(") && previous.kind == DDL_TOKEN_WORD:
+		return ddlOperatorKeywords[strings.ToLower(previous.text)]
+	case previous.kind == DDL_TOKEN_WORD && strings.HasPrefix(previous.text, "_") &&
+		token.kind == DDL_TOKEN_STRING:
+		return false
+	}
+	return true
+}
+
+/*
+* Check if the token is a unary operator, which follows no operand
+ */
+func isUnaryOperator(previous, token *ddlToken) bool {
+	if !token.isSymbol("-") && !token.isSymbol("+") && !token.isSymbol("~") && !token.isSymbol("!") {
+		return false
+	}
+	switch {
+	case previous == nil:
+		return true
+	case previous.kind == DDL_TOKEN_SYMBOL:
+		return previous.text != ")"
+	case previous.kind == DDL_TOKEN_WORD:
+		lower := strings.ToLower(previous.text)
+		return ddlExpressionKeywords[lower] && lower != "null" && lower != "true" &&
+			lower != "false" && lower != "unknown" && lower != "maxvalue"
+	}
+	return false
+}
+
+/*
+* Normalize an expression, see formatExpressionTokens.
+@param[in]	expression	SQL expression
+@return canonical expression, the expression itself if it cannot be tokenized
+*/
+func NormalizeExpression(expression string) string {
+	tokens, err := tokenizeDDL(expression)
+	if err != nil {
+		return expression
+	}
+	return formatExpressionTokens(tokens[:len(tokens)-1])
+}
@@ -0,0 +1,2107 @@
+package ibd2schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	/** collation_server of MySQL 8.0 */
+	DDL_DEFAULT_COLLATION = "utf8mb4_0900_ai_ci"
+)
+
+type DDLParseOptions struct {
+	/** schema of tables whose name is not qualified, if there is no USE statement */
+	SchemaName string
+	/** collation of tables without CHARSET or COLLATE */
+	DefaultCollation string
+	/** mysql_version_id of the parsed tables */
+	MysqlVersionID uint32
+}
+
+func NewDDLParseOptions() *DDLParseOptions {
+	return &DDLParseOptions{DefaultCollation: DDL_DEFAULT_COLLATION}
+}
+
+type ddlColumn struct {
+	name       string
+	typeName   string
+	args       []string
+	elements   []string
+	unsigned   bool
+	zerofill   bool
+	charset    string
+	collation  string
+	binaryAttr bool
+	/** nil if neither NULL nor NOT NULL is given */
+	nullable *bool
+	/** DEFAULT clause tokens, nil if there is none */
+	defaultTokens []*ddlToken
+	onUpdate      string
+	autoIncrement bool
+	comment       string
+	generated     string
+	virtual       bool
+	hidden        HiddenType
+	srid          *uint32
+}
+
+type ddlKeyPart struct {
+	column string
+	/** normalized expression of a functional key part */
+	expression string
+	/** prefix length in characters, 0 for the whole column */
+	length     uint32
+	descending bool
+}
+
+type ddlIndex struct {
+	name      string
+	indexType IndexType
+	parts     []*ddlKeyPart
+	algorithm IndexAlgorithm
+	explicit  bool
+	comment   string
+	parser    string
+	visible   bool
+}
+
+type ddlForeignKey struct {
+	name       string
+	symbol     string
+	indexName  string
+	columns    []string
+	refSchema  string
+	refTable   string
+	refColumns []string
+	onDelete   FKRule
+	onUpdate   FKRule
+	match      FKMatchOption
+}
+
+type ddlCheck struct {
+	name     string
+	clause   string
+	enforced bool
+}
+
+type ddlPartition struct {
+	name          string
+	description   string
+	comment       string
+	subpartitions []*ddlPartition
+}
+
+type ddlPartitioning struct {
+	partitionType    PartitionType
+	expression       string
+	number           int
+	subpartitionType SubpartitionType
+	subExpression    string
+	subNumber        int
+	partitions       []*ddlPartition
+}
+
+type ddlTable struct {
+	schemaName   string
+	name         string
+	columns      []*ddlColumn
+	indexes      []*ddlIndex
+	foreignKeys  []*ddlForeignKey
+	checks       []*ddlCheck
+	engine       string
+	charset      string
+	collation    string
+	comment      string
+	rowFormat    RowFormat
+	options      map[string]string
+	partitioning *ddlPartitioning
+}
+
+type ddlParser struct {
+	sql    string
+	tokens []*ddlToken
+	pos    int
+	opts   *DDLParseOptions
+	schema string
+}
+
+func (p *ddlParser) peek() *ddlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *ddlParser) peekAt(n int) *ddlToken {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *ddlParser) next() *ddlToken {
+	token := p.tokens[p.pos]
+	if token.kind != DDL_TOKEN_EOF {
+		p.pos++
+	}
+	return token
+}
+
+/*
+* Consume the keywords if the next tokens are these keywords
+ */
+func (p *ddlParser) accept(keywords ...string) bool {
+	for n, keyword := range keywords {
+		if !p.peekAt(n).is(keyword) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+func (p *ddlParser) acceptSymbol(symbol string) bool {
+	if p.peek().isSymbol(symbol) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *ddlParser) errorf(format string, args ...interface{}) error {
+	token := p.peek()
+	line := strings.Count(p.sql[:token.pos], "\n") + 1
+	near := p.sql[token.pos:]
+	if len(near) > 30 {
+		near = near[:30]
+	}
+	return fmt.Errorf("line %d near '%s': %s", line, near, fmt.Sprintf(format, args...))
+}
+
+func (p *ddlParser) expect(keywords ...string) error {
+	if !p.accept(keywords...) {
+		return p.errorf("expected %s", strings.Join(keywords, " "))
+	}
+	return nil
+}
+
+func (p *ddlParser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return p.errorf("expected '%s'", symbol)
+	}
+	return nil
+}
+
+func (p *ddlParser) parseIdent() (string, error) {
+	token := p.peek()
+	if !token.isIdent() {
+		return "", p.errorf("expected identifier")
+	}
+	p.pos++
+	return token.text, nil
+}
+
+/*
+* Parse a name that may be qualified by a schema name
+ */
+func (p *ddlParser) parseQualifiedName() (schema, name string, err error) {
+	name, err = p.parseIdent()
+	if err != nil {
+		return "", "", err
+	}
+	if p.acceptSymbol(".") {
+		schema = name
+		name, err = p.parseIdent()
+	}
+	return schema, name, err
+}
+
+/*
+* Parse a string literal, adjacent strings are concatenated
+ */
+func (p *ddlParser) parseString() (string, error) {
+	if p.peek().kind == DDL_TOKEN_WORD && strings.HasPrefix(p.peek().text, "_") &&
+		p.peekAt(1).kind == DDL_TOKEN_STRING {
+		/* character set introducer */
+		p.pos++
+	}
+	if p.peek().kind != DDL_TOKEN_STRING {
+		return "", p.errorf("expected string")
+	}
+	var b strings.Builder
+	for p.peek().kind == DDL_TOKEN_STRING {
+		b.WriteString(p.next().text)
+	}
+	return b.String(), nil
+}
+
+func (p *ddlParser) parseNumber() (string, error) {
+	if p.peek().kind != DDL_TOKEN_NUMBER {
+		return "", p.errorf("expected number")
+	}
+	return p.next().text, nil
+}
+
+/*
+* Parse a value of a table or partition option, after an optional '='
+ */
+func (p *ddlParser) parseOptionValue() (string, error) {
+	p.acceptSymbol("=")
+	token := p.next()
+	switch token.kind {
+	case DDL_TOKEN_STRING, DDL_TOKEN_NUMBER, DDL_TOKEN_WORD, DDL_TOKEN_QUOTED_IDENT:
+		return token.text, nil
+	}
+	p.pos--
+	return "", p.errorf("expected option value")
+}
+
+/*
+* Parse the tokens enclosed in parentheses
+@return tokens between the parentheses
+*/
+func (p *ddlParser) parseParenthesized() ([]*ddlToken, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	start, depth := p.pos, 1
+	for {
+		token := p.next()
+		switch {
+		case token.kind == DDL_TOKEN_EOF:
+			return nil, p.errorf("unbalanced parentheses")
+		case token.isSymbol("("):
+			depth++
+		case token.isSymbol(")"):
+			depth--
+			if depth == 0 {
+				return p.tokens[start : p.pos-1], nil
+			}
+		}
+	}
+}
+
+/*
+* Parse a parenthesized list of identifiers
+ */
+func (p *ddlParser) parseIdentList() (names []string, err error) {
+	if err = p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	for {
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if p.acceptSymbol(")") {
+			return names, nil
+		}
+		if err = p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+/*
+* Skip the tokens up to the end of the statement
+ */
+func (p *ddlParser) skipStatement() {
+	for p.peek().kind != DDL_TOKEN_EOF && !p.peek().isSymbol(";") {
+		p.next()
+	}
+}
+
+/*
+* Parse the CREATE TABLE statements of an SQL script like the output of
+mysqldump or a schema repository into the typed model. The statements are
+converted to the SDI of MySQL 8.0 and parsed as such, so the tables can be
+compared with the tables of .ibd files, see DiffTableSchemaLists. USE
+statements set the schema of the following tables, other statements are
+ignored.
+@param[in]	script	SQL script
+@param[in]	opts	parse options, nil for defaults
+@return parsed tables in statement order
+*/
+func ParseDDLScript(script string, opts *DDLParseOptions) (tables []*TableSchema, err error) {
+	if opts == nil {
+		opts = NewDDLParseOptions()
+	}
+	tokens, err := tokenizeDDL(script)
+	if err != nil {
+		return nil, err
+	}
+	p := &ddlParser{sql: script, tokens: tokens, opts: opts, schema: opts.SchemaName}
+	tables = make([]*TableSchema, 0)
+	for p.peek().kind != DDL_TOKEN_EOF {
+		switch {
+		case p.acceptSymbol(";"):
+			continue
+		case p.accept("USE"):
+			p.schema, err = p.parseIdent()
+			if err != nil {
+				return nil, err
+			}
+		case p.peek().is("CREATE") && (p.peekAt(1).is("TABLE") ||
+			(p.peekAt(1).is("TEMPORARY") && p.peekAt(2).is("TABLE"))):
+			table, err := p.parseCreateTable()
+			if err != nil {
+				return nil, err
+			}
+			tableSchema, err := table.toTableSchema(opts)
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %v", table.name, err)
+			}
+			tables = append(tables, tableSchema)
+		}
+		p.skipStatement()
+	}
+	return tables, nil
+}
+
+/*
+* Parse one CREATE TABLE statement, see ParseDDLScript.
+ */
+func ParseCreateTable(ddl string, opts *DDLParseOptions) (*TableSchema, error) {
+	tables, err := ParseDDLScript(ddl, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(tables) != 1 {
+		return nil, fmt.Errorf("expected 1 CREATE TABLE statement, got %d", len(tables))
+	}
+	return tables[0], nil
+}
+
+func (p *ddlParser) parseCreateTable() (table *ddlTable, err error) {
+	p.accept("CREATE")
+	p.accept("TEMPORARY")
+	if err = p.expect("TABLE"); err != nil {
+		return nil, err
+	}
+	p.accept("IF", "NOT", "EXISTS")
+	table = &ddlTable{options: make(map[string]string)}
+	table.schemaName, table.name, err = p.parseQualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if table.schemaName == "" {
+		table.schemaName = p.schema
+	}
+	if p.peek().is("LIKE") || p.peek().is("AS") || p.peek().is("SELECT") {
+		return nil, p.errorf("CREATE TABLE %s is not supported", p.peek().text)
+	}
+	if err = p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	for {
+		if err = p.parseCreateDefinition(table); err != nil {
+			return nil, err
+		}
+		if p.acceptSymbol(")") {
+			break
+		}
+		if err = p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+	if err = p.parseTableOptions(table); err != nil {
+		return nil, err
+	}
+	if p.accept("PARTITION", "BY") {
+		if table.partitioning, err = p.parsePartitioning(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.peek().isSymbol(";") && p.peek().kind != DDL_TOKEN_EOF {
+		return nil, p.errorf("unexpected token")
+	}
+	return table, nil
+}
+
+/*
+* Parse an optional constraint symbol, CONSTRAINT [symbol]
+ */
+func (p *ddlParser) parseConstraintSymbol() (symbol string, err error) {
+	if !p.accept("CONSTRAINT") {
+		return "", nil
+	}
+	if p.peek().isIdent() && !p.peek().is("PRIMARY") && !p.peek().is("UNIQUE") &&
+		!p.peek().is("FOREIGN") && !p.peek().is("CHECK") {
+		return p.parseIdent()
+	}
+	return "", nil
+}
+
+func (p *ddlParser) parseCreateDefinition(table *ddlTable) (err error) {
+	symbol, err := p.parseConstraintSymbol()
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.accept("PRIMARY", "KEY"):
+		index := &ddlIndex{name: "PRIMARY", indexType: IT_PRIMARY}
+		return p.parseIndexDefinition(table, index, false)
+	case p.accept("UNIQUE"):
+		if !p.accept("INDEX") {
+			p.accept("KEY")
+		}
+		index := &ddlIndex{name: symbol, indexType: IT_UNIQUE}
+		return p.parseIndexDefinition(table, index, true)
+	case p.accept("FOREIGN", "KEY"):
+		return p.parseForeignKey(table, symbol)
+	case p.accept("CHECK"):
+		return p.parseCheck(table, symbol)
+	case symbol != "":
+		return p.errorf("expected PRIMARY KEY, UNIQUE, FOREIGN KEY or CHECK")
+	case p.accept("INDEX") || p.accept("KEY"):
+		return p.parseIndexDefinition(table, &ddlIndex{indexType: IT_MULTIPLE}, true)
+	case p.accept("FULLTEXT") || p.accept("SPATIAL"):
+		index := &ddlIndex{indexType: IT_FULLTEXT}
+		if p.tokens[p.pos-1].is("SPATIAL") {
+			index.indexType = IT_SPATIAL
+		}
+		if !p.accept("INDEX") {
+			p.accept("KEY")
+		}
+		return p.parseIndexDefinition(table, index, true)
+	}
+	return p.parseColumnDefinition(table)
+}
+
+/*
+* Parse the index name, type, key parts and options of an index definition
+@param[in]	table		table of the index
+@param[in]	index		index with the type and name given so far
+@param[in]	withName	true if the definition may name the index
+*/
+func (p *ddlParser) parseIndexDefinition(table *ddlTable, index *ddlIndex, withName bool) (err error) {
+	index.visible = true
+	if withName && p.peek().isIdent() && !p.peek().is("USING") && !p.peek().isSymbol("(") {
+		if index.name, err = p.parseIdent(); err != nil {
+			return err
+		}
+	}
+	if err = p.parseIndexType(index); err != nil {
+		return err
+	}
+	if err = p.expectSymbol("("); err != nil {
+		return err
+	}
+	for {
+		part := &ddlKeyPart{}
+		if p.peek().isSymbol("(") {
+			tokens, err := p.parseParenthesized()
+			if err != nil {
+				return err
+			}
+			part.expression = formatExpressionTokens(tokens)
+		} else {
+			if part.column, err = p.parseIdent(); err != nil {
+				return err
+			}
+			if p.acceptSymbol("(") {
+				length, err := p.parseNumber()
+				if err != nil {
+					return err
+				}
+				n, _ := strconv.ParseUint(length, 10, 32)
+				part.length = uint32(n)
+				if err = p.expectSymbol(")"); err != nil {
+					return err
+				}
+			}
+		}
+		if p.accept("DESC") {
+			part.descending = true
+		} else {
+			p.accept("ASC")
+		}
+		index.parts = append(index.parts, part)
+		if p.acceptSymbol(")") {
+			break
+		}
+		if err = p.expectSymbol(","); err != nil {
+			return err
+		}
+	}
+	for {
+		switch {
+		case p.peek().is("USING") || p.peek().is("TYPE"):
+			if err = p.parseIndexType(index); err != nil {
+				return err
+			}
+		case p.accept("KEY_BLOCK_SIZE"):
+			if _, err = p.parseOptionValue(); err != nil {
+				return err
+			}
+		case p.accept("COMMENT"):
+			if index.comment, err = p.parseString(); err != nil {
+				return err
+			}
+		case p.accept("WITH", "PARSER"):
+			if index.parser, err = p.parseIdent(); err != nil {
+				return err
+			}
+		case p.accept("VISIBLE"):
+			index.visible = true
+		case p.accept("INVISIBLE"):
+			index.visible = false
+		case p.accept("ENGINE_ATTRIBUTE") || p.accept("SECONDARY_ENGINE_ATTRIBUTE"):
+			if _, err = p.parseOptionValue(); err != nil {
+				return err
+			}
+		default:
+			table.indexes = append(table.indexes, index)
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) parseIndexType(index *ddlIndex) error {
+	if !p.accept("USING") && !p.accept("TYPE") {
+		return nil
+	}
+	switch {
+	case p.accept("BTREE"):
+		index.algorithm = IA_BTREE
+	case p.accept("HASH"):
+		index.algorithm = IA_HASH
+	case p.accept("RTREE"):
+		index.algorithm = IA_RTREE
+	default:
+		return p.errorf("unknown index type")
+	}
+	index.explicit = true
+	return nil
+}
+
+func (p *ddlParser) parseFKRule() (FKRule, error) {
+	switch {
+	case p.accept("RESTRICT"):
+		return FK_RULE_RESTRICT, nil
+	case p.accept("CASCADE"):
+		return FK_RULE_CASCADE, nil
+	case p.accept("SET", "NULL"):
+		return FK_RULE_SET_NULL, nil
+	case p.accept("SET", "DEFAULT"):
+		return FK_RULE_SET_DEFAULT, nil
+	case p.accept("NO", "ACTION"):
+		return FK_RULE_NO_ACTION, nil
+	}
+	return 0, p.errorf("unknown foreign key rule")
+}
+
+/*
+* Parse the REFERENCES clause of a foreign key
+ */
+func (p *ddlParser) parseReferences(fk *ddlForeignKey) (err error) {
+	if err = p.expect("REFERENCES"); err != nil {
+		return err
+	}
+	if fk.refSchema, fk.refTable, err = p.parseQualifiedName(); err != nil {
+		return err
+	}
+	if fk.refColumns, err = p.parseIdentList(); err != nil {
+		return err
+	}
+	fk.onDelete, fk.onUpdate, fk.match = FK_RULE_NO_ACTION, FK_RULE_NO_ACTION, FK_OPTION_NONE
+	for {
+		switch {
+		case p.accept("MATCH", "FULL"):
+			fk.match = FK_OPTION_FULL
+		case p.accept("MATCH", "PARTIAL"):
+			fk.match = FK_OPTION_PARTIAL
+		case p.accept("MATCH", "SIMPLE"):
+			fk.match = FK_OPTION_NONE
+		case p.accept("ON", "DELETE"):
+			if fk.onDelete, err = p.parseFKRule(); err != nil {
+				return err
+			}
+		case p.accept("ON", "UPDATE"):
+			if fk.onUpdate, err = p.parseFKRule(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) parseForeignKey(table *ddlTable, symbol string) (err error) {
+	fk := &ddlForeignKey{name: symbol, symbol: symbol}
+	if p.peek().isIdent() {
+		if fk.indexName, err = p.parseIdent(); err != nil {
+			return err
+		}
+	}
+	if fk.columns, err = p.parseIdentList(); err != nil {
+		return err
+	}
+	if err = p.parseReferences(fk); err != nil {
+		return err
+	}
+	table.foreignKeys = append(table.foreignKeys, fk)
+	return nil
+}
+
+func (p *ddlParser) parseCheck(table *ddlTable, symbol string) error {
+	tokens, err := p.parseParenthesized()
+	if err != nil {
+		return err
+	}
+	check := &ddlCheck{name: symbol, clause: formatExpressionTokens(tokens), enforced: true}
+	if p.accept("NOT", "ENFORCED") {
+		check.enforced = false
+	} else {
+		p.accept("ENFORCED")
+	}
+	table.checks = append(table.checks, check)
+	return nil
+}
+
+/*
+* Parse the data type of a column, e.g. "int(10) unsigned zerofill" or
+"national varchar(10)".
+*/
+func (p *ddlParser) parseDataType(column *ddlColumn) (err error) {
+	national := p.accept("NATIONAL")
+	typeName, err := p.parseIdent()
+	if err != nil {
+		return err
+	}
+	typeName = strings.ToLower(typeName)
+	switch {
+	case typeName == "long" && p.accept("VARBINARY"):
+		typeName = "mediumblob"
+	case typeName == "long" && (p.accept("VARCHAR") || p.accept("VARCHARACTER")):
+		typeName = "mediumtext"
+	case typeName == "long":
+		typeName = "mediumtext"
+	case typeName == "double":
+		p.accept("PRECISION")
+	case (typeName == "char" || typeName == "character" || typeName == "nchar") && p.accept("VARYING"):
+		typeName = "varchar"
+		national = national || typeName == "nchar"
+	case typeName == "nchar" && p.accept("VARCHAR"):
+		typeName, national = "varchar", true
+	}
+	if typeName == "nchar" || typeName == "nvarchar" {
+		typeName, national = strings.TrimPrefix(typeName, "n"), true
+	}
+	if national {
+		column.charset = "utf8mb3"
+	}
+	column.typeName = typeName
+	switch typeName {
+	case "enum", "set":
+		if err = p.expectSymbol("("); err != nil {
+			return err
+		}
+		for {
+			element, err := p.parseString()
+			if err != nil {
+				return err
+			}
+			column.elements = append(column.elements, element)
+			if p.acceptSymbol(")") {
+				break
+			}
+			if err = p.expectSymbol(","); err != nil {
+				return err
+			}
+		}
+	default:
+		if p.acceptSymbol("(") {
+			for {
+				arg, err := p.parseNumber()
+				if err != nil {
+					return err
+				}
+				column.args = append(column.args, arg)
+				if p.acceptSymbol(")") {
+					break
+				}
+				if err = p.expectSymbol(","); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for {
+		switch {
+		case p.accept("UNSIGNED"):
+			column.unsigned = true
+		case p.accept("SIGNED"):
+		case p.accept("ZEROFILL"):
+			column.zerofill, column.unsigned = true, true
+		case p.accept("BINARY"):
+			column.binaryAttr = true
+		case p.accept("ASCII"):
+			column.charset = "latin1"
+		case p.accept("UNICODE"):
+			column.charset = "ucs2"
+		case p.accept("CHARACTER", "SET") || p.accept("CHARSET") || p.accept("CHAR", "SET"):
+			if column.charset, err = p.parseIdent(); err != nil {
+				return err
+			}
+		case p.accept("COLLATE"):
+			if column.collation, err = p.parseIdent(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *ddlParser) parseColumnDefinition(table *ddlTable) (err error) {
+	column := &ddlColumn{hidden: HT_VISIBLE, virtual: true}
+	if column.name, err = p.parseIdent(); err != nil {
+		return err
+	}
+	if err = p.parseDataType(column); err != nil {
+		return err
+	}
+	if column.typeName == "serial" {
+		column.typeName, column.unsigned, column.autoIncrement = "bigint", true, true
+		notNull := false
+		column.nullable = &notNull
+		table.indexes = append(table.indexes, &ddlIndex{indexType: IT_UNIQUE, visible: true,
+			parts: []*ddlKeyPart{{column: column.name}}})
+	}
+	setNullable := func(nullable bool) {
+		column.nullable = &nullable
+	}
+	for {
+		switch {
+		case p.accept("NOT", "NULL"):
+			setNullable(false)
+		case p.accept("NULL"):
+			setNullable(true)
+		case p.accept("DEFAULT"):
+			if column.defaultTokens, err = p.parseDefaultTokens(); err != nil {
+				return err
+			}
+		case p.accept("ON", "UPDATE"):
+			tokens, err := p.parseDefaultTokens()
+			if err != nil {
+				return err
+			}
+			column.onUpdate = normalizeCurrentTimestamp(tokens)
+		case p.accept("AUTO_INCREMENT"):
+			column.autoIncrement = true
+		case p.accept("UNIQUE"):
+			if !p.accept("KEY") {
+				p.accept("INDEX")
+			}
+			table.indexes = append(table.indexes, &ddlIndex{indexType: IT_UNIQUE, visible: true,
+				parts: []*ddlKeyPart{{column: column.name}}})
+		case p.accept("PRIMARY", "KEY") || p.accept("KEY"):
+			table.indexes = append(table.indexes, &ddlIndex{name: "PRIMARY", indexType: IT_PRIMARY,
+				visible: true, parts: []*ddlKeyPart{{column: column.name}}})
+		case p.accept("COMMENT"):
+			if column.comment, err = p.parseString(); err != nil {
+				return err
+			}
+		case p.accept("COLLATE"):
+			if column.collation, err = p.parseIdent(); err != nil {
+				return err
+			}
+		case p.accept("VISIBLE"):
+			column.hidden = HT_VISIBLE
+		case p.accept("INVISIBLE"):
+			column.hidden = HT_HIDDEN_USER
+		case p.accept("GENERATED", "ALWAYS", "AS") || p.accept("AS"):
+			tokens, err := p.parseParenthesized()
+			if err != nil {
+				return err
+			}
+			column.generated = formatExpressionTokens(tokens)
+		case p.accept("VIRTUAL"):
+			column.virtual = true
+		case p.accept("STORED") || p.accept("PERSISTENT"):
+			column.virtual = false
+		case p.accept("SRID"):
+			number, err := p.parseNumber()
+			if err != nil {
+				return err
+			}
+			srid, _ := strconv.ParseUint(number, 10, 32)
+			value := uint32(srid)
+			column.srid = &value
+		case p.accept("COLUMN_FORMAT") || p.accept("STORAGE") ||
+			p.accept("ENGINE_ATTRIBUTE") || p.accept("SECONDARY_ENGINE_ATTRIBUTE"):
+			if _, err = p.parseOptionValue(); err != nil {
+				return err
+			}
+		case p.peek().is("CONSTRAINT") || p.peek().is("CHECK"):
+			symbol, err := p.parseConstraintSymbol()
+			if err != nil {
+				return err
+			}
+			if err = p.expect("CHECK"); err != nil {
+				return err
+			}
+			if err = p.parseCheck(table, symbol); err != nil {
+				return err
+			}
+		case p.peek().is("REFERENCES"):
+			/* column level foreign keys are ignored by MySQL */
+			if err = p.parseReferences(&ddlForeignKey{}); err != nil {
+				return err
+			}
+		default:
+			table.columns = append(table.columns, column)
+			return nil
+		}
+	}
+}
+
+/*
+* Parse the value of a DEFAULT or ON UPDATE clause, a literal, a function
+like CURRENT_TIMESTAMP(3) or a parenthesized expression.
+*/
+func (p *ddlParser) parseDefaultTokens() (tokens []*ddlToken, err error) {
+	start := p.pos
+	switch token := p.peek(); {
+	case token.isSymbol("("):
+		if _, err = p.parseParenthesized(); err != nil {
+			return nil, err
+		}
+	case token.isSymbol("-") || token.isSymbol("+"):
+		p.next()
+		if _, err = p.parseNumber(); err != nil {
+			return nil, err
+		}
+	case token.kind == DDL_TOKEN_STRING || (token.kind == DDL_TOKEN_WORD &&
+		strings.HasPrefix(token.text, "_") && p.peekAt(1).kind == DDL_TOKEN_STRING):
+		if _, err = p.parseString(); err != nil {
+			return nil, err
+		}
+	case token.kind == DDL_TOKEN_WORD:
+		p.next()
+		if p.peek().isSymbol("(") {
+			if _, err = p.parseParenthesized(); err != nil {
+				return nil, err
+			}
+		}
+	case token.kind == DDL_TOKEN_EOF:
+		return nil, p.errorf("expected default value")
+	default:
+		p.next()
+	}
+	return p.tokens[start:p.pos], nil
+}
+
+/*
+* Normalize CURRENT_TIMESTAMP and its synonyms, keeping the precision.
+@return e.g. "CURRENT_TIMESTAMP(3)", empty if the tokens are no such function
+*/
+func normalizeCurrentTimestamp(tokens []*ddlToken) string {
+	if len(tokens) == 0 || tokens[0].kind != DDL_TOKEN_WORD {
+		return ""
+	}
+	switch strings.ToLower(tokens[0].text) {
+	case "current_timestamp", "now", "localtime", "localtimestamp":
+	default:
+		return ""
+	}
+	precision := ""
+	for _, token := range tokens[1:] {
+		if token.kind == DDL_TOKEN_NUMBER && token.text != "0" {
+			precision = token.text
+		}
+	}
+	if precision != "" {
+		return fmt.Sprintf("CURRENT_TIMESTAMP(%s)", precision)
+	}
+	return "CURRENT_TIMESTAMP"
+}
+
+func (p *ddlParser) parseTableOptions(table *ddlTable) (err error) {
+	for {
+		p.acceptSymbol(",")
+		token := p.peek()
+		if token.kind == DDL_TOKEN_EOF || token.isSymbol(";") || token.is("PARTITION") {
+			return nil
+		}
+		p.accept("DEFAULT")
+		var value string
+		switch {
+		case p.accept("ENGINE") || p.accept("TYPE"):
+			table.engine, err = p.parseOptionValue()
+		case p.accept("CHARACTER", "SET") || p.accept("CHARSET") || p.accept("CHAR", "SET"):
+			table.charset, err = p.parseOptionValue()
+		case p.accept("COLLATE"):
+			table.collation, err = p.parseOptionValue()
+		case p.accept("COMMENT"):
+			p.acceptSymbol("=")
+			table.comment, err = p.parseString()
+		case p.accept("ROW_FORMAT"):
+			if value, err = p.parseOptionValue(); err == nil {
+				err = table.setRowFormat(value)
+			}
+		case p.accept("DATA", "DIRECTORY") || p.accept("INDEX", "DIRECTORY"):
+			_, err = p.parseOptionValue()
+		case p.accept("TABLESPACE"):
+			if _, err = p.parseOptionValue(); err == nil && p.accept("STORAGE") {
+				_, err = p.parseIdent()
+			}
+		case p.accept("STORAGE"):
+			_, err = p.parseIdent()
+		case p.accept("UNION"):
+			p.acceptSymbol("=")
+			_, err = p.parseParenthesized()
+		case p.accept("START", "TRANSACTION"):
+		case p.peek().kind == DDL_TOKEN_WORD:
+			name := strings.ToLower(p.next().text)
+			if value, err = p.parseOptionValue(); err == nil {
+				err = table.setOption(name, value)
+			}
+		default:
+			return p.errorf("unexpected token in table options")
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+/*
+* Row formats of ROW_FORMAT and their values of the row_type option
+ */
+var ddlRowFormats = map[string]struct {
+	rowFormat RowFormat
+	rowType   string
+}{
+	"default":    {RF_DYNAMIC, "0"},
+	"fixed":      {RF_FIXED, "1"},
+	"dynamic":    {RF_DYNAMIC, "2"},
+	"compressed": {RF_COMPRESSED, "3"},
+	"redundant":  {RF_REDUNDANT, "4"},
+	"compact":    {RF_COMPACT, "5"},
+}
+
+func (t *ddlTable) setRowFormat(value string) error {
+	format, ok := ddlRowFormats[strings.ToLower(value)]
+	if !ok {
+		return fmt.Errorf("unknown row format %s", value)
+	}
+	t.rowFormat = format.rowFormat
+	t.options["row_type"] = format.rowType
+	return nil
+}
+
+/*
+* Set a table option as the server stores it in the options of the SDI
+ */
+func (t *ddlTable) setOption(name, value string) error {
+	upper := strings.ToUpper(value)
+	switch name {
+	case "auto_increment", "autoextend_size", "insert_method", "connection", "password",
+		"engine_attribute", "secondary_engine_attribute", "secondary_engine":
+	case "avg_row_length", "key_block_size", "stats_sample_pages", "max_rows", "min_rows":
+		if upper == "DEFAULT" {
+			value = "0"
+		}
+		t.options[name] = value
+	case "checksum", "table_checksum", "delay_key_write":
+		if name == "table_checksum" {
+			name = "checksum"
+		}
+		if value != "0" {
+			t.options[name] = "1"
+		}
+	case "pack_keys":
+		if upper != "DEFAULT" {
+			t.options[name] = value
+		}
+	case "stats_persistent":
+		if upper != "DEFAULT" {
+			t.options[name] = value
+		}
+	case "stats_auto_recalc":
+		/* HA_STATS_AUTO_RECALC_DEFAULT, ON, OFF */
+		switch upper {
+		case "1":
+			t.options[name] = "1"
+		case "0":
+			t.options[name] = "2"
+		default:
+			t.options[name] = "0"
+		}
+	case "compression":
+		t.options["compress"] = value
+	case "encryption":
+		t.options["encrypt_type"] = upper
+	default:
+		return fmt.Errorf("unknown table option %s", name)
+	}
+	return nil
+}
+
+func (p *ddlParser) parsePartitionType(subpartition bool) (partitionType PartitionType,
+	expression string, err error) {
+	linear := p.accept("LINEAR")
+	switch {
+	case p.accept("HASH"):
+		partitionType = PT_HASH
+		if linear {
+			partitionType = PT_LINEAR_HASH
+		}
+		tokens, err := p.parseParenthesized()
+		if err != nil {
+			return 0, "", err
+		}
+		return partitionType, formatExpressionTokens(tokens), nil
+	case p.accept("KEY"):
+		partitionType = PT_KEY_55
+		if p.accept("ALGORITHM") {
+			value, err := p.parseOptionValue()
+			if err != nil {
+				return 0, "", err
+			}
+			if value == "1" {
+				partitionType = PT_KEY_51
+			}
+		}
+		if linear {
+			partitionType += PT_LINEAR_KEY_55 - PT_KEY_55
+		}
+		tokens, err := p.parseParenthesized()
+		if err != nil {
+			return 0, "", err
+		}
+		return partitionType, formatExpressionTokens(tokens), nil
+	case subpartition:
+		return 0, "", p.errorf("expected HASH or KEY subpartitioning")
+	case p.accept("RANGE"):
+		partitionType = PT_RANGE
+	case p.accept("LIST"):
+		partitionType = PT_LIST
+	default:
+		return 0, "", p.errorf("unknown partitioning")
+	}
+	if p.accept("COLUMNS") {
+		partitionType += PT_RANGE_COLUMNS - PT_RANGE
+	}
+	tokens, err := p.parseParenthesized()
+	if err != nil {
+		return 0, "", err
+	}
+	return partitionType, formatExpressionTokens(tokens), nil
+}
+
+/*
+* Parse the partition options after PARTITION BY
+ */
+func (p *ddlParser) parsePartitioning() (partitioning *ddlPartitioning, err error) {
+	partitioning = &ddlPartitioning{}
+	partitioning.partitionType, partitioning.expression, err = p.parsePartitionType(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.accept("PARTITIONS") {
+		number, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		partitioning.number, _ = strconv.Atoi(number)
+	}
+	if p.accept("SUBPARTITION", "BY") {
+		var subpartitionType PartitionType
+		subpartitionType, partitioning.subExpression, err = p.parsePartitionType(true)
+		if err != nil {
+			return nil, err
+		}
+		/* HASH, KEY_51, KEY_55, LINEAR_HASH, ... have the same order */
+		partitioning.subpartitionType = SubpartitionType(subpartitionType)
+		if p.accept("SUBPARTITIONS") {
+			number, err := p.parseNumber()
+			if err != nil {
+				return nil, err
+			}
+			partitioning.subNumber, _ = strconv.Atoi(number)
+		}
+	}
+	if !p.peek().isSymbol("(") {
+		return partitioning, nil
+	}
+	p.next()
+	for {
+		partition, err := p.parsePartitionDefinition("PARTITION")
+		if err != nil {
+			return nil, err
+		}
+		partitioning.partitions = append(partitioning.partitions, partition)
+		if p.acceptSymbol(")") {
+			return partitioning, nil
+		}
+		if err = p.expectSymbol(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *ddlParser) parsePartitionDefinition(keyword string) (partition *ddlPartition, err error) {
+	if err = p.expect(keyword); err != nil {
+		return nil, err
+	}
+	partition = &ddlPartition{}
+	if partition.name, err = p.parseIdent(); err != nil {
+		return nil, err
+	}
+	if p.accept("VALUES") {
+		switch {
+		case p.accept("LESS", "THAN", "MAXVALUE"):
+			partition.description = "MAXVALUE"
+		case p.accept("LESS", "THAN") || p.accept("IN"):
+			tokens, err := p.parseParenthesized()
+			if err != nil {
+				return nil, err
+			}
+			partition.description = formatExpressionTokens(tokens)
+		default:
+			return nil, p.errorf("expected LESS THAN or IN")
+		}
+	}
+	for {
+		p.accept("STORAGE")
+		switch {
+		case p.accept("COMMENT"):
+			p.acceptSymbol("=")
+			if partition.comment, err = p.parseString(); err != nil {
+				return nil, err
+			}
+		case p.accept("ENGINE") || p.accept("TABLESPACE") || p.accept("MAX_ROWS") ||
+			p.accept("MIN_ROWS") || p.accept("DATA", "DIRECTORY") || p.accept("INDEX", "DIRECTORY"):
+			if _, err = p.parseOptionValue(); err != nil {
+				return nil, err
+			}
+		case keyword == "PARTITION" && p.acceptSymbol("("):
+			for {
+				subpartition, err := p.parsePartitionDefinition("SUBPARTITION")
+				if err != nil {
+					return nil, err
+				}
+				partition.subpartitions = append(partition.subpartitions, subpartition)
+				if p.acceptSymbol(")") {
+					break
+				}
+				if err = p.expectSymbol(","); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return partition, nil
+		}
+	}
+}
+
+/*
+* Resolved type of a parsed column, the SDI column attributes that depend
+on the data type.
+*/
+type ddlColumnType struct {
+	columnType        ColumnType
+	columnTypeUTF8    string
+	charLength        uint64
+	numericPrecision  uint32
+	numericScale      uint32
+	numericScaleNull  bool
+	datetimePrecision uint32
+	datetimeNull      bool
+	collation         *Collation
+	/** true if the type has a character set */
+	hasCharset bool
+}
+
+/*
+* Display widths of integer types, signed and unsigned
+ */
+var ddlIntegerTypes = map[string]struct {
+	columnType ColumnType
+	signed     uint64
+	unsigned   uint64
+	precision  uint32
+}{
+	"tinyint":   {CT_TINY, 4, 3, 3},
+	"smallint":  {CT_SHORT, 6, 5, 5},
+	"mediumint": {CT_INT24, 9, 8, 7},
+	"int":       {CT_LONG, 11, 10, 10},
+	"bigint":    {CT_LONGLONG, 20, 20, 19},
+}
+
+var ddlTypeAliases = map[string]string{
+	"integer": "int", "int1": "tinyint", "int2": "smallint", "int3": "mediumint",
+	"middleint": "mediumint", "int4": "int", "int8": "bigint", "dec": "decimal",
+	"numeric": "decimal", "fixed": "decimal", "real": "double", "float4": "float",
+	"float8": "double", "character": "char", "varcharacter": "varchar",
+	"geometrycollection": "geomcollection",
+}
+
+var ddlBlobTypes = []struct {
+	maxLength  uint64
+	columnType ColumnType
+	text       string
+	blob       string
+}{
+	{255, CT_TINY_BLOB, "tinytext", "tinyblob"},
+	{65535, CT_BLOB, "text", "blob"},
+	{16777215, CT_MEDIUM_BLOB, "mediumtext", "mediumblob"},
+	{4294967295, CT_LONG_BLOB, "longtext", "longblob"},
+}
+
+/*
+* Resolve the collation of a string column
+ */
+func (c *ddlColumn) resolveCollation(tableCollation *Collation) (collation *Collation, err error) {
+	switch {
+	case c.collation != "":
+		collation, err = GetCollationByName(c.collation)
+	case c.charset != "":
+		collation, err = GetDefaultCollation(c.charset)
+	default:
+		collation = tableCollation
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.binaryAttr && collation.CharsetName != "binary" {
+		return GetCollationByName(collation.CharsetName + "_bin")
+	}
+	return collation, nil
+}
+
+/*
+* Resolve the data type of the column into the attributes of the SDI
+ */
+func (c *ddlColumn) resolveType(tableCollation *Collation) (t *ddlColumnType, err error) {
+	t = &ddlColumnType{collation: tableCollation, numericScaleNull: true, datetimeNull: true}
+	typeName := c.typeName
+	if alias, ok := ddlTypeAliases[typeName]; ok {
+		typeName = alias
+	}
+	arg := func(n int, defaultValue uint64) uint64 {
+		if n < len(c.args) {
+			value, _ := strconv.ParseUint(c.args[n], 10, 64)
+			return value
+		}
+		return defaultValue
+	}
+	suffix := ""
+	if c.unsigned {
+		suffix += " unsigned"
+	}
+	if c.zerofill {
+		suffix += " zerofill"
+	}
+	binary, _ := GetCollationByID(63)
+	/* CHARACTER SET binary turns strings into binary strings */
+	if c.charset != "" && strings.EqualFold(c.charset, "binary") {
+		switch typeName {
+		case "char":
+			typeName = "binary"
+		case "varchar":
+			typeName = "varbinary"
+		case "tinytext", "text", "mediumtext", "longtext":
+			typeName = strings.Replace(typeName, "text", "blob", 1)
+		}
+	}
+	switch typeName {
+	case "bool", "boolean":
+		t.columnType, t.columnTypeUTF8, t.charLength, t.numericPrecision = CT_TINY, "tinyint(1)", 1, 3
+		t.numericScaleNull = false
+	case "tinyint", "smallint", "mediumint", "int", "bigint":
+		integer := ddlIntegerTypes[typeName]
+		width := integer.signed
+		if c.unsigned {
+			width = integer.unsigned
+		}
+		width = arg(0, width)
+		t.columnType, t.charLength, t.numericPrecision = integer.columnType, width, integer.precision
+		if typeName == "bigint" && c.unsigned {
+			t.numericPrecision = 20
+		}
+		t.numericScaleNull = false
+		t.columnTypeUTF8 = typeName
+		if c.zerofill || (typeName == "tinyint" && len(c.args) != 0 && width == 1) {
+			t.columnTypeUTF8 += fmt.Sprintf("(%d)", width)
+		}
+		t.columnTypeUTF8 += suffix
+	case "decimal":
+		precision, scale := arg(0, 10), arg(1, 0)
+		t.columnType, t.numericPrecision, t.numericScale = CT_NEWDECIMAL, uint32(precision), uint32(scale)
+		t.numericScaleNull = false
+		t.charLength = precision + 1
+		if scale > 0 {
+			t.charLength++
+		}
+		t.columnTypeUTF8 = fmt.Sprintf("decimal(%d,%d)%s", precision, scale, suffix)
+	case "float", "double":
+		t.columnType, t.numericPrecision, t.charLength = CT_FLOAT, 12, 12
+		if typeName == "double" || (len(c.args) == 1 && arg(0, 0) > 24) {
+			typeName, t.columnType, t.numericPrecision, t.charLength = "double", CT_DOUBLE, 22, 22
+		}
+		t.columnTypeUTF8 = typeName
+		if len(c.args) == 2 {
+			t.numericPrecision, t.numericScale = uint32(arg(0, 0)), uint32(arg(1, 0))
+			t.numericScaleNull = false
+			t.charLength = arg(0, 0)
+			t.columnTypeUTF8 += fmt.Sprintf("(%d,%d)", arg(0, 0), arg(1, 0))
+		}
+		t.columnTypeUTF8 += suffix
+	case "bit":
+		length := arg(0, 1)
+		t.columnType, t.numericPrecision, t.charLength = CT_BIT, uint32(length), length
+		t.columnTypeUTF8 = fmt.Sprintf("bit(%d)", length)
+	case "date":
+		t.columnType, t.columnTypeUTF8, t.charLength = CT_NEWDATE, "date", 10
+	case "datetime", "timestamp", "time":
+		fsp := arg(0, 0)
+		t.columnType, t.charLength = CT_DATETIME2, 19
+		switch typeName {
+		case "timestamp":
+			t.columnType = CT_TIMESTAMP2
+		case "time":
+			t.columnType, t.charLength = CT_TIME2, 10
+		}
+		t.datetimePrecision, t.datetimeNull = uint32(fsp), false
+		t.columnTypeUTF8 = typeName
+		if fsp > 0 {
+			t.charLength += fsp + 1
+			t.columnTypeUTF8 += fmt.Sprintf("(%d)", fsp)
+		}
+	case "year":
+		t.columnType, t.columnTypeUTF8, t.charLength = CT_YEAR, "year", 4
+	case "char", "varchar", "binary", "varbinary":
+		length := arg(0, 1)
+		t.columnType = CT_STRING
+		if strings.HasPrefix(typeName, "var") {
+			t.columnType = CT_VARCHAR
+		}
+		t.columnTypeUTF8 = fmt.Sprintf("%s(%d)", typeName, length)
+		if strings.HasSuffix(typeName, "binary") {
+			t.collation, t.charLength = binary, length
+		} else {
+			if t.collation, err = c.resolveCollation(tableCollation); err != nil {
+				return nil, err
+			}
+			t.hasCharset = true
+			t.charLength = length * uint64(t.collation.Maxlen)
+		}
+	case "tinytext", "text", "mediumtext", "longtext", "tinyblob", "blob", "mediumblob", "longblob":
+		isText := strings.HasSuffix(typeName, "text")
+		if isText {
+			if t.collation, err = c.resolveCollation(tableCollation); err != nil {
+				return nil, err
+			}
+			t.hasCharset = true
+		} else {
+			t.collation = binary
+		}
+		for _, blobType := range ddlBlobTypes {
+			matches := blobType.text == typeName || blobType.blob == typeName
+			if len(c.args) != 0 && (typeName == "text" || typeName == "blob") {
+				/* TEXT(n) and BLOB(n) take the smallest type for n characters */
+				matches = arg(0, 0)*uint64(t.collation.Maxlen) <= blobType.maxLength
+			}
+			if matches {
+				t.columnType, t.charLength = blobType.columnType, blobType.maxLength
+				t.columnTypeUTF8 = blobType.blob
+				if isText {
+					t.columnTypeUTF8 = blobType.text
+				}
+				break
+			}
+		}
+	case "enum", "set":
+		if t.collation, err = c.resolveCollation(tableCollation); err != nil {
+			return nil, err
+		}
+		t.hasCharset = true
+		t.columnType = CT_ENUM
+		if typeName == "set" {
+			t.columnType = CT_SET
+		}
+		quoted := make([]string, len(c.elements))
+		maxLength, totalLength := 0, 0
+		for n, element := range c.elements {
+			quoted[n] = "'" + strings.ReplaceAll(element, "'", "''") + "'"
+			length := len([]rune(element))
+			if length > maxLength {
+				maxLength = length
+			}
+			totalLength += length
+		}
+		t.columnTypeUTF8 = fmt.Sprintf("%s(%s)", typeName, strings.Join(quoted, ","))
+		t.charLength = uint64(maxLength * t.collation.Maxlen)
+		if typeName == "set" && len(c.elements) != 0 {
+			t.charLength = uint64((totalLength + len(c.elements) - 1) * t.collation.Maxlen)
+		}
+	case "json":
+		t.columnType, t.columnTypeUTF8, t.charLength, t.collation = CT_JSON, "json", 4294967295, binary
+	case "geometry", "point", "linestring", "polygon", "multipoint", "multilinestring",
+		"multipolygon", "geomcollection":
+		t.columnType, t.columnTypeUTF8, t.charLength, t.collation = CT_GEOMETRY, typeName,
+			4294967295, binary
+	default:
+		return nil, fmt.Errorf("unsupported data type %s of column %s", c.typeName, c.name)
+	}
+	return t, nil
+}
+
+/*
+* Get the value of a DEFAULT literal as the server stores it in
+default_value_utf8.
+@param[in]	t	resolved type of the column
+@return value, and the default option of a function or an expression
+*/
+func (c *ddlColumn) resolveDefault(t *ddlColumnType) (value, option string, isNull bool, err error) {
+	tokens := c.defaultTokens
+	if current := normalizeCurrentTimestamp(tokens); current != "" {
+		return current, current, false, nil
+	}
+	if tokens[0].isSymbol("(") {
+		return "", "(" + formatExpressionTokens(tokens[1:len(tokens)-1]) + ")", false, nil
+	}
+	negative := false
+	if tokens[0].isSymbol("-") || tokens[0].isSymbol("+") {
+		negative = tokens[0].isSymbol("-")
+		tokens = tokens[1:]
+	}
+	if tokens[0].kind == DDL_TOKEN_WORD && strings.HasPrefix(tokens[0].text, "_") && len(tokens) > 1 {
+		tokens = tokens[1:]
+	}
+	token := tokens[0]
+	var text string
+	var number *big.Int
+	switch {
+	case token.is("NULL"):
+		return "", "", true, nil
+	case token.is("TRUE"):
+		text = "1"
+	case token.is("FALSE"):
+		text = "0"
+	case token.kind == DDL_TOKEN_STRING:
+		for _, t := range tokens {
+			text += t.text
+		}
+	case token.kind == DDL_TOKEN_NUMBER:
+		text = token.text
+		if negative {
+			text = "-" + text
+		}
+	case token.kind == DDL_TOKEN_HEX:
+		number, _ = new(big.Int).SetString(token.text, 16)
+	case token.kind == DDL_TOKEN_BIT:
+		number, _ = new(big.Int).SetString(token.text, 2)
+	default:
+		return "", "", false, fmt.Errorf("unsupported default value %s of column %s", token.text, c.name)
+	}
+	if number == nil && (token.kind == DDL_TOKEN_HEX || token.kind == DDL_TOKEN_BIT) {
+		return "", "", false, fmt.Errorf("invalid default value of column %s", c.name)
+	}
+	switch t.columnType {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG, CT_YEAR:
+		if number != nil {
+			text = number.String()
+		}
+		r, ok := new(big.Rat).SetString(strings.TrimSpace(text))
+		if !ok {
+			return "", "", false, fmt.Errorf("invalid default value '%s' of column %s", text, c.name)
+		}
+		/* round half away from zero */
+		f, _ := r.Float64()
+		value = strconv.FormatFloat(math.Round(f), 'f', 0, 64)
+		if r.IsInt() {
+			value = r.Num().String()
+		}
+		if t.columnType == CT_YEAR {
+			year, _ := strconv.Atoi(value)
+			switch {
+			case year == 0 && token.kind == DDL_TOKEN_STRING && text != "0000":
+				year = 2000
+			case year > 0 && year < 70:
+				year += 2000
+			case year >= 70 && year < 100:
+				year += 1900
+			}
+			value = fmt.Sprintf("%04d", year)
+		}
+	case CT_NEWDECIMAL:
+		r, ok := new(big.Rat).SetString(strings.TrimSpace(text))
+		if !ok {
+			return "", "", false, fmt.Errorf("invalid default value '%s' of column %s", text, c.name)
+		}
+		value = r.FloatString(int(t.numericScale))
+	case CT_FLOAT, CT_DOUBLE:
+		f, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return "", "", false, fmt.Errorf("invalid default value '%s' of column %s", text, c.name)
+		}
+		value = strconv.FormatFloat(f, 'g', -1, 64)
+		if math.Abs(f) < 1e15 {
+			value = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	case CT_BIT:
+		if number == nil {
+			if number, _ = new(big.Int).SetString(text, 10); number == nil {
+				number = new(big.Int).SetBytes([]byte(text))
+			}
+		}
+		value = fmt.Sprintf("b'%s'", number.Text(2))
+	case CT_NEWDATE, CT_DATETIME2, CT_TIMESTAMP2:
+		value = text
+		if dt, err := parseDatetimeValue(text); err == nil {
+			value = fmt.Sprintf("%04d-%02d-%02d", dt.Year, dt.Month, dt.Day)
+			if t.columnType != CT_NEWDATE {
+				value += fmt.Sprintf(" %02d:%02d:%02d", dt.Hour, dt.Minute, dt.Second)
+				value += formatFraction(dt.Microsecond, t.datetimePrecision)
+			}
+		}
+	case CT_TIME2:
+		value = text
+		if tm, err := parseTimeValue(text); err == nil {
+			value = fmt.Sprintf("%02d:%02d:%02d", tm.Hour, tm.Minute, tm.Second)
+			if tm.Negative {
+				value = "-" + value
+			}
+			value += formatFraction(tm.Microsecond, t.datetimePrecision)
+		}
+	case CT_ENUM, CT_SET:
+		value = text
+		names := strings.Split(text, ",")
+		for n, name := range names {
+			for _, element := range c.elements {
+				if strings.EqualFold(strings.TrimRight(element, " "), strings.TrimRight(name, " ")) {
+					names[n] = element
+				}
+			}
+		}
+		if len(text) != 0 {
+			value = strings.Join(names, ",")
+		}
+	default:
+		value = text
+		if number != nil {
+			value = string(number.Bytes())
+		}
+	}
+	return value, "", false, nil
+}
+
+/*
+* Get a name for an unnamed index like the server does: the name of the
+first column, made unique with a suffix _2, _3, ...
+*/
+func getUniqueIndexName(base string, indexes []*ddlIndex) string {
+	exists := func(name string) bool {
+		if strings.EqualFold(name, "PRIMARY") {
+			return true
+		}
+		for _, index := range indexes {
+			if strings.EqualFold(index.name, name) {
+				return true
+			}
+		}
+		return false
+	}
+	if !exists(base) {
+		return base
+	}
+	for n := 2; ; n++ {
+		name := fmt.Sprintf("%s_%d", base, n)
+		if !exists(name) {
+			return name
+		}
+	}
+}
+
+/*
+* Check if an index supports a foreign key, which needs the foreign key
+columns as the leading key parts.
+*/
+func (index *ddlIndex) supportsForeignKey(fk *ddlForeignKey) bool {
+	if index.indexType == IT_FULLTEXT || index.indexType == IT_SPATIAL ||
+		len(index.parts) < len(fk.columns) {
+		return false
+	}
+	for n, column := range fk.columns {
+		part := index.parts[n]
+		if part.expression != "" || part.length != 0 || !strings.EqualFold(part.column, column) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* Complete the table like the server does on CREATE TABLE: name unnamed
+indexes, foreign keys and check constraints, make primary key columns NOT
+NULL, add the indexes of foreign keys and sort the indexes.
+*/
+func (t *ddlTable) complete() {
+	named := make([]*ddlIndex, 0, len(t.indexes))
+	for _, index := range t.indexes {
+		if index.name == "" {
+			base := "functional_index"
+			if index.parts[0].column != "" {
+				base = index.parts[0].column
+			}
+			index.name = getUniqueIndexName(base, named)
+		}
+		if index.indexType != IT_PRIMARY && !index.explicit {
+			index.algorithm = IA_BTREE
+		}
+		named = append(named, index)
+	}
+	for _, index := range t.indexes {
+		if index.indexType != IT_PRIMARY {
+			continue
+		}
+		for _, part := range index.parts {
+			for _, column := range t.columns {
+				if !strings.EqualFold(column.name, part.column) {
+					continue
+				}
+				notNull := false
+				column.nullable = &notNull
+				/* DEFAULT NULL of a primary key column means no default, like
+				a NOT NULL column without DEFAULT */
+				if len(column.defaultTokens) == 1 && column.defaultTokens[0].is("NULL") {
+					column.defaultTokens = nil
+				}
+			}
+		}
+	}
+	fkNumber := 0
+	for _, fk := range t.foreignKeys {
+		if fk.name == "" {
+			fkNumber++
+			fk.name = fmt.Sprintf("%s_ibfk_%d", t.name, fkNumber)
+		}
+		if fk.refSchema == "" {
+			fk.refSchema = t.schemaName
+		}
+		supported := false
+		for _, index := range t.indexes {
+			if index.supportsForeignKey(fk) {
+				supported = true
+				break
+			}
+		}
+		if supported {
+			continue
+		}
+		name := fk.symbol
+		if name == "" {
+			name = fk.indexName
+		}
+		if name == "" {
+			name = getUniqueIndexName(fk.columns[0], t.indexes)
+		}
+		index := &ddlIndex{name: name, indexType: IT_MULTIPLE, algorithm: IA_BTREE, visible: true}
+		for _, column := range fk.columns {
+			index.parts = append(index.parts, &ddlKeyPart{column: column})
+		}
+		t.indexes = append(t.indexes, index)
+	}
+	checkNumber := 0
+	for _, check := range t.checks {
+		if check.name == "" {
+			checkNumber++
+			check.name = fmt.Sprintf("%s_chk_%d", t.name, checkNumber)
+		}
+	}
+	/* PRIMARY KEY first, then UNIQUE, other keys and FULLTEXT keys last */
+	rank := func(index *ddlIndex) int {
+		switch index.indexType {
+		case IT_PRIMARY:
+			return 0
+		case IT_UNIQUE:
+			return 1
+		case IT_FULLTEXT:
+			return 3
+		}
+		return 2
+	}
+	sorted := make([]*ddlIndex, 0, len(t.indexes))
+	for r := 0; r <= 3; r++ {
+		for _, index := range t.indexes {
+			if rank(index) == r {
+				sorted = append(sorted, index)
+			}
+		}
+	}
+	t.indexes = sorted
+}
+
+/*
+* Convert the parsed table into the dd_object of its SDI
+ */
+func (t *ddlTable) toDDObject(opts *DDLParseOptions) (ddObject map[string]interface{}, err error) {
+	t.complete()
+	collationName := opts.DefaultCollation
+	if collationName == "" {
+		collationName = DDL_DEFAULT_COLLATION
+	}
+	tableCollation, err := GetCollationByName(collationName)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case t.collation != "":
+		tableCollation, err = GetCollationByName(t.collation)
+	case t.charset != "":
+		tableCollation, err = GetDefaultCollation(t.charset)
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]interface{}, 0, len(t.columns))
+	columnOpx := make(map[string]int)
+	for n, c := range t.columns {
+		column, err := c.toDDObject(tableCollation, n)
+		if err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+		columnOpx[strings.ToLower(c.name)] = n
+	}
+	indexes := make([]interface{}, 0, len(t.indexes))
+	for n, index := range t.indexes {
+		elements := make([]interface{}, 0, len(index.parts))
+		for i, part := range index.parts {
+			opx, ok := columnOpx[strings.ToLower(part.column)]
+			if part.expression != "" {
+				/* functional key parts index a hidden virtual column */
+				hidden := &ddlColumn{
+					name:      fmt.Sprintf("!hidden!%s!%d!0", index.name, i),
+					typeName:  "longblob",
+					generated: part.expression,
+					virtual:   true,
+					hidden:    HT_HIDDEN_SQL,
+				}
+				column, err := hidden.toDDObject(tableCollation, len(columns))
+				if err != nil {
+					return nil, err
+				}
+				opx, ok = len(columns), true
+				columns = append(columns, column)
+			}
+			if !ok {
+				return nil, fmt.Errorf("key column '%s' doesn't exist in table", part.column)
+			}
+			length := columns[opx].(map[string]interface{})[`char_length`].(uint64)
+			if part.length != 0 {
+				maxlen := columns[opx].(map[string]interface{})[`maxlen`].(int)
+				length = uint64(part.length) * uint64(maxlen)
+			}
+			order := IEO_ASC
+			if part.descending {
+				order = IEO_DESC
+			}
+			elements = append(elements, map[string]interface{}{
+				`ordinal_position`: i + 1,
+				`length`:           length,
+				`order`:            order,
+				`hidden`:           false,
+				`column_opx`:       opx,
+			})
+		}
+		options := "flags=0;"
+		if index.parser != "" {
+			options += "parser_name=" + index.parser + ";"
+		}
+		algorithm := index.algorithm
+		switch {
+		case index.indexType == IT_FULLTEXT:
+			algorithm = IA_FULLTEXT
+		case index.indexType == IT_SPATIAL && !index.explicit:
+			algorithm = IA_RTREE
+		case algorithm == 0:
+			algorithm = IA_BTREE
+		}
+		indexes = append(indexes, map[string]interface{}{
+			`name`:                  index.name,
+			`hidden`:                false,
+			`is_generated`:          false,
+			`ordinal_position`:      n + 1,
+			`comment`:               index.comment,
+			`options`:               options,
+			`se_private_data`:       "",
+			`type`:                  index.indexType,
+			`algorithm`:             algorithm,
+			`is_algorithm_explicit`: index.explicit,
+			`is_visible`:            index.visible,
+			`engine`:                t.engine,
+			`elements`:              elements,
+		})
+	}
+	for _, column := range columns {
+		delete(column.(map[string]interface{}), `maxlen`)
+	}
+	foreignKeys := make([]interface{}, 0, len(t.foreignKeys))
+	for _, fk := range t.foreignKeys {
+		if len(fk.columns) != len(fk.refColumns) {
+			return nil, fmt.Errorf("foreign key %s has %d columns but references %d",
+				fk.name, len(fk.columns), len(fk.refColumns))
+		}
+		elements := make([]interface{}, 0, len(fk.columns))
+		for n, name := range fk.columns {
+			opx, ok := columnOpx[strings.ToLower(name)]
+			if !ok {
+				return nil, fmt.Errorf("foreign key column '%s' doesn't exist in table", name)
+			}
+			elements = append(elements, map[string]interface{}{
+				`column_opx`:             opx,
+				`ordinal_position`:       n + 1,
+				`referenced_column_name`: fk.refColumns[n],
+			})
+		}
+		foreignKeys = append(foreignKeys, map[string]interface{}{
+			`name`:                          fk.name,
+			`match_option`:                  fk.match,
+			`update_rule`:                   fk.onUpdate,
+			`delete_rule`:                   fk.onDelete,
+			`referenced_table_catalog_name`: "def",
+			`referenced_table_schema_name`:  fk.refSchema,
+			`referenced_table_name`:         fk.refTable,
+			`elements`:                      elements,
+		})
+	}
+	checks := make([]interface{}, 0, len(t.checks))
+	for _, check := range t.checks {
+		state := CC_ENFORCED
+		if !check.enforced {
+			state = CC_NOT_ENFORCED
+		}
+		checks = append(checks, map[string]interface{}{
+			`name`:              check.name,
+			`state`:             state,
+			`check_clause_utf8`: check.clause,
+		})
+	}
+	if t.engine == "" {
+		t.engine = "InnoDB"
+	}
+	rowFormat := t.rowFormat
+	if rowFormat == 0 {
+		rowFormat = RF_DYNAMIC
+		if size, ok := t.options["key_block_size"]; ok && size != "0" {
+			rowFormat = RF_COMPRESSED
+		}
+	}
+	options := map[string]string{
+		"avg_row_length":     "0",
+		"key_block_size":     "0",
+		"keys_disabled":      "0",
+		"pack_record":        "1",
+		"stats_auto_recalc":  "0",
+		"stats_sample_pages": "0",
+		"encrypt_type":       "N",
+	}
+	for key, value := range t.options {
+		options[key] = value
+	}
+	ddObject = map[string]interface{}{
+		`name`:              t.name,
+		`schema_ref`:        t.schemaName,
+		`mysql_version_id`:  opts.MysqlVersionID,
+		`hidden`:            HT_VISIBLE,
+		`options`:           formatKeyValueList(options),
+		`columns`:           columns,
+		`indexes`:           indexes,
+		`foreign_keys`:      foreignKeys,
+		`check_constraints`: checks,
+		`engine`:            t.engine,
+		`comment`:           t.comment,
+		`row_format`:        rowFormat,
+		`se_private_id`:     0,
+		`collation_id`:      tableCollation.ID,
+		`partition_type`:    PT_NONE,
+	}
+	if t.partitioning != nil {
+		t.partitioning.addToDDObject(ddObject)
+	}
+	return ddObject, nil
+}
+
+/*
+* Format a key-value list like the options of SDI JSON, sorted by key
+ */
+func formatKeyValueList(kv map[string]string) string {
+	keys := make([]string, 0, len(kv))
+	for key := range kv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		b.WriteString(key + "=" + kv[key] + ";")
+	}
+	return b.String()
+}
+
+func (c *ddlColumn) toDDObject(tableCollation *Collation, opx int) (column map[string]interface{}, err error) {
+	t, err := c.resolveType(tableCollation)
+	if err != nil {
+		return nil, err
+	}
+	nullable := c.nullable == nil || *c.nullable
+	if c.typeName == "timestamp" && c.nullable == nil {
+		/* explicit_defaults_for_timestamp makes TIMESTAMP columns nullable as others */
+		nullable = true
+	}
+	hasNoDefault, defaultNull, utf8Null := false, true, true
+	value, option := "", ""
+	switch {
+	case c.generated != "":
+	case c.defaultTokens != nil:
+		var isNull bool
+		value, option, isNull, err = c.resolveDefault(t)
+		if err != nil {
+			return nil, err
+		}
+		defaultNull, utf8Null = isNull, isNull
+	case !nullable || c.autoIncrement:
+		hasNoDefault, defaultNull = true, false
+	}
+	elements := make([]interface{}, 0, len(c.elements))
+	for n, element := range c.elements {
+		elements = append(elements, map[string]interface{}{
+			`name`:  base64.StdEncoding.EncodeToString([]byte(element)),
+			`index`: n + 1,
+		})
+	}
+	srsID := uint32(0)
+	if c.srid != nil {
+		srsID = *c.srid
+	}
+	return map[string]interface{}{
+		`name`:                       c.name,
+		`type`:                       t.columnType,
+		`is_nullable`:                nullable,
+		`is_zerofill`:                c.zerofill,
+		`is_unsigned`:                c.unsigned,
+		`is_auto_increment`:          c.autoIncrement,
+		`is_virtual`:                 c.generated != "" && c.virtual,
+		`hidden`:                     c.hidden,
+		`ordinal_position`:           opx + 1,
+		`char_length`:                t.charLength,
+		`numeric_precision`:          t.numericPrecision,
+		`numeric_scale`:              t.numericScale,
+		`numeric_scale_null`:         t.numericScaleNull,
+		`datetime_precision`:         t.datetimePrecision,
+		`datetime_precision_null`:    t.datetimeNull,
+		`has_no_default`:             hasNoDefault,
+		`default_value_null`:         defaultNull,
+		`srs_id_null`:                c.srid == nil,
+		`srs_id`:                     srsID,
+		`default_value`:              "",
+		`default_value_utf8_null`:    utf8Null,
+		`default_value_utf8`:         value,
+		`default_option`:             option,
+		`update_option`:              c.onUpdate,
+		`comment`:                    c.comment,
+		`generation_expression`:      c.generated,
+		`generation_expression_utf8`: c.generated,
+		`options`:                    "",
+		`se_private_data`:            "",
+		`engine_attribute`:           "",
+		`secondary_engine_attribute`: "",
+		`column_key`:                 1,
+		`column_type_utf8`:           t.columnTypeUTF8,
+		`elements`:                   elements,
+		`collation_id`:               t.collation.ID,
+		`is_explicit_collation`:      t.hasCharset && (c.charset != "" || c.collation != "" || c.binaryAttr),
+		/* removed before the conversion to JSON */
+		`maxlen`: t.collation.Maxlen,
+	}, nil
+}
+
+func (pt *ddlPartitioning) addToDDObject(ddObject map[string]interface{}) {
+	ddObject[`partition_type`] = pt.partitionType
+	ddObject[`partition_expression_utf8`] = pt.expression
+	ddObject[`subpartition_type`] = pt.subpartitionType
+	ddObject[`subpartition_expression_utf8`] = pt.subExpression
+	ddObject[`default_partitioning`] = DP_NO
+	ddObject[`default_subpartitioning`] = DP_NONE
+	partitions := pt.partitions
+	if len(partitions) == 0 {
+		/* partitions p0, p1, ... of PARTITIONS n */
+		ddObject[`default_partitioning`] = DP_YES
+		number := pt.number
+		if number > 0 {
+			ddObject[`default_partitioning`] = DP_NUMBER
+		} else {
+			number = 1
+		}
+		for n := 0; n < number; n++ {
+			partitions = append(partitions, &ddlPartition{name: fmt.Sprintf("p%d", n)})
+		}
+	}
+	if pt.subpartitionType != ST_NONE {
+		ddObject[`default_subpartitioning`] = DP_NO
+		if pt.subNumber > 0 {
+			ddObject[`default_subpartitioning`] = DP_NUMBER
+		}
+	}
+	list := make([]interface{}, 0, len(partitions))
+	for n, partition := range partitions {
+		subpartitions := make([]interface{}, 0)
+		if pt.subpartitionType != ST_NONE {
+			names := make([]string, 0)
+			for _, sp := range partition.subpartitions {
+				names = append(names, sp.name)
+			}
+			for i := len(names); len(partition.subpartitions) == 0 && i < pt.subNumber; i++ {
+				names = append(names, fmt.Sprintf("%ssp%d", partition.name, i))
+			}
+			for i, name := range names {
+				subpartitions = append(subpartitions, map[string]interface{}{
+					`name`: name, `number`: i, `engine`: "InnoDB",
+				})
+			}
+		}
+		list = append(list, map[string]interface{}{
+			`name`:             partition.name,
+			`number`:           n,
+			`description_utf8`: partition.description,
+			`engine`:           "InnoDB",
+			`comment`:          partition.comment,
+			`subpartitions`:    subpartitions,
+		})
+	}
+	ddObject[`partitions`] = list
+}
+
+/*
+* Convert the parsed table into a table schema through its SDI, so it is
+parsed exactly like the tables of .ibd files.
+*/
+func (t *ddlTable) toTableSchema(opts *DDLParseOptions) (*TableSchema, error) {
+	ddObject, err := t.toDDObject(opts)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		`mysqld_version_id`: opts.MysqlVersionID,
+		`dd_object_type`:    "Table",
+		`dd_object`:         ddObject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sdi := &SDI{Type: 1, UncompressedData: data, UncompressedDataLen: uint64(len(data))}
+	if err = sdi.DumpTableSchema(); err != nil {
+		return nil, err
+	}
+	return sdi.TableSchema, nil
+}
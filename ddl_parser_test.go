@@ -0,0 +1,107 @@
+package ibd2schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCreateTableRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		/** expected rendered DDL */
+		want string
+	}{
+		{
+			name: "plain",
+			ddl: "CREATE TABLE `t` (`id` int NOT NULL AUTO_INCREMENT, `name` varchar(20) DEFAULT NULL, " +
+				"PRIMARY KEY (`id`), KEY `idx_name` (`name`)) ENGINE=InnoDB",
+			want: "CREATE TABLE `t` (\n" +
+				"  `id` int NOT NULL AUTO_INCREMENT,\n" +
+				"  `name` varchar(20) DEFAULT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  KEY `idx_name` (`name`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+		},
+		{
+			name: "quotes in comments and defaults",
+			ddl: "CREATE TABLE `t` (`id` int NOT NULL, " +
+				"`note` varchar(20) DEFAULT 'it''s \\\\ here' COMMENT 'line\\none', " +
+				"PRIMARY KEY (`id`), KEY `idx_note` (`note`) COMMENT 'note''s index') " +
+				"ENGINE=InnoDB COMMENT='it\\'s a table'",
+			want: "CREATE TABLE `t` (\n" +
+				"  `id` int NOT NULL,\n" +
+				"  `note` varchar(20) DEFAULT 'it''s \\\\ here' COMMENT 'line\\none',\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  KEY `idx_note` (`note`) COMMENT 'note''s index'\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT = 'it''s a table'",
+		},
+		{
+			name: "bit default",
+			ddl:  "CREATE TABLE `t` (`id` int NOT NULL, `b` bit(3) DEFAULT b'101', PRIMARY KEY (`id`))",
+			want: "CREATE TABLE `t` (\n" +
+				"  `id` int NOT NULL,\n" +
+				"  `b` bit(3) DEFAULT b'101',\n" +
+				"  PRIMARY KEY (`id`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+		},
+		{
+			name: "backticks in names",
+			ddl: "CREATE TABLE `a``b` (`i``d` int NOT NULL, `p` int NOT NULL, " +
+				"PRIMARY KEY (`i``d`), KEY `k``1` (`p`), " +
+				"CONSTRAINT `c``1` FOREIGN KEY (`p`) REFERENCES `a``b` (`i``d`)) ENGINE=InnoDB",
+			want: "CREATE TABLE `a``b` (\n" +
+				"  `i``d` int NOT NULL,\n" +
+				"  `p` int NOT NULL,\n" +
+				"  PRIMARY KEY (`i``d`),\n" +
+				"  KEY `k``1` (`p`),\n" +
+				"  CONSTRAINT `c``1` FOREIGN KEY (`p`) REFERENCES `a``b` (`i``d`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+		},
+		{
+			name: "primary key column with default null",
+			ddl: "CREATE TABLE `t` (`id` int NOT NULL, `other` int DEFAULT NULL, " +
+				"PRIMARY KEY (`id`,`other`)) ENGINE=InnoDB",
+			want: "CREATE TABLE `t` (\n" +
+				"  `id` int NOT NULL,\n" +
+				"  `other` int NOT NULL,\n" +
+				"  PRIMARY KEY (`id`,`other`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, err := ParseCreateTable(tt.ddl, nil)
+			if err != nil {
+				t.Fatalf("parse failed, err:%v", err)
+			}
+			if table.DDL != tt.want {
+				t.Fatalf("DDL mismatch\ngot:\n%s\nwant:\n%s", table.DDL, tt.want)
+			}
+			reparsed, err := ParseCreateTable(table.DDL, nil)
+			if err != nil {
+				t.Fatalf("parse rendered DDL failed, err:%v", err)
+			}
+			if reparsed.DDL != table.DDL {
+				t.Fatalf("DDL does not round-trip\ngot:\n%s\nwant:\n%s", reparsed.DDL, table.DDL)
+			}
+		})
+	}
+}
+
+func TestParseCreateTablePrimaryKeyDefaultNull(t *testing.T) {
+	table, err := ParseCreateTable("CREATE TABLE `t` (`id` int, `other` int DEFAULT NULL, "+
+		"PRIMARY KEY (`id`,`other`))", nil)
+	if err != nil {
+		t.Fatalf("parse failed, err:%v", err)
+	}
+	for _, column := range table.GetUserColumns() {
+		if column.IsNullable || column.DefaultValueNull || !column.HasNoDefault {
+			t.Errorf("column %s: nullable %v, default null %v, no default %v", column.Name,
+				column.IsNullable, column.DefaultValueNull, column.HasNoDefault)
+		}
+		if strings.Contains(column.GetDefinition(), "DEFAULT") {
+			t.Errorf("column %s has a default: %s", column.Name, column.GetDefinition())
+		}
+	}
+}
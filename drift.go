@@ -0,0 +1,455 @@
+package ibd2schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type DriftOptions struct {
+	/** number of files parsed in parallel, default the number of CPUs */
+	Parallelism int
+	/** compare the physical options too, see GetFingerprint */
+	IncludePhysical bool
+	/** identify tables by name only, for shards with different schema names */
+	IgnoreSchemaNames bool
+	/** schemas that are not compared */
+	ExcludeSchemas []string
+	/** directory of the files extracted from xbstream archives, default os.TempDir() */
+	TempDir string
+}
+
+func NewDriftOptions() *DriftOptions {
+	return &DriftOptions{
+		Parallelism:    runtime.NumCPU(),
+		ExcludeSchemas: []string{"mysql", "sys", "performance_schema", "information_schema"},
+	}
+}
+
+/*
+* Group of sources with the same variant of a table
+ */
+type DriftVariant struct {
+	Fingerprint string   `json:"fingerprint"`
+	Sources     []string `json:"sources"`
+	/** table of the first source */
+	Table *TableSchema `json:"-"`
+	/** changes from the baseline variant to this variant, nil for the baseline */
+	Diff *TableDiff `json:"diff,omitempty"`
+}
+
+type TableDrift struct {
+	SchemaName string `json:"schema,omitempty"`
+	Name       string `json:"table"`
+	/** variants by number of sources, the first one is the baseline */
+	Variants []*DriftVariant `json:"variants"`
+	/** sources without the table */
+	Missing []string `json:"missing,omitempty"`
+}
+
+/*
+* Group of sources whose tables have the same fingerprints
+ */
+type SourceVariant struct {
+	Fingerprint string   `json:"fingerprint"`
+	Sources     []string `json:"sources"`
+}
+
+type DriftError struct {
+	Source string `json:"source"`
+	Path   string `json:"path"`
+	Error  string `json:"error"`
+}
+
+type DriftReport struct {
+	Sources        []string         `json:"sources"`
+	SourceVariants []*SourceVariant `json:"source_variants"`
+	/** tables with more than one variant or missing in some sources */
+	Drifts []*TableDrift `json:"drifts"`
+	Errors []*DriftError `json:"errors,omitempty"`
+	Tables int           `json:"tables"`
+}
+
+/*
+* File of a source to parse, either an .ibd file or an xbstream archive
+ */
+type driftJob struct {
+	source   string
+	path     string
+	xbstream bool
+}
+
+type driftResult struct {
+	source string
+	path   string
+	tables []*TableSchema
+	err    error
+}
+
+/*
+* List the files of a source: the .ibd files of a directory tree, an
+xbstream archive or a single .ibd file.
+*/
+func listDriftJobs(source string) (jobs []*driftJob, err error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		magic := make([]byte, len(XBSTREAM_CHUNK_MAGIC))
+		n, _ := io.ReadFull(file, magic)
+		return []*driftJob{{source: source, path: source, xbstream: IsXbstream(magic[:n])}}, nil
+	}
+	paths, err := ListTablespaceFiles(source)
+	for _, path := range paths {
+		jobs = append(jobs, &driftJob{source: source, path: path})
+	}
+	return jobs, err
+}
+
+/*
+* List the file-per-table tablespaces of a data directory or a prepared
+backup, excluding the data dictionary tablespace mysql.ibd.
+@param[in]	dir	root directory
+@return paths of the .ibd files in lexical order
+*/
+func ListTablespaceFiles(dir string) (paths []string, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isUserTablespace(path, dir) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+/*
+* Check if a file of a data directory is a file-per-table tablespace,
+excluding the data dictionary tablespace mysql.ibd.
+*/
+func isUserTablespace(path, root string) bool {
+	if !strings.HasSuffix(path, ".ibd") {
+		return false
+	}
+	rel, err := filepath.Rel(root, path)
+	return err != nil || filepath.ToSlash(rel) != "mysql.ibd"
+}
+
+/*
+* Parse the tables of an .ibd file
+ */
+func loadTablespaceTables(r io.Reader) ([]*TableSchema, error) {
+	ts, err := NewTableSpace(r)
+	if err != nil {
+		return nil, err
+	}
+	return ts.GetTableSchemas()
+}
+
+/*
+* Parse the tables of the .ibd files of an xbstream archive. Each file is
+extracted to a temporary file that is removed after parsing.
+@param[in]	r		xbstream archive
+@param[in]	tempDir	directory of the temporary files
+@param[in]	report	called with the tables or the error of each .ibd file
+*/
+func loadXbstreamTables(r io.Reader, tempDir string,
+	report func(path string, tables []*TableSchema, err error)) error {
+	files := make(map[string]*os.File)
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	reader := NewXbstreamReader(r)
+	for {
+		chunk, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if !isUserTablespace(chunk.Path, ".") {
+			if strings.Contains(chunk.Path, ".ibd.") && chunk.Type == XBSTREAM_CHUNK_TYPE_EOF {
+				report(chunk.Path, nil, fmt.Errorf("compressed or encrypted files are not supported"))
+			}
+			continue
+		}
+		file, ok := files[chunk.Path]
+		if !ok {
+			if file, err = os.CreateTemp(tempDir, "ibd2schema-*.ibd"); err != nil {
+				return err
+			}
+			files[chunk.Path] = file
+		}
+		if chunk.Type != XBSTREAM_CHUNK_TYPE_EOF {
+			if err = chunk.WriteTo(file); err != nil {
+				return fmt.Errorf("extract %s failed, err:%v", chunk.Path, err)
+			}
+			continue
+		}
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		tables, err := loadTablespaceTables(file)
+		report(chunk.Path, tables, err)
+		file.Close()
+		os.Remove(file.Name())
+		delete(files, chunk.Path)
+	}
+	for path := range files {
+		report(path, nil, fmt.Errorf("truncated archive, no end of file"))
+	}
+	return nil
+}
+
+func (job *driftJob) run(opts *DriftOptions, results chan<- *driftResult) {
+	file, err := os.Open(job.path)
+	if err != nil {
+		results <- &driftResult{source: job.source, path: job.path, err: err}
+		return
+	}
+	defer file.Close()
+	if !job.xbstream {
+		tables, err := loadTablespaceTables(file)
+		results <- &driftResult{source: job.source, path: job.path, tables: tables, err: err}
+		return
+	}
+	err = loadXbstreamTables(file, opts.TempDir, func(path string, tables []*TableSchema, err error) {
+		results <- &driftResult{source: job.source, path: path, tables: tables, err: err}
+	})
+	if err != nil {
+		results <- &driftResult{source: job.source, path: job.path, err: err}
+	}
+}
+
+/*
+* Scan the tables of several sources in parallel and report the tables
+that differ between the sources. A source is a directory like a data
+directory or a prepared backup, an xbstream archive or an .ibd file.
+Tables are compared by fingerprint and the variants of a table are
+described by their diff from the most common variant.
+@param[in]	sources	paths of the sources
+@param[in]	opts	options, nil for defaults
+*/
+func ScanDrift(sources []string, opts *DriftOptions) *DriftReport {
+	if opts == nil {
+		opts = NewDriftOptions()
+	}
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
+	report := &DriftReport{Sources: sources}
+	jobs := make([]*driftJob, 0)
+	for _, source := range sources {
+		sourceJobs, err := listDriftJobs(source)
+		if err != nil {
+			report.Errors = append(report.Errors,
+				&DriftError{Source: source, Path: source, Error: err.Error()})
+			continue
+		}
+		jobs = append(jobs, sourceJobs...)
+	}
+	jobChan := make(chan *driftJob)
+	results := make(chan *driftResult)
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobChan {
+				job.run(opts, results)
+			}
+		}()
+	}
+	go func() {
+		for _, job := range jobs {
+			jobChan <- job
+		}
+		close(jobChan)
+		wg.Wait()
+		close(results)
+	}()
+	/* tables of each source by key */
+	sourceTables := make(map[string]map[string]*TableSchema)
+	excluded := make(map[string]bool)
+	for _, schema := range opts.ExcludeSchemas {
+		excluded[schema] = true
+	}
+	for result := range results {
+		if result.err != nil {
+			report.Errors = append(report.Errors,
+				&DriftError{Source: result.source, Path: result.path, Error: result.err.Error()})
+			continue
+		}
+		if sourceTables[result.source] == nil {
+			sourceTables[result.source] = make(map[string]*TableSchema)
+		}
+		for _, table := range result.tables {
+			if table.Hidden != HT_VISIBLE || excluded[table.SchemaName] {
+				continue
+			}
+			sourceTables[result.source][opts.getTableKey(table)] = table
+		}
+	}
+	sort.Slice(report.Errors, func(i, j int) bool {
+		return report.Errors[i].Path < report.Errors[j].Path
+	})
+	report.compare(sourceTables, opts)
+	return report
+}
+
+func (opts *DriftOptions) getTableKey(table *TableSchema) string {
+	if opts.IgnoreSchemaNames {
+		return fmt.Sprintf("`%s`", table.Name)
+	}
+	return fmt.Sprintf("`%s`.`%s`", table.SchemaName, table.Name)
+}
+
+/*
+* Group the sources by the variants of each table and of all tables
+ */
+func (report *DriftReport) compare(sourceTables map[string]map[string]*TableSchema, opts *DriftOptions) {
+	keys := make(map[string]bool)
+	for _, tables := range sourceTables {
+		for key := range tables {
+			keys[key] = true
+		}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	report.Tables = len(sortedKeys)
+	report.Drifts = make([]*TableDrift, 0)
+	/* fingerprint lines of each source */
+	sourceLines := make(map[string][]string)
+	for _, key := range sortedKeys {
+		drift := &TableDrift{}
+		variants := make(map[string]*DriftVariant)
+		for _, source := range report.Sources {
+			table, ok := sourceTables[source][key]
+			if !ok {
+				drift.Missing = append(drift.Missing, source)
+				sourceLines[source] = append(sourceLines[source], key+" missing")
+				continue
+			}
+			if drift.Name == "" {
+				drift.Name = table.Name
+				if !opts.IgnoreSchemaNames {
+					drift.SchemaName = table.SchemaName
+				}
+			}
+			fingerprint := table.GetFingerprint(opts.IncludePhysical)
+			sourceLines[source] = append(sourceLines[source], key+" "+fingerprint)
+			variant, ok := variants[fingerprint]
+			if !ok {
+				variant = &DriftVariant{Fingerprint: fingerprint, Table: table}
+				variants[fingerprint] = variant
+				drift.Variants = append(drift.Variants, variant)
+			}
+			variant.Sources = append(variant.Sources, source)
+		}
+		if len(drift.Variants) == 1 && len(drift.Missing) == 0 {
+			continue
+		}
+		sort.SliceStable(drift.Variants, func(i, j int) bool {
+			return len(drift.Variants[i].Sources) > len(drift.Variants[j].Sources)
+		})
+		baseline := drift.Variants[0].Table
+		for _, variant := range drift.Variants[1:] {
+			variant.Diff = DiffTableSchemas(baseline, variant.Table)
+		}
+		report.Drifts = append(report.Drifts, drift)
+	}
+	variants := make(map[string]*SourceVariant)
+	for _, source := range report.Sources {
+		sum := sha256.Sum256([]byte(strings.Join(sourceLines[source], "\n")))
+		fingerprint := hex.EncodeToString(sum[:])
+		variant, ok := variants[fingerprint]
+		if !ok {
+			variant = &SourceVariant{Fingerprint: fingerprint}
+			variants[fingerprint] = variant
+			report.SourceVariants = append(report.SourceVariants, variant)
+		}
+		variant.Sources = append(variant.Sources, source)
+	}
+	sort.SliceStable(report.SourceVariants, func(i, j int) bool {
+		return len(report.SourceVariants[i].Sources) > len(report.SourceVariants[j].Sources)
+	})
+}
+
+/*
+* Check if the sources differ or could not be scanned completely
+ */
+func (report *DriftReport) HasDrift() bool {
+	return len(report.Drifts) != 0 || len(report.SourceVariants) > 1 || len(report.Errors) != 0
+}
+
+/*
+* Get the report as text: the source variants, then the variants of each
+drifted table with their diff from the baseline.
+*/
+func (report *DriftReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Scanned %d sources, %d tables, %d source variants\n",
+		len(report.Sources), report.Tables, len(report.SourceVariants))
+	for n, variant := range report.SourceVariants {
+		fmt.Fprintf(&b, "Source variant %d (%d sources): %s\n", n+1, len(variant.Sources),
+			strings.Join(variant.Sources, ", "))
+	}
+	for _, drift := range report.Drifts {
+		b.WriteString("\n")
+		if drift.SchemaName != "" {
+			fmt.Fprintf(&b, "Table `%s`.`%s`: %d variants\n", drift.SchemaName, drift.Name, len(drift.Variants))
+		} else {
+			fmt.Fprintf(&b, "Table `%s`: %d variants\n", drift.Name, len(drift.Variants))
+		}
+		for n, variant := range drift.Variants {
+			fmt.Fprintf(&b, "  variant %d %.12s (%d sources): %s\n", n+1, variant.Fingerprint,
+				len(variant.Sources), strings.Join(variant.Sources, ", "))
+			if variant.Diff == nil {
+				continue
+			}
+			lines := strings.Split(strings.TrimRight(variant.Diff.String(), "\n"), "\n")
+			for _, line := range lines[1:] {
+				fmt.Fprintf(&b, "  %s\n", line)
+			}
+		}
+		if len(drift.Missing) != 0 {
+			fmt.Fprintf(&b, "  missing in: %s\n", strings.Join(drift.Missing, ", "))
+		}
+	}
+	if len(report.Errors) != 0 {
+		b.WriteString("\nErrors:\n")
+		for _, e := range report.Errors {
+			fmt.Fprintf(&b, "  %s: %s: %s\n", e.Source, e.Path, e.Error)
+		}
+	}
+	return b.String()
+}
+
+func (report *DriftReport) DumpJson() ([]byte, error) {
+	return json.MarshalIndent(report, "", "  ")
+}
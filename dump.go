@@ -0,0 +1,131 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+/*
+* Session settings at the start of a mysqldump script, restored by
+DUMP_FOOTER
+*/
+const DUMP_HEADER = `/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;
+/*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;
+/*!50503 SET NAMES utf8mb4 */;
+/*!40103 SET @OLD_TIME_ZONE=@@TIME_ZONE */;
+/*!40103 SET TIME_ZONE='+00:00' */;
+/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;
+/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;
+/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;
+/*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;
+`
+
+/*
+* Session settings at the end of a mysqldump script
+ */
+const DUMP_FOOTER = `/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
+
+/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
+/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;
+/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS */;
+/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;
+/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;
+/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;
+/*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;
+`
+
+type DumpOptions struct {
+	/** DROP TABLE IF EXISTS before each table, like --add-drop-table */
+	AddDropTable bool
+	/** CREATE DATABASE IF NOT EXISTS and USE for each schema, like
+	--databases; otherwise the tables are created in the current database */
+	CreateDatabases bool
+	/** origin of the tables in the header, e.g. the data directory */
+	Source string
+	/** time in the last line, none if zero, like --skip-dump-date */
+	DumpDate time.Time
+	/** server version to restore into, nil for the version of the tables;
+	the warnings precede the statement of their table as comments */
+	Target *TargetVersion
+}
+
+func NewDumpOptions() *DumpOptions {
+	return &DumpOptions{
+		AddDropTable:    true,
+		CreateDatabases: true,
+	}
+}
+
+/*
+* Generate a schema only script in the layout of mysqldump --no-data: the
+session settings header and footer, then for each schema in name order its
+CREATE DATABASE and its tables, referenced tables first. Foreign key checks
+are disabled by the header, as in mysqldump, so cycles and references to
+missing tables do not fail the restore.
+@param[in]	tables	table schemas, hidden tables are ignored
+@param[in]	opts	dump options, nil for defaults
+@return SQL script
+*/
+func GenerateDumpScript(tables []*TableSchema, opts *DumpOptions) string {
+	if opts == nil {
+		opts = NewDumpOptions()
+	}
+	order := NewFKGraph(tables).GetCreateOrder()
+	schemaTables := make(map[string][]*TableSchema)
+	for _, table := range order {
+		schemaTables[table.SchemaName] = append(schemaTables[table.SchemaName], table)
+	}
+	schemaNames := make([]string, 0, len(schemaTables))
+	for name := range schemaTables {
+		schemaNames = append(schemaNames, name)
+	}
+	sort.Strings(schemaNames)
+	renderer := &MySQLRenderer{Target: opts.Target}
+	scriptWarnings := renderer.getScriptWarnings(tables)
+	renderOpts := &RenderOptions{}
+	var b strings.Builder
+	b.WriteString("-- ibd2schema dump, schema only\n--\n")
+	if opts.Source != "" {
+		fmt.Fprintf(&b, "-- Source: %s\n", opts.Source)
+	}
+	if opts.Target != nil {
+		fmt.Fprintf(&b, "-- Target: %s\n", opts.Target)
+	}
+	b.WriteString("-- ------------------------------------------------------\n\n")
+	b.WriteString(DUMP_HEADER)
+	for _, schemaName := range schemaNames {
+		if opts.CreateDatabases {
+			name := quoteMySQLIdentifier(schemaName)
+			fmt.Fprintf(&b, "\n--\n-- Current Database: %s\n--\n\n", name)
+			fmt.Fprintf(&b, "CREATE DATABASE /*!32312 IF NOT EXISTS*/ %s;\n\n", name)
+			fmt.Fprintf(&b, "USE %s;\n", name)
+		}
+		for _, table := range schemaTables[schemaName] {
+			name := quoteMySQLIdentifier(table.Name)
+			fmt.Fprintf(&b, "\n--\n-- Table structure for table %s\n--\n\n", name)
+			if opts.AddDropTable {
+				fmt.Fprintf(&b, "DROP TABLE IF EXISTS %s;\n", name)
+			}
+			statement, warnings := renderer.renderTableWithoutPartitioning(table, renderOpts)
+			writeWarnings(&b, append(warnings, scriptWarnings[table]...))
+			b.WriteString("/*!40101 SET @saved_cs_client     = @@character_set_client */;\n")
+			b.WriteString("/*!50503 SET character_set_client = utf8mb4 */;\n")
+			b.WriteString(statement)
+			if table.Partitioning != nil {
+				fmt.Fprintf(&b, "\n/*!50100 %s */", table.Partitioning.GetDefinition())
+			}
+			b.WriteString(";\n/*!40101 SET character_set_client = @saved_cs_client */;\n")
+		}
+	}
+	b.WriteString("\n")
+	b.WriteString(DUMP_FOOTER)
+	if opts.DumpDate.IsZero() {
+		b.WriteString("\n-- Dump completed\n")
+	} else {
+		fmt.Fprintf(&b, "\n-- Dump completed on %s\n", opts.DumpDate.Format("2006-01-02 15:04:05"))
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package ibd2schema
+
+import (
+	"testing"
+)
+
+func TestGenerateDumpScriptParsesBack(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{
+			name: "quotes",
+			script: "USE `app`;\n" +
+				"CREATE TABLE `it's` (`id` int NOT NULL, `na``me` varchar(20) DEFAULT 'it''s' " +
+				"COMMENT 'a \\\\ b', PRIMARY KEY (`id`), KEY `k``1` (`na``me`) COMMENT 'key''s') " +
+				"ENGINE=InnoDB COMMENT='table''s';\n",
+		},
+		{
+			name: "foreign keys and partitions",
+			script: "USE `app`;\n" +
+				"CREATE TABLE `child` (`id` int NOT NULL, `parent_id` int DEFAULT NULL, PRIMARY KEY (`id`), " +
+				"CONSTRAINT `fk_parent` FOREIGN KEY (`parent_id`) REFERENCES `parent` (`id`)) ENGINE=InnoDB;\n" +
+				"CREATE TABLE `parent` (`id` int NOT NULL, PRIMARY KEY (`id`)) ENGINE=InnoDB " +
+				"PARTITION BY HASH (`id`) PARTITIONS 4;\n" +
+				"USE `other`;\n" +
+				"CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`)) ENGINE=InnoDB;\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables, err := ParseDDLScript(tt.script, nil)
+			if err != nil {
+				t.Fatalf("parse script failed, err:%v", err)
+			}
+			dump := GenerateDumpScript(tables, nil)
+			restored, err := ParseDDLScript(dump, nil)
+			if err != nil {
+				t.Fatalf("parse dump failed, err:%v\n%s", err, dump)
+			}
+			if len(restored) != len(tables) {
+				t.Fatalf("dump has %d tables, expected %d\n%s", len(restored), len(tables), dump)
+			}
+			ddls := make(map[string]string)
+			for _, table := range tables {
+				ddls[table.SchemaName+"."+table.Name] = table.DDL
+			}
+			for _, table := range restored {
+				ddl, ok := ddls[table.SchemaName+"."+table.Name]
+				if !ok {
+					t.Fatalf("unexpected table %s.%s in dump", table.SchemaName, table.Name)
+				}
+				if table.DDL != ddl {
+					t.Errorf("table %s.%s\ngot:\n%s\nwant:\n%s", table.SchemaName, table.Name, table.DDL, ddl)
+				}
+			}
+		})
+	}
+}
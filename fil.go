@@ -137,6 +137,10 @@ const (
 	page. */
 	FIL_PAGE_NEXT = 12
 
+	/** if there is a 'natural' predecessor of the page, its offset.
+	Otherwise FIL_NULL. */
+	FIL_PAGE_PREV = 8
+
 	FIL_NULL = math.MaxUint32
 )
 
@@ -0,0 +1,181 @@
+package ibd2schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tidwall/gjson"
+)
+
+/*
+* Members of dd objects that are internal to the storage engine or change
+without a schema change, they are not part of a fingerprint.
+*/
+var fingerprintIgnoredMembers = map[string]bool{
+	`se_private_data`:                     true,
+	`se_private_id`:                       true,
+	`created`:                             true,
+	`last_altered`:                        true,
+	`mysql_version_id`:                    true,
+	`mysqld_version_id`:                   true,
+	`last_checked_for_upgrade_version_id`: true,
+	`schema_ref`:                          true,
+	/* binary default in the record format, default_value_utf8 is kept */
+	`default_value`: true,
+}
+
+/*
+* Members of the table and its partitions that describe the physical
+storage, they are part of a fingerprint only if physical options are
+included.
+*/
+var fingerprintPhysicalMembers = []string{`options`, `row_format`, `tablespace_ref`}
+
+/*
+* Compute the fingerprint of a table from the dd_object of its SDI: the
+SHA-256 of a canonical JSON that excludes SE internal members, ids,
+timestamps and version ids, hidden SE columns like DB_TRX_ID and the schema
+name, so identical tables of different schemas or servers have the same
+fingerprint. Column references are resolved to column names.
+@param[in]	ddObject		Data Dictionary JSON object of a table
+@param[in]	includePhysical	include table options, row format and tablespaces
+@return hex encoded fingerprint
+*/
+func computeFingerprint(ddObject gjson.Result, includePhysical bool) (string, error) {
+	table, ok := ddObject.Value().(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("dd_object is not an object")
+	}
+	schemaName := ddObject.Get(`schema_ref`).String()
+	columns, _ := table[`columns`].([]interface{})
+	columnNames := make([]interface{}, len(columns))
+	userColumns := make([]interface{}, 0, len(columns))
+	for opx, column := range columns {
+		c, _ := column.(map[string]interface{})
+		columnNames[opx] = c[`name`]
+		if hidden, _ := c[`hidden`].(float64); HiddenType(hidden) != HT_HIDDEN_SE {
+			userColumns = append(userColumns, column)
+		}
+	}
+	table[`columns`] = userColumns
+	/* the column_opx of indexes and foreign keys change with hidden columns */
+	resolveColumns := func(member string, skipHidden bool) {
+		objects, _ := table[member].([]interface{})
+		for _, object := range objects {
+			o, _ := object.(map[string]interface{})
+			elements, _ := o[`elements`].([]interface{})
+			resolved := make([]interface{}, 0, len(elements))
+			for _, element := range elements {
+				e, _ := element.(map[string]interface{})
+				if hidden, _ := e[`hidden`].(bool); hidden && skipHidden {
+					continue
+				}
+				if opx, ok := e[`column_opx`].(float64); ok && int(opx) < len(columnNames) {
+					delete(e, `column_opx`)
+					e[`column_name`] = columnNames[int(opx)]
+				}
+				resolved = append(resolved, e)
+			}
+			o[`elements`] = resolved
+		}
+	}
+	resolveColumns(`indexes`, true)
+	resolveColumns(`foreign_keys`, false)
+	/* references within the same schema do not depend on the schema name */
+	foreignKeys, _ := table[`foreign_keys`].([]interface{})
+	for _, fk := range foreignKeys {
+		f, _ := fk.(map[string]interface{})
+		if f[`referenced_table_schema_name`] == schemaName {
+			f[`referenced_table_schema_name`] = ""
+		}
+	}
+	if !includePhysical {
+		removePhysicalMembers(table)
+	}
+	pruneFingerprintMembers(table)
+	data, err := json.Marshal(table)
+	if err != nil {
+		return "", fmt.Errorf("marshal dd_object failed, err:%v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+/*
+* Remove the physical members from a table and its partitions, and the
+tablespaces of its indexes.
+*/
+func removePhysicalMembers(table map[string]interface{}) {
+	for _, member := range fingerprintPhysicalMembers {
+		delete(table, member)
+	}
+	indexes, _ := table[`indexes`].([]interface{})
+	for _, index := range indexes {
+		if i, ok := index.(map[string]interface{}); ok {
+			delete(i, `tablespace_ref`)
+		}
+	}
+	partitions, _ := table[`partitions`].([]interface{})
+	for _, partition := range partitions {
+		p, ok := partition.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		removePhysicalMembers(p)
+		subpartitions, _ := p[`subpartitions`].([]interface{})
+		for _, subpartition := range subpartitions {
+			if sp, ok := subpartition.(map[string]interface{}); ok {
+				removePhysicalMembers(sp)
+			}
+		}
+	}
+}
+
+/*
+* Remove the members that are not part of a fingerprint from a dd object
+and all objects it contains.
+*/
+func pruneFingerprintMembers(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for member, child := range v {
+			if fingerprintIgnoredMembers[member] {
+				delete(v, member)
+				continue
+			}
+			pruneFingerprintMembers(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			pruneFingerprintMembers(child)
+		}
+	}
+}
+
+/*
+* Parse the fingerprints of a table, see computeFingerprint
+@param[in]	ddObject	Data Dictionary JSON object
+*/
+func (ts *TableSchema) parseFingerprints(ddObject gjson.Result) (err error) {
+	ts.Fingerprint, err = computeFingerprint(ddObject, false)
+	if err != nil {
+		return err
+	}
+	ts.PhysicalFingerprint, err = computeFingerprint(ddObject, true)
+	return err
+}
+
+/*
+* Get the fingerprint of the table.
+@param[in]	includePhysical	true to include the physical options, e.g.
+ROW_FORMAT, KEY_BLOCK_SIZE, STATS_PERSISTENT and tablespaces
+@return hex encoded SHA-256, empty if the table was not parsed from an SDI
+*/
+func (ts *TableSchema) GetFingerprint(includePhysical bool) string {
+	if includePhysical {
+		return ts.PhysicalFingerprint
+	}
+	return ts.Fingerprint
+}
@@ -0,0 +1,350 @@
+package ibd2schema
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+* Foreign key of a table as an edge from the table to the referenced table
+ */
+type FKEdge struct {
+	Table      *TableSchema
+	ForeignKey *ForeignKey
+	/** nil if the referenced table is not in the graph */
+	Referenced *TableSchema
+}
+
+/*
+* Check if the foreign key references its own table
+ */
+func (e *FKEdge) IsSelfReference() bool {
+	return e.Referenced == e.Table
+}
+
+func (e *FKEdge) String() string {
+	return fmt.Sprintf("`%s`.`%s`.`%s` -> `%s`.`%s`", e.Table.SchemaName, e.Table.Name,
+		e.ForeignKey.Name, e.ForeignKey.ReferencedTableSchemaName, e.ForeignKey.ReferencedTableName)
+}
+
+/*
+* Graph of the foreign keys between tables, e.g. of all tables of a data
+directory
+*/
+type FKGraph struct {
+	/** visible tables ordered by schema and name */
+	Tables []*TableSchema
+	/** foreign keys whose referenced table is in the graph */
+	Edges []*FKEdge
+	/** foreign keys whose referenced table is not in the graph */
+	Dangling []*FKEdge
+	/** strongly connected tables, each cycle ordered by schema and name */
+	Cycles [][]*TableSchema
+	/** edges by referencing table */
+	edges map[*TableSchema][]*FKEdge
+}
+
+func getTableKey(schemaName, name string) string {
+	return fmt.Sprintf("`%s`.`%s`", schemaName, name)
+}
+
+/*
+* Build the foreign key graph of tables. Referenced tables are looked up by
+exact name first, then case insensitive as with lower_case_table_names.
+@param[in]	tables	tables, hidden tables are ignored
+*/
+func NewFKGraph(tables []*TableSchema) *FKGraph {
+	g := &FKGraph{
+		Tables:   make([]*TableSchema, 0, len(tables)),
+		Edges:    make([]*FKEdge, 0),
+		Dangling: make([]*FKEdge, 0),
+		Cycles:   make([][]*TableSchema, 0),
+		edges:    make(map[*TableSchema][]*FKEdge),
+	}
+	byKey := make(map[string]*TableSchema)
+	byLowerKey := make(map[string]*TableSchema)
+	for _, table := range tables {
+		if table.Hidden != HT_VISIBLE {
+			continue
+		}
+		g.Tables = append(g.Tables, table)
+		key := getTableKey(table.SchemaName, table.Name)
+		byKey[key] = table
+		byLowerKey[strings.ToLower(key)] = table
+	}
+	sort.SliceStable(g.Tables, func(i, j int) bool {
+		return getTableKey(g.Tables[i].SchemaName, g.Tables[i].Name) <
+			getTableKey(g.Tables[j].SchemaName, g.Tables[j].Name)
+	})
+	for _, table := range g.Tables {
+		for _, fk := range table.ForeignKeys {
+			key := getTableKey(fk.ReferencedTableSchemaName, fk.ReferencedTableName)
+			referenced, ok := byKey[key]
+			if !ok {
+				referenced, ok = byLowerKey[strings.ToLower(key)]
+			}
+			edge := &FKEdge{Table: table, ForeignKey: fk, Referenced: referenced}
+			if !ok {
+				g.Dangling = append(g.Dangling, edge)
+				continue
+			}
+			g.Edges = append(g.Edges, edge)
+			g.edges[table] = append(g.edges[table], edge)
+		}
+	}
+	g.findCycles()
+	return g
+}
+
+/*
+* Find the cycles with Tarjan's algorithm for strongly connected
+components. Self references are not cycles, a table can be created with a
+foreign key to itself.
+*/
+func (g *FKGraph) findCycles() {
+	index := make(map[*TableSchema]int)
+	lowLink := make(map[*TableSchema]int)
+	onStack := make(map[*TableSchema]bool)
+	stack := make([]*TableSchema, 0)
+	counter := 0
+	var connect func(table *TableSchema)
+	connect = func(table *TableSchema) {
+		index[table], lowLink[table] = counter, counter
+		counter++
+		stack = append(stack, table)
+		onStack[table] = true
+		for _, edge := range g.edges[table] {
+			referenced := edge.Referenced
+			if _, visited := index[referenced]; !visited {
+				connect(referenced)
+				if lowLink[referenced] < lowLink[table] {
+					lowLink[table] = lowLink[referenced]
+				}
+			} else if onStack[referenced] && index[referenced] < lowLink[table] {
+				lowLink[table] = index[referenced]
+			}
+		}
+		if lowLink[table] != index[table] {
+			return
+		}
+		component := make([]*TableSchema, 0)
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == table {
+				break
+			}
+		}
+		if len(component) > 1 {
+			g.Cycles = append(g.Cycles, component)
+		}
+	}
+	for _, table := range g.Tables {
+		if _, visited := index[table]; !visited {
+			connect(table)
+		}
+	}
+	position := g.getPositions(g.Tables)
+	for _, cycle := range g.Cycles {
+		sort.Slice(cycle, func(i, j int) bool { return position[cycle[i]] < position[cycle[j]] })
+	}
+	sort.Slice(g.Cycles, func(i, j int) bool {
+		return position[g.Cycles[i][0]] < position[g.Cycles[j][0]]
+	})
+}
+
+/*
+* Min-heap of component ids, the position of the first table of the component
+ */
+type componentHeap []int
+
+func (h componentHeap) Len() int            { return len(h) }
+func (h componentHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h componentHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *componentHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *componentHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func (g *FKGraph) getPositions(tables []*TableSchema) map[*TableSchema]int {
+	position := make(map[*TableSchema]int, len(tables))
+	for n, table := range tables {
+		position[table] = n
+	}
+	return position
+}
+
+/*
+* Get the order to create the tables in: referenced tables before the
+tables that reference them. Tables of a cycle cannot be ordered, they are
+ordered by name and the foreign keys to later tables must be added after
+all tables are created, see GetDeferredForeignKeys.
+@return tables in creation order, ties are ordered by schema and name
+*/
+func (g *FKGraph) GetCreateOrder() []*TableSchema {
+	/* the tables of a cycle are created together, as one node */
+	component := make(map[*TableSchema]int)
+	for n, table := range g.Tables {
+		component[table] = n
+	}
+	for _, cycle := range g.Cycles {
+		for _, table := range cycle {
+			component[table] = component[cycle[0]]
+		}
+	}
+	members := make(map[int][]*TableSchema)
+	for _, table := range g.Tables {
+		members[component[table]] = append(members[component[table]], table)
+	}
+	/* number of referenced components that are not created yet */
+	pending := make(map[int]int)
+	dependents := make(map[int][]int)
+	for _, edge := range g.Edges {
+		from, to := component[edge.Table], component[edge.Referenced]
+		if from == to {
+			continue
+		}
+		pending[from]++
+		dependents[to] = append(dependents[to], from)
+	}
+	/* Kahn's algorithm, always taking the first ready component by name */
+	ready := &componentHeap{}
+	for c := range members {
+		if pending[c] == 0 {
+			heap.Push(ready, c)
+		}
+	}
+	order := make([]*TableSchema, 0, len(g.Tables))
+	for ready.Len() > 0 {
+		next := heap.Pop(ready).(int)
+		order = append(order, members[next]...)
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+	return order
+}
+
+/*
+* Get the foreign keys that cannot be created with their table in the
+given order, the foreign keys to tables that are created later. Dangling
+foreign keys are not included.
+@param[in]	order	creation order, see GetCreateOrder
+*/
+func (g *FKGraph) GetDeferredForeignKeys(order []*TableSchema) []*FKEdge {
+	position := g.getPositions(order)
+	deferred := make([]*FKEdge, 0)
+	for _, table := range order {
+		for _, edge := range g.edges[table] {
+			if !edge.IsSelfReference() && position[edge.Referenced] > position[table] {
+				deferred = append(deferred, edge)
+			}
+		}
+	}
+	return deferred
+}
+
+type RestoreOptions struct {
+	/** create all tables without foreign keys and add them with ALTER TABLE */
+	SplitForeignKeys bool
+	/** add CREATE DATABASE IF NOT EXISTS before the first table of a schema */
+	CreateDatabases bool
+}
+
+func NewRestoreOptions() *RestoreOptions {
+	return &RestoreOptions{CreateDatabases: true}
+}
+
+/*
+* Generate a script that creates the tables in an order that MySQL accepts
+with foreign_key_checks enabled. Foreign keys of cycles are added with
+ALTER TABLE after all tables are created. Dangling foreign keys are added
+last with foreign_key_checks disabled.
+@param[in]	opts	restore options, nil for defaults
+@return SQL script
+*/
+func (g *FKGraph) GenerateRestoreScript(opts *RestoreOptions) string {
+	if opts == nil {
+		opts = NewRestoreOptions()
+	}
+	order := g.GetCreateOrder()
+	trailing := g.GetDeferredForeignKeys(order)
+	if opts.SplitForeignKeys {
+		trailing = make([]*FKEdge, 0, len(g.Edges))
+		for _, table := range order {
+			trailing = append(trailing, g.edges[table]...)
+		}
+	}
+	skipped := make(map[*ForeignKey]bool)
+	for _, edge := range append(trailing, g.Dangling...) {
+		skipped[edge.ForeignKey] = true
+	}
+	var b strings.Builder
+	currentSchema := ""
+	created := make(map[string]bool)
+	use := func(schemaName string) {
+		if schemaName == currentSchema {
+			return
+		}
+		if opts.CreateDatabases && !created[schemaName] {
+			fmt.Fprintf(&b, "CREATE DATABASE IF NOT EXISTS %s;\n", quoteMySQLIdentifier(schemaName))
+			created[schemaName] = true
+		}
+		fmt.Fprintf(&b, "USE %s;\n\n", quoteMySQLIdentifier(schemaName))
+		currentSchema = schemaName
+	}
+	renderer := &MySQLRenderer{skippedForeignKeys: skipped}
+	for _, table := range order {
+		use(table.SchemaName)
+		fmt.Fprintf(&b, "%s;\n\n", renderer.RenderTable(table, &RenderOptions{}))
+	}
+	alter := func(table *TableSchema, fk *ForeignKey) {
+		use(table.SchemaName)
+		fmt.Fprintf(&b, "ALTER TABLE %s ADD %s;\n", quoteMySQLIdentifier(table.Name), fk.GetDefinition())
+	}
+	for _, edge := range trailing {
+		alter(edge.Table, edge.ForeignKey)
+	}
+	if len(g.Dangling) != 0 {
+		b.WriteString("\n-- referenced tables that do not exist\nSET FOREIGN_KEY_CHECKS=0;\n")
+		for _, edge := range g.Dangling {
+			alter(edge.Table, edge.ForeignKey)
+		}
+		b.WriteString("SET FOREIGN_KEY_CHECKS=1;\n")
+	}
+	return b.String()
+}
+
+/*
+* Get the cycles and dangling foreign keys as text
+ */
+func (g *FKGraph) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d tables, %d foreign keys, %d cycles, %d dangling foreign keys\n",
+		len(g.Tables), len(g.Edges)+len(g.Dangling), len(g.Cycles), len(g.Dangling))
+	for n, cycle := range g.Cycles {
+		names := make([]string, len(cycle))
+		for i, table := range cycle {
+			names[i] = getTableKey(table.SchemaName, table.Name)
+		}
+		fmt.Fprintf(&b, "cycle %d: %s\n", n+1, strings.Join(names, ", "))
+	}
+	for _, edge := range g.Dangling {
+		fmt.Fprintf(&b, "dangling: %s\n", edge.String())
+	}
+	b.WriteString("create order:\n")
+	for n, table := range g.GetCreateOrder() {
+		fmt.Fprintf(&b, "  %d %s\n", n+1, getTableKey(table.SchemaName, table.Name))
+	}
+	return b.String()
+}
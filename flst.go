@@ -0,0 +1,74 @@
+package ibd2schema
+
+import "encoding/binary"
+
+// file based list
+const (
+	/* Offsets of the fields in a list base node */
+	FLST_LEN   = 0
+	FLST_FIRST = 4
+	FLST_LAST  = 4 + FIL_ADDR_SIZE
+	/* Offsets of the fields in a list node */
+	FLST_PREV = 0
+	FLST_NEXT = FIL_ADDR_SIZE
+	/** First in address is the page offset. */
+	FIL_ADDR_PAGE = 0
+	/** Then comes 2-byte byte offset within page.*/
+	FIL_ADDR_BYTE = 4
+)
+
+/*
+* File space address
+ */
+type FilAddr struct {
+	/** Page number within a space */
+	PageNum uint32
+	/** Byte offset within the page */
+	Boffset uint16
+}
+
+func NewFilAddr(data []byte) *FilAddr {
+	return &FilAddr{
+		PageNum: binary.BigEndian.Uint32(data[FIL_ADDR_PAGE:]),
+		Boffset: binary.BigEndian.Uint16(data[FIL_ADDR_BYTE:]),
+	}
+}
+
+/*
+* Check if the address is null.
+ */
+func (addr *FilAddr) IsNull() bool {
+	return addr.PageNum == FIL_NULL
+}
+
+/*
+* Base node of a file based list
+ */
+type FlstBaseNode struct {
+	Len   uint32
+	First *FilAddr
+	Last  *FilAddr
+}
+
+func NewFlstBaseNode(data []byte) *FlstBaseNode {
+	return &FlstBaseNode{
+		Len:   binary.BigEndian.Uint32(data[FLST_LEN:]),
+		First: NewFilAddr(data[FLST_FIRST:]),
+		Last:  NewFilAddr(data[FLST_LAST:]),
+	}
+}
+
+/*
+* Node of a file based list
+ */
+type FlstNode struct {
+	Prev *FilAddr
+	Next *FilAddr
+}
+
+func NewFlstNode(data []byte) *FlstNode {
+	return &FlstNode{
+		Prev: NewFilAddr(data[FLST_PREV:]),
+		Next: NewFilAddr(data[FLST_NEXT:]),
+	}
+}
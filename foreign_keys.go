@@ -33,14 +33,20 @@ func NewForeignKeyElement(element gjson.Result) *ForeignKeyElement {
 }
 
 type ForeignKey struct {
-	Name                      string
-	UpdateRule                FKRule
-	DeleteRule                FKRule
+	Name        string
+	UpdateRule  FKRule
+	DeleteRule  FKRule
+	MatchOption FKMatchOption
+	/** names of the referencing columns */
+	ColumnNames []string
+	/** names of the referenced columns */
 	ReferenceNames            []string
 	ReferencedTableSchemaName string
 	ReferencedTableName       string
-	Gjson                     gjson.Result
-	DDL                       string
+	/** schema of the referencing table, the referenced table is qualified
+	with its schema if it differs */
+	TableSchemaName string
+	Gjson           gjson.Result
 }
 
 func NewForeignKey(fk gjson.Result) *ForeignKey {
@@ -48,6 +54,8 @@ func NewForeignKey(fk gjson.Result) *ForeignKey {
 		Name:                      fk.Get(`name`).String(),
 		UpdateRule:                FKRule(fk.Get(`update_rule`).Int()),
 		DeleteRule:                FKRule(fk.Get(`delete_rule`).Int()),
+		MatchOption:               FKMatchOption(fk.Get(`match_option`).Int()),
+		ColumnNames:               make([]string, 0),
 		ReferenceNames:            make([]string, 0),
 		ReferencedTableSchemaName: fk.Get(`referenced_table_schema_name`).String(),
 		ReferencedTableName:       fk.Get(`referenced_table_name`).String(),
@@ -55,17 +63,16 @@ func NewForeignKey(fk gjson.Result) *ForeignKey {
 	}
 }
 
-func (fk *ForeignKey) parseName() {
-	fk.DDL += fmt.Sprintf("  CONSTRAINT `%s` FOREIGN KEY (", fk.Name)
-}
-
-func (fk *ForeignKey) parseElementDDL(element *ForeignKeyElement, columnCache ColumnCache) (err error) {
+/*
+* Add the referencing and referenced columns of an element
+ */
+func (fk *ForeignKey) addElement(element *ForeignKeyElement, columnCache ColumnCache) (err error) {
 	column, ok := columnCache[element.ColumnOpx]
 	if !ok {
 		return fmt.Errorf("index column %d not found in the column map", element.ColumnOpx)
 	}
+	fk.ColumnNames = append(fk.ColumnNames, column.Name)
 	fk.ReferenceNames = append(fk.ReferenceNames, element.ReferencedColumnName)
-	fk.DDL += fmt.Sprintf("`%s`, ", column.Name)
 	return nil
 }
 
@@ -76,57 +83,14 @@ func (fk *ForeignKey) parseElements(columnCache ColumnCache) (err error) {
 		if err != nil {
 			return err
 		}
-		fkElement := NewForeignKeyElement(e)
-		err = fk.parseElementDDL(fkElement, columnCache)
+		err = fk.addElement(NewForeignKeyElement(e), columnCache)
 		if err != nil {
 			return err
 		}
 	}
-	fk.DDL = fk.DDL[:len(fk.DDL)-2] + ") REFERENCES "
 	return nil
 }
 
-func (fk *ForeignKey) parseReference(tableSchema string) {
-	if fk.ReferencedTableSchemaName != tableSchema {
-		fk.DDL += fmt.Sprintf("`%s`.`%s` (", fk.ReferencedTableSchemaName, fk.ReferencedTableName)
-	} else {
-		fk.DDL += fmt.Sprintf("`%s` (", fk.ReferencedTableName)
-	}
-	for _, rn := range fk.ReferenceNames {
-		fk.DDL += fmt.Sprintf("`%s`, ", rn)
-	}
-	fk.DDL = fk.DDL[:len(fk.DDL)-2] + ")"
-}
-
-func (fk *ForeignKey) parseAction() {
-	// ON DELETE
-	switch fk.DeleteRule {
-	case FK_RULE_RESTRICT:
-		fk.DDL += " ON DELETE RESTRICT"
-	case FK_RULE_CASCADE:
-		fk.DDL += " ON DELETE CASCADE"
-	case FK_RULE_SET_NULL:
-		fk.DDL += " ON DELETE SET NULL"
-	case FK_RULE_SET_DEFAULT:
-		fk.DDL += " ON DELETE SET DEFAULT"
-	case FK_RULE_NO_ACTION:
-	default:
-	}
-	// ON UPDATE
-	switch fk.UpdateRule {
-	case FK_RULE_RESTRICT:
-		fk.DDL += " ON UPDATE RESTRICT"
-	case FK_RULE_CASCADE:
-		fk.DDL += " ON UPDATE CASCADE"
-	case FK_RULE_SET_NULL:
-		fk.DDL += " ON UPDATE SET NULL"
-	case FK_RULE_SET_DEFAULT:
-		fk.DDL += " ON UPDATE SET DEFAULT"
-	case FK_RULE_NO_ACTION:
-	default:
-	}
-}
-
 func CheckForeignKeyMembers(fk gjson.Result) error {
 	if !fk.IsObject() {
 		return fmt.Errorf("foreign key is not an object")
@@ -151,26 +115,55 @@ func CheckForeignKeyElementMembers(element gjson.Result) error {
 	return nil
 }
 
+/*
+* Parse the foreign keys section of SDI JSON
+@param[in]	ddObject	Data Dictionary JSON object
+@param[in]	columnCache	columns of the table
+@param[in]	tableSchema	schema name of the table
+@return parsed foreign keys
+*/
 func ParseForeignKeys(ddObject gjson.Result, columnCache ColumnCache, tableSchema string) (
-	ddl string, err error) {
+	fkList []*ForeignKey, err error) {
 	foreignKeys := ddObject.Get(`foreign_keys`)
 	if !foreignKeys.Exists() {
-		return "", fmt.Errorf(`table foreign keys not found`)
+		return nil, fmt.Errorf(`table foreign keys not found`)
 	}
+	fkList = make([]*ForeignKey, 0)
 	for _, fk := range foreignKeys.Array() {
 		err = CheckForeignKeyMembers(fk)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		foreignKey := NewForeignKey(fk)
-		foreignKey.parseName()
+		foreignKey.TableSchemaName = tableSchema
 		err = foreignKey.parseElements(columnCache)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		foreignKey.parseReference(tableSchema)
-		foreignKey.parseAction()
-		ddl += fmt.Sprintf(foreignKey.DDL + ",\n")
+		fkList = append(fkList, foreignKey)
+	}
+	return fkList, nil
+}
+
+/*
+* Get the definition of the foreign key as in CREATE TABLE, without
+indentation.
+*/
+func (fk *ForeignKey) GetDefinition() string {
+	definition := fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES ", quoteMySQLIdentifier(fk.Name),
+		quoteMySQLIdentifiers(fk.ColumnNames, ", "))
+	if fk.ReferencedTableSchemaName != fk.TableSchemaName {
+		definition += quoteMySQLIdentifier(fk.ReferencedTableSchemaName) + "."
+	}
+	definition += fmt.Sprintf("%s (%s)", quoteMySQLIdentifier(fk.ReferencedTableName),
+		quoteMySQLIdentifiers(fk.ReferenceNames, ", "))
+	switch fk.DeleteRule {
+	case FK_RULE_RESTRICT, FK_RULE_CASCADE, FK_RULE_SET_NULL, FK_RULE_SET_DEFAULT:
+		definition += " ON DELETE " + fk.DeleteRule.String()
+	}
+	switch fk.UpdateRule {
+	case FK_RULE_RESTRICT, FK_RULE_CASCADE, FK_RULE_SET_NULL, FK_RULE_SET_DEFAULT:
+		definition += " ON UPDATE " + fk.UpdateRule.String()
 	}
-	return ddl, nil
+	return definition
 }
@@ -0,0 +1,116 @@
+package ibd2schema
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// FSEG file segment
+const (
+	/** space id of the inode */
+	FSEG_HDR_SPACE = 0
+	/** page number of the inode */
+	FSEG_HDR_PAGE_NO = 4
+	/** byte offset of the inode */
+	FSEG_HDR_OFFSET = 8
+	/** the list node for linking segment inode pages */
+	FSEG_INODE_PAGE_NODE = FSEG_PAGE_DATA
+	/** offset of the first segment inode on an inode page */
+	FSEG_ARR_OFFSET = FSEG_PAGE_DATA + FLST_NODE_SIZE
+	/** 8 bytes of segment id: if this is 0, it means that the header is unused */
+	FSEG_ID = 0
+	/** number of used segment pages in the FSEG_NOT_FULL list */
+	FSEG_NOT_FULL_N_USED = 8
+	/** list of free extents of this segment */
+	FSEG_FREE = 12
+	/** list of partially free extents */
+	FSEG_NOT_FULL = FSEG_FREE + FLST_BASE_NODE_SIZE
+	/** list of full extents */
+	FSEG_FULL = FSEG_NOT_FULL + FLST_BASE_NODE_SIZE
+	/** magic number used in debugging */
+	FSEG_MAGIC_N = FSEG_FULL + FLST_BASE_NODE_SIZE
+	/** array of individual pages belonging to this segment in fsp fragment
+	extent lists */
+	FSEG_FRAG_ARR = FSEG_MAGIC_N + 4
+	/** a fragment page slot contains its page number within space, FIL_NULL
+	means that the slot is not in use */
+	FSEG_FRAG_SLOT_SIZE = 4
+	/** expected value of FSEG_MAGIC_N */
+	FSEG_MAGIC_N_VALUE = 97937874
+)
+
+/*
+* File segment header which points to the inode describing the file segment
+ */
+type FsegHeader struct {
+	SpaceID uint32
+	PageNum uint32
+	Offset  uint16
+}
+
+func NewFsegHeader(data []byte) *FsegHeader {
+	return &FsegHeader{
+		SpaceID: binary.BigEndian.Uint32(data[FSEG_HDR_SPACE:]),
+		PageNum: binary.BigEndian.Uint32(data[FSEG_HDR_PAGE_NO:]),
+		Offset:  binary.BigEndian.Uint16(data[FSEG_HDR_OFFSET:]),
+	}
+}
+
+/*
+* Number of slots in the fragment page array of a segment inode
+@param[in]	pageSize	page size
+@return number of fragment page slots
+*/
+func FsegFragArrNSlots(pageSize *PageSize) uint32 {
+	return FspExtentSize(pageSize.Logical) / 2
+}
+
+/*
+* Size of a segment inode
+@param[in]	pageSize	page size
+@return size of a segment inode in bytes
+*/
+func FsegInodeSize(pageSize *PageSize) uint32 {
+	return FSEG_FRAG_ARR + FsegFragArrNSlots(pageSize)*FSEG_FRAG_SLOT_SIZE
+}
+
+/*
+* File segment inode
+ */
+type FsegInode struct {
+	ID           uint64
+	NotFullNUsed uint32
+	Free         *FlstBaseNode
+	NotFull      *FlstBaseNode
+	Full         *FlstBaseNode
+	MagicN       uint32
+	/** page numbers of the used fragment page slots */
+	FragPages []uint32
+}
+
+func NewFsegInode(data []byte, pageSize *PageSize) (inode *FsegInode, err error) {
+	if uint32(len(data)) < FsegInodeSize(pageSize) {
+		return nil, fmt.Errorf("inode data len %d < inode size %d",
+			len(data), FsegInodeSize(pageSize))
+	}
+	inode = &FsegInode{
+		ID:           binary.BigEndian.Uint64(data[FSEG_ID:]),
+		NotFullNUsed: binary.BigEndian.Uint32(data[FSEG_NOT_FULL_N_USED:]),
+		Free:         NewFlstBaseNode(data[FSEG_FREE:]),
+		NotFull:      NewFlstBaseNode(data[FSEG_NOT_FULL:]),
+		Full:         NewFlstBaseNode(data[FSEG_FULL:]),
+		MagicN:       binary.BigEndian.Uint32(data[FSEG_MAGIC_N:]),
+		FragPages:    make([]uint32, 0),
+	}
+	if inode.MagicN != FSEG_MAGIC_N_VALUE {
+		return nil, fmt.Errorf("invalid inode magic number %d", inode.MagicN)
+	}
+	for n := uint32(0); n < FsegFragArrNSlots(pageSize); n++ {
+		pageNum := binary.BigEndian.Uint32(data[FSEG_FRAG_ARR+n*FSEG_FRAG_SLOT_SIZE:])
+		if pageNum == FIL_NULL {
+			continue
+		}
+		inode.FragPages = append(inode.FragPages, pageNum)
+	}
+	return inode, nil
+}
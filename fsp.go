@@ -8,8 +8,17 @@ const (
 	FSP_HEADER_OFFSET = FIL_PAGE_DATA
 	// The number of bytes required to store SDI root page number(4) and SDI version(4) at Page 0
 	FSP_SDI_HEADER_LEN = 8
+	// space id
+	FSP_SPACE_ID = 0
+	// current size of the space in pages
+	FSP_SIZE = 8
+	/** Minimum page number for which the free list has not been initialized:
+	the pages >= this limit are, by definition, free */
+	FSP_FREE_LIMIT = 12
 	// fsp_space_t.flags, similar to dict_table_t::flags
 	FSP_SPACE_FLAGS = 16
+	// number of used pages in the FSP_FREE_FRAG list
+	FSP_FRAG_N_USED = 20
 	// Number of flag bits used to indicate the tablespace page size
 	FSP_FLAGS_WIDTH_PAGE_SSIZE uint32 = 4
 	/** Zero relative shift position of the POST_ANTELOPE field */
@@ -87,7 +96,7 @@ func FspHeaderGetFlags(page []byte) uint32 {
 */
 func FspHeaderGetField(page []byte, field uint32) uint32 {
 	offset := FSP_HEADER_OFFSET + field
-	return binary.LittleEndian.Uint32(page[offset : offset+4])
+	return binary.BigEndian.Uint32(page[offset : offset+4])
 }
 
 // FspFlagsGetPageSsize returns the value of the PAGE_SSIZE field from the given flags.
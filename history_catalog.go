@@ -0,0 +1,453 @@
+package ibd2schema
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+/*
+* Buckets of the history catalog. Keys are made of the instance, schema and
+table names separated by HISTORY_KEY_SEPARATOR, followed by the backup time
+as big endian unix nanoseconds, so the keys of a table or of a snapshot are
+sorted by time.
+*/
+var (
+	/** instance, backup time -> HistorySnapshot */
+	HISTORY_BUCKET_SNAPSHOTS = []byte("snapshots")
+	/** instance, schema, table, backup time -> HistoryTable */
+	HISTORY_BUCKET_TABLES = []byte("tables")
+	/** instance, backup time, schema, table -> key of HISTORY_BUCKET_TABLES */
+	HISTORY_BUCKET_SNAPSHOT_TABLES = []byte("snapshot_tables")
+	/** SHA-256 of the SDI -> SDI JSON, shared by the snapshots of a table */
+	HISTORY_BUCKET_SDIS = []byte("sdis")
+)
+
+const HISTORY_KEY_SEPARATOR = 0
+
+/*
+* Backup of an instance in the history catalog
+ */
+type HistorySnapshot struct {
+	Instance   string    `json:"instance"`
+	BackupTime time.Time `json:"backup_time"`
+	IngestedAt time.Time `json:"ingested_at"`
+	/** path of the ingested backup */
+	Source string `json:"source"`
+	Tables int    `json:"tables"`
+}
+
+/*
+* Table of a snapshot in the history catalog
+ */
+type HistoryTable struct {
+	Instance            string    `json:"instance"`
+	SchemaName          string    `json:"schema"`
+	Name                string    `json:"table"`
+	BackupTime          time.Time `json:"backup_time"`
+	Fingerprint         string    `json:"fingerprint"`
+	PhysicalFingerprint string    `json:"physical_fingerprint"`
+	DDL                 string    `json:"ddl"`
+	/** key of the SDI in HISTORY_BUCKET_SDIS */
+	SDIHash string `json:"sdi_hash"`
+}
+
+type HistoryAction string
+
+const (
+	HISTORY_CREATED   HistoryAction = "created"
+	HISTORY_CHANGED   HistoryAction = "changed"
+	HISTORY_UNCHANGED HistoryAction = "unchanged"
+	HISTORY_DROPPED   HistoryAction = "dropped"
+)
+
+/*
+* State of a table in a snapshot compared to the previous snapshot
+ */
+type HistoryEntry struct {
+	BackupTime time.Time     `json:"backup_time"`
+	Action     HistoryAction `json:"action"`
+	/** nil if the table was dropped */
+	Table *HistoryTable `json:"table,omitempty"`
+}
+
+/*
+* Schema history of MySQL instances, the schemas of successive backups in
+an embedded bbolt database.
+*/
+type HistoryCatalog struct {
+	DB *bolt.DB
+}
+
+/*
+* Open or create a history catalog
+@param[in]	path	file of the catalog
+*/
+func OpenHistoryCatalog(path string) (c *HistoryCatalog, err error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open history catalog %s failed, err:%v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{HISTORY_BUCKET_SNAPSHOTS, HISTORY_BUCKET_TABLES,
+			HISTORY_BUCKET_SNAPSHOT_TABLES, HISTORY_BUCKET_SDIS} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create history buckets failed, err:%v", err)
+	}
+	return &HistoryCatalog{DB: db}, nil
+}
+
+func (c *HistoryCatalog) Close() error {
+	return c.DB.Close()
+}
+
+/*
+* Build a key of names followed by a time
+@param[in]	t		time of the key, zero for a prefix of names only
+*/
+func makeHistoryKey(t time.Time, names ...string) []byte {
+	var key bytes.Buffer
+	for _, name := range names {
+		key.WriteString(name)
+		key.WriteByte(HISTORY_KEY_SEPARATOR)
+	}
+	if !t.IsZero() {
+		binary.Write(&key, binary.BigEndian, uint64(t.UnixNano()))
+	}
+	return key.Bytes()
+}
+
+func makeSnapshotTableKey(instance string, t time.Time, schemaName, name string) []byte {
+	key := makeHistoryKey(t, instance)
+	return append(key, makeHistoryKey(time.Time{}, schemaName, name)...)
+}
+
+/*
+* Ingest the SDIs of a backup as a snapshot of an instance. Ingesting a
+snapshot again replaces it. The SDIs of a table are stored once while they
+do not change.
+@param[in]	instance	name of the instance
+@param[in]	backupTime	time of the backup
+@param[in]	source		path of the backup, for information
+@param[in]	sdis		SDIs with parsed table schemas, see DumpSchemas
+*/
+func (c *HistoryCatalog) Ingest(instance string, backupTime time.Time, source string,
+	sdis []*SDI) (snapshot *HistorySnapshot, err error) {
+	backupTime = backupTime.UTC()
+	snapshot = &HistorySnapshot{Instance: instance, BackupTime: backupTime,
+		IngestedAt: time.Now().UTC(), Source: source}
+	err = c.DB.Update(func(tx *bolt.Tx) error {
+		if err := c.deleteSnapshot(tx, instance, backupTime); err != nil {
+			return err
+		}
+		tables := tx.Bucket(HISTORY_BUCKET_TABLES)
+		snapshotTables := tx.Bucket(HISTORY_BUCKET_SNAPSHOT_TABLES)
+		sdiBucket := tx.Bucket(HISTORY_BUCKET_SDIS)
+		for _, sdi := range sdis {
+			ts := sdi.TableSchema
+			if ts == nil || ts.Hidden != HT_VISIBLE {
+				continue
+			}
+			data := sdi.DumpJson()
+			sum := sha256.Sum256(data)
+			record := &HistoryTable{
+				Instance:            instance,
+				SchemaName:          ts.SchemaName,
+				Name:                ts.Name,
+				BackupTime:          backupTime,
+				Fingerprint:         ts.Fingerprint,
+				PhysicalFingerprint: ts.PhysicalFingerprint,
+				DDL:                 ts.DDL,
+				SDIHash:             hex.EncodeToString(sum[:]),
+			}
+			if sdiBucket.Get(sum[:]) == nil {
+				if err := sdiBucket.Put(sum[:], data); err != nil {
+					return err
+				}
+			}
+			value, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			key := makeHistoryKey(backupTime, instance, ts.SchemaName, ts.Name)
+			if err = tables.Put(key, value); err != nil {
+				return err
+			}
+			err = snapshotTables.Put(makeSnapshotTableKey(instance, backupTime, ts.SchemaName, ts.Name), key)
+			if err != nil {
+				return err
+			}
+			snapshot.Tables++
+		}
+		value, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(HISTORY_BUCKET_SNAPSHOTS).Put(makeHistoryKey(backupTime, instance), value)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ingest snapshot %s of %s failed, err:%v", backupTime, instance, err)
+	}
+	return snapshot, nil
+}
+
+/*
+* Delete the tables of a snapshot. SDIs are kept, they may be shared.
+ */
+func (c *HistoryCatalog) deleteSnapshot(tx *bolt.Tx, instance string, backupTime time.Time) error {
+	tables := tx.Bucket(HISTORY_BUCKET_TABLES)
+	snapshotTables := tx.Bucket(HISTORY_BUCKET_SNAPSHOT_TABLES)
+	prefix := makeHistoryKey(backupTime, instance)
+	keys := make([][]byte, 0)
+	cursor := snapshotTables.Cursor()
+	for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+		if err := tables.Delete(v); err != nil {
+			return err
+		}
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, key := range keys {
+		if err := snapshotTables.Delete(key); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(HISTORY_BUCKET_SNAPSHOTS).Delete(prefix)
+}
+
+/*
+* Get the instances of the catalog in name order
+ */
+func (c *HistoryCatalog) GetInstances() (instances []string, err error) {
+	snapshots, err := c.GetSnapshots("")
+	if err != nil {
+		return nil, err
+	}
+	for _, snapshot := range snapshots {
+		if len(instances) == 0 || instances[len(instances)-1] != snapshot.Instance {
+			instances = append(instances, snapshot.Instance)
+		}
+	}
+	return instances, nil
+}
+
+/*
+* Get the snapshots of an instance in time order
+@param[in]	instance	name of the instance, empty for all instances
+*/
+func (c *HistoryCatalog) GetSnapshots(instance string) (snapshots []*HistorySnapshot, err error) {
+	prefix := []byte(nil)
+	if instance != "" {
+		prefix = makeHistoryKey(time.Time{}, instance)
+	}
+	err = c.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(HISTORY_BUCKET_SNAPSHOTS).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			snapshot := &HistorySnapshot{}
+			if err := json.Unmarshal(v, snapshot); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return nil
+	})
+	return snapshots, err
+}
+
+/*
+* Find the snapshot of an instance at a time
+@param[in]	t	backup time, the latest snapshot at or before it is taken
+@return snapshot, error if there is none
+*/
+func (c *HistoryCatalog) FindSnapshot(instance string, t time.Time) (*HistorySnapshot, error) {
+	snapshots, err := c.GetSnapshots(instance)
+	if err != nil {
+		return nil, err
+	}
+	var found *HistorySnapshot
+	for _, snapshot := range snapshots {
+		if !snapshot.BackupTime.After(t) {
+			found = snapshot
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no snapshot of %s at or before %s", instance, t.UTC().Format(time.RFC3339))
+	}
+	return found, nil
+}
+
+/*
+* Get the tables of a snapshot in schema and name order
+ */
+func (c *HistoryCatalog) GetSnapshotTables(instance string, backupTime time.Time) (
+	tables []*HistoryTable, err error) {
+	prefix := makeHistoryKey(backupTime.UTC(), instance)
+	err = c.DB.View(func(tx *bolt.Tx) error {
+		tableBucket := tx.Bucket(HISTORY_BUCKET_TABLES)
+		cursor := tx.Bucket(HISTORY_BUCKET_SNAPSHOT_TABLES).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			table := &HistoryTable{}
+			if err := json.Unmarshal(tableBucket.Get(v), table); err != nil {
+				return err
+			}
+			tables = append(tables, table)
+		}
+		return nil
+	})
+	return tables, err
+}
+
+/*
+* Get the history of a table: its state in each snapshot of the instance
+from the first snapshot that has it on.
+@param[in]	changesOnly	skip the snapshots in which the table is unchanged
+@param[in]	includePhysical	compare the physical options too
+*/
+func (c *HistoryCatalog) GetTableHistory(instance, schemaName, name string, changesOnly,
+	includePhysical bool) (entries []*HistoryEntry, err error) {
+	snapshots, err := c.GetSnapshots(instance)
+	if err != nil {
+		return nil, err
+	}
+	versions := make(map[int64]*HistoryTable)
+	prefix := makeHistoryKey(time.Time{}, instance, schemaName, name)
+	err = c.DB.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(HISTORY_BUCKET_TABLES).Cursor()
+		for k, v := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+			table := &HistoryTable{}
+			if err := json.Unmarshal(v, table); err != nil {
+				return err
+			}
+			versions[table.BackupTime.UnixNano()] = table
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var previous *HistoryTable
+	for _, snapshot := range snapshots {
+		table := versions[snapshot.BackupTime.UnixNano()]
+		entry := &HistoryEntry{BackupTime: snapshot.BackupTime, Table: table}
+		switch {
+		case table == nil && previous == nil:
+			continue
+		case table == nil:
+			entry.Action = HISTORY_DROPPED
+		case previous == nil:
+			entry.Action = HISTORY_CREATED
+		case table.getFingerprint(includePhysical) != previous.getFingerprint(includePhysical):
+			entry.Action = HISTORY_CHANGED
+		default:
+			entry.Action = HISTORY_UNCHANGED
+		}
+		previous = table
+		if changesOnly && entry.Action == HISTORY_UNCHANGED {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (t *HistoryTable) getFingerprint(includePhysical bool) string {
+	if includePhysical {
+		return t.PhysicalFingerprint
+	}
+	return t.Fingerprint
+}
+
+/*
+* Load the table schema of a table of the catalog from its SDI
+ */
+func (c *HistoryCatalog) LoadTableSchema(table *HistoryTable) (ts *TableSchema, err error) {
+	sum, err := hex.DecodeString(table.SDIHash)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	err = c.DB.View(func(tx *bolt.Tx) error {
+		data = append(data, tx.Bucket(HISTORY_BUCKET_SDIS).Get(sum)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("SDI %s of `%s`.`%s` not found", table.SDIHash, table.SchemaName, table.Name)
+	}
+	sdis, err := ParseSDIDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(sdis) != 1 || sdis[0].TableSchema == nil {
+		return nil, fmt.Errorf("SDI %s is not a table", table.SDIHash)
+	}
+	return sdis[0].TableSchema, nil
+}
+
+func (c *HistoryCatalog) loadSnapshotSchemas(instance string, backupTime time.Time) (
+	[]*TableSchema, error) {
+	tables, err := c.GetSnapshotTables(instance, backupTime)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]*TableSchema, 0, len(tables))
+	for _, table := range tables {
+		ts, err := c.LoadTableSchema(table)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, ts)
+	}
+	return schemas, nil
+}
+
+/*
+* Compare two snapshots of an instance
+@param[in]	oldTime	time of the old snapshot, see FindSnapshot
+@param[in]	newTime	time of the new snapshot
+@return diffs of the tables that changed, were created or dropped
+*/
+func (c *HistoryCatalog) DiffSnapshots(instance string, oldTime, newTime time.Time) (
+	diffs []*TableDiff, err error) {
+	oldSnapshot, err := c.FindSnapshot(instance, oldTime)
+	if err != nil {
+		return nil, err
+	}
+	newSnapshot, err := c.FindSnapshot(instance, newTime)
+	if err != nil {
+		return nil, err
+	}
+	oldTables, err := c.loadSnapshotSchemas(instance, oldSnapshot.BackupTime)
+	if err != nil {
+		return nil, err
+	}
+	newTables, err := c.loadSnapshotSchemas(instance, newSnapshot.BackupTime)
+	if err != nil {
+		return nil, err
+	}
+	diffs = make([]*TableDiff, 0)
+	for _, diff := range DiffTableSchemaLists(oldTables, newTables) {
+		if diff.HasChanges() {
+			diffs = append(diffs, diff)
+		}
+	}
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return getTableKey(diffs[i].SchemaName, diffs[i].Name) < getTableKey(diffs[j].SchemaName, diffs[j].Name)
+	})
+	return diffs, nil
+}
@@ -2,6 +2,7 @@ package ibd2schema
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/tidwall/gjson"
@@ -28,6 +29,7 @@ type IndexElement struct {
 	Length    int64
 	Hidden    bool
 	ColumnOpx int
+	Order     IndexElementOrder
 }
 
 func NewIndexElement(e gjson.Result) *IndexElement {
@@ -35,6 +37,7 @@ func NewIndexElement(e gjson.Result) *IndexElement {
 		Length:    e.Get("length").Int(),
 		Hidden:    e.Get("hidden").Bool(),
 		ColumnOpx: int(e.Get("column_opx").Int()),
+		Order:     IndexElementOrder(e.Get("order").Int()),
 	}
 }
 
@@ -43,14 +46,24 @@ type Index struct {
 	Type                IndexType
 	Hidden              bool
 	GJson               gjson.Result
-	DDL                 string
 	Algorithm           IndexAlgorithm
 	IsAlgorithmExplicit bool
 	Comment             string
+	IsVisible           bool
+	Options             map[string]string
+	SEPrivateData       map[string]string
+	/** user defined key parts, see ParseIndexes */
+	KeyParts []*IndexKeyPart
+	/** index id in InnoDB, 0 if unknown */
+	ID uint64
+	/** root page number of the index B-tree, FIL_NULL if unknown */
+	RootPageNum uint32
+	/** physical fields of the index records, see GetFields */
+	Fields []*IndexField
 }
 
 func NewIndex(i gjson.Result) *Index {
-	return &Index{
+	index := &Index{
 		Name:                i.Get("name").String(),
 		Type:                IndexType(i.Get("type").Int()),
 		Hidden:              i.Get("hidden").Bool(),
@@ -58,46 +71,52 @@ func NewIndex(i gjson.Result) *Index {
 		Algorithm:           IndexAlgorithm(i.Get("algorithm").Int()),
 		IsAlgorithmExplicit: i.Get("is_algorithm_explicit").Bool(),
 		Comment:             i.Get("comment").String(),
+		IsVisible:           i.Get("is_visible").Bool(),
+		Options:             ParseKeyValueList(i.Get("options").String()),
+		SEPrivateData:       ParseKeyValueList(i.Get("se_private_data").String()),
+		KeyParts:            make([]*IndexKeyPart, 0),
+		RootPageNum:         FIL_NULL,
 	}
+	if id, err := strconv.ParseUint(index.SEPrivateData["id"], 10, 64); err == nil {
+		index.ID = id
+	}
+	if root, err := strconv.ParseUint(index.SEPrivateData["root"], 10, 32); err == nil {
+		index.RootPageNum = uint32(root)
+	}
+	return index
 }
 
-func (i *Index) parseType() (err error) {
+/*
+* Check that the index type is supported
+ */
+func (i *Index) checkType() (err error) {
 	switch i.Type {
-	case IT_PRIMARY:
-		i.DDL += "  PRIMARY KEY ("
-	case IT_UNIQUE:
-		i.DDL += fmt.Sprintf("  UNIQUE KEY `%s` (", i.Name)
-	case IT_MULTIPLE:
-		i.DDL += fmt.Sprintf("  KEY `%s` (", i.Name)
-	case IT_FULLTEXT:
-		i.DDL += fmt.Sprintf("  FULLTEXT KEY `%s` (", i.Name)
-	case IT_SPATIAL:
-		i.DDL += fmt.Sprintf("  SPATIAL KEY `%s` (", i.Name)
+	case IT_PRIMARY, IT_UNIQUE, IT_MULTIPLE, IT_FULLTEXT, IT_SPATIAL:
+		return nil
 	default:
 		return fmt.Errorf("unsuported index type %d", i.Type)
 	}
-	return nil
 }
 
-func (i *Index) parseElementDDL(element *IndexElement, columnCache ColumnCache) (err error) {
+/*
+* Add a user defined key part of the index
+@param[in]	element		index element of SDI JSON
+@param[in]	columnCache	columns of the table
+*/
+func (i *Index) addKeyPart(element *IndexElement, columnCache ColumnCache) (err error) {
 	column, ok := columnCache[element.ColumnOpx]
 	if !ok {
 		return fmt.Errorf("index column %d not found in the column map", element.ColumnOpx)
 	}
-	if i.Type == IT_MULTIPLE && column.Hidden == HT_HIDDEN_SQL {
-		i.DDL += fmt.Sprintf("(%s)", column.GenerationExpression)
-	} else if i.Type == IT_FULLTEXT || i.Type == IT_SPATIAL {
-		i.DDL += fmt.Sprintf("`%s`,", column.Name)
-	} else {
-		i.DDL += fmt.Sprintf("`%s`", column.Name)
-		/* check prefix index */
-		if column.SupportPrefixIndex() {
-			if element.Length != int64(column.Size) {
-				i.DDL += fmt.Sprintf("(%d)", element.Length/int64(column.Collation.Maxlen))
-			}
-		}
-		i.DDL += ","
+	keyPart := &IndexKeyPart{
+		Column:     column,
+		Descending: element.Order == IEO_DESC,
+	}
+	if i.Type != IT_FULLTEXT && i.Type != IT_SPATIAL && column.SupportPrefixIndex() &&
+		element.Length != int64(column.Size) {
+		keyPart.Length = uint32(element.Length / int64(column.Collation.Maxlen))
 	}
+	i.KeyParts = append(i.KeyParts, keyPart)
 	return nil
 }
 
@@ -113,32 +132,33 @@ func (i *Index) parseElements(columnCache ColumnCache) (err error) {
 			// skip hidden elements
 			continue
 		}
-		err = i.parseElementDDL(element, columnCache)
+		err = i.addKeyPart(element, columnCache)
 		if err != nil {
 			return err
 		}
 	}
-	i.DDL = strings.TrimSuffix(i.DDL, ",")
-	i.DDL += ")"
 	return nil
 }
 
-func (i *Index) parseAlgorithm() (err error) {
+/*
+* Check that an explicit index algorithm is supported
+ */
+func (i *Index) checkAlgorithm() (err error) {
 	if !i.IsAlgorithmExplicit {
 		return nil
 	}
 	switch i.Algorithm {
-	case IA_BTREE:
-		i.DDL += " USING BTREE"
-	case IA_HASH:
-		i.DDL += " USING HASH"
+	case IA_BTREE, IA_HASH:
 	default:
 		return fmt.Errorf("unsupported index algorithm %s", i.Algorithm.String())
 	}
 	return nil
 }
 
-func (i *Index) parseOptions() (err error) {
+/*
+* Check that the index options are supported
+ */
+func (i *Index) checkOptions() (err error) {
 	options := i.GJson.Get("options")
 	optionsList := strings.Split(options.String(), ";")
 	for _, option := range optionsList {
@@ -154,19 +174,10 @@ func (i *Index) parseOptions() (err error) {
 				return fmt.Errorf("unsupported options flags %s", opt[1])
 			}
 		case "parser_name":
-			i.DDL += fmt.Sprintf(" /*!50100 WITH PARSER `%s` */ ", opt[1])
 		default:
 			return fmt.Errorf("unsupported option %s", opt[0])
 		}
 	}
-	i.DDL += ",\n"
-	return nil
-}
-
-func (i *Index) parseComment() (err error) {
-	if i.Comment != "" {
-		i.DDL += fmt.Sprintf(" COMMENT '%s'", i.Comment)
-	}
 	return nil
 }
 
@@ -196,48 +207,240 @@ func CheckIndexElementMembers(element gjson.Result) error {
 
 /*
 * Parse the indexes section of SDI JSON
-@param[in]	    dd_object	    Data Dictionary JSON object
-@param[in,out]	ddl     	    DDL string
-@return False in case of errors
+@param[in]	ddObject	Data Dictionary JSON object
+@param[in]	columnCache	columns of the table
+@return all indexes of the table, including hidden ones
 */
-func ParseIndexes(ddObject gjson.Result, columnCache ColumnCache) (
-	ddl string, err error) {
+func ParseIndexes(ddObject gjson.Result, columnCache ColumnCache) (indexList []*Index, err error) {
 	indexes := ddObject.Get(`indexes`)
 	if !indexes.Exists() {
-		return "", fmt.Errorf(`table indexes not found`)
+		return nil, fmt.Errorf(`table indexes not found`)
 	}
+	indexList = make([]*Index, 0)
 	for _, i := range indexes.Array() {
 		err = CheckIndexMembers(i)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		index := NewIndex(i)
+		indexList = append(indexList, index)
 		if index.Hidden {
 			// skip hidden indexes
 			continue
 		}
 		// parse attributes
-		err = index.parseType()
+		err = index.checkType()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		err = index.parseElements(columnCache)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		err = index.parseAlgorithm()
+		err = index.checkAlgorithm()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		err = index.parseOptions()
+		err = index.checkOptions()
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		err = index.parseComment()
+	}
+	return indexList, nil
+}
+
+/*
+* Get the definition of the index as in CREATE TABLE, without indentation,
+e.g. "UNIQUE KEY `name` (`a`,`b`(10)) COMMENT 'x'"
+*/
+func (i *Index) GetDefinition() string {
+	return i.getDefinition(" /*!80000 INVISIBLE */")
+}
+
+/*
+* Render the definition of the index from its attributes
+@param[in]	invisible	clause of an invisible index, empty to render it visible
+*/
+func (i *Index) getDefinition(invisible string) string {
+	var definition string
+	switch i.Type {
+	case IT_PRIMARY:
+		definition = "PRIMARY KEY"
+	case IT_UNIQUE:
+		definition = "UNIQUE KEY " + quoteMySQLIdentifier(i.Name)
+	case IT_FULLTEXT:
+		definition = "FULLTEXT KEY " + quoteMySQLIdentifier(i.Name)
+	case IT_SPATIAL:
+		definition = "SPATIAL KEY " + quoteMySQLIdentifier(i.Name)
+	default:
+		definition = "KEY " + quoteMySQLIdentifier(i.Name)
+	}
+	keyParts := make([]string, len(i.KeyParts))
+	for n, keyPart := range i.KeyParts {
+		keyParts[n] = keyPart.String()
+	}
+	definition += fmt.Sprintf(" (%s)", strings.Join(keyParts, ","))
+	if i.IsAlgorithmExplicit {
+		definition += " USING " + strings.ToUpper(i.Algorithm.String())
+	}
+	if parser := i.Options["parser_name"]; parser != "" {
+		definition += fmt.Sprintf(" /*!50100 WITH PARSER %s */", quoteMySQLIdentifier(parser))
+	}
+	if i.Comment != "" {
+		definition += " COMMENT " + quoteMySQLString(i.Comment)
+	}
+	if !i.IsVisible && i.Type != IT_PRIMARY {
+		definition += invisible
+	}
+	return definition
+}
+
+/*
+* User defined key part of an index
+ */
+type IndexKeyPart struct {
+	/** indexed column, a hidden generated column for a functional key part */
+	Column *Column
+	/** length of the column prefix in characters, 0 if the whole column is indexed */
+	Length     uint32
+	Descending bool
+}
+
+/*
+* Get the key part as in the DDL, e.g. "`name`(10) DESC" or "(abs(`a`))"
+ */
+func (kp *IndexKeyPart) String() string {
+	s := quoteMySQLIdentifier(kp.Column.Name)
+	if expression := kp.GetExpression(); expression != "" {
+		s = fmt.Sprintf("(%s)", expression)
+	}
+	if kp.Length != 0 {
+		s += fmt.Sprintf("(%d)", kp.Length)
+	}
+	if kp.Descending {
+		s += " DESC"
+	}
+	return s
+}
+
+/*
+* Get the expression of a functional key part.
+@return generation expression, empty if the key part is a column
+*/
+func (kp *IndexKeyPart) GetExpression() string {
+	if kp.Column.Hidden == HT_HIDDEN_SQL {
+		return kp.Column.GenerationExpressionUTF8
+	}
+	return ""
+}
+
+/*
+* Physical field of an index record
+ */
+type IndexField struct {
+	Column *Column
+	/** length of the column prefix in bytes, 0 if the whole column is indexed */
+	PrefixLen uint32
+	/** stored length of the field, 0 if the field has variable length */
+	FixedLen uint32
+	/** true if the element is not a user defined key part */
+	Hidden bool
+	/** true if the key part is sorted in descending order */
+	Descending bool
+}
+
+/*
+* Get the physical fields of the index records, in the order they are stored.
+Hidden elements like the primary key suffix of a secondary index and the
+system columns of a clustered index are included.
+@param[in]	columnCache	columns of the table
+@return index fields
+*/
+func (i *Index) GetFields(columnCache ColumnCache) (fields []*IndexField, err error) {
+	if i.Fields != nil {
+		return i.Fields, nil
+	}
+	fields = make([]*IndexField, 0)
+	for _, e := range i.GJson.Get("elements").Array() {
+		err = CheckIndexElementMembers(e)
+		if err != nil {
+			return nil, err
+		}
+		element := NewIndexElement(e)
+		column, ok := columnCache[element.ColumnOpx]
+		if !ok {
+			return nil, fmt.Errorf("index column %d not found in the column map", element.ColumnOpx)
+		}
+		field := &IndexField{
+			Column:     column,
+			FixedLen:   column.GetFixedSize(),
+			Hidden:     element.Hidden,
+			Descending: element.Order == IEO_DESC,
+		}
+		if column.SupportPrefixIndex() && element.Length > 0 &&
+			uint64(element.Length) < column.Size {
+			field.PrefixLen = uint32(element.Length)
+			if field.FixedLen != 0 {
+				field.FixedLen = field.PrefixLen
+			}
+		}
+		fields = append(fields, field)
+	}
+	i.Fields = fields
+	return fields, nil
+}
+
+/*
+* Check if the index is the clustered index, which contains the system
+column DB_TRX_ID.
+*/
+func (i *Index) IsClustered(columnCache ColumnCache) (bool, error) {
+	fields, err := i.GetFields(columnCache)
+	if err != nil {
+		return false, err
+	}
+	for _, field := range fields {
+		if field.Column.isSystemColumn() && field.Column.Name == "DB_TRX_ID" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+/*
+* Get the number of fields that uniquely determine a record on the non-leaf
+levels of the B-tree, which are the fields stored in node pointers.
+@param[in]	columnCache	columns of the table
+@return number of fields in node pointers, excluding the child page number
+*/
+func (i *Index) GetNUniqInTree(columnCache ColumnCache) (n int, err error) {
+	fields, err := i.GetFields(columnCache)
+	if err != nil {
+		return 0, err
+	}
+	for n, field := range fields {
+		if field.Column.isSystemColumn() && field.Column.Name == "DB_TRX_ID" {
+			return n, nil
+		}
+	}
+	return len(fields), nil
+}
+
+/*
+* Get the clustered index of a table.
+@param[in]	indexes		indexes of the table
+@param[in]	columnCache	columns of the table
+@return clustered index
+*/
+func GetClusteredIndex(indexes []*Index, columnCache ColumnCache) (*Index, error) {
+	for _, index := range indexes {
+		isClustered, err := index.IsClustered(columnCache)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		if isClustered {
+			return index, nil
 		}
-		ddl += index.DDL
 	}
-	return ddl, nil
+	return nil, fmt.Errorf("clustered index not found")
 }
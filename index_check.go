@@ -0,0 +1,407 @@
+package ibd2schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const (
+	/** Maximum number of inconsistent entries described in a check result */
+	INDEX_CHECK_MAX_MESSAGES = 100
+)
+
+/*
+* Result of checking a secondary index against the clustered index
+ */
+type IndexCheck struct {
+	IndexName string
+	/** reason why the index is not checked, empty if checked */
+	SkipReason string
+	/** number of rows in the clustered index */
+	Rows uint64
+	/** number of entries in the secondary index */
+	Entries uint64
+	/** number of rows without entry in the secondary index */
+	MissingEntries uint64
+	/** number of entries whose primary key is not in the clustered index */
+	OrphanEntries uint64
+	/** number of entries whose values differ from the clustered index row */
+	MismatchedEntries uint64
+	/** number of extra entries of rows which already have an entry */
+	DuplicateEntries uint64
+	/** descriptions of the first inconsistent entries */
+	Messages []string
+}
+
+/*
+* Check if the secondary index matches the clustered index.
+ */
+func (ic *IndexCheck) IsConsistent() bool {
+	return ic.MissingEntries == 0 && ic.OrphanEntries == 0 &&
+		ic.MismatchedEntries == 0 && ic.DuplicateEntries == 0
+}
+
+func (ic *IndexCheck) addMessage(format string, a ...interface{}) {
+	if len(ic.Messages) < INDEX_CHECK_MAX_MESSAGES {
+		ic.Messages = append(ic.Messages, fmt.Sprintf(format, a...))
+	}
+}
+
+/*
+* Encode a decoded value as a string which is equal only for equal values
+ */
+func encodeValueKey(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "N"
+	case []byte:
+		return fmt.Sprintf("B%x", v)
+	case string:
+		return fmt.Sprintf("S%q", v)
+	}
+	return fmt.Sprintf("V%v", value)
+}
+
+/*
+* Describe decoded values like (`a`=1,`b`='x')
+ */
+func describeValues(fields []*IndexField, values []interface{}) string {
+	parts := make([]string, len(fields))
+	for n, field := range fields {
+		switch v := values[n].(type) {
+		case nil:
+			parts[n] = fmt.Sprintf("`%s`=NULL", field.Column.Name)
+		case []byte:
+			parts[n] = fmt.Sprintf("`%s`=0x%x", field.Column.Name, v)
+		case string:
+			parts[n] = fmt.Sprintf("`%s`=%q", field.Column.Name, v)
+		default:
+			parts[n] = fmt.Sprintf("`%s`=%v", field.Column.Name, v)
+		}
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+/*
+* Get the reason why a secondary index can not be checked against the
+clustered index, empty if it can be checked.
+*/
+func getIndexCheckSkipReason(index *Index, fields []*IndexField) string {
+	switch index.Type {
+	case IT_FULLTEXT:
+		return "fulltext index is stored in auxiliary tables"
+	case IT_SPATIAL:
+		return "spatial index stores minimum bounding rectangles"
+	}
+	if index.RootPageNum == FIL_NULL {
+		return "index has no B-tree in the tablespace"
+	}
+	for _, field := range fields {
+		options := ParseKeyValueList(field.Column.GJson.Get(`options`).String())
+		if options["is_array"] == "1" {
+			return "multi-valued index has several entries per row"
+		}
+	}
+	return ""
+}
+
+/*
+* Get the fields of a secondary index which hold the primary key, in the
+order of the primary key.
+@return position of each primary key field in the secondary index fields
+*/
+func getPKFieldPositions(pkFields []*IndexField, fields []*IndexField) (positions []int, err error) {
+	positions = make([]int, len(pkFields))
+	for n, pkField := range pkFields {
+		positions[n] = -1
+		for pos, field := range fields {
+			if field.Column == pkField.Column && field.PrefixLen == pkField.PrefixLen {
+				positions[n] = pos
+				break
+			}
+		}
+		if positions[n] == -1 {
+			return nil, fmt.Errorf("primary key column %s not found in secondary index",
+				pkField.Column.Name)
+		}
+	}
+	return positions, nil
+}
+
+/*
+* Get the expected entry of a clustered index row in a secondary index.
+@param[in]	index		secondary index
+@param[in]	columnPositions	position of each column's full value in the clustered index record
+@param[in]	row		decoded clustered index record
+@return values of the leading fields up to the first virtual column, and the
+encoded value of each field, empty for virtual columns
+*/
+func getExpectedEntry(index *Index, columnPositions map[*Column]int, row []interface{}) (
+	bound []interface{}, keys []string, err error) {
+	bound = make([]interface{}, 0, len(index.Fields))
+	keys = make([]string, len(index.Fields))
+	for n, field := range index.Fields {
+		if field.Column.IsVirtual {
+			continue
+		}
+		pos, ok := columnPositions[field.Column]
+		if !ok {
+			return nil, nil, fmt.Errorf("column %s of index %s not found in clustered index",
+				field.Column.Name, index.Name)
+		}
+		value := row[pos]
+		if field.PrefixLen != 0 {
+			value = GetValuePrefix(field.Column, value, field.PrefixLen)
+		}
+		if len(bound) == n {
+			bound = append(bound, value)
+		}
+		keys[n] = encodeValueKey(value)
+	}
+	return bound, keys, nil
+}
+
+/*
+* Find the record of an index with the given field values. The B-tree is
+descended to the bound, and the records in the bound are compared exactly, as
+strings of non-binary collations are only compared approximately.
+@param[in]	index		index to search
+@param[in]	columnCache	columns of the table
+@param[in]	bound		values of the leading fields
+@param[in]	keys		encoded value of each field, empty for unchecked fields
+@return decoded record, nil if no record which is not delete marked is equal
+*/
+func (ts *TableSpace) findIndexRecord(index *Index, columnCache ColumnCache, bound []interface{},
+	keys []string) (values []interface{}, err error) {
+	keyBound := &KeyBound{Values: bound, Inclusive: true}
+	it, err := ts.newIndexIterator(context.Background(), index, columnCache,
+		&ScanOptions{Lower: keyBound, Upper: keyBound})
+	if err != nil {
+		return nil, err
+	}
+	for {
+		page, rec, err := it.nextRecord()
+		if err != nil || rec == nil {
+			return nil, err
+		}
+		values, err = ts.DecodeRecord(page, rec)
+		if err != nil {
+			return nil, err
+		}
+		match := true
+		for n, key := range keys {
+			if key != "" && key != encodeValueKey(values[n]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return values, nil
+		}
+	}
+}
+
+/*
+* Walk each secondary index of a table and check that every entry matches
+exactly one row of the clustered index with the same column values, and that
+every row has an entry. The row of each entry is looked up in the clustered
+index, and the rows are only walked again to describe the rows without entry,
+so memory does not grow with the table. Lookups on leading string key parts
+of non-binary collations read from the first leaf page, see Scan. Delete
+marked records are ignored, and values of virtual columns are not compared as
+they are not stored in the clustered index. This is the offline version of
+CHECK TABLE.
+@param[in]	tableSchema	table of the tablespace
+@return check result of each secondary index
+*/
+func (ts *TableSpace) CheckTableIndexes(tableSchema *TableSchema) (checks []*IndexCheck, err error) {
+	columnCache := tableSchema.Columns
+	clustered, err := GetClusteredIndex(tableSchema.Indexes, columnCache)
+	if err != nil {
+		return nil, err
+	}
+	clusteredFields, err := clustered.GetFields(columnCache)
+	if err != nil {
+		return nil, err
+	}
+	nPK, err := clustered.GetNUniqInTree(columnCache)
+	if err != nil {
+		return nil, err
+	}
+	pkFields := clusteredFields[:nPK]
+	/* position of each column's full value in the clustered index record */
+	columnPositions := make(map[*Column]int)
+	for pos, field := range clusteredFields {
+		if field.PrefixLen == 0 {
+			columnPositions[field.Column] = pos
+		}
+	}
+
+	checks = make([]*IndexCheck, 0)
+	/* checked secondary indexes and their results */
+	secondaries := make([]*Index, 0)
+	active := make([]*IndexCheck, 0)
+	for _, index := range tableSchema.Indexes {
+		if index == clustered {
+			continue
+		}
+		fields, err := index.GetFields(columnCache)
+		if err != nil {
+			return nil, err
+		}
+		check := &IndexCheck{IndexName: index.Name, Messages: make([]string, 0)}
+		checks = append(checks, check)
+		check.SkipReason = getIndexCheckSkipReason(index, fields)
+		if check.SkipReason != "" {
+			continue
+		}
+		secondaries = append(secondaries, index)
+		active = append(active, check)
+	}
+	if len(secondaries) == 0 {
+		return checks, nil
+	}
+
+	rows := uint64(0)
+	err = ts.WalkRecords(clustered, columnCache, func(page *Page, rec *Record) (bool, error) {
+		if !rec.IsDeleteMarked() {
+			rows++
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for n, index := range secondaries {
+		check := active[n]
+		check.Rows = rows
+		pkPositions, err := getPKFieldPositions(pkFields, index.Fields)
+		if err != nil {
+			return nil, err
+		}
+		/* entries of a row with the same values are adjacent in the index */
+		matchedRows := uint64(0)
+		lastEntry, lastCount := "", 0
+		err = ts.WalkRecords(index, columnCache, func(page *Page, rec *Record) (bool, error) {
+			if rec.IsDeleteMarked() {
+				return true, nil
+			}
+			check.Entries++
+			values, err := ts.DecodeRecord(page, rec)
+			if err != nil {
+				return false, err
+			}
+			pkKeys := make([]string, nPK)
+			pkValues := make([]interface{}, nPK)
+			for i, pos := range pkPositions {
+				pkKeys[i] = encodeValueKey(values[pos])
+				pkValues[i] = values[pos]
+			}
+			keys := make([]string, 0)
+			keyFields := make([]*IndexField, 0)
+			keyValues := make([]interface{}, 0)
+			for i, field := range index.Fields {
+				if field.Hidden || field.Column.IsVirtual {
+					continue
+				}
+				keys = append(keys, encodeValueKey(values[i]))
+				keyFields = append(keyFields, field)
+				keyValues = append(keyValues, values[i])
+			}
+			pkDesc := describeValues(pkFields, pkValues)
+			row, err := ts.findIndexRecord(clustered, columnCache, pkValues, pkKeys)
+			if err != nil {
+				return false, err
+			}
+			if row == nil {
+				check.OrphanEntries++
+				check.addMessage("entry %s of primary key %s has no row in clustered index",
+					describeValues(keyFields, keyValues), pkDesc)
+				return true, nil
+			}
+			_, expected, err := getExpectedEntry(index, columnPositions, row)
+			if err != nil {
+				return false, err
+			}
+			for i, field := range index.Fields {
+				if field.Hidden || field.Column.IsVirtual || expected[i] == encodeValueKey(values[i]) {
+					continue
+				}
+				check.MismatchedEntries++
+				check.addMessage("entry %s of primary key %s does not match the row",
+					describeValues(keyFields, keyValues), pkDesc)
+				return true, nil
+			}
+			entry := strings.Join(keys, "\x1f") + "\x1e" + strings.Join(pkKeys, "\x1f")
+			if entry == lastEntry {
+				lastCount++
+				check.DuplicateEntries++
+				check.addMessage("row of primary key %s has %d entries", pkDesc, lastCount)
+				return true, nil
+			}
+			matchedRows++
+			lastEntry, lastCount = entry, 1
+			return true, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if matchedRows >= rows {
+			continue
+		}
+		check.MissingEntries = rows - matchedRows
+		/* describe the first rows without entry */
+		found := uint64(0)
+		err = ts.WalkRecords(clustered, columnCache, func(page *Page, rec *Record) (bool, error) {
+			if rec.IsDeleteMarked() {
+				return true, nil
+			}
+			row, err := ts.DecodeRecord(page, rec)
+			if err != nil {
+				return false, err
+			}
+			bound, expected, err := getExpectedEntry(index, columnPositions, row)
+			if err != nil {
+				return false, err
+			}
+			entry, err := ts.findIndexRecord(index, columnCache, bound, expected)
+			if err != nil {
+				return false, err
+			}
+			if entry == nil {
+				found++
+				check.addMessage("row of primary key %s has no entry", describeValues(pkFields, row[:nPK]))
+			}
+			return found < check.MissingEntries && len(check.Messages) < INDEX_CHECK_MAX_MESSAGES, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return checks, nil
+}
+
+/*
+* Check the secondary indexes of all tables in the tablespace against their
+clustered indexes, see CheckTableIndexes.
+*/
+func (ts *TableSpace) DumpIndexChecks() (err error) {
+	if ts.TableSchemas == nil {
+		err = ts.DumpSchemas()
+		if err != nil {
+			return err
+		}
+	}
+	for _, tableSchema := range ts.TableSchemas {
+		if tableSchema.Hidden != HT_VISIBLE {
+			continue
+		}
+		tableSchema.IndexChecks, err = ts.CheckTableIndexes(tableSchema)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package ibd2schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+* Why an index is redundant
+ */
+type RedundancyKind string
+
+const (
+	/** same key parts as another index */
+	REDUNDANCY_DUPLICATE RedundancyKind = "duplicate"
+	/** non-unique index whose key parts are a leftmost prefix of another index */
+	REDUNDANCY_PREFIX RedundancyKind = "prefix"
+	/** unique index whose leftmost key parts are the primary key, so the
+	primary key already makes its rows unique */
+	REDUNDANCY_PRIMARY_KEY RedundancyKind = "primary_key"
+)
+
+type RedundantIndex struct {
+	SchemaName string         `json:"schema"`
+	TableName  string         `json:"table"`
+	Index      *Index         `json:"-"`
+	IndexName  string         `json:"index"`
+	Definition string         `json:"definition"`
+	Kind       RedundancyKind `json:"kind"`
+	/** index that makes the index redundant */
+	CoveredBy           *Index `json:"-"`
+	CoveredByName       string `json:"covered_by"`
+	CoveredByDefinition string `json:"covered_by_definition"`
+	/** foreign keys that no other index supports, MySQL refuses to drop the
+	index before them */
+	ForeignKeys   []string `json:"foreign_keys,omitempty"`
+	DropStatement string   `json:"drop_statement"`
+	/** page statistics of the index, nil if not available */
+	Stats *IndexStats `json:"-"`
+	/** number of pages used by the index, 0 if not available */
+	Pages uint32 `json:"pages,omitempty"`
+	/** bytes reserved by the index, 0 if not available */
+	Size uint64 `json:"size,omitempty"`
+}
+
+/*
+* Check if the columns are the leftmost whole-column key parts of the index
+ */
+func (i *Index) hasLeftmostColumns(names []string) bool {
+	if len(i.KeyParts) < len(names) {
+		return false
+	}
+	for n, name := range names {
+		keyPart := i.KeyParts[n]
+		if keyPart.Length != 0 || keyPart.GetExpression() != "" ||
+			!strings.EqualFold(keyPart.Column.Name, name) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* Check if two key parts index the same column or expression in the same
+order, the hidden columns of functional key parts have generated names.
+*/
+func (kp *IndexKeyPart) isSameColumn(other *IndexKeyPart) bool {
+	if kp.Descending != other.Descending || kp.GetExpression() != other.GetExpression() {
+		return false
+	}
+	return kp.GetExpression() != "" || kp.Column.Name == other.Column.Name
+}
+
+/*
+* Check if the key parts of an index are a leftmost prefix of the key parts
+of another index, the last key part may index a shorter column prefix.
+*/
+func (i *Index) isPrefixOf(other *Index) bool {
+	if len(i.KeyParts) == 0 || len(i.KeyParts) > len(other.KeyParts) {
+		return false
+	}
+	for n, keyPart := range i.KeyParts {
+		otherPart := other.KeyParts[n]
+		if !keyPart.isSameColumn(otherPart) {
+			return false
+		}
+		if keyPart.Length == otherPart.Length {
+			continue
+		}
+		if n != len(i.KeyParts)-1 || keyPart.Length == 0 ||
+			(otherPart.Length != 0 && otherPart.Length < keyPart.Length) {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+* Check if an index has the same key parts as another index
+ */
+func (i *Index) hasSameKeyParts(other *Index) bool {
+	return len(i.KeyParts) == len(other.KeyParts) && i.isPrefixOf(other) && other.isPrefixOf(i)
+}
+
+/*
+* Get the rank of the index to keep among duplicate indexes, the primary key
+first, then unique indexes.
+*/
+func (i *Index) getKeepRank() int {
+	switch i.Type {
+	case IT_PRIMARY:
+		return 0
+	case IT_UNIQUE:
+		return 1
+	}
+	return 2
+}
+
+/*
+* Find the duplicate and redundant B-tree indexes of a table: indexes with the
+same key parts as another index, non-unique indexes whose key parts are a
+leftmost prefix of another index and unique indexes that start with the
+primary key. Of duplicate indexes the primary key, else a unique index, else
+the first index is kept.
+@param[in]	table	table with its indexes and, optionally, index statistics
+@return redundant indexes in index order
+*/
+func FindRedundantIndexes(table *TableSchema) []*RedundantIndex {
+	indexes := make([]*Index, 0)
+	for _, index := range table.GetUserIndexes() {
+		if index.Type != IT_FULLTEXT && index.Type != IT_SPATIAL {
+			indexes = append(indexes, index)
+		}
+	}
+	dropped := make(map[*Index]bool)
+	findings := make([]*RedundantIndex, 0)
+	add := func(index, coveredBy *Index, kind RedundancyKind) {
+		dropped[index] = true
+		findings = append(findings, table.newRedundantIndex(index, coveredBy, kind))
+	}
+	/* duplicates first, so prefixes refer to the kept index */
+	for n, index := range indexes {
+		for m, other := range indexes {
+			if m == n || dropped[other] || !index.hasSameKeyParts(other) {
+				continue
+			}
+			if other.getKeepRank() < index.getKeepRank() ||
+				other.getKeepRank() == index.getKeepRank() && m < n {
+				add(index, other, REDUNDANCY_DUPLICATE)
+				break
+			}
+		}
+	}
+	var primaryKey *Index
+	for _, index := range indexes {
+		if index.Type == IT_PRIMARY {
+			primaryKey = index
+		}
+	}
+	for _, index := range indexes {
+		if dropped[index] || index.Type == IT_PRIMARY {
+			continue
+		}
+		if index.Type == IT_UNIQUE {
+			if primaryKey != nil && primaryKey.isPrefixOf(index) {
+				add(index, primaryKey, REDUNDANCY_PRIMARY_KEY)
+			}
+			continue
+		}
+		for _, other := range indexes {
+			if other != index && !dropped[other] && index.isPrefixOf(other) {
+				add(index, other, REDUNDANCY_PREFIX)
+				break
+			}
+		}
+	}
+	table.checkForeignKeysOfDroppedIndexes(findings, dropped)
+	return findings
+}
+
+func (ts *TableSchema) newRedundantIndex(index, coveredBy *Index, kind RedundancyKind) *RedundantIndex {
+	redundant := &RedundantIndex{
+		SchemaName:          ts.SchemaName,
+		TableName:           ts.Name,
+		Index:               index,
+		IndexName:           index.Name,
+		Definition:          index.GetDefinition(),
+		Kind:                kind,
+		CoveredBy:           coveredBy,
+		CoveredByName:       coveredBy.Name,
+		CoveredByDefinition: coveredBy.GetDefinition(),
+		DropStatement: fmt.Sprintf("ALTER TABLE %s.%s %s, ALGORITHM=%s;", quoteMySQLIdentifier(ts.SchemaName),
+			quoteMySQLIdentifier(ts.Name), getDropIndexClause(index), ALTER_ALGORITHM_INPLACE),
+	}
+	for _, stats := range ts.IndexStats {
+		if (index.ID != 0 && stats.IndexID == index.ID) || (index.ID == 0 && stats.IndexName == index.Name) {
+			redundant.Stats = stats
+			redundant.Pages = stats.LeafPages + stats.NonLeafPages
+			redundant.Size = stats.ReservedSize
+			break
+		}
+	}
+	return redundant
+}
+
+/*
+* Record the foreign keys that need a dropped index because none of the
+remaining indexes starts with their columns.
+*/
+func (ts *TableSchema) checkForeignKeysOfDroppedIndexes(findings []*RedundantIndex, dropped map[*Index]bool) {
+	for _, fk := range ts.ForeignKeys {
+		var supporting *RedundantIndex
+		supported := false
+		for _, index := range ts.GetUserIndexes() {
+			if index.Type == IT_FULLTEXT || index.Type == IT_SPATIAL || !index.hasLeftmostColumns(fk.ColumnNames) {
+				continue
+			}
+			if !dropped[index] {
+				supported = true
+				break
+			}
+			for _, finding := range findings {
+				if finding.Index == index && supporting == nil {
+					supporting = finding
+				}
+			}
+		}
+		if !supported && supporting != nil {
+			supporting.ForeignKeys = append(supporting.ForeignKeys, fk.Name)
+		}
+	}
+}
+
+/*
+* Get why the index is redundant
+ */
+func (ri *RedundantIndex) getReason() string {
+	var reason string
+	switch ri.Kind {
+	case REDUNDANCY_DUPLICATE:
+		reason = fmt.Sprintf("duplicates %s", ri.CoveredByDefinition)
+	case REDUNDANCY_PREFIX:
+		reason = fmt.Sprintf("is a prefix of %s", ri.CoveredByDefinition)
+	case REDUNDANCY_PRIMARY_KEY:
+		reason = fmt.Sprintf("is unique by %s", ri.CoveredByDefinition)
+	}
+	if len(ri.ForeignKeys) != 0 {
+		reason += fmt.Sprintf(", but foreign keys %s need it", quoteNames(ri.ForeignKeys))
+	}
+	return reason
+}
+
+/*
+* Get the finding as text, e.g.
+"`db`.`t` KEY `a` (`a`) is a prefix of KEY `ab` (`a`,`b`), 16 pages, 262144 bytes"
+*/
+func (ri *RedundantIndex) String() string {
+	s := fmt.Sprintf("`%s`.`%s` %s %s", ri.SchemaName, ri.TableName, ri.Definition, ri.getReason())
+	if ri.Stats != nil {
+		s += fmt.Sprintf(", %d pages, %d bytes", ri.Pages, ri.Size)
+	}
+	return s
+}
+
+/*
+* Generate the statements dropping redundant indexes, one ALTER TABLE per
+table. Indexes that foreign keys need are left in a comment.
+@param[in]	redundant	redundant indexes, see FindRedundantIndexes
+@return SQL script
+*/
+func GenerateDropIndexScript(redundant []*RedundantIndex) string {
+	var b strings.Builder
+	tables := make([]string, 0)
+	clauses := make(map[string][]string)
+	for _, ri := range redundant {
+		fmt.Fprintf(&b, "-- %s\n", ri.String())
+		if len(ri.ForeignKeys) != 0 {
+			continue
+		}
+		table := fmt.Sprintf("`%s`.`%s`", ri.SchemaName, ri.TableName)
+		if _, ok := clauses[table]; !ok {
+			tables = append(tables, table)
+		}
+		clauses[table] = append(clauses[table], getDropIndexClause(ri.Index))
+	}
+	for _, table := range tables {
+		fmt.Fprintf(&b, "ALTER TABLE %s %s, ALGORITHM=%s;\n", table, strings.Join(clauses[table], ", "),
+			ALTER_ALGORITHM_INPLACE)
+	}
+	return b.String()
+}
+
+func DumpRedundantIndexesJson(redundant []*RedundantIndex) ([]byte, error) {
+	return json.MarshalIndent(redundant, "", "  ")
+}
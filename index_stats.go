@@ -0,0 +1,289 @@
+package ibd2schema
+
+import (
+	"fmt"
+)
+
+/*
+* Page usage of a file segment, collected from its inode and extent descriptors
+ */
+type SegmentStats struct {
+	SegmentID uint64
+	/** number of extents in the FSEG_FULL list */
+	FullExtents uint32
+	/** number of extents in the FSEG_NOT_FULL list */
+	NotFullExtents uint32
+	/** number of extents in the FSEG_FREE list */
+	FreeExtents uint32
+	/** number of pages in the fragment array */
+	FragPages uint32
+	/** number of pages reserved by the segment */
+	ReservedPages uint32
+	/** number of pages used by the segment */
+	UsedPages uint32
+	/** page numbers of the used pages */
+	PageNums []uint32
+}
+
+/*
+* Number of extents which have at least one used page.
+ */
+func (ss *SegmentStats) ExtentsInUse() uint32 {
+	return ss.FullExtents + ss.NotFullExtents
+}
+
+/*
+* Number of pages reserved by the segment but not used.
+ */
+func (ss *SegmentStats) FreePages() uint32 {
+	return ss.ReservedPages - ss.UsedPages
+}
+
+/*
+* Page and size statistics of an index B-tree
+ */
+type IndexStats struct {
+	IndexName   string
+	IndexID     uint64
+	RootPageNum uint32
+	/** number of levels of the B-tree, a tree with only the root page has
+	height 1 */
+	Height uint16
+	/** number of pages on level 0 */
+	LeafPages uint32
+	/** number of pages above level 0 */
+	NonLeafPages uint32
+	/** number of extents with used pages in both segments */
+	ExtentsInUse uint32
+	/** number of pages reserved by both segments but not used */
+	FreePages uint32
+	/** number of fragment pages of both segments */
+	FragPages uint32
+	/** bytes used by records and page directories */
+	DataSize uint64
+	/** bytes reserved by both segments */
+	ReservedSize uint64
+	/** average ratio of used space of the index pages */
+	AvgFillFactor float64
+	LeafSegment   *SegmentStats
+	TopSegment    *SegmentStats
+}
+
+/*
+* Read the inode of a file segment.
+@param[in]	header	file segment header
+@return file segment inode
+*/
+func (ts *TableSpace) FetchFsegInode(header *FsegHeader) (inode *FsegInode, err error) {
+	if header.SpaceID != ts.SpaceID {
+		return nil, fmt.Errorf("segment space id %d != tablespace id %d",
+			header.SpaceID, ts.SpaceID)
+	}
+	page, err := ts.FetchPage(header.PageNum)
+	if err != nil {
+		return nil, fmt.Errorf("fetch inode page failed, err:%v", err)
+	}
+	page.GetPageType()
+	if page.PageType != FIL_PAGE_INODE {
+		return nil, fmt.Errorf("page %d type %d is not FIL_PAGE_INODE",
+			header.PageNum, page.PageType)
+	}
+	if uint32(header.Offset) < FSEG_ARR_OFFSET ||
+		uint32(header.Offset)+FsegInodeSize(ts.PageSize) > uint32(len(page.OriginData)) {
+		return nil, fmt.Errorf("invalid inode offset %d", header.Offset)
+	}
+	return NewFsegInode(page.OriginData[header.Offset:], ts.PageSize)
+}
+
+/*
+* Read the extent descriptor pointed by the list node address.
+@param[in]	addr	address of XDES_FLST_NODE of the extent descriptor
+@return extent descriptor
+*/
+func (ts *TableSpace) FetchXdes(addr *FilAddr) (xdes *Xdes, err error) {
+	xdesSize := GetXdesSize(ts.PageSize)
+	entryOffset := uint32(addr.Boffset) - XDES_FLST_NODE
+	if uint32(addr.Boffset) < XDES_ARR_OFFSET+XDES_FLST_NODE ||
+		(entryOffset-XDES_ARR_OFFSET)%xdesSize != 0 {
+		return nil, fmt.Errorf("invalid extent descriptor offset %d", addr.Boffset)
+	}
+	page, err := ts.FetchPage(addr.PageNum)
+	if err != nil {
+		return nil, fmt.Errorf("fetch extent descriptor page failed, err:%v", err)
+	}
+	page.GetPageType()
+	if page.PageType != FIL_PAGE_TYPE_FSP_HDR && page.PageType != FIL_PAGE_TYPE_XDES {
+		return nil, fmt.Errorf("page %d type %d is not an extent descriptor page",
+			addr.PageNum, page.PageType)
+	}
+	extentSize := FspExtentSize(ts.PageSize.Logical)
+	firstPageNum := addr.PageNum + (entryOffset-XDES_ARR_OFFSET)/xdesSize*extentSize
+	return NewXdes(firstPageNum, page.OriginData[entryOffset:], ts.PageSize), nil
+}
+
+/*
+* Read all extent descriptors of an extent list.
+@param[in]	base	base node of the list
+@return extent descriptors in list order
+*/
+func (ts *TableSpace) FetchXdesList(base *FlstBaseNode) (xdesList []*Xdes, err error) {
+	xdesList = make([]*Xdes, 0, base.Len)
+	addr := base.First
+	for !addr.IsNull() {
+		if uint32(len(xdesList)) >= base.Len {
+			return nil, fmt.Errorf("extent list is longer than its length %d", base.Len)
+		}
+		xdes, err := ts.FetchXdes(addr)
+		if err != nil {
+			return nil, err
+		}
+		xdesList = append(xdesList, xdes)
+		addr = xdes.Node.Next
+	}
+	if uint32(len(xdesList)) != base.Len {
+		return nil, fmt.Errorf("extent list length %d != expected %d",
+			len(xdesList), base.Len)
+	}
+	return xdesList, nil
+}
+
+/*
+* Collect page usage of a file segment.
+@param[in]	header	file segment header
+@return segment statistics
+*/
+func (ts *TableSpace) GetSegmentStats(header *FsegHeader) (ss *SegmentStats, err error) {
+	inode, err := ts.FetchFsegInode(header)
+	if err != nil {
+		return nil, err
+	}
+	ss = &SegmentStats{
+		SegmentID:      inode.ID,
+		FullExtents:    inode.Full.Len,
+		NotFullExtents: inode.NotFull.Len,
+		FreeExtents:    inode.Free.Len,
+		FragPages:      uint32(len(inode.FragPages)),
+		PageNums:       make([]uint32, 0),
+	}
+	extentSize := FspExtentSize(ts.PageSize.Logical)
+	ss.ReservedPages = (ss.FullExtents+ss.NotFullExtents+ss.FreeExtents)*extentSize + ss.FragPages
+	ss.PageNums = append(ss.PageNums, inode.FragPages...)
+	for _, base := range []*FlstBaseNode{inode.Full, inode.NotFull} {
+		xdesList, err := ts.FetchXdesList(base)
+		if err != nil {
+			return nil, err
+		}
+		for _, xdes := range xdesList {
+			if xdes.SegmentID != inode.ID {
+				return nil, fmt.Errorf("extent of page %d belongs to segment %d, expected %d",
+					xdes.FirstPageNum, xdes.SegmentID, inode.ID)
+			}
+			ss.PageNums = append(ss.PageNums, xdes.UsedPages()...)
+		}
+	}
+	ss.UsedPages = uint32(len(ss.PageNums))
+	return ss, nil
+}
+
+/*
+* Collect page and size statistics of an index by following its root page
+to the leaf and non-leaf file segments.
+@param[in]	index	index with root page number in se_private_data
+@return index statistics
+*/
+func (ts *TableSpace) GetIndexStats(index *Index) (is *IndexStats, err error) {
+	if index.RootPageNum == FIL_NULL {
+		return nil, fmt.Errorf("root page of index %s not found", index.Name)
+	}
+	root, err := ts.FetchPage(index.RootPageNum)
+	if err != nil {
+		return nil, fmt.Errorf("fetch root page of index %s failed, err:%v", index.Name, err)
+	}
+	root.GetPageType()
+	if root.PageType != FIL_PAGE_INDEX && root.PageType != FIL_PAGE_RTREE {
+		return nil, fmt.Errorf("root page %d of index %s is not an index page",
+			index.RootPageNum, index.Name)
+	}
+	root.GetIndexID()
+	if root.IndexID != index.ID {
+		return nil, fmt.Errorf("root page %d belongs to index id %d, expected %d",
+			index.RootPageNum, root.IndexID, index.ID)
+	}
+	root.GetPageLevel()
+	is = &IndexStats{
+		IndexName:   index.Name,
+		IndexID:     index.ID,
+		RootPageNum: index.RootPageNum,
+		Height:      root.PageLevel + 1,
+	}
+	is.LeafSegment, err = ts.GetSegmentStats(root.GetFsegHeader(PAGE_BTR_SEG_LEAF))
+	if err != nil {
+		return nil, fmt.Errorf("read leaf segment of index %s failed, err:%v", index.Name, err)
+	}
+	is.TopSegment, err = ts.GetSegmentStats(root.GetFsegHeader(PAGE_BTR_SEG_TOP))
+	if err != nil {
+		return nil, fmt.Errorf("read non-leaf segment of index %s failed, err:%v", index.Name, err)
+	}
+	var fillFactorSum float64
+	for _, ss := range []*SegmentStats{is.LeafSegment, is.TopSegment} {
+		is.ExtentsInUse += ss.ExtentsInUse()
+		is.FreePages += ss.FreePages()
+		is.FragPages += ss.FragPages
+		is.ReservedSize += uint64(ss.ReservedPages) * uint64(ts.PageSize.Physical)
+		for _, pageNum := range ss.PageNums {
+			page, err := ts.FetchPage(pageNum)
+			if err != nil {
+				return nil, fmt.Errorf("fetch page of index %s failed, err:%v", index.Name, err)
+			}
+			page.GetPageType()
+			if page.PageType != FIL_PAGE_INDEX && page.PageType != FIL_PAGE_RTREE {
+				continue
+			}
+			page.GetIndexID()
+			if page.IndexID != index.ID {
+				continue
+			}
+			page.GetPageLevel()
+			if page.PageLevel == 0 {
+				is.LeafPages++
+			} else {
+				is.NonLeafPages++
+			}
+			dataSize := page.GetDataSize()
+			is.DataSize += uint64(dataSize)
+			fillFactorSum += float64(dataSize) / float64(page.GetMaxDataSize())
+		}
+	}
+	if is.LeafPages+is.NonLeafPages > 0 {
+		is.AvgFillFactor = fillFactorSum / float64(is.LeafPages+is.NonLeafPages)
+	}
+	return is, nil
+}
+
+/*
+* Collect page and size statistics of all indexes of the tables in the
+tablespace. Indexes without B-tree in the tablespace (e.g. FULLTEXT) are
+skipped.
+*/
+func (ts *TableSpace) DumpIndexStats() (err error) {
+	if ts.TableSchemas == nil {
+		err = ts.DumpSchemas()
+		if err != nil {
+			return err
+		}
+	}
+	for _, tableSchema := range ts.TableSchemas {
+		tableSchema.IndexStats = make([]*IndexStats, 0, len(tableSchema.Indexes))
+		for _, index := range tableSchema.Indexes {
+			if index.Type == IT_FULLTEXT || index.RootPageNum == FIL_NULL {
+				continue
+			}
+			is, err := ts.GetIndexStats(index)
+			if err != nil {
+				return err
+			}
+			tableSchema.IndexStats = append(tableSchema.IndexStats, is)
+		}
+	}
+	return nil
+}
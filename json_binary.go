@@ -0,0 +1,325 @@
+package ibd2schema
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/* https://github.com/mysql/mysql-server/blob/trunk/sql-common/json_binary.h */
+const (
+	JSONB_TYPE_SMALL_OBJECT = 0x0
+	JSONB_TYPE_LARGE_OBJECT = 0x1
+	JSONB_TYPE_SMALL_ARRAY  = 0x2
+	JSONB_TYPE_LARGE_ARRAY  = 0x3
+	JSONB_TYPE_LITERAL      = 0x4
+	JSONB_TYPE_INT16        = 0x5
+	JSONB_TYPE_UINT16       = 0x6
+	JSONB_TYPE_INT32        = 0x7
+	JSONB_TYPE_UINT32       = 0x8
+	JSONB_TYPE_INT64        = 0x9
+	JSONB_TYPE_UINT64       = 0xA
+	JSONB_TYPE_DOUBLE       = 0xB
+	JSONB_TYPE_STRING       = 0xC
+	JSONB_TYPE_OPAQUE       = 0xF
+
+	JSONB_NULL_LITERAL  = 0x0
+	JSONB_TRUE_LITERAL  = 0x1
+	JSONB_FALSE_LITERAL = 0x2
+
+	/* MySQL field types of opaque values */
+	MYSQL_TYPE_TIMESTAMP  = 7
+	MYSQL_TYPE_DATE       = 10
+	MYSQL_TYPE_TIME       = 11
+	MYSQL_TYPE_DATETIME   = 12
+	MYSQL_TYPE_NEWDECIMAL = 246
+)
+
+/*
+* Convert a JSON value in the MySQL binary format to its text
+representation, formatted like the output of SELECT.
+@param[in]	data	binary JSON document
+@return JSON text
+*/
+func JSONBinaryToText(data []byte) (text string, err error) {
+	/* an empty value is a JSON null, written by e.g. INSTANT ADD COLUMN */
+	if len(data) == 0 {
+		return "null", nil
+	}
+	var sb strings.Builder
+	err = jsonbWriteValue(&sb, data[0], data[1:], 0)
+	if err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func jsonbWriteValue(sb *strings.Builder, valueType byte, data []byte, depth int) (err error) {
+	if depth > 100 {
+		return fmt.Errorf("JSON document is too deep")
+	}
+	switch valueType {
+	case JSONB_TYPE_SMALL_OBJECT, JSONB_TYPE_LARGE_OBJECT,
+		JSONB_TYPE_SMALL_ARRAY, JSONB_TYPE_LARGE_ARRAY:
+		return jsonbWriteContainer(sb, valueType, data, depth)
+	case JSONB_TYPE_LITERAL:
+		if len(data) < 1 {
+			return fmt.Errorf("JSON literal is truncated")
+		}
+		switch data[0] {
+		case JSONB_NULL_LITERAL:
+			sb.WriteString("null")
+		case JSONB_TRUE_LITERAL:
+			sb.WriteString("true")
+		case JSONB_FALSE_LITERAL:
+			sb.WriteString("false")
+		default:
+			return fmt.Errorf("unknown JSON literal %d", data[0])
+		}
+	case JSONB_TYPE_INT16, JSONB_TYPE_UINT16:
+		if len(data) < 2 {
+			return fmt.Errorf("JSON int16 is truncated")
+		}
+		if valueType == JSONB_TYPE_INT16 {
+			sb.WriteString(strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(data))), 10))
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(binary.LittleEndian.Uint16(data)), 10))
+		}
+	case JSONB_TYPE_INT32, JSONB_TYPE_UINT32:
+		if len(data) < 4 {
+			return fmt.Errorf("JSON int32 is truncated")
+		}
+		if valueType == JSONB_TYPE_INT32 {
+			sb.WriteString(strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(data))), 10))
+		} else {
+			sb.WriteString(strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10))
+		}
+	case JSONB_TYPE_INT64, JSONB_TYPE_UINT64:
+		if len(data) < 8 {
+			return fmt.Errorf("JSON int64 is truncated")
+		}
+		if valueType == JSONB_TYPE_INT64 {
+			sb.WriteString(strconv.FormatInt(int64(binary.LittleEndian.Uint64(data)), 10))
+		} else {
+			sb.WriteString(strconv.FormatUint(binary.LittleEndian.Uint64(data), 10))
+		}
+	case JSONB_TYPE_DOUBLE:
+		if len(data) < 8 {
+			return fmt.Errorf("JSON double is truncated")
+		}
+		f := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		sb.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	case JSONB_TYPE_STRING:
+		length, n, err := jsonbReadVariableLength(data)
+		if err != nil {
+			return err
+		}
+		if uint64(len(data)) < uint64(n)+length {
+			return fmt.Errorf("JSON string is truncated")
+		}
+		jsonWriteString(sb, string(data[n:uint64(n)+length]))
+	case JSONB_TYPE_OPAQUE:
+		if len(data) < 1 {
+			return fmt.Errorf("JSON opaque value is truncated")
+		}
+		fieldType := data[0]
+		length, n, err := jsonbReadVariableLength(data[1:])
+		if err != nil {
+			return err
+		}
+		if uint64(len(data)) < uint64(1+n)+length {
+			return fmt.Errorf("JSON opaque value is truncated")
+		}
+		return jsonbWriteOpaque(sb, fieldType, data[1+n:uint64(1+n)+length])
+	default:
+		return fmt.Errorf("unknown JSON value type %d", valueType)
+	}
+	return nil
+}
+
+func jsonbWriteContainer(sb *strings.Builder, valueType byte, data []byte, depth int) (err error) {
+	isLarge := valueType == JSONB_TYPE_LARGE_OBJECT || valueType == JSONB_TYPE_LARGE_ARRAY
+	isObject := valueType == JSONB_TYPE_SMALL_OBJECT || valueType == JSONB_TYPE_LARGE_OBJECT
+	offsetSize := 2
+	if isLarge {
+		offsetSize = 4
+	}
+	readOffset := func(pos int) (uint32, error) {
+		if pos+offsetSize > len(data) {
+			return 0, fmt.Errorf("JSON container is truncated")
+		}
+		if isLarge {
+			return binary.LittleEndian.Uint32(data[pos:]), nil
+		}
+		return uint32(binary.LittleEndian.Uint16(data[pos:])), nil
+	}
+	count, err := readOffset(0)
+	if err != nil {
+		return err
+	}
+	size, err := readOffset(offsetSize)
+	if err != nil {
+		return err
+	}
+	if uint32(len(data)) < size {
+		return fmt.Errorf("JSON container size %d exceeds data length %d", size, len(data))
+	}
+	keyEntrySize := offsetSize + 2
+	valueEntrySize := 1 + offsetSize
+	headerSize := 2 * offsetSize
+	if isObject {
+		headerSize += int(count) * keyEntrySize
+	}
+	headerSize += int(count) * valueEntrySize
+	if headerSize > int(size) {
+		return fmt.Errorf("JSON container header size %d exceeds size %d", headerSize, size)
+	}
+	if isObject {
+		sb.WriteString("{")
+	} else {
+		sb.WriteString("[")
+	}
+	for i := 0; i < int(count); i++ {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if isObject {
+			keyEntry := 2*offsetSize + i*keyEntrySize
+			keyOffset, err := readOffset(keyEntry)
+			if err != nil {
+				return err
+			}
+			keyLen := uint32(binary.LittleEndian.Uint16(data[keyEntry+offsetSize:]))
+			if keyOffset+keyLen > size {
+				return fmt.Errorf("JSON key exceeds container")
+			}
+			jsonWriteString(sb, string(data[keyOffset:keyOffset+keyLen]))
+			sb.WriteString(": ")
+		}
+		valueEntry := 2*offsetSize + i*valueEntrySize
+		if isObject {
+			valueEntry += int(count) * keyEntrySize
+		}
+		entryType := data[valueEntry]
+		if jsonbIsInlined(entryType, isLarge) {
+			err = jsonbWriteValue(sb, entryType, data[valueEntry+1:valueEntry+valueEntrySize], depth+1)
+		} else {
+			var valueOffset uint32
+			valueOffset, err = readOffset(valueEntry + 1)
+			if err != nil {
+				return err
+			}
+			if valueOffset >= size {
+				return fmt.Errorf("JSON value offset %d exceeds container", valueOffset)
+			}
+			err = jsonbWriteValue(sb, entryType, data[valueOffset:size], depth+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if isObject {
+		sb.WriteString("}")
+	} else {
+		sb.WriteString("]")
+	}
+	return nil
+}
+
+/*
+* Check if a value of the type is stored inline in the value entry
+ */
+func jsonbIsInlined(valueType byte, isLarge bool) bool {
+	switch valueType {
+	case JSONB_TYPE_LITERAL, JSONB_TYPE_INT16, JSONB_TYPE_UINT16:
+		return true
+	case JSONB_TYPE_INT32, JSONB_TYPE_UINT32:
+		return isLarge
+	}
+	return false
+}
+
+/*
+* Read a variable length integer, 7 bits in each byte with the highest bit
+set if more bytes follow.
+@return the value and the number of bytes read
+*/
+func jsonbReadVariableLength(data []byte) (length uint64, n int, err error) {
+	for n = 0; n < len(data) && n < 5; n++ {
+		length |= uint64(data[n]&0x7f) << (7 * n)
+		if data[n]&0x80 == 0 {
+			return length, n + 1, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid JSON variable length")
+}
+
+func jsonbWriteOpaque(sb *strings.Builder, fieldType byte, data []byte) (err error) {
+	switch fieldType {
+	case MYSQL_TYPE_NEWDECIMAL:
+		if len(data) < 2 {
+			return fmt.Errorf("JSON decimal is truncated")
+		}
+		precision, scale := uint32(data[0]), uint32(data[1])
+		value, err := DecodeDecimal(data[2:], precision, scale)
+		if err != nil {
+			return err
+		}
+		sb.WriteString(value)
+		return nil
+	case MYSQL_TYPE_DATE, MYSQL_TYPE_DATETIME, MYSQL_TYPE_TIMESTAMP, MYSQL_TYPE_TIME:
+		if len(data) < 8 {
+			return fmt.Errorf("JSON temporal value is truncated")
+		}
+		packed := int64(binary.LittleEndian.Uint64(data))
+		var value string
+		switch fieldType {
+		case MYSQL_TYPE_DATE:
+			value = unpackDatetime(packed, 0).Date.String()
+		case MYSQL_TYPE_TIME:
+			value = unpackTime(packed, 6).String()
+		default:
+			value = unpackDatetime(packed, 6).String()
+		}
+		jsonWriteString(sb, value)
+		return nil
+	}
+	jsonWriteString(sb, fmt.Sprintf("base64:type%d:%s", fieldType,
+		base64.StdEncoding.EncodeToString(data)))
+	return nil
+}
+
+/*
+* Write a JSON string literal with escaping
+ */
+func jsonWriteString(sb *strings.Builder, s string) {
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+}
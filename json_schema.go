@@ -0,0 +1,302 @@
+package ibd2schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+* Dialect of the generated JSON Schema documents
+ */
+const JSON_SCHEMA_DRAFT = "https://json-schema.org/draft/2020-12/schema"
+
+/*
+* JSON Schema of a row or a column value. Values are those of a change event,
+e.g. of Debezium with decimal.handling.mode=string: decimals, dates and times
+are strings and binary values are base64 strings.
+*/
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	/** a type name or a list of type names */
+	Type                 interface{}          `json:"type,omitempty"`
+	Properties           JSONSchemaProperties `json:"properties,omitempty"`
+	Required             []string             `json:"required,omitempty"`
+	AdditionalProperties *bool                `json:"additionalProperties,omitempty"`
+	Enum                 []interface{}        `json:"enum,omitempty"`
+	Minimum              json.Number          `json:"minimum,omitempty"`
+	Maximum              json.Number          `json:"maximum,omitempty"`
+	MaxLength            uint64               `json:"maxLength,omitempty"`
+	Pattern              string               `json:"pattern,omitempty"`
+	Format               string               `json:"format,omitempty"`
+	ContentEncoding      string               `json:"contentEncoding,omitempty"`
+	ContentMediaType     string               `json:"contentMediaType,omitempty"`
+	/** JSON of the default value, empty if none */
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+/*
+* Property of an object schema
+ */
+type JSONSchemaProperty struct {
+	Name   string
+	Schema *JSONSchema
+}
+
+/*
+* Properties of an object schema in column order
+ */
+type JSONSchemaProperties []*JSONSchemaProperty
+
+/*
+* Marshal the properties as a JSON object that keeps the column order
+ */
+func (p JSONSchemaProperties) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for n, property := range p {
+		if n != 0 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(property.Name)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := json.Marshal(property.Schema)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(schema)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+/*
+* Get the constant default value of a column
+@return default value as in default_value_utf8 and true, or false if the
+column has no default, a NULL default or an expression default
+*/
+func (c *Column) getLiteralDefault() (string, bool) {
+	if c.GenerationExpressionUTF8 != "" || c.DefaultValueUTF8Null || c.IsAutoIncrement ||
+		c.DefaultOption != "" || strings.HasPrefix(c.DefaultValueUTF8, "0000-00-00") {
+		return "", false
+	}
+	return c.DefaultValueUTF8, true
+}
+
+/*
+* Get the range of an integer column
+@return minimum and maximum
+*/
+func (c *Column) getIntegerRange() (json.Number, json.Number) {
+	var bits uint
+	switch c.Type {
+	case CT_TINY:
+		bits = 8
+	case CT_SHORT:
+		bits = 16
+	case CT_INT24:
+		bits = 24
+	case CT_LONG:
+		bits = 32
+	default:
+		if c.IsUnsigned {
+			return "0", "18446744073709551615"
+		}
+		return "-9223372036854775808", "9223372036854775807"
+	}
+	if c.IsUnsigned {
+		return "0", json.Number(fmt.Sprint(uint64(1)<<bits - 1))
+	}
+	return json.Number(fmt.Sprint(-int64(1) << (bits - 1))), json.Number(fmt.Sprint(int64(1)<<(bits-1) - 1))
+}
+
+/*
+* Get the pattern of the values of a decimal column, e.g. ^-?\d{1,8}(\.\d{1,2})?$
+ */
+func (c *Column) getDecimalPattern() string {
+	sign := "-?"
+	if c.IsUnsigned {
+		sign = ""
+	}
+	integer := fmt.Sprintf(`\d{1,%d}`, c.NumericPrecision-c.NumericScale)
+	if c.NumericPrecision == c.NumericScale {
+		integer = "0"
+	}
+	if c.NumericScale == 0 {
+		return fmt.Sprintf("^%s%s$", sign, integer)
+	}
+	return fmt.Sprintf(`^%s%s(\.\d{1,%d})?$`, sign, integer, c.NumericScale)
+}
+
+/*
+* Get the JSON Schema of the values of a column, without nullability
+ */
+func (c *Column) getJSONSchemaType() *JSONSchema {
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG:
+		minimum, maximum := c.getIntegerRange()
+		return &JSONSchema{Type: "integer", Minimum: minimum, Maximum: maximum}
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		return &JSONSchema{Type: "string", Pattern: c.getDecimalPattern()}
+	case CT_FLOAT, CT_DOUBLE:
+		schema := &JSONSchema{Type: "number"}
+		if c.IsUnsigned {
+			schema.Minimum = "0"
+		}
+		return schema
+	case CT_BIT:
+		if c.NumericPrecision == 1 {
+			return &JSONSchema{Type: "boolean"}
+		}
+		return &JSONSchema{Type: "string", ContentEncoding: "base64"}
+	case CT_YEAR:
+		return &JSONSchema{Type: "integer", Minimum: "0", Maximum: "2155"}
+	case CT_DATE, CT_NEWDATE:
+		return &JSONSchema{Type: "string", Format: "date"}
+	case CT_TIME, CT_TIME2:
+		return &JSONSchema{Type: "string", Pattern: `^-?\d{2,3}:\d{2}:\d{2}(\.\d{1,6})?$`}
+	case CT_DATETIME, CT_DATETIME2:
+		return &JSONSchema{Type: "string", Pattern: `^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d{1,6})?$`}
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING:
+		if !c.isText() {
+			return &JSONSchema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &JSONSchema{Type: "string", MaxLength: c.getCharLength()}
+	case CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return &JSONSchema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &JSONSchema{Type: "string"}
+	case CT_ENUM:
+		values := make([]interface{}, len(c.Elements))
+		for n, element := range c.Elements {
+			values[n] = element
+		}
+		return &JSONSchema{Enum: values}
+	case CT_JSON:
+		return &JSONSchema{Type: "string", ContentMediaType: "application/json"}
+	case CT_GEOMETRY:
+		additional := false
+		return &JSONSchema{
+			Type: "object",
+			Properties: JSONSchemaProperties{
+				{Name: "wkb", Schema: &JSONSchema{Type: "string", ContentEncoding: "base64"}},
+				{Name: "srid", Schema: &JSONSchema{Type: []string{"integer", "null"}}},
+			},
+			Required:             []string{"wkb"},
+			AdditionalProperties: &additional,
+		}
+	}
+	return &JSONSchema{Type: "string"}
+}
+
+/*
+* Get the JSON of the default value of a column for its JSON Schema
+@return JSON, nil if the column has no constant default
+*/
+func (c *Column) getJSONSchemaDefault() json.RawMessage {
+	if c.DefaultValueNull && c.DefaultValueUTF8Null && c.IsNullable {
+		return json.RawMessage("null")
+	}
+	value, ok := c.getLiteralDefault()
+	if !ok {
+		return nil
+	}
+	var data []byte
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG, CT_FLOAT, CT_DOUBLE, CT_YEAR:
+		if json.Valid([]byte(value)) {
+			return json.RawMessage(value)
+		}
+		return nil
+	case CT_BIT:
+		bits, ok := parseBitLiteral(value)
+		if !ok {
+			return nil
+		}
+		if c.NumericPrecision == 1 {
+			return json.RawMessage(fmt.Sprint(bits != 0))
+		}
+		data, _ = json.Marshal(getBitBytes(bits, c.NumericPrecision))
+		return data
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			data, _ = json.Marshal([]byte(value))
+			return data
+		}
+	case CT_GEOMETRY, CT_JSON:
+		return nil
+	}
+	data, _ = json.Marshal(value)
+	return data
+}
+
+/*
+* Generate the JSON Schema of the rows of a table: an object with a property
+for each column in column order, NOT NULL columns are required.
+@param[in]	table	table schema
+@return JSON Schema document
+*/
+func GenerateJSONSchema(table *TableSchema) *JSONSchema {
+	additional := false
+	schema := &JSONSchema{
+		Schema:               JSON_SCHEMA_DRAFT,
+		ID:                   fmt.Sprintf("urn:mysql:%s:%s", table.SchemaName, table.Name),
+		Title:                fmt.Sprintf("%s.%s", table.SchemaName, table.Name),
+		Description:          table.Comment,
+		Type:                 "object",
+		Properties:           make(JSONSchemaProperties, 0),
+		Required:             make([]string, 0),
+		AdditionalProperties: &additional,
+	}
+	for _, column := range table.GetUserColumns() {
+		property := column.getJSONSchemaType()
+		property.Description = column.Comment
+		if column.IsNullable {
+			switch t := property.Type.(type) {
+			case string:
+				property.Type = []string{t, "null"}
+			case nil:
+				property.Enum = append(property.Enum, nil)
+			}
+		} else {
+			schema.Required = append(schema.Required, column.Name)
+		}
+		property.Default = column.getJSONSchemaDefault()
+		schema.Properties = append(schema.Properties, &JSONSchemaProperty{Name: column.Name, Schema: property})
+	}
+	return schema
+}
+
+/*
+* Renderer of a JSON array of the JSON Schema documents of the tables
+ */
+type JSONSchemaRenderer struct{}
+
+func (r *JSONSchemaRenderer) Name() string {
+	return "jsonschema"
+}
+
+func (r *JSONSchemaRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	schemas := make([]*JSONSchema, len(tables))
+	for n, table := range tables {
+		schemas[n] = GenerateJSONSchema(table)
+	}
+	data, err := json.MarshalIndent(schemas, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
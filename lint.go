@@ -0,0 +1,582 @@
+package ibd2schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+* Level of a lint finding, the values are the SARIF result levels
+ */
+type LintLevel string
+
+const (
+	LINT_ERROR   LintLevel = "error"
+	LINT_WARNING LintLevel = "warning"
+	LINT_NOTE    LintLevel = "note"
+)
+
+/*
+* Kind of the object a finding is about, the values are the SARIF logical
+location kinds
+*/
+type LintObjectKind string
+
+const (
+	LINT_TABLE       LintObjectKind = "table"
+	LINT_COLUMN      LintObjectKind = "column"
+	LINT_INDEX       LintObjectKind = "index"
+	LINT_FOREIGN_KEY LintObjectKind = "foreignKey"
+)
+
+const LINT_SARIF_SCHEMA = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type LintFinding struct {
+	RuleID string    `json:"rule_id"`
+	Level  LintLevel `json:"level"`
+	/** file the table was read from */
+	Source     string         `json:"source"`
+	SchemaName string         `json:"schema"`
+	TableName  string         `json:"table"`
+	ObjectKind LintObjectKind `json:"object_kind"`
+	/** name of the column, index or foreign key, empty for the table */
+	ObjectName string `json:"object_name,omitempty"`
+	Message    string `json:"message"`
+}
+
+/*
+* Get the fully qualified name of the object, e.g. "db.t.a"
+ */
+func (f *LintFinding) GetFullyQualifiedName() string {
+	name := f.SchemaName + "." + f.TableName
+	if f.ObjectName != "" {
+		name += "." + f.ObjectName
+	}
+	return name
+}
+
+/*
+* Rule of the linter. A rule checks one table and returns its findings, the
+linter fills in the rule id, source and table name of the findings.
+*/
+type LintRule interface {
+	/** unique id of the rule, e.g. "no-primary-key" */
+	ID() string
+	/** one sentence describing what the rule checks */
+	Description() string
+	/** level of the findings unless a finding sets its own */
+	DefaultLevel() LintLevel
+	Check(table *TableSchema) []*LintFinding
+}
+
+/*
+* Get the built-in rules with their default settings
+ */
+func GetDefaultLintRules() []LintRule {
+	return []LintRule{
+		&NoPrimaryKeyRule{},
+		&Utf8mb3Rule{},
+		&ForeignKeyIndexRule{},
+		&RedundantIndexRule{},
+		NewFloatMoneyRule(),
+		&Timestamp2038Rule{},
+		NewLongIndexColumnRule(),
+	}
+}
+
+type Linter struct {
+	Rules    []LintRule
+	Findings []*LintFinding
+	/** number of tables checked */
+	Tables int
+}
+
+/*
+* Create a linter
+@param[in]	rules	rules to check, default GetDefaultLintRules
+*/
+func NewLinter(rules ...LintRule) *Linter {
+	if len(rules) == 0 {
+		rules = GetDefaultLintRules()
+	}
+	return &Linter{
+		Rules:    rules,
+		Findings: make([]*LintFinding, 0),
+	}
+}
+
+/*
+* Remove rules from the linter
+@param[in]	ids	ids of the rules to remove
+@return error if a rule is unknown
+*/
+func (l *Linter) Disable(ids ...string) error {
+	disabled := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		disabled[id] = true
+	}
+	rules := make([]LintRule, 0, len(l.Rules))
+	for _, rule := range l.Rules {
+		if disabled[rule.ID()] {
+			delete(disabled, rule.ID())
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	for id := range disabled {
+		return fmt.Errorf("unknown lint rule %s", id)
+	}
+	l.Rules = rules
+	return nil
+}
+
+/*
+* Check the tables of a source with all rules and add the findings
+@param[in]	source	file the tables were read from
+@param[in]	tables	tables to check
+@return findings of the tables
+*/
+func (l *Linter) Lint(source string, tables []*TableSchema) []*LintFinding {
+	findings := make([]*LintFinding, 0)
+	for _, table := range tables {
+		for _, rule := range l.Rules {
+			for _, finding := range rule.Check(table) {
+				finding.RuleID = rule.ID()
+				if finding.Level == "" {
+					finding.Level = rule.DefaultLevel()
+				}
+				finding.Source = source
+				finding.SchemaName = table.SchemaName
+				finding.TableName = table.Name
+				findings = append(findings, finding)
+			}
+		}
+	}
+	l.Findings = append(l.Findings, findings...)
+	l.Tables += len(tables)
+	return findings
+}
+
+/*
+* Count the findings at a level
+ */
+func (l *Linter) CountFindings(level LintLevel) (n int) {
+	for _, finding := range l.Findings {
+		if finding.Level == level {
+			n++
+		}
+	}
+	return n
+}
+
+/*
+* Get the findings as text, one line per finding
+ */
+func (l *Linter) String() string {
+	var b strings.Builder
+	for _, f := range l.Findings {
+		fmt.Fprintf(&b, "%s: %s `%s`.`%s`", f.Source, f.Level, f.SchemaName, f.TableName)
+		if f.ObjectKind == LINT_FOREIGN_KEY {
+			fmt.Fprintf(&b, " foreign key `%s`", f.ObjectName)
+		} else if f.ObjectName != "" {
+			fmt.Fprintf(&b, " %s `%s`", f.ObjectKind, f.ObjectName)
+		}
+		fmt.Fprintf(&b, ": %s [%s]\n", f.Message, f.RuleID)
+	}
+	fmt.Fprintf(&b, "%d tables, %d errors, %d warnings, %d notes\n", l.Tables,
+		l.CountFindings(LINT_ERROR), l.CountFindings(LINT_WARNING), l.CountFindings(LINT_NOTE))
+	return b.String()
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	DefaultConfiguration struct {
+		Level LintLevel `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string         `json:"fullyQualifiedName"`
+	Kind               LintObjectKind `json:"kind"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	LogicalLocations []*sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifResult struct {
+	RuleID    string           `json:"ruleId"`
+	RuleIndex int              `json:"ruleIndex"`
+	Level     LintLevel        `json:"level"`
+	Message   sarifMessage     `json:"message"`
+	Locations []*sarifLocation `json:"locations"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string       `json:"name"`
+			InformationURI string       `json:"informationUri"`
+			Rules          []*sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+/*
+* Dump the findings as a SARIF 2.1.0 log with one run
+ */
+func (l *Linter) DumpSarif() ([]byte, error) {
+	run := &sarifRun{Results: make([]*sarifResult, 0, len(l.Findings))}
+	run.Tool.Driver.Name = "ibd2schema"
+	run.Tool.Driver.InformationURI = "https://github.com/zing22845/go-ibd2schema"
+	run.Tool.Driver.Rules = make([]*sarifRule, 0, len(l.Rules))
+	ruleIndexes := make(map[string]int, len(l.Rules))
+	for n, rule := range l.Rules {
+		r := &sarifRule{
+			ID:               rule.ID(),
+			ShortDescription: sarifMessage{Text: rule.Description()},
+		}
+		r.DefaultConfiguration.Level = rule.DefaultLevel()
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, r)
+		ruleIndexes[rule.ID()] = n
+	}
+	for _, f := range l.Findings {
+		location := &sarifLocation{
+			LogicalLocations: []*sarifLogicalLocation{{
+				FullyQualifiedName: f.GetFullyQualifiedName(),
+				Kind:               f.ObjectKind,
+			}},
+		}
+		location.PhysicalLocation.ArtifactLocation.URI = f.Source
+		run.Results = append(run.Results, &sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: ruleIndexes[f.RuleID],
+			Level:     f.Level,
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []*sarifLocation{location},
+		})
+	}
+	return json.MarshalIndent(&sarifLog{
+		Schema:  LINT_SARIF_SCHEMA,
+		Version: "2.1.0",
+		Runs:    []*sarifRun{run},
+	}, "", "  ")
+}
+
+func (l *Linter) DumpJson() ([]byte, error) {
+	return json.MarshalIndent(l.Findings, "", "  ")
+}
+
+/*
+* Get the primary key of a table, nil if the table has none
+ */
+func (ts *TableSchema) GetPrimaryKey() *Index {
+	for _, index := range ts.GetUserIndexes() {
+		if index.Type == IT_PRIMARY {
+			return index
+		}
+	}
+	return nil
+}
+
+/*
+* Check if the primary key is a generated invisible primary key
+ */
+func (i *Index) isGipk() bool {
+	return i.Type == IT_PRIMARY && len(i.KeyParts) == 1 && i.KeyParts[0].Column.isGipk()
+}
+
+/*
+* Tables without a primary key, InnoDB then clusters the rows by the hidden
+DB_ROW_ID, and tables with only a generated invisible primary key.
+*/
+type NoPrimaryKeyRule struct {
+	/** do not report generated invisible primary keys */
+	AllowGipk bool
+}
+
+func (r *NoPrimaryKeyRule) ID() string {
+	return "no-primary-key"
+}
+
+func (r *NoPrimaryKeyRule) Description() string {
+	return "Tables should have an explicit primary key"
+}
+
+func (r *NoPrimaryKeyRule) DefaultLevel() LintLevel {
+	return LINT_ERROR
+}
+
+func (r *NoPrimaryKeyRule) Check(table *TableSchema) []*LintFinding {
+	primaryKey := table.GetPrimaryKey()
+	if primaryKey == nil {
+		return []*LintFinding{{
+			ObjectKind: LINT_TABLE,
+			Message:    "table has no primary key",
+		}}
+	}
+	if primaryKey.isGipk() && !r.AllowGipk {
+		return []*LintFinding{{
+			Level:      LINT_WARNING,
+			ObjectKind: LINT_TABLE,
+			Message: fmt.Sprintf("table has only the generated invisible primary key `%s`",
+				primaryKey.KeyParts[0].Column.Name),
+		}}
+	}
+	return nil
+}
+
+/*
+* Columns and table defaults using the deprecated utf8mb3 character set
+ */
+type Utf8mb3Rule struct{}
+
+func (r *Utf8mb3Rule) ID() string {
+	return "utf8mb3-charset"
+}
+
+func (r *Utf8mb3Rule) Description() string {
+	return "The utf8mb3 character set is deprecated and cannot store 4-byte characters, use utf8mb4"
+}
+
+func (r *Utf8mb3Rule) DefaultLevel() LintLevel {
+	return LINT_WARNING
+}
+
+func (r *Utf8mb3Rule) Check(table *TableSchema) (findings []*LintFinding) {
+	if table.Collation != nil && table.Collation.CharsetName == "utf8mb3" {
+		findings = append(findings, &LintFinding{
+			ObjectKind: LINT_TABLE,
+			Message:    fmt.Sprintf("table default collation %s uses utf8mb3", table.Collation.Name),
+		})
+	}
+	for _, column := range table.GetUserColumns() {
+		if column.hasCharset() && column.Collation != nil && column.Collation.CharsetName == "utf8mb3" {
+			findings = append(findings, &LintFinding{
+				ObjectKind: LINT_COLUMN,
+				ObjectName: column.Name,
+				Message:    fmt.Sprintf("column collation %s uses utf8mb3", column.Collation.Name),
+			})
+		}
+	}
+	return findings
+}
+
+/*
+* Foreign keys whose columns are not the leftmost columns of an index, which
+makes the checks of the referencing rows scan the table.
+*/
+type ForeignKeyIndexRule struct{}
+
+func (r *ForeignKeyIndexRule) ID() string {
+	return "fk-without-index"
+}
+
+func (r *ForeignKeyIndexRule) Description() string {
+	return "The columns of a foreign key should be the leftmost columns of an index"
+}
+
+func (r *ForeignKeyIndexRule) DefaultLevel() LintLevel {
+	return LINT_ERROR
+}
+
+func (r *ForeignKeyIndexRule) Check(table *TableSchema) (findings []*LintFinding) {
+	indexes := table.GetUserIndexes()
+	for _, fk := range table.ForeignKeys {
+		supported := false
+		for _, index := range indexes {
+			if index.Type != IT_FULLTEXT && index.Type != IT_SPATIAL && index.hasLeftmostColumns(fk.ColumnNames) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			findings = append(findings, &LintFinding{
+				ObjectKind: LINT_FOREIGN_KEY,
+				ObjectName: fk.Name,
+				Message:    fmt.Sprintf("no index starts with the columns %s", quoteNames(fk.ColumnNames)),
+			})
+		}
+	}
+	return findings
+}
+
+/*
+* Duplicate and redundant indexes, see FindRedundantIndexes
+ */
+type RedundantIndexRule struct{}
+
+func (r *RedundantIndexRule) ID() string {
+	return "redundant-index"
+}
+
+func (r *RedundantIndexRule) Description() string {
+	return "Indexes should not duplicate or be a leftmost prefix of another index"
+}
+
+func (r *RedundantIndexRule) DefaultLevel() LintLevel {
+	return LINT_WARNING
+}
+
+func (r *RedundantIndexRule) Check(table *TableSchema) (findings []*LintFinding) {
+	for _, redundant := range FindRedundantIndexes(table) {
+		findings = append(findings, &LintFinding{
+			ObjectKind: LINT_INDEX,
+			ObjectName: redundant.IndexName,
+			Message:    redundant.getReason(),
+		})
+	}
+	return findings
+}
+
+/*
+* Approximate FLOAT and DOUBLE columns whose names suggest amounts of money
+ */
+type FloatMoneyRule struct {
+	/** lower case substrings of the column names */
+	NamePatterns []string
+}
+
+func NewFloatMoneyRule() *FloatMoneyRule {
+	return &FloatMoneyRule{
+		NamePatterns: []string{"price", "amount", "cost", "balance", "money", "salary", "fee",
+			"payment", "total", "tax", "discount", "revenue", "credit", "debit"},
+	}
+}
+
+func (r *FloatMoneyRule) ID() string {
+	return "float-money"
+}
+
+func (r *FloatMoneyRule) Description() string {
+	return "Amounts of money should be stored exactly in DECIMAL columns, not in FLOAT or DOUBLE"
+}
+
+func (r *FloatMoneyRule) DefaultLevel() LintLevel {
+	return LINT_WARNING
+}
+
+func (r *FloatMoneyRule) Check(table *TableSchema) (findings []*LintFinding) {
+	for _, column := range table.GetUserColumns() {
+		if column.Type != CT_FLOAT && column.Type != CT_DOUBLE {
+			continue
+		}
+		name := strings.ToLower(column.Name)
+		for _, pattern := range r.NamePatterns {
+			if strings.Contains(name, pattern) {
+				findings = append(findings, &LintFinding{
+					ObjectKind: LINT_COLUMN,
+					ObjectName: column.Name,
+					Message: fmt.Sprintf("%s column looks like an amount of money, use DECIMAL",
+						column.ColumnTypeUTF8),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+/*
+* TIMESTAMP columns, which cannot store times after 2038-01-19 03:14:07 UTC
+ */
+type Timestamp2038Rule struct{}
+
+func (r *Timestamp2038Rule) ID() string {
+	return "timestamp-2038"
+}
+
+func (r *Timestamp2038Rule) Description() string {
+	return "TIMESTAMP columns cannot store times after 2038-01-19 03:14:07 UTC, use DATETIME"
+}
+
+func (r *Timestamp2038Rule) DefaultLevel() LintLevel {
+	return LINT_NOTE
+}
+
+func (r *Timestamp2038Rule) Check(table *TableSchema) (findings []*LintFinding) {
+	for _, column := range table.GetUserColumns() {
+		if column.Type == CT_TIMESTAMP || column.Type == CT_TIMESTAMP2 {
+			findings = append(findings, &LintFinding{
+				ObjectKind: LINT_COLUMN,
+				ObjectName: column.Name,
+				Message:    "TIMESTAMP column overflows after 2038-01-19 03:14:07 UTC",
+			})
+		}
+	}
+	return findings
+}
+
+/*
+* Whole VARCHAR and CHAR columns in indexes whose key parts are longer than
+a limit, 767 bytes by default which is the limit of the REDUNDANT and
+COMPACT row formats.
+*/
+type LongIndexColumnRule struct {
+	MaxBytes uint64
+}
+
+func NewLongIndexColumnRule() *LongIndexColumnRule {
+	return &LongIndexColumnRule{MaxBytes: 767}
+}
+
+func (r *LongIndexColumnRule) ID() string {
+	return "long-index-column"
+}
+
+func (r *LongIndexColumnRule) Description() string {
+	return "Long VARCHAR and CHAR key parts make large indexes, index a column prefix instead"
+}
+
+func (r *LongIndexColumnRule) DefaultLevel() LintLevel {
+	return LINT_WARNING
+}
+
+func (r *LongIndexColumnRule) Check(table *TableSchema) (findings []*LintFinding) {
+	for _, index := range table.GetUserIndexes() {
+		if index.Type == IT_FULLTEXT || index.Type == IT_SPATIAL {
+			continue
+		}
+		long := make([]string, 0)
+		for _, keyPart := range index.KeyParts {
+			column := keyPart.Column
+			if column.Type != CT_VARCHAR && column.Type != CT_STRING && column.Type != CT_VAR_STRING {
+				continue
+			}
+			size := column.Size
+			if keyPart.Length != 0 && column.Collation != nil {
+				size = uint64(keyPart.Length) * uint64(column.Collation.Maxlen)
+			}
+			if size > r.MaxBytes {
+				long = append(long, fmt.Sprintf("`%s` (%d bytes)", column.Name, size))
+			}
+		}
+		if len(long) != 0 {
+			findings = append(findings, &LintFinding{
+				ObjectKind: LINT_INDEX,
+				ObjectName: index.Name,
+				Message: fmt.Sprintf("key parts longer than %d bytes: %s", r.MaxBytes,
+					strings.Join(long, ", ")),
+			})
+		}
+	}
+	return findings
+}
@@ -1,12 +1,182 @@
 package ibd2schema
 
+import (
+	"encoding/binary"
+	"fmt"
+)
+
 const (
 	/** 8 bytes containing the length of the externally stored part of the LOB.
 	  The 2 highest bits are reserved to the flags below. */
 	BTR_EXTERN_LEN = 12
 	/** page number where stored */
 	BTR_EXTERN_PAGE_NO = 4
-	LOB_HDR_PART_LEN   = 0
-	LOB_HDR_SIZE       = 10
-	LOB_PAGE_DATA = FIL_PAGE_DATA + LOB_HDR_SIZE
+	/** space id where stored */
+	BTR_EXTERN_SPACE_ID = 0
+	/** The reference in a field for which data is stored on a different page. */
+	BTR_EXTERN_FIELD_REF_SIZE = 20
+	LOB_HDR_PART_LEN          = 0
+	LOB_HDR_SIZE              = 10
+	LOB_PAGE_DATA             = FIL_PAGE_DATA + LOB_HDR_SIZE
+	/** part length of an uncompressed BLOB page in the old format */
+	BTR_BLOB_HDR_PART_LEN = 0
+	/** next page number of an uncompressed BLOB page in the old format */
+	BTR_BLOB_HDR_NEXT_PAGE_NO = 4
+	/** size of the header of an uncompressed BLOB page in the old format */
+	BTR_BLOB_HDR_SIZE = 8
+	/** length of data stored in the first page of a LOB */
+	LOB_FIRST_OFFSET_DATA_LEN = FIL_PAGE_DATA + 16
+	/** list of index entries of a LOB */
+	LOB_FIRST_OFFSET_INDEX_LIST = FIL_PAGE_DATA + 26
+	/** start of the index entries on the first page of a LOB */
+	LOB_FIRST_PAGE_DATA = LOB_FIRST_OFFSET_INDEX_LIST + 2*FLST_BASE_NODE_SIZE
+	/** length of data stored in a data page of a LOB */
+	LOB_DATA_OFFSET_DATA_LEN = FIL_PAGE_DATA + 1
+	/** start of the data on a data page of a LOB */
+	LOB_DATA_PAGE_DATA = LOB_DATA_OFFSET_DATA_LEN + 4 + 6
+	/** list node of an index entry of a LOB */
+	LOB_INDEX_ENTRY_OFFSET_NODE = 0
+	/** page number of an index entry of a LOB */
+	LOB_INDEX_ENTRY_OFFSET_PAGE_NO = 48
+	/** data length of an index entry of a LOB */
+	LOB_INDEX_ENTRY_OFFSET_DATA_LEN = 52
+	/** size of an index entry of a LOB */
+	LOB_INDEX_ENTRY_SIZE = 60
 )
+
+/*
+* Number of index entries on the first page of a LOB
+@param[in]	pageSize	page size
+@return number of index entries
+*/
+func LobFirstPageNIndexEntries(pageSize *PageSize) uint32 {
+	switch pageSize.Physical {
+	case 4 * KiB:
+		return 1
+	case 8 * KiB:
+		return 5
+	case 32 * KiB:
+		return 20
+	case 64 * KiB:
+		return 40
+	}
+	return 10
+}
+
+/*
+* Read the externally stored part of a field.
+@param[in]	ref	the 20 bytes field reference at the end of the field
+@return externally stored data
+*/
+func (ts *TableSpace) FetchExternData(ref []byte) (data []byte, err error) {
+	if len(ref) != BTR_EXTERN_FIELD_REF_SIZE {
+		return nil, fmt.Errorf("invalid extern field reference length %d", len(ref))
+	}
+	spaceID := binary.BigEndian.Uint32(ref[BTR_EXTERN_SPACE_ID:])
+	if spaceID != ts.SpaceID {
+		return nil, fmt.Errorf("extern field space id %d != tablespace id %d", spaceID, ts.SpaceID)
+	}
+	pageNum := binary.BigEndian.Uint32(ref[BTR_EXTERN_PAGE_NO:])
+	/* the 2 highest bits of length are flags, and the high 4 bytes are
+	always 0 */
+	length := binary.BigEndian.Uint32(ref[BTR_EXTERN_LEN+4:])
+	page, err := ts.FetchPage(pageNum)
+	if err != nil {
+		return nil, fmt.Errorf("fetch LOB page failed, err:%v", err)
+	}
+	page.GetPageType()
+	switch page.PageType {
+	case FIL_PAGE_TYPE_LOB_FIRST:
+		data, err = ts.readLob(page, length)
+	case FIL_PAGE_TYPE_BLOB:
+		data, err = ts.readBlobChain(page, length)
+	default:
+		return nil, fmt.Errorf("LOB page type %d of page %d is not supported", page.PageType, pageNum)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if uint32(len(data)) != length {
+		return nil, fmt.Errorf("LOB length %d != expected %d", len(data), length)
+	}
+	return data, nil
+}
+
+/*
+* Read a LOB stored in the format since MySQL 8.0, by following the index
+entries of the first page.
+*/
+func (ts *TableSpace) readLob(firstPage *Page, length uint32) (data []byte, err error) {
+	data = make([]byte, 0, length)
+	base := NewFlstBaseNode(firstPage.OriginData[LOB_FIRST_OFFSET_INDEX_LIST:])
+	addr := base.First
+	for n := uint32(0); !addr.IsNull(); n++ {
+		if n >= base.Len {
+			return nil, fmt.Errorf("LOB index list is longer than its length %d", base.Len)
+		}
+		page, err := ts.FetchPage(addr.PageNum)
+		if err != nil {
+			return nil, fmt.Errorf("fetch LOB index page failed, err:%v", err)
+		}
+		if int(addr.Boffset)+LOB_INDEX_ENTRY_SIZE > len(page.OriginData) {
+			return nil, fmt.Errorf("invalid LOB index entry offset %d", addr.Boffset)
+		}
+		entry := page.OriginData[addr.Boffset:]
+		entryPageNum := binary.BigEndian.Uint32(entry[LOB_INDEX_ENTRY_OFFSET_PAGE_NO:])
+		entryDataLen := binary.BigEndian.Uint32(entry[LOB_INDEX_ENTRY_OFFSET_DATA_LEN:])
+		var part []byte
+		if entryPageNum == firstPage.PageNum {
+			start := LOB_FIRST_PAGE_DATA + LobFirstPageNIndexEntries(ts.PageSize)*LOB_INDEX_ENTRY_SIZE
+			part = firstPage.OriginData[start:]
+		} else {
+			dataPage, err := ts.FetchPage(entryPageNum)
+			if err != nil {
+				return nil, fmt.Errorf("fetch LOB data page failed, err:%v", err)
+			}
+			dataPage.GetPageType()
+			if dataPage.PageType != FIL_PAGE_TYPE_LOB_DATA {
+				return nil, fmt.Errorf("page %d type %d is not FIL_PAGE_TYPE_LOB_DATA",
+					entryPageNum, dataPage.PageType)
+			}
+			part = dataPage.OriginData[LOB_DATA_PAGE_DATA:]
+		}
+		if uint32(len(part)) < entryDataLen {
+			return nil, fmt.Errorf("LOB part length %d exceeds page", entryDataLen)
+		}
+		data = append(data, part[:entryDataLen]...)
+		addr = NewFlstNode(entry[LOB_INDEX_ENTRY_OFFSET_NODE:]).Next
+	}
+	return data, nil
+}
+
+/*
+* Read a BLOB stored as a chain of FIL_PAGE_TYPE_BLOB pages, the format
+before MySQL 8.0.
+*/
+func (ts *TableSpace) readBlobChain(page *Page, length uint32) (data []byte, err error) {
+	data = make([]byte, 0, length)
+	for {
+		partLen := binary.BigEndian.Uint32(page.OriginData[FIL_PAGE_DATA+BTR_BLOB_HDR_PART_LEN:])
+		start := uint32(FIL_PAGE_DATA + BTR_BLOB_HDR_SIZE)
+		if start+partLen > uint32(len(page.OriginData)) {
+			return nil, fmt.Errorf("BLOB part length %d exceeds page", partLen)
+		}
+		data = append(data, page.OriginData[start:start+partLen]...)
+		nextPageNum := binary.BigEndian.Uint32(page.OriginData[FIL_PAGE_DATA+BTR_BLOB_HDR_NEXT_PAGE_NO:])
+		if nextPageNum == FIL_NULL {
+			return data, nil
+		}
+		if uint32(len(data)) >= length {
+			return nil, fmt.Errorf("BLOB chain is longer than %d", length)
+		}
+		page, err = ts.FetchPage(nextPageNum)
+		if err != nil {
+			return nil, fmt.Errorf("fetch BLOB page failed, err:%v", err)
+		}
+		page.GetPageType()
+		if page.PageType != FIL_PAGE_TYPE_BLOB {
+			return nil, fmt.Errorf("page %d type %d is not FIL_PAGE_TYPE_BLOB",
+				nextPageNum, page.PageType)
+		}
+	}
+}
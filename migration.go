@@ -0,0 +1,904 @@
+package ibd2schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+/*
+* ALTER TABLE algorithm, ordered from the fastest to the slowest
+ */
+type AlterAlgorithm string
+
+const (
+	/** no ALTER TABLE, e.g. CREATE, DROP or RENAME TABLE */
+	ALTER_ALGORITHM_NONE    AlterAlgorithm = ""
+	ALTER_ALGORITHM_INSTANT AlterAlgorithm = "INSTANT"
+	ALTER_ALGORITHM_INPLACE AlterAlgorithm = "INPLACE"
+	ALTER_ALGORITHM_COPY    AlterAlgorithm = "COPY"
+)
+
+func (a AlterAlgorithm) rank() int {
+	switch a {
+	case ALTER_ALGORITHM_INSTANT:
+		return 1
+	case ALTER_ALGORITHM_INPLACE:
+		return 2
+	case ALTER_ALGORITHM_COPY:
+		return 3
+	}
+	return 0
+}
+
+/*
+* Get the slower of two algorithms
+ */
+func slowerAlgorithm(a, b AlterAlgorithm) AlterAlgorithm {
+	if b.rank() > a.rank() {
+		return b
+	}
+	return a
+}
+
+const (
+	/** first version that supports ALGORITHM=INSTANT */
+	MYSQL_VERSION_INSTANT = 80012
+	/** first version that supports RENAME COLUMN with ALGORITHM=INSTANT */
+	MYSQL_VERSION_INSTANT_RENAME_COLUMN = 80028
+	/** first version that adds and drops columns at any position instantly */
+	MYSQL_VERSION_INSTANT_ANY_POSITION = 80029
+)
+
+type MigrationOptions struct {
+	/** server version that runs the statements, e.g. 80034, 0 for the newer
+	mysql_version_id of the two tables */
+	ServerVersion uint32
+}
+
+func NewMigrationOptions() *MigrationOptions {
+	return &MigrationOptions{}
+}
+
+type MigrationStatement struct {
+	SQL       string         `json:"sql"`
+	Algorithm AlterAlgorithm `json:"algorithm,omitempty"`
+	Object    DiffObject     `json:"object"`
+	Name      string         `json:"name"`
+	/** why a faster algorithm cannot be used */
+	Reason string `json:"reason,omitempty"`
+}
+
+type TableMigration struct {
+	SchemaName string                `json:"schema"`
+	Name       string                `json:"table"`
+	Statements []*MigrationStatement `json:"statements"`
+	/** changes that are not migrated by the statements */
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+/*
+* State of generating the migration of one table
+ */
+type migrationBuilder struct {
+	oldTable  *TableSchema
+	newTable  *TableSchema
+	version   uint32
+	migration *TableMigration
+	/** new column names by old column names of renamed columns */
+	renamedColumns map[string]string
+}
+
+func (b *migrationBuilder) tableName() string {
+	return quoteMySQLIdentifier(b.newTable.SchemaName) + "." + quoteMySQLIdentifier(b.newTable.Name)
+}
+
+/*
+* Add an ALTER TABLE statement with an explicit ALGORITHM clause.
+@param[in]	object		kind of the changed object
+@param[in]	name		name of the changed object
+@param[in]	clauses		alter clauses, e.g. "DROP INDEX `a`"
+@param[in]	algorithm	fastest algorithm that supports the change
+@param[in]	reason		why a faster algorithm cannot be used
+*/
+func (b *migrationBuilder) alter(object DiffObject, name string, clauses []string,
+	algorithm AlterAlgorithm, reason string) {
+	b.migration.Statements = append(b.migration.Statements, &MigrationStatement{
+		SQL: fmt.Sprintf("ALTER TABLE %s %s, ALGORITHM=%s", b.tableName(),
+			strings.Join(clauses, ", "), algorithm),
+		Algorithm: algorithm,
+		Object:    object,
+		Name:      name,
+		Reason:    reason,
+	})
+}
+
+/*
+* Check if a changed attribute that lists columns, like key_parts, only
+differs by renamed columns, which MySQL renames itself.
+*/
+func (b *migrationBuilder) isRenamedColumnsOnly(attribute *AttributeDiff) bool {
+	old := attribute.Old
+	for oldName, newName := range b.renamedColumns {
+		old = strings.ReplaceAll(old, quoteMySQLIdentifier(oldName), quoteMySQLIdentifier(newName))
+	}
+	return old == attribute.New
+}
+
+/*
+* Remove the attributes that only differ by renamed columns
+ */
+func (b *migrationBuilder) removeRenamedColumns(diff *ObjectDiff) *ObjectDiff {
+	result := *diff
+	result.Attributes = make([]*AttributeDiff, 0, len(diff.Attributes))
+	for _, attribute := range diff.Attributes {
+		switch attribute.Name {
+		case "key_parts", "columns":
+			if b.isRenamedColumnsOnly(attribute) {
+				continue
+			}
+		}
+		result.Attributes = append(result.Attributes, attribute)
+	}
+	return &result
+}
+
+func (b *migrationBuilder) warn(format string, args ...interface{}) {
+	b.migration.Warnings = append(b.migration.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (b *migrationBuilder) hasFulltextIndex() bool {
+	for _, index := range b.oldTable.Indexes {
+		if index.Type == IT_FULLTEXT {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+* Check if the table allows instant column changes at all, and why not.
+ */
+func (b *migrationBuilder) instantColumnBlocker() string {
+	switch {
+	case b.version < MYSQL_VERSION_INSTANT:
+		return "ALGORITHM=INSTANT requires MySQL 8.0.12"
+	case b.oldTable.RowFormat == RF_COMPRESSED:
+		return "ROW_FORMAT=COMPRESSED does not support instant column changes"
+	case b.hasFulltextIndex():
+		return "tables with a FULLTEXT index do not support instant column changes"
+	}
+	return ""
+}
+
+func findColumn(table *TableSchema, name string) *Column {
+	for _, column := range table.GetUserColumns() {
+		if column.Name == name {
+			return column
+		}
+	}
+	return nil
+}
+
+func findIndex(table *TableSchema, name string) *Index {
+	for _, index := range table.GetUserIndexes() {
+		if index.Name == name {
+			return index
+		}
+	}
+	return nil
+}
+
+func findForeignKey(table *TableSchema, name string) *ForeignKey {
+	for _, fk := range table.ForeignKeys {
+		if fk.Name == name {
+			return fk
+		}
+	}
+	return nil
+}
+
+func findCheckConstraint(table *TableSchema, name string) *CheckConstraint {
+	for _, cc := range table.CheckConstraints {
+		if cc.Name == name {
+			return cc
+		}
+	}
+	return nil
+}
+
+func findPartition(table *TableSchema, name string) *Partition {
+	if table.Partitioning == nil {
+		return nil
+	}
+	for _, p := range table.Partitioning.Partitions {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func getDiffAttribute(diff *ObjectDiff, name string) *AttributeDiff {
+	for _, attribute := range diff.Attributes {
+		if attribute.Name == name {
+			return attribute
+		}
+	}
+	return nil
+}
+
+func getOldName(diff *ObjectDiff) string {
+	if diff.OldName != "" {
+		return diff.OldName
+	}
+	return diff.Name
+}
+
+/*
+* Get the FIRST or AFTER clause that puts the column at its position in the
+new table.
+*/
+func (b *migrationBuilder) getColumnPosition(name string) string {
+	previous := ""
+	for _, column := range b.newTable.GetUserColumns() {
+		if column.Name == name {
+			break
+		}
+		previous = column.Name
+	}
+	if previous == "" {
+		return "FIRST"
+	}
+	return "AFTER " + quoteMySQLIdentifier(previous)
+}
+
+/*
+* Check if a column is added after all columns of the old table
+ */
+func (b *migrationBuilder) isAddedAtEnd(name string, added map[string]bool) bool {
+	columns := b.newTable.GetUserColumns()
+	for n, column := range columns {
+		if column.Name != name {
+			continue
+		}
+		for _, next := range columns[n+1:] {
+			if !added[next.Name] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (b *migrationBuilder) addColumn(diff *ObjectDiff, added map[string]bool) {
+	column := findColumn(b.newTable, diff.Name)
+	clause := "ADD COLUMN " + column.GetDefinition()
+	atEnd := b.isAddedAtEnd(diff.Name, added)
+	if !atEnd {
+		clause += " " + b.getColumnPosition(diff.Name)
+	}
+	algorithm, reason := ALTER_ALGORITHM_INSTANT, b.instantColumnBlocker()
+	switch {
+	case column.GenerationExpressionUTF8 != "" && !column.IsVirtual:
+		algorithm, reason = ALTER_ALGORITHM_COPY, "adding a STORED generated column copies the table"
+	case column.IsAutoIncrement:
+		algorithm, reason = ALTER_ALGORITHM_INPLACE, "adding an AUTO_INCREMENT column rebuilds the table"
+	case column.GenerationExpressionUTF8 != "" && b.version >= MYSQL_VERSION_INSTANT:
+		reason = ""
+	case reason != "":
+		algorithm = ALTER_ALGORITHM_INPLACE
+	case !atEnd && b.version < MYSQL_VERSION_INSTANT_ANY_POSITION:
+		algorithm = ALTER_ALGORITHM_INPLACE
+		reason = "adding a column before the last column instantly requires MySQL 8.0.29"
+	}
+	b.alter(DIFF_OBJECT_COLUMN, diff.Name, []string{clause}, algorithm, reason)
+}
+
+func (b *migrationBuilder) dropColumn(diff *ObjectDiff) {
+	column := findColumn(b.oldTable, diff.Name)
+	algorithm, reason := ALTER_ALGORITHM_INSTANT, b.instantColumnBlocker()
+	switch {
+	case column.GenerationExpressionUTF8 != "" && column.IsVirtual && b.version >= MYSQL_VERSION_INSTANT:
+		reason = ""
+	case reason != "":
+		algorithm = ALTER_ALGORITHM_INPLACE
+	case b.version < MYSQL_VERSION_INSTANT_ANY_POSITION:
+		algorithm = ALTER_ALGORITHM_INPLACE
+		reason = "dropping a column instantly requires MySQL 8.0.29"
+	}
+	b.alter(DIFF_OBJECT_COLUMN, diff.Name, []string{"DROP COLUMN " + quoteMySQLIdentifier(diff.Name)},
+		algorithm, reason)
+}
+
+/*
+* Get the number of bytes that store the value of an ENUM or SET column
+ */
+func (c *Column) getElementsSize() int {
+	if c.Type == CT_ENUM {
+		if len(c.Elements) > 255 {
+			return 2
+		}
+		return 1
+	}
+	size := (len(c.Elements) + 7) / 8
+	if size > 4 {
+		return 8
+	}
+	return size
+}
+
+/*
+* Get the algorithm of a column type change.
+ */
+func getTypeChangeAlgorithm(oldColumn, newColumn *Column) (AlterAlgorithm, string) {
+	if oldColumn.Type != newColumn.Type {
+		return ALTER_ALGORITHM_COPY, "changing the column data type copies the table"
+	}
+	switch newColumn.Type {
+	case CT_VARCHAR:
+		if newColumn.Size >= oldColumn.Size && (oldColumn.Size < 256) == (newColumn.Size < 256) {
+			return ALTER_ALGORITHM_INPLACE, ""
+		}
+		return ALTER_ALGORITHM_COPY,
+			"shortening a VARCHAR or changing its length bytes copies the table"
+	case CT_ENUM, CT_SET:
+		if len(newColumn.Elements) >= len(oldColumn.Elements) &&
+			oldColumn.getElementsSize() == newColumn.getElementsSize() {
+			appended := true
+			for n, element := range oldColumn.Elements {
+				if newColumn.Elements[n] != element {
+					appended = false
+					break
+				}
+			}
+			if appended {
+				return ALTER_ALGORITHM_INSTANT, ""
+			}
+		}
+		return ALTER_ALGORITHM_COPY,
+			"only appending ENUM or SET elements without changing the storage size is instant"
+	}
+	return ALTER_ALGORITHM_COPY, "changing the column data type copies the table"
+}
+
+/*
+* Get the algorithm of a changed column from its changed attributes
+ */
+func (b *migrationBuilder) getColumnChangeAlgorithm(diff *ObjectDiff, oldColumn,
+	newColumn *Column) (algorithm AlterAlgorithm, reason string) {
+	algorithm = ALTER_ALGORITHM_INSTANT
+	if diff.Action == DIFF_RENAMED && b.version < MYSQL_VERSION_INSTANT_RENAME_COLUMN {
+		algorithm, reason = ALTER_ALGORITHM_INPLACE, "renaming a column instantly requires MySQL 8.0.28"
+	}
+	update := func(a AlterAlgorithm, r string) {
+		if a.rank() > algorithm.rank() {
+			algorithm, reason = a, r
+		}
+	}
+	for _, attribute := range diff.Attributes {
+		switch attribute.Name {
+		case "default", "invisible":
+			if b.version < MYSQL_VERSION_INSTANT {
+				update(ALTER_ALGORITHM_INPLACE, "ALGORITHM=INSTANT requires MySQL 8.0.12")
+			}
+		case "type":
+			update(getTypeChangeAlgorithm(oldColumn, newColumn))
+		case "comment", "on_update":
+			update(ALTER_ALGORITHM_INPLACE, "")
+		case "nullable":
+			update(ALTER_ALGORITHM_INPLACE, "changing the nullability rebuilds the table")
+		case "position":
+			update(ALTER_ALGORITHM_INPLACE, "reordering columns rebuilds the table")
+		case "collation":
+			update(ALTER_ALGORITHM_COPY, "changing the column collation copies the table")
+		default:
+			update(ALTER_ALGORITHM_COPY,
+				fmt.Sprintf("changing the column %s copies the table", attribute.Name))
+		}
+	}
+	return algorithm, reason
+}
+
+func (b *migrationBuilder) changeColumn(diff *ObjectDiff) {
+	oldName := getOldName(diff)
+	oldColumn, newColumn := findColumn(b.oldTable, oldName), findColumn(b.newTable, diff.Name)
+	algorithm, reason := b.getColumnChangeAlgorithm(diff, oldColumn, newColumn)
+	onlyInvisible := len(diff.Attributes) == 1 && diff.Attributes[0].Name == "invisible"
+	var clause string
+	switch {
+	case diff.Action == DIFF_RENAMED && len(diff.Attributes) == 0:
+		clause = fmt.Sprintf("RENAME COLUMN %s TO %s", quoteMySQLIdentifier(oldName),
+			quoteMySQLIdentifier(diff.Name))
+	case diff.Action == DIFF_CHANGED && onlyInvisible && newColumn.isHiddenUser():
+		clause = fmt.Sprintf("ALTER COLUMN %s SET INVISIBLE", quoteMySQLIdentifier(diff.Name))
+	case diff.Action == DIFF_CHANGED && onlyInvisible:
+		clause = fmt.Sprintf("ALTER COLUMN %s SET VISIBLE", quoteMySQLIdentifier(diff.Name))
+	case diff.Action == DIFF_RENAMED:
+		clause = fmt.Sprintf("CHANGE COLUMN %s %s", quoteMySQLIdentifier(oldName), newColumn.GetDefinition())
+	default:
+		clause = "MODIFY COLUMN " + newColumn.GetDefinition()
+	}
+	if getDiffAttribute(diff, "position") != nil {
+		clause += " " + b.getColumnPosition(diff.Name)
+	}
+	b.alter(DIFF_OBJECT_COLUMN, diff.Name, []string{clause}, algorithm, reason)
+}
+
+func getDropIndexClause(index *Index) string {
+	if index.Type == IT_PRIMARY {
+		return "DROP PRIMARY KEY"
+	}
+	return "DROP INDEX " + quoteMySQLIdentifier(index.Name)
+}
+
+/*
+* Get the algorithm of adding an index
+ */
+func getAddIndexAlgorithm(index *Index) (AlterAlgorithm, string) {
+	if index.GetExpressionKeyParts() != 0 {
+		return ALTER_ALGORITHM_COPY, "adding a functional index adds a hidden virtual column"
+	}
+	return ALTER_ALGORITHM_INPLACE, ""
+}
+
+/*
+* Get the number of functional key parts of the index
+ */
+func (i *Index) GetExpressionKeyParts() (n int) {
+	for _, keyPart := range i.KeyParts {
+		if keyPart.GetExpression() != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func (b *migrationBuilder) dropIndex(diff *ObjectDiff) {
+	index := findIndex(b.oldTable, diff.Name)
+	algorithm, reason := ALTER_ALGORITHM_INPLACE, ""
+	if index.Type == IT_PRIMARY {
+		algorithm, reason = ALTER_ALGORITHM_COPY,
+			"dropping the primary key without adding a new one copies the table"
+	}
+	b.alter(DIFF_OBJECT_INDEX, diff.Name, []string{getDropIndexClause(index)}, algorithm, reason)
+}
+
+func (b *migrationBuilder) addIndex(diff *ObjectDiff) {
+	index := findIndex(b.newTable, diff.Name)
+	algorithm, reason := getAddIndexAlgorithm(index)
+	b.alter(DIFF_OBJECT_INDEX, diff.Name, []string{"ADD " + index.GetDefinition()},
+		algorithm, reason)
+}
+
+/*
+* Rename, toggle the visibility of or rebuild a changed index.
+ */
+func (b *migrationBuilder) changeIndex(diff *ObjectDiff) {
+	oldName := getOldName(diff)
+	oldIndex, newIndex := findIndex(b.oldTable, oldName), findIndex(b.newTable, diff.Name)
+	onlyVisible := len(diff.Attributes) == 0 ||
+		(len(diff.Attributes) == 1 && diff.Attributes[0].Name == "visible")
+	if onlyVisible {
+		if diff.Action == DIFF_RENAMED {
+			b.alter(DIFF_OBJECT_INDEX, diff.Name,
+				[]string{fmt.Sprintf("RENAME INDEX %s TO %s", quoteMySQLIdentifier(oldName),
+					quoteMySQLIdentifier(diff.Name))},
+				ALTER_ALGORITHM_INSTANT, "")
+		}
+		if len(diff.Attributes) != 0 {
+			visibility := "INVISIBLE"
+			if newIndex.IsVisible {
+				visibility = "VISIBLE"
+			}
+			b.alter(DIFF_OBJECT_INDEX, diff.Name,
+				[]string{fmt.Sprintf("ALTER INDEX %s %s", quoteMySQLIdentifier(diff.Name), visibility)},
+				ALTER_ALGORITHM_INSTANT, "")
+		}
+		return
+	}
+	algorithm, reason := getAddIndexAlgorithm(newIndex)
+	b.alter(DIFF_OBJECT_INDEX, diff.Name,
+		[]string{getDropIndexClause(oldIndex), "ADD " + newIndex.GetDefinition()}, algorithm, reason)
+}
+
+func (b *migrationBuilder) addForeignKey(name string) {
+	fk := findForeignKey(b.newTable, name)
+	b.alter(DIFF_OBJECT_FOREIGN_KEY, name, []string{"ADD " + fk.GetDefinition()},
+		ALTER_ALGORITHM_INPLACE, "ALGORITHM=INPLACE requires foreign_key_checks=0, otherwise use COPY")
+}
+
+func (b *migrationBuilder) addCheckConstraint(name string) {
+	cc := findCheckConstraint(b.newTable, name)
+	b.alter(DIFF_OBJECT_CHECK_CONSTRAINT, name, []string{"ADD " + cc.GetDefinition()},
+		ALTER_ALGORITHM_COPY, "adding a check constraint validates the rows by copying the table")
+}
+
+func (b *migrationBuilder) changeCheckConstraint(diff *ObjectDiff) {
+	oldName := getOldName(diff)
+	if diff.Action == DIFF_CHANGED && len(diff.Attributes) == 1 &&
+		diff.Attributes[0].Name == "enforced" {
+		if findCheckConstraint(b.newTable, diff.Name).State == CC_NOT_ENFORCED {
+			b.alter(DIFF_OBJECT_CHECK_CONSTRAINT, diff.Name,
+				[]string{fmt.Sprintf("ALTER CHECK %s NOT ENFORCED", quoteMySQLIdentifier(diff.Name))},
+				ALTER_ALGORITHM_INPLACE, "")
+		} else {
+			b.alter(DIFF_OBJECT_CHECK_CONSTRAINT, diff.Name,
+				[]string{fmt.Sprintf("ALTER CHECK %s ENFORCED", quoteMySQLIdentifier(diff.Name))},
+				ALTER_ALGORITHM_COPY, "enforcing a check constraint validates the rows by copying the table")
+		}
+		return
+	}
+	b.alter(DIFF_OBJECT_CHECK_CONSTRAINT, oldName,
+		[]string{"DROP CHECK " + quoteMySQLIdentifier(oldName)}, ALTER_ALGORITHM_INPLACE, "")
+	b.addCheckConstraint(diff.Name)
+}
+
+/*
+* SQL option names of the table options in SDI, quoted options take a string
+ */
+var tableOptionNames = map[string]string{
+	"avg_row_length":     "AVG_ROW_LENGTH",
+	"checksum":           "CHECKSUM",
+	"delay_key_write":    "DELAY_KEY_WRITE",
+	"key_block_size":     "KEY_BLOCK_SIZE",
+	"max_rows":           "MAX_ROWS",
+	"min_rows":           "MIN_ROWS",
+	"pack_keys":          "PACK_KEYS",
+	"stats_persistent":   "STATS_PERSISTENT",
+	"stats_auto_recalc":  "STATS_AUTO_RECALC",
+	"stats_sample_pages": "STATS_SAMPLE_PAGES",
+	"compress":           "COMPRESSION",
+	"encrypt_type":       "ENCRYPTION",
+}
+
+/*
+* Get the table option clause of a changed SDI table option
+@param[in]	name	option name in SDI
+@param[in]	value	new value, empty if the option was removed
+@return option clause, empty if the option is unknown
+*/
+func getTableOptionClause(name, value string) string {
+	option, ok := tableOptionNames[name]
+	if !ok {
+		return ""
+	}
+	switch name {
+	case "compress":
+		if value == "" {
+			value = "None"
+		}
+		return option + "=" + quoteMySQLString(value)
+	case "encrypt_type":
+		if value == "" {
+			value = "N"
+		}
+		return option + "=" + quoteMySQLString(value)
+	case "stats_auto_recalc":
+		/* HA_STATS_AUTO_RECALC_DEFAULT, ON, OFF */
+		switch value {
+		case "1":
+			return option + "=1"
+		case "2":
+			return option + "=0"
+		}
+		return option + "=DEFAULT"
+	case "stats_persistent":
+		if value == "" {
+			return option + "=DEFAULT"
+		}
+	}
+	if value == "" {
+		value = "0"
+	}
+	return fmt.Sprintf("%s=%s", option, value)
+}
+
+func (b *migrationBuilder) changeTableOptions(diff *ObjectDiff) {
+	clauses := make([]string, 0)
+	algorithm, reason := ALTER_ALGORITHM_INPLACE, ""
+	for _, attribute := range diff.Attributes {
+		switch attribute.Name {
+		case "engine":
+			clauses = append(clauses, "ENGINE="+attribute.New)
+			algorithm, reason = ALTER_ALGORITHM_COPY, "changing the storage engine copies the table"
+		case "collation":
+			collation := b.newTable.Collation
+			clauses = append(clauses, fmt.Sprintf("DEFAULT CHARSET=%s COLLATE=%s",
+				collation.CharsetName, collation.Name))
+		case "row_format":
+			clauses = append(clauses, "ROW_FORMAT="+attribute.New)
+		case "comment":
+			clauses = append(clauses, "COMMENT="+quoteMySQLString(attribute.New))
+		case "partition_by", "subpartition_by":
+			b.warn("changing %s from %s to %s is not migrated", strings.ReplaceAll(attribute.Name, "_", " "),
+				quoteEmpty(attribute.Old), quoteEmpty(attribute.New))
+		default:
+			clause := getTableOptionClause(attribute.Name, attribute.New)
+			if clause == "" {
+				b.warn("changing table option %s from %s to %s is not migrated", attribute.Name,
+					quoteEmpty(attribute.Old), quoteEmpty(attribute.New))
+				continue
+			}
+			clauses = append(clauses, clause)
+		}
+	}
+	if len(clauses) != 0 {
+		b.alter(DIFF_OBJECT_TABLE, diff.Name, clauses, algorithm, reason)
+	}
+}
+
+/*
+* Get the definition of a RANGE or LIST partition as in CREATE TABLE
+ */
+func (p *Partition) getDefinition(partitionType PartitionType) string {
+	definition := "PARTITION " + quoteMySQLIdentifier(p.Name)
+	switch partitionType {
+	case PT_RANGE, PT_RANGE_COLUMNS:
+		if p.DescriptionUTF8 == "MAXVALUE" {
+			definition += " VALUES LESS THAN MAXVALUE"
+		} else {
+			definition += fmt.Sprintf(" VALUES LESS THAN (%s)", p.DescriptionUTF8)
+		}
+	case PT_LIST, PT_LIST_COLUMNS:
+		definition += fmt.Sprintf(" VALUES IN (%s)", p.DescriptionUTF8)
+	}
+	if p.Comment != "" {
+		definition += " COMMENT = " + quoteMySQLString(p.Comment)
+	}
+	return definition
+}
+
+/*
+* Add or drop RANGE and LIST partitions, other partition changes are only
+reported as warnings.
+*/
+func (b *migrationBuilder) changePartitions(diffs []*ObjectDiff) {
+	if len(diffs) == 0 {
+		return
+	}
+	oldPartitioning, newPartitioning := b.oldTable.Partitioning, b.newTable.Partitioning
+	if oldPartitioning == nil || newPartitioning == nil ||
+		oldPartitioning.Type != newPartitioning.Type {
+		return
+	}
+	switch newPartitioning.Type {
+	case PT_RANGE, PT_RANGE_COLUMNS, PT_LIST, PT_LIST_COLUMNS:
+	default:
+		b.warn("changing the partitions of %s partitioning is not migrated", newPartitioning.Type.String())
+		return
+	}
+	for _, diff := range diffs {
+		switch diff.Action {
+		case DIFF_REMOVED:
+			b.alter(DIFF_OBJECT_PARTITION, diff.Name,
+				[]string{"DROP PARTITION " + quoteMySQLIdentifier(diff.Name)}, ALTER_ALGORITHM_INPLACE, "")
+		case DIFF_ADDED:
+			p := findPartition(b.newTable, diff.Name)
+			b.alter(DIFF_OBJECT_PARTITION, diff.Name,
+				[]string{fmt.Sprintf("ADD PARTITION (%s)", p.getDefinition(newPartitioning.Type))},
+				ALTER_ALGORITHM_INPLACE, "")
+		default:
+			b.warn("changing partition `%s` is not migrated, use REORGANIZE PARTITION", getOldName(diff))
+		}
+	}
+}
+
+/*
+* Generate the statements that turn the old table into the new table. The
+statements run one after another: foreign keys, check constraints and
+indexes are dropped first, then columns are dropped, added and changed in
+the column order of the new table, then indexes, check constraints and
+foreign keys are added, and the table options and partitions are changed
+last. Every ALTER TABLE has the ALGORITHM of the fastest way MySQL can run
+it, derived from the column and index metadata of the SDI.
+@param[in]	oldTable	table to change, e.g. of a restored replica
+@param[in]	newTable	target table
+@param[in]	opts		migration options, nil for defaults
+@return statements and warnings of the migration
+*/
+func GenerateTableMigration(oldTable, newTable *TableSchema, opts *MigrationOptions) *TableMigration {
+	if opts == nil {
+		opts = NewMigrationOptions()
+	}
+	b := &migrationBuilder{
+		oldTable:  oldTable,
+		newTable:  newTable,
+		version:   opts.ServerVersion,
+		migration: &TableMigration{SchemaName: newTable.SchemaName, Name: newTable.Name},
+	}
+	if b.version == 0 {
+		b.version = oldTable.MysqlVersionID
+		if newTable.MysqlVersionID > b.version {
+			b.version = newTable.MysqlVersionID
+		}
+	}
+	b.migration.Statements = make([]*MigrationStatement, 0)
+	tableDiff := DiffTableSchemas(oldTable, newTable)
+	if tableDiff.OldName != "" || tableDiff.OldSchemaName != "" {
+		b.migration.Statements = append(b.migration.Statements, &MigrationStatement{
+			SQL: fmt.Sprintf("RENAME TABLE %s.%s TO %s", quoteMySQLIdentifier(oldTable.SchemaName),
+				quoteMySQLIdentifier(oldTable.Name), b.tableName()),
+			Object: DIFF_OBJECT_TABLE,
+			Name:   newTable.Name,
+		})
+	}
+	b.renamedColumns = make(map[string]string)
+	for _, diff := range tableDiff.Diffs {
+		if diff.Object == DIFF_OBJECT_COLUMN && diff.Action == DIFF_RENAMED {
+			b.renamedColumns[diff.OldName] = diff.Name
+		}
+	}
+	byObject := make(map[DiffObject][]*ObjectDiff)
+	for _, diff := range tableDiff.Diffs {
+		switch diff.Object {
+		case DIFF_OBJECT_INDEX, DIFF_OBJECT_FOREIGN_KEY:
+			if diff.Action == DIFF_CHANGED || diff.Action == DIFF_RENAMED {
+				diff = b.removeRenamedColumns(diff)
+				if diff.Action == DIFF_CHANGED && len(diff.Attributes) == 0 {
+					continue
+				}
+			}
+		}
+		byObject[diff.Object] = append(byObject[diff.Object], diff)
+	}
+	/* drop foreign keys, check constraints and indexes */
+	for _, diff := range byObject[DIFF_OBJECT_FOREIGN_KEY] {
+		if diff.Action != DIFF_ADDED {
+			name := getOldName(diff)
+			b.alter(DIFF_OBJECT_FOREIGN_KEY, name,
+				[]string{"DROP FOREIGN KEY " + quoteMySQLIdentifier(name)}, ALTER_ALGORITHM_INPLACE, "")
+		}
+	}
+	for _, diff := range byObject[DIFF_OBJECT_CHECK_CONSTRAINT] {
+		if diff.Action == DIFF_REMOVED {
+			b.alter(DIFF_OBJECT_CHECK_CONSTRAINT, diff.Name,
+				[]string{"DROP CHECK " + quoteMySQLIdentifier(diff.Name)}, ALTER_ALGORITHM_INPLACE, "")
+		}
+	}
+	for _, diff := range byObject[DIFF_OBJECT_INDEX] {
+		if diff.Action == DIFF_REMOVED {
+			b.dropIndex(diff)
+		}
+	}
+	/* columns */
+	columnDiffs := make(map[string]*ObjectDiff)
+	added := make(map[string]bool)
+	for _, diff := range byObject[DIFF_OBJECT_COLUMN] {
+		switch diff.Action {
+		case DIFF_REMOVED:
+			b.dropColumn(diff)
+			continue
+		case DIFF_ADDED:
+			added[diff.Name] = true
+		}
+		columnDiffs[diff.Name] = diff
+	}
+	for _, column := range newTable.GetUserColumns() {
+		diff, ok := columnDiffs[column.Name]
+		switch {
+		case !ok:
+		case diff.Action == DIFF_ADDED:
+			b.addColumn(diff, added)
+		default:
+			b.changeColumn(diff)
+		}
+	}
+	/* add or change indexes, check constraints and foreign keys */
+	for _, diff := range byObject[DIFF_OBJECT_INDEX] {
+		switch diff.Action {
+		case DIFF_ADDED:
+			b.addIndex(diff)
+		case DIFF_CHANGED, DIFF_RENAMED:
+			b.changeIndex(diff)
+		}
+	}
+	for _, diff := range byObject[DIFF_OBJECT_CHECK_CONSTRAINT] {
+		switch diff.Action {
+		case DIFF_ADDED:
+			b.addCheckConstraint(diff.Name)
+		case DIFF_CHANGED, DIFF_RENAMED:
+			b.changeCheckConstraint(diff)
+		}
+	}
+	for _, diff := range byObject[DIFF_OBJECT_FOREIGN_KEY] {
+		if diff.Action != DIFF_REMOVED {
+			b.addForeignKey(diff.Name)
+		}
+	}
+	/* table options and partitions */
+	for _, diff := range byObject[DIFF_OBJECT_TABLE] {
+		b.changeTableOptions(diff)
+	}
+	b.changePartitions(byObject[DIFF_OBJECT_PARTITION])
+	return b.migration
+}
+
+/*
+* Get the CREATE TABLE statement of a table qualified by its schema name
+ */
+func (ts *TableSchema) getQualifiedDDL() string {
+	name := quoteMySQLIdentifier(ts.Name)
+	prefix := "CREATE TABLE " + name
+	if !strings.HasPrefix(ts.DDL, prefix) {
+		return ts.DDL
+	}
+	return "CREATE TABLE " + quoteMySQLIdentifier(ts.SchemaName) + "." + name + ts.DDL[len(prefix):]
+}
+
+/*
+* Generate the migrations of two lists of tables, tables are paired as in
+DiffTableSchemaLists. Removed tables are dropped and added tables are
+created.
+@param[in]	oldTables	tables to change
+@param[in]	newTables	target tables
+@param[in]	opts		migration options, nil for defaults
+@return migrations of the tables that differ
+*/
+func GenerateMigrations(oldTables, newTables []*TableSchema, opts *MigrationOptions) (
+	migrations []*TableMigration) {
+	pairs, removed, added := pairTableSchemas(oldTables, newTables)
+	for _, table := range removed {
+		migrations = append(migrations, &TableMigration{SchemaName: table.SchemaName, Name: table.Name,
+			Statements: []*MigrationStatement{{
+				SQL:    "DROP TABLE " + quoteMySQLIdentifier(table.SchemaName) + "." + quoteMySQLIdentifier(table.Name),
+				Object: DIFF_OBJECT_TABLE,
+				Name:   table.Name,
+			}}})
+	}
+	for _, pair := range pairs {
+		migration := GenerateTableMigration(pair[0], pair[1], opts)
+		if len(migration.Statements) != 0 || len(migration.Warnings) != 0 {
+			migrations = append(migrations, migration)
+		}
+	}
+	for _, table := range added {
+		migrations = append(migrations, &TableMigration{SchemaName: table.SchemaName, Name: table.Name,
+			Statements: []*MigrationStatement{{
+				SQL:    table.getQualifiedDDL(),
+				Object: DIFF_OBJECT_TABLE,
+				Name:   table.Name,
+			}}})
+	}
+	return migrations
+}
+
+/*
+* Get the slowest algorithm of the migration
+ */
+func (m *TableMigration) GetAlgorithm() (algorithm AlterAlgorithm) {
+	for _, statement := range m.Statements {
+		algorithm = slowerAlgorithm(algorithm, statement.Algorithm)
+	}
+	return algorithm
+}
+
+/*
+* Get the migration as an SQL script, warnings and the reasons of slower
+algorithms are comments.
+*/
+func (m *TableMigration) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- `%s`.`%s`\n", m.SchemaName, m.Name)
+	for _, warning := range m.Warnings {
+		fmt.Fprintf(&b, "-- WARNING: %s\n", warning)
+	}
+	for _, statement := range m.Statements {
+		if statement.Reason != "" {
+			fmt.Fprintf(&b, "-- %s: %s\n", statement.Algorithm, statement.Reason)
+		}
+		fmt.Fprintf(&b, "%s;\n", statement.SQL)
+	}
+	return b.String()
+}
+
+/*
+* Get the migrations as an indented JSON array
+ */
+func DumpMigrationsJson(migrations []*TableMigration) ([]byte, error) {
+	if migrations == nil {
+		migrations = make([]*TableMigration, 0)
+	}
+	return json.MarshalIndent(migrations, "", "  ")
+}
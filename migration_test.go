@@ -0,0 +1,66 @@
+package ibd2schema
+
+import (
+	"testing"
+)
+
+/*
+* Parse a CREATE TABLE statement of the test schema
+ */
+func parseTestTable(t *testing.T, ddl string) *TableSchema {
+	t.Helper()
+	table, err := ParseCreateTable(ddl, &DDLParseOptions{SchemaName: "test", DefaultCollation: DDL_DEFAULT_COLLATION})
+	if err != nil {
+		t.Fatalf("parse %s failed, err:%v", ddl, err)
+	}
+	return table
+}
+
+func TestGenerateTableMigrationQuoting(t *testing.T) {
+	tests := []struct {
+		name   string
+		oldDDL string
+		newDDL string
+		want   []string
+	}{
+		{
+			name:   "table comment",
+			oldDDL: "CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`)) COMMENT='old'",
+			newDDL: "CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`)) COMMENT='it''s new'",
+			want:   []string{"ALTER TABLE `test`.`t` COMMENT='it''s new', ALGORITHM=INPLACE"},
+		},
+		{
+			name: "partition comment",
+			oldDDL: "CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`)) PARTITION BY RANGE (`id`) " +
+				"(PARTITION `p0` VALUES LESS THAN (10))",
+			newDDL: "CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`)) PARTITION BY RANGE (`id`) " +
+				"(PARTITION `p0` VALUES LESS THAN (10), PARTITION `p``1` VALUES LESS THAN MAXVALUE COMMENT 'p''s')",
+			want: []string{"ALTER TABLE `test`.`t` ADD PARTITION (PARTITION `p``1` VALUES LESS THAN MAXVALUE " +
+				"COMMENT = 'p''s'), ALGORITHM=INPLACE"},
+		},
+		{
+			name:   "column names",
+			oldDDL: "CREATE TABLE `t` (`id` int NOT NULL, `a``b` int, PRIMARY KEY (`id`))",
+			newDDL: "CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`))",
+			want:   []string{"ALTER TABLE `test`.`t` DROP COLUMN `a``b`, ALGORITHM=INSTANT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migration := GenerateTableMigration(parseTestTable(t, tt.oldDDL), parseTestTable(t, tt.newDDL),
+				&MigrationOptions{ServerVersion: 80030})
+			got := make([]string, 0)
+			for _, statement := range migration.Statements {
+				got = append(got, statement.SQL)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got statements %q, want %q", got, tt.want)
+			}
+			for n := range got {
+				if got[n] != tt.want[n] {
+					t.Errorf("statement %d\ngot:  %s\nwant: %s", n, got[n], tt.want[n])
+				}
+			}
+		})
+	}
+}
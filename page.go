@@ -34,6 +34,14 @@ const (
 	PAGE_DIR_SLOT_SIZE = 2
 	/** number of user records on the page */
 	PAGE_N_RECS = 16
+	/** number of bytes in deleted records */
+	PAGE_GARBAGE = 8
+	/** file segment header for the leaf pages in a B-tree: defined only on the
+	root page of a B-tree, but not in the root of an ibuf tree */
+	PAGE_BTR_SEG_LEAF = 36
+	/** file segment header for the non-leaf pages in a B-tree: defined only on
+	the root page of a B-tree, but not in the root of an ibuf tree */
+	PAGE_BTR_SEG_TOP = 36 + FSEG_HEADER_SIZE
 	/** First user record in creation (insertion) order, not necessarily collation
 	  order; this record may have been deleted */
 	PAGE_HEAP_NO_USER_LOW = 2
@@ -111,6 +119,9 @@ type Page struct {
 	PageLevel        uint16
 	PageType         PageType
 	NextPageNum      uint32
+	PrevPageNum      uint32
+	IndexID          uint64
+	Garbage          uint16
 }
 
 func NewPage(pageNum uint32, pageSize *PageSize, originData []byte) (p *Page, err error) {
@@ -219,7 +230,7 @@ func (p *Page) ZipDirGet(slotID uint32) uint16 {
 @return true if the page is empty (PAGE_N_RECS = 0)
 */
 func (p *Page) IsEmpty() bool {
-	return binary.LittleEndian.Uint16(p.UncompressedData[PAGE_HEADER+PAGE_N_RECS:]) == 0
+	return binary.BigEndian.Uint16(p.UncompressedData[PAGE_HEADER+PAGE_N_RECS:]) == 0
 }
 
 /*
@@ -227,11 +238,61 @@ func (p *Page) IsEmpty() bool {
 @return true if the page is a B-tree leaf (PAGE_LEVEL = 0)
 */
 func (p *Page) GetPageLevel() {
-	p.PageLevel = binary.LittleEndian.Uint16(p.UncompressedData[PAGE_HEADER+PAGE_LEVEL:])
+	p.PageLevel = binary.BigEndian.Uint16(p.UncompressedData[PAGE_HEADER+PAGE_LEVEL:])
 }
 
 func (p *Page) GetNextPageNum() {
-	p.NextPageNum = binary.LittleEndian.Uint32(p.UncompressedData[FIL_PAGE_NEXT:])
+	p.NextPageNum = binary.BigEndian.Uint32(p.UncompressedData[FIL_PAGE_NEXT:])
+}
+
+func (p *Page) GetPrevPageNum() {
+	p.PrevPageNum = binary.BigEndian.Uint32(p.UncompressedData[FIL_PAGE_PREV:])
+}
+
+/*
+* Get the id of the index the page belongs to.
+ */
+func (p *Page) GetIndexID() {
+	p.IndexID = binary.BigEndian.Uint64(p.UncompressedData[PAGE_HEADER+PAGE_INDEX_ID:])
+}
+
+/*
+* Get the number of bytes in deleted records on the page.
+ */
+func (p *Page) GetGarbage() {
+	p.Garbage = p.HeaderGetField(PAGE_GARBAGE)
+}
+
+/*
+* Get the number of bytes occupied by user records on the page, including
+the page directory slots beyond the two of an empty page but excluding
+deleted records.
+@return data size of the page
+*/
+func (p *Page) GetDataSize() uint32 {
+	p.GetHeapTop()
+	p.GetGarbage()
+	p.GetNSlots()
+	return uint32(p.HeapTop) - PAGE_NEW_SUPREMUM_END - uint32(p.Garbage) +
+		uint32(p.NSlots)*PAGE_DIR_SLOT_SIZE - (PAGE_EMPTY_DIR_START - PAGE_DIR)
+}
+
+/*
+* Get the space available for records and directory slots on an empty page,
+which already has the slots of the infimum and supremum records.
+@return maximum data size of a page
+*/
+func (p *Page) GetMaxDataSize() uint32 {
+	return p.Logical - PAGE_NEW_SUPREMUM_END - PAGE_EMPTY_DIR_START
+}
+
+/*
+* Read a file segment header stored in the page header of a B-tree root page.
+@param[in]	field	PAGE_BTR_SEG_LEAF or PAGE_BTR_SEG_TOP
+@return file segment header
+*/
+func (p *Page) GetFsegHeader(field uint32) *FsegHeader {
+	return NewFsegHeader(p.UncompressedData[PAGE_HEADER+field:])
 }
 
 /*
@@ -0,0 +1,552 @@
+package ibd2schema
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v17/parquet"
+	"github.com/apache/arrow/go/v17/parquet/compress"
+	"github.com/apache/arrow/go/v17/parquet/file"
+	"github.com/apache/arrow/go/v17/parquet/schema"
+)
+
+const (
+	/** default number of rows in a row group */
+	PARQUET_DEFAULT_ROW_GROUP_ROWS = 128 * 1024
+	/** key of the table DDL in the key-value metadata of the file */
+	PARQUET_DDL_METADATA_KEY = "ibd2schema.ddl"
+)
+
+type ParquetOptions struct {
+	/** number of rows buffered for each row group */
+	RowGroupRows int64
+	/** compression codec of the column chunks */
+	Compression compress.Compression
+	/** write zero or invalid dates as NULL, the temporal columns become
+	nullable; otherwise they fail the export */
+	ZeroDateAsNull bool
+	/** rows and columns to export, nil for the whole table */
+	Scan *ScanOptions
+}
+
+/*
+* Get the default Parquet options: snappy compression and
+PARQUET_DEFAULT_ROW_GROUP_ROWS rows per row group.
+*/
+func NewParquetOptions() *ParquetOptions {
+	return &ParquetOptions{
+		RowGroupRows: PARQUET_DEFAULT_ROW_GROUP_ROWS,
+		Compression:  compress.Codecs.Snappy,
+	}
+}
+
+/*
+* Buffered values of a Parquet column in the current row group.
+ */
+type parquetColumn struct {
+	column   *Column
+	node     *schema.PrimitiveNode
+	nullable bool
+	/** convert a decoded value to the physical value, nil for NULL */
+	convert   func(value interface{}) (interface{}, error)
+	defLevels []int16
+	int32s    []int32
+	int64s    []int64
+	float32s  []float32
+	float64s  []float64
+	bytes     []parquet.ByteArray
+	fixed     []parquet.FixedLenByteArray
+}
+
+/*
+* Get the byte length of a FIXED_LEN_BYTE_ARRAY that holds any unscaled
+decimal of the precision.
+*/
+func decimalFixedLen(precision uint32) int {
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(precision)), nil)
+	for n := 1; ; n++ {
+		/* largest positive value of n bytes in two's complement */
+		max := new(big.Int).Lsh(big.NewInt(1), uint(8*n-1))
+		if max.Cmp(limit) >= 0 {
+			return n
+		}
+	}
+}
+
+/*
+* Convert a decimal string like "-123.45" to its unscaled integer.
+ */
+func decimalUnscaled(value string, scale uint32) (*big.Int, error) {
+	intPart, fracPart, _ := strings.Cut(value, ".")
+	if uint32(len(fracPart)) > scale {
+		return nil, fmt.Errorf("decimal %s has more than %d fraction digits", value, scale)
+	}
+	fracPart += strings.Repeat("0", int(scale)-len(fracPart))
+	unscaled, ok := new(big.Int).SetString(intPart+fracPart, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %s", value)
+	}
+	return unscaled, nil
+}
+
+/*
+* Encode an integer as big-endian two's complement of the given length.
+ */
+func twosComplement(v *big.Int, length int) []byte {
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, new(big.Int).Lsh(big.NewInt(1), uint(8*length)))
+	}
+	b := v.Bytes()
+	out := make([]byte, length)
+	copy(out[length-len(b):], b)
+	return out
+}
+
+/*
+* Check if a date is a zero or otherwise invalid date like '2024-02-00'.
+ */
+func isInvalidDate(d Date) bool {
+	if d.Year == 0 || d.Month < 1 || d.Month > 12 || d.Day < 1 {
+		return true
+	}
+	return time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC).Day() != d.Day
+}
+
+/*
+* Map a column to a Parquet column with its logical type.
+@param[in]	column	table column
+@param[in]	opts	Parquet options
+@return Parquet column
+*/
+func newParquetColumn(column *Column, opts *ParquetOptions) (pc *parquetColumn, err error) {
+	pc = &parquetColumn{column: column, nullable: column.IsNullable}
+	var (
+		logicalType  schema.LogicalType = schema.NoLogicalType{}
+		physicalType parquet.Type
+		typeLen      = -1
+	)
+	/* handle the zero dates of temporal columns */
+	checkDate := func(d Date, value interface{}) (bool, error) {
+		if !isInvalidDate(d) {
+			return true, nil
+		}
+		if opts.ZeroDateAsNull {
+			return false, nil
+		}
+		return false, fmt.Errorf("column %s has invalid date %v", column.Name, value)
+	}
+	switch column.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG:
+		bitWidth := map[ColumnType]int8{CT_TINY: 8, CT_SHORT: 16, CT_INT24: 32, CT_LONG: 32}
+		physicalType = parquet.Types.Int32
+		logicalType = schema.NewIntLogicalType(bitWidth[column.Type], !column.IsUnsigned)
+		pc.convert = func(value interface{}) (interface{}, error) {
+			switch v := value.(type) {
+			case int64:
+				return int32(v), nil
+			case uint64:
+				return int32(uint32(v)), nil
+			}
+			return nil, fmt.Errorf("unexpected %T", value)
+		}
+	case CT_LONGLONG:
+		physicalType = parquet.Types.Int64
+		logicalType = schema.NewIntLogicalType(64, !column.IsUnsigned)
+		pc.convert = func(value interface{}) (interface{}, error) {
+			switch v := value.(type) {
+			case int64:
+				return v, nil
+			case uint64:
+				return int64(v), nil
+			}
+			return nil, fmt.Errorf("unexpected %T", value)
+		}
+	case CT_YEAR:
+		physicalType = parquet.Types.Int32
+		logicalType = schema.NewIntLogicalType(16, false)
+		pc.convert = func(value interface{}) (interface{}, error) {
+			return int32(value.(int64)), nil
+		}
+	case CT_BIT:
+		physicalType = parquet.Types.Int64
+		logicalType = schema.NewIntLogicalType(64, false)
+		pc.convert = func(value interface{}) (interface{}, error) {
+			return int64(value.(uint64)), nil
+		}
+	case CT_FLOAT:
+		physicalType = parquet.Types.Float
+		pc.convert = func(value interface{}) (interface{}, error) {
+			return value.(float32), nil
+		}
+	case CT_DOUBLE:
+		physicalType = parquet.Types.Double
+		pc.convert = func(value interface{}) (interface{}, error) {
+			return value.(float64), nil
+		}
+	case CT_NEWDECIMAL:
+		precision, scale := column.NumericPrecision, column.NumericScale
+		logicalType = schema.NewDecimalLogicalType(int32(precision), int32(scale))
+		switch {
+		case precision <= 9:
+			physicalType = parquet.Types.Int32
+		case precision <= 18:
+			physicalType = parquet.Types.Int64
+		default:
+			physicalType = parquet.Types.FixedLenByteArray
+			typeLen = decimalFixedLen(precision)
+		}
+		pc.convert = func(value interface{}) (interface{}, error) {
+			unscaled, err := decimalUnscaled(value.(string), scale)
+			if err != nil {
+				return nil, err
+			}
+			switch physicalType {
+			case parquet.Types.Int32:
+				return int32(unscaled.Int64()), nil
+			case parquet.Types.Int64:
+				return unscaled.Int64(), nil
+			}
+			return parquet.FixedLenByteArray(twosComplement(unscaled, typeLen)), nil
+		}
+	case CT_DATE, CT_NEWDATE:
+		physicalType = parquet.Types.Int32
+		logicalType = schema.DateLogicalType{}
+		pc.nullable = pc.nullable || opts.ZeroDateAsNull
+		pc.convert = func(value interface{}) (interface{}, error) {
+			d := value.(Date)
+			if ok, err := checkDate(d, value); !ok {
+				return nil, err
+			}
+			t := time.Date(d.Year, time.Month(d.Month), d.Day, 0, 0, 0, 0, time.UTC)
+			return int32(t.Unix() / 86400), nil
+		}
+	case CT_DATETIME, CT_DATETIME2:
+		physicalType = parquet.Types.Int64
+		logicalType = schema.NewTimestampLogicalType(false, schema.TimeUnitMicros)
+		pc.nullable = pc.nullable || opts.ZeroDateAsNull
+		pc.convert = func(value interface{}) (interface{}, error) {
+			dt := value.(Datetime)
+			if ok, err := checkDate(dt.Date, value); !ok {
+				return nil, err
+			}
+			t := time.Date(dt.Year, time.Month(dt.Month), dt.Day, dt.Hour, dt.Minute, dt.Second,
+				dt.Microsecond*1000, time.UTC)
+			return t.UnixMicro(), nil
+		}
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		physicalType = parquet.Types.Int64
+		logicalType = schema.NewTimestampLogicalType(true, schema.TimeUnitMicros)
+		pc.nullable = pc.nullable || opts.ZeroDateAsNull
+		pc.convert = func(value interface{}) (interface{}, error) {
+			t := value.(time.Time)
+			/* '0000-00-00 00:00:00' is stored as 0 */
+			if t.Unix() == 0 && t.Nanosecond() == 0 {
+				if opts.ZeroDateAsNull {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("column %s has zero timestamp", column.Name)
+			}
+			return t.UnixMicro(), nil
+		}
+	case CT_TIME, CT_TIME2:
+		/* plain microseconds, TIME(MICROS) cannot hold -838:59:59..838:59:59 */
+		physicalType = parquet.Types.Int64
+		pc.convert = func(value interface{}) (interface{}, error) {
+			return int64(value.(Time).Duration() / time.Microsecond), nil
+		}
+	case CT_ENUM:
+		physicalType = parquet.Types.ByteArray
+		logicalType = schema.EnumLogicalType{}
+	case CT_JSON:
+		physicalType = parquet.Types.ByteArray
+		logicalType = schema.JSONLogicalType{}
+	case CT_SET, CT_STRING, CT_VARCHAR, CT_VAR_STRING, CT_TINY_BLOB, CT_MEDIUM_BLOB,
+		CT_LONG_BLOB, CT_BLOB:
+		physicalType = parquet.Types.ByteArray
+		if column.Collation.CharsetName != "binary" {
+			logicalType = schema.StringLogicalType{}
+		}
+	case CT_GEOMETRY:
+		physicalType = parquet.Types.ByteArray
+	default:
+		return nil, fmt.Errorf("unsupported type %d of column %s", column.Type, column.Name)
+	}
+	if physicalType == parquet.Types.ByteArray {
+		pc.convert = func(value interface{}) (interface{}, error) {
+			switch v := value.(type) {
+			case string:
+				return parquet.ByteArray(v), nil
+			case []byte:
+				return parquet.ByteArray(v), nil
+			}
+			return nil, fmt.Errorf("unexpected %T", value)
+		}
+	}
+	repetition := parquet.Repetitions.Required
+	if pc.nullable {
+		repetition = parquet.Repetitions.Optional
+	}
+	pc.node, err = schema.NewPrimitiveNodeLogical(column.Name, repetition, logicalType,
+		physicalType, typeLen, -1)
+	if err != nil {
+		return nil, fmt.Errorf("create parquet column %s failed, err:%v", column.Name, err)
+	}
+	return pc, nil
+}
+
+/*
+* Append a decoded value to the buffer of the column.
+ */
+func (pc *parquetColumn) append(value interface{}) (err error) {
+	if value != nil {
+		value, err = pc.convert(value)
+		if err != nil {
+			return fmt.Errorf("convert value of column %s failed, err:%v", pc.column.Name, err)
+		}
+	}
+	if value == nil {
+		if !pc.nullable {
+			return fmt.Errorf("NULL value of NOT NULL column %s", pc.column.Name)
+		}
+		pc.defLevels = append(pc.defLevels, 0)
+		return nil
+	}
+	if pc.nullable {
+		pc.defLevels = append(pc.defLevels, 1)
+	}
+	switch v := value.(type) {
+	case int32:
+		pc.int32s = append(pc.int32s, v)
+	case int64:
+		pc.int64s = append(pc.int64s, v)
+	case float32:
+		pc.float32s = append(pc.float32s, v)
+	case float64:
+		pc.float64s = append(pc.float64s, v)
+	case parquet.ByteArray:
+		pc.bytes = append(pc.bytes, v)
+	case parquet.FixedLenByteArray:
+		pc.fixed = append(pc.fixed, v)
+	}
+	return nil
+}
+
+/*
+* Write the buffered values to a column chunk and reset the buffer.
+ */
+func (pc *parquetColumn) flush(cw file.ColumnChunkWriter) (err error) {
+	var defLevels []int16
+	if pc.nullable {
+		defLevels = pc.defLevels
+	}
+	switch w := cw.(type) {
+	case *file.Int32ColumnChunkWriter:
+		_, err = w.WriteBatch(pc.int32s, defLevels, nil)
+	case *file.Int64ColumnChunkWriter:
+		_, err = w.WriteBatch(pc.int64s, defLevels, nil)
+	case *file.Float32ColumnChunkWriter:
+		_, err = w.WriteBatch(pc.float32s, defLevels, nil)
+	case *file.Float64ColumnChunkWriter:
+		_, err = w.WriteBatch(pc.float64s, defLevels, nil)
+	case *file.ByteArrayColumnChunkWriter:
+		_, err = w.WriteBatch(pc.bytes, defLevels, nil)
+	case *file.FixedLenByteArrayColumnChunkWriter:
+		_, err = w.WriteBatch(pc.fixed, defLevels, nil)
+	default:
+		err = fmt.Errorf("unexpected column writer %T", cw)
+	}
+	if err != nil {
+		return fmt.Errorf("write column %s failed, err:%v", pc.column.Name, err)
+	}
+	pc.defLevels, pc.int32s, pc.int64s = pc.defLevels[:0], pc.int32s[:0], pc.int64s[:0]
+	pc.float32s, pc.float64s = pc.float32s[:0], pc.float64s[:0]
+	pc.bytes, pc.fixed = pc.bytes[:0], pc.fixed[:0]
+	return nil
+}
+
+/*
+* Map columns to Parquet columns.
+@return Parquet columns and the root node of their schema
+*/
+func newParquetColumns(name string, columns []*Column, opts *ParquetOptions) (
+	pcs []*parquetColumn, root *schema.GroupNode, err error) {
+	fields := make(schema.FieldList, 0, len(columns))
+	for _, column := range columns {
+		pc, err := newParquetColumn(column, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		pcs = append(pcs, pc)
+		fields = append(fields, pc.node)
+	}
+	root, err = schema.NewGroupNode(name, parquet.Repetitions.Required, fields, -1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create parquet schema failed, err:%v", err)
+	}
+	return pcs, root, nil
+}
+
+/*
+* Get the Parquet schema of the columns.
+@param[in]	name	name of the root node, usually the table name
+@param[in]	columns	columns in file order
+@param[in]	opts	Parquet options, nil for the defaults
+@return Parquet schema
+*/
+func NewParquetSchema(name string, columns []*Column, opts *ParquetOptions) (
+	sc *schema.Schema, err error) {
+	if opts == nil {
+		opts = NewParquetOptions()
+	}
+	_, root, err := newParquetColumns(name, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+	return schema.NewSchema(root), nil
+}
+
+/*
+* Writer of decoded rows to a Parquet file, rows are buffered and written
+in row groups.
+*/
+type ParquetWriter struct {
+	writer       *file.Writer
+	columns      []*parquetColumn
+	rowGroupRows int64
+	nBuffered    int64
+	NumRows      uint64
+}
+
+/*
+* Create a Parquet writer.
+@param[in]	w	destination of the file
+@param[in]	name	name of the root node, usually the table name
+@param[in]	columns	columns of the rows, see Row.Columns
+@param[in]	opts	Parquet options, nil for the defaults
+@param[in]	metadata	key-value metadata of the file
+@return Parquet writer
+*/
+func NewParquetWriter(w io.Writer, name string, columns []*Column, opts *ParquetOptions,
+	metadata map[string]string) (pw *ParquetWriter, err error) {
+	if opts == nil {
+		opts = NewParquetOptions()
+	}
+	pw = &ParquetWriter{rowGroupRows: opts.RowGroupRows}
+	if pw.rowGroupRows <= 0 {
+		pw.rowGroupRows = PARQUET_DEFAULT_ROW_GROUP_ROWS
+	}
+	var root *schema.GroupNode
+	pw.columns, root, err = newParquetColumns(name, columns, opts)
+	if err != nil {
+		return nil, err
+	}
+	props := parquet.NewWriterProperties(
+		parquet.WithCompression(opts.Compression),
+		parquet.WithMaxRowGroupLength(pw.rowGroupRows),
+		parquet.WithCreatedBy("go-ibd2schema"),
+	)
+	pw.writer = file.NewParquetWriter(w, root, file.WithWriterProps(props))
+	for key, value := range metadata {
+		if err = pw.writer.AppendKeyValueMetadata(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return pw, nil
+}
+
+/*
+* Buffer a row, the row group is written when it is full.
+@param[in]	values	decoded values in the order of the writer columns
+*/
+func (pw *ParquetWriter) WriteRow(values []interface{}) (err error) {
+	if len(values) != len(pw.columns) {
+		return fmt.Errorf("row has %d values, expected %d", len(values), len(pw.columns))
+	}
+	for n, pc := range pw.columns {
+		if err = pc.append(values[n]); err != nil {
+			return err
+		}
+	}
+	pw.nBuffered++
+	pw.NumRows++
+	if pw.nBuffered >= pw.rowGroupRows {
+		return pw.Flush()
+	}
+	return nil
+}
+
+/*
+* Write the buffered rows as a row group.
+ */
+func (pw *ParquetWriter) Flush() (err error) {
+	if pw.nBuffered == 0 {
+		return nil
+	}
+	rgw := pw.writer.AppendRowGroup()
+	for _, pc := range pw.columns {
+		cw, err := rgw.NextColumn()
+		if err != nil {
+			return fmt.Errorf("start column %s failed, err:%v", pc.column.Name, err)
+		}
+		if err = pc.flush(cw); err != nil {
+			return err
+		}
+	}
+	if err = rgw.Close(); err != nil {
+		return fmt.Errorf("close row group failed, err:%v", err)
+	}
+	pw.nBuffered = 0
+	return nil
+}
+
+/*
+* Write the buffered rows and the file footer.
+ */
+func (pw *ParquetWriter) Close() (err error) {
+	if err = pw.Flush(); err != nil {
+		return err
+	}
+	return pw.writer.Close()
+}
+
+/*
+* Export the rows of a table to a Parquet file. The schema is derived from
+the SDI columns and the table DDL is saved in the file metadata.
+@param[in]	ctx		context to cancel the export
+@param[in]	tableSchema	table schema from the SDI
+@param[in]	w		destination of the file
+@param[in]	opts		Parquet options, nil for the defaults
+@return number of exported rows
+*/
+func (ts *TableSpace) ExportParquet(ctx context.Context, tableSchema *TableSchema, w io.Writer,
+	opts *ParquetOptions) (rows uint64, err error) {
+	if opts == nil {
+		opts = NewParquetOptions()
+	}
+	it, err := ts.Scan(ctx, tableSchema, opts.Scan)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+	pw, err := NewParquetWriter(w, tableSchema.Name, it.Columns(), opts,
+		map[string]string{PARQUET_DDL_METADATA_KEY: tableSchema.DDL})
+	if err != nil {
+		return 0, err
+	}
+	for it.Next() {
+		if err = pw.WriteRow(it.Row().Values); err != nil {
+			return pw.NumRows, err
+		}
+	}
+	if err = it.Err(); err != nil {
+		return pw.NumRows, err
+	}
+	if err = pw.Close(); err != nil {
+		return pw.NumRows, err
+	}
+	return pw.NumRows, nil
+}
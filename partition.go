@@ -0,0 +1,168 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+/*
+* Value of a partition in VALUES LESS THAN or VALUES IN
+ */
+type PartitionValue struct {
+	/** true for MAXVALUE */
+	MaxValue bool
+	/** true for NULL */
+	NullValue bool
+	/** number of the value list of LIST COLUMNS partitions */
+	ListNum uint32
+	/** number of the column of COLUMNS partitions */
+	ColumnNum uint32
+	ValueUTF8 string
+}
+
+type Partition struct {
+	Name   string
+	Number uint32
+	/** value expression, e.g. "10" of VALUES LESS THAN (10) */
+	DescriptionUTF8 string
+	Engine          string
+	Comment         string
+	Options         map[string]string
+	SEPrivateID     uint64
+	SEPrivateData   map[string]string
+	Values          []*PartitionValue
+	Subpartitions   []*Partition
+	GJson           gjson.Result
+}
+
+func NewPartition(p gjson.Result) (partition *Partition, err error) {
+	if !p.IsObject() {
+		return nil, fmt.Errorf("partition is not an object")
+	}
+	if err = CheckMember(p, `name`); err != nil {
+		return nil, err
+	}
+	partition = &Partition{
+		Name:            p.Get(`name`).String(),
+		Number:          uint32(p.Get(`number`).Uint()),
+		DescriptionUTF8: p.Get(`description_utf8`).String(),
+		Engine:          p.Get(`engine`).String(),
+		Comment:         p.Get(`comment`).String(),
+		Options:         ParseKeyValueList(p.Get(`options`).String()),
+		SEPrivateID:     p.Get(`se_private_id`).Uint(),
+		SEPrivateData:   ParseKeyValueList(p.Get(`se_private_data`).String()),
+		Values:          make([]*PartitionValue, 0),
+		Subpartitions:   make([]*Partition, 0),
+		GJson:           p,
+	}
+	for _, v := range p.Get(`values`).Array() {
+		partition.Values = append(partition.Values, &PartitionValue{
+			MaxValue:  v.Get(`max_value`).Bool(),
+			NullValue: v.Get(`null_value`).Bool(),
+			ListNum:   uint32(v.Get(`list_num`).Uint()),
+			ColumnNum: uint32(v.Get(`column_num`).Uint()),
+			ValueUTF8: v.Get(`value_utf8`).String(),
+		})
+	}
+	for _, sp := range p.Get(`subpartitions`).Array() {
+		subpartition, err := NewPartition(sp)
+		if err != nil {
+			return nil, err
+		}
+		partition.Subpartitions = append(partition.Subpartitions, subpartition)
+	}
+	return partition, nil
+}
+
+/*
+* Partitioning scheme of a table
+ */
+type Partitioning struct {
+	Type                       PartitionType
+	ExpressionUTF8             string
+	DefaultPartitioning        DefaultPartitioning
+	SubpartitionType           SubpartitionType
+	SubpartitionExpressionUTF8 string
+	DefaultSubpartitioning     DefaultPartitioning
+	Partitions                 []*Partition
+}
+
+/*
+* Parse the partitioning of SDI JSON
+@param[in]	ddObject	Data Dictionary JSON object
+@return partitioning, nil if the table is not partitioned
+*/
+func ParsePartitioning(ddObject gjson.Result) (partitioning *Partitioning, err error) {
+	partitionType := PartitionType(ddObject.Get(`partition_type`).Int())
+	if partitionType == PT_NONE {
+		return nil, nil
+	}
+	partitioning = &Partitioning{
+		Type:                       partitionType,
+		ExpressionUTF8:             ddObject.Get(`partition_expression_utf8`).String(),
+		DefaultPartitioning:        DefaultPartitioning(ddObject.Get(`default_partitioning`).Int()),
+		SubpartitionType:           SubpartitionType(ddObject.Get(`subpartition_type`).Int()),
+		SubpartitionExpressionUTF8: ddObject.Get(`subpartition_expression_utf8`).String(),
+		DefaultSubpartitioning:     DefaultPartitioning(ddObject.Get(`default_subpartitioning`).Int()),
+		Partitions:                 make([]*Partition, 0),
+	}
+	for _, p := range ddObject.Get(`partitions`).Array() {
+		partition, err := NewPartition(p)
+		if err != nil {
+			return nil, err
+		}
+		partitioning.Partitions = append(partitioning.Partitions, partition)
+	}
+	return partitioning, nil
+}
+
+/*
+* Get the PARTITION BY clause as in CREATE TABLE, e.g.
+"PARTITION BY HASH (`id`) PARTITIONS 4"
+*/
+func (pi *Partitioning) GetDefinition() string {
+	var b strings.Builder
+	switch pi.Type {
+	case PT_KEY_51, PT_LINEAR_KEY_51:
+		fmt.Fprintf(&b, "PARTITION BY %s ALGORITHM = 1 (%s)", pi.Type.String(), pi.ExpressionUTF8)
+	default:
+		fmt.Fprintf(&b, "PARTITION BY %s (%s)", pi.Type.String(), pi.ExpressionUTF8)
+	}
+	if pi.SubpartitionType != ST_NONE {
+		switch pi.SubpartitionType {
+		case ST_KEY_51, ST_LINEAR_KEY_51:
+			fmt.Fprintf(&b, "\nSUBPARTITION BY %s ALGORITHM = 1 (%s)", pi.SubpartitionType.String(),
+				pi.SubpartitionExpressionUTF8)
+		default:
+			fmt.Fprintf(&b, "\nSUBPARTITION BY %s (%s)", pi.SubpartitionType.String(),
+				pi.SubpartitionExpressionUTF8)
+		}
+		if pi.DefaultSubpartitioning == DP_NUMBER && len(pi.Partitions) != 0 {
+			fmt.Fprintf(&b, "\nSUBPARTITIONS %d", len(pi.Partitions[0].Subpartitions))
+		}
+	}
+	switch pi.DefaultPartitioning {
+	case DP_YES:
+		return b.String()
+	case DP_NUMBER:
+		fmt.Fprintf(&b, "\nPARTITIONS %d", len(pi.Partitions))
+		return b.String()
+	}
+	partitions := make([]string, len(pi.Partitions))
+	for n, p := range pi.Partitions {
+		partitions[n] = p.getDefinition(pi.Type)
+		if pi.SubpartitionType == ST_NONE || pi.DefaultSubpartitioning == DP_YES ||
+			pi.DefaultSubpartitioning == DP_NUMBER {
+			continue
+		}
+		subpartitions := make([]string, len(p.Subpartitions))
+		for m, sp := range p.Subpartitions {
+			subpartitions[m] = "SUBPARTITION " + quoteMySQLIdentifier(sp.Name)
+		}
+		partitions[n] += fmt.Sprintf("\n  (%s)", strings.Join(subpartitions, ",\n   "))
+	}
+	fmt.Fprintf(&b, "\n(%s)", strings.Join(partitions, ",\n "))
+	return b.String()
+}
@@ -0,0 +1,642 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+* Longest identifier of PostgreSQL, longer names are truncated
+ */
+const POSTGRES_MAX_IDENTIFIER_LEN = 63
+
+type PostgresOptions struct {
+	/** the tables are created in PostgreSQL schemas named like the MySQL
+	schemas if IncludeSchemaName is set, else in the search path */
+	RenderOptions
+	/** add CHECK (column >= 0) constraints to unsigned columns */
+	UnsignedChecks bool
+	/** create an enum type for each ENUM column, else a text column with a
+	CHECK constraint */
+	EnumTypes bool
+	/** text search configuration of the tsvector of FULLTEXT indexes */
+	TextSearchConfig string
+	/** collation of columns with case-insensitive MySQL collations, e.g. a
+	nondeterministic ICU collation created beforehand, none if empty */
+	CaseInsensitiveCollation string
+}
+
+func NewPostgresOptions() *PostgresOptions {
+	return &PostgresOptions{
+		RenderOptions:    *NewRenderOptions(),
+		UnsignedChecks:   true,
+		EnumTypes:        true,
+		TextSearchConfig: "simple",
+	}
+}
+
+/*
+* PostgreSQL statements of a table. Foreign keys are separate ALTER TABLE
+statements, so they can be added after all tables are created.
+*/
+type PostgresTable struct {
+	SchemaName string `json:"schema"`
+	Name       string `json:"table"`
+	/** CREATE TYPE, CREATE TABLE, CREATE INDEX and COMMENT statements */
+	Statements []string `json:"statements"`
+	/** ALTER TABLE statements adding the foreign keys */
+	ForeignKeys []string `json:"foreign_keys,omitempty"`
+	/** lossy or incomplete translations */
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+/*
+* State of rendering one table
+ */
+type postgresRenderer struct {
+	table  *TableSchema
+	opts   *PostgresOptions
+	result *PostgresTable
+	/** CHECK constraints of the columns */
+	checks []string
+	warned map[string]bool
+}
+
+/*
+* Quote a PostgreSQL identifier, e.g. "order"
+ */
+func quotePostgresIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+/*
+* Quote a PostgreSQL string literal
+ */
+func quotePostgresString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func (r *postgresRenderer) warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	if !r.warned[warning] {
+		r.warned[warning] = true
+		r.result.Warnings = append(r.result.Warnings, warning)
+	}
+}
+
+/*
+* Quote an identifier, warning if PostgreSQL truncates it
+ */
+func (r *postgresRenderer) quote(name string) string {
+	if len(name) > POSTGRES_MAX_IDENTIFIER_LEN {
+		r.warn("identifier %s is longer than %d bytes and is truncated", name, POSTGRES_MAX_IDENTIFIER_LEN)
+	}
+	return quotePostgresIdentifier(name)
+}
+
+/*
+* Get the qualified name of a table
+ */
+func (r *postgresRenderer) qualify(schemaName, name string) string {
+	if r.opts.IncludeSchemaName && schemaName != "" {
+		return r.quote(schemaName) + "." + r.quote(name)
+	}
+	return r.quote(name)
+}
+
+func (r *postgresRenderer) tableName() string {
+	return r.qualify(r.table.SchemaName, r.table.Name)
+}
+
+/*
+* Name an index or type after the table, their names are unique per schema
+in PostgreSQL but per table in MySQL
+*/
+func (r *postgresRenderer) objectName(name string) string {
+	return r.table.Name + "_" + name
+}
+
+/*
+* Convert a MySQL expression of SDI JSON, only the identifier quotes are
+converted so functions and operators may need a review.
+*/
+func (r *postgresRenderer) convertExpression(expression, context string) string {
+	converted, err := rewriteQuotedIdentifiers(expression, quotePostgresIdentifier)
+	if err != nil {
+		r.warn("%s: %v", context, err)
+		return expression
+	}
+	r.warn("%s is MySQL syntax, review the functions and operators of %s", context, converted)
+	return converted
+}
+
+/*
+* Check if the column stores text, not bytes
+ */
+func (c *Column) isText() bool {
+	return c.Collation == nil || c.Collation.CharsetName != "binary"
+}
+
+/*
+* Get the length of a character column in characters
+ */
+func (c *Column) getCharLength() uint64 {
+	if c.Collation == nil || c.Collation.Maxlen == 0 {
+		return c.Size
+	}
+	return c.Size / uint64(c.Collation.Maxlen)
+}
+
+/*
+* Check if the column is a tinyint(1), which is used as boolean
+ */
+func (c *Column) isBoolean() bool {
+	return c.Type == CT_TINY && strings.HasPrefix(c.ColumnTypeUTF8, "tinyint(1)") && !c.IsUnsigned
+}
+
+/*
+* Get the geometry type of a GEOMETRY column, e.g. "Point"
+ */
+func (c *Column) getGeometryType() string {
+	geometryTypes := map[string]string{
+		"point":              "Point",
+		"linestring":         "LineString",
+		"polygon":            "Polygon",
+		"multipoint":         "MultiPoint",
+		"multilinestring":    "MultiLineString",
+		"multipolygon":       "MultiPolygon",
+		"geomcollection":     "GeometryCollection",
+		"geometrycollection": "GeometryCollection",
+	}
+	if t, ok := geometryTypes[strings.ToLower(c.ColumnTypeUTF8)]; ok {
+		return t
+	}
+	return "Geometry"
+}
+
+func (r *postgresRenderer) getEnumValues(c *Column) string {
+	values := make([]string, len(c.Elements))
+	for n, element := range c.Elements {
+		values[n] = quotePostgresString(element)
+	}
+	return strings.Join(values, ", ")
+}
+
+/*
+* Get the PostgreSQL type of a column, adding the CHECK constraints and enum
+types it needs.
+*/
+func (r *postgresRenderer) getColumnType(c *Column) string {
+	name := r.quote(c.Name)
+	unsigned := func(pgType string) string {
+		if c.IsUnsigned && r.opts.UnsignedChecks {
+			r.checks = append(r.checks, fmt.Sprintf("CHECK (%s >= 0)", name))
+		}
+		return pgType
+	}
+	switch c.Type {
+	case CT_TINY:
+		if c.isBoolean() {
+			r.warn("tinyint(1) column %s is boolean, values other than 0 and 1 are lost", c.Name)
+			return "boolean"
+		}
+		return unsigned("smallint")
+	case CT_SHORT:
+		if c.IsUnsigned {
+			return unsigned("integer")
+		}
+		return "smallint"
+	case CT_INT24:
+		return unsigned("integer")
+	case CT_LONG:
+		if c.IsUnsigned {
+			return unsigned("bigint")
+		}
+		return "integer"
+	case CT_LONGLONG:
+		/* bigint everywhere, so identity columns and the foreign keys
+		referencing them have the same type */
+		if c.IsUnsigned {
+			r.warn("bigint unsigned column %s is bigint, values above 2^63-1 overflow", c.Name)
+			return unsigned("bigint")
+		}
+		return "bigint"
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		return unsigned(fmt.Sprintf("numeric(%d,%d)", c.NumericPrecision, c.NumericScale))
+	case CT_FLOAT:
+		return unsigned("real")
+	case CT_DOUBLE:
+		return unsigned("double precision")
+	case CT_BIT:
+		return fmt.Sprintf("bit varying(%d)", c.NumericPrecision)
+	case CT_YEAR:
+		r.checks = append(r.checks, fmt.Sprintf("CHECK (%s = 0 OR %s BETWEEN 1901 AND 2155)", name, name))
+		return "smallint"
+	case CT_DATE, CT_NEWDATE:
+		return "date"
+	case CT_TIME, CT_TIME2:
+		r.warn("time column %s only holds times of day, MySQL TIME ranges from -838:59:59 to 838:59:59", c.Name)
+		return fmt.Sprintf("time(%d)", c.DatetimePrecision)
+	case CT_DATETIME, CT_DATETIME2:
+		return fmt.Sprintf("timestamp(%d) without time zone", c.DatetimePrecision)
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		return fmt.Sprintf("timestamp(%d) with time zone", c.DatetimePrecision)
+	case CT_VARCHAR, CT_VAR_STRING:
+		if !c.isText() {
+			return "bytea"
+		}
+		return fmt.Sprintf("varchar(%d)", c.getCharLength())
+	case CT_STRING:
+		if !c.isText() {
+			return "bytea"
+		}
+		return fmt.Sprintf("char(%d)", c.getCharLength())
+	case CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return "bytea"
+		}
+		return "text"
+	case CT_JSON:
+		r.warn("json column %s is jsonb, which does not keep key order and duplicate keys", c.Name)
+		return "jsonb"
+	case CT_ENUM:
+		if r.opts.EnumTypes {
+			typeName := r.qualify(r.table.SchemaName, r.objectName(c.Name))
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)",
+				typeName, r.getEnumValues(c)))
+			return typeName
+		}
+		r.checks = append(r.checks, fmt.Sprintf("CHECK (%s IN (%s))", name, r.getEnumValues(c)))
+		return "text"
+	case CT_SET:
+		r.warn("set column %s is text with comma separated values, the values are not checked", c.Name)
+		return "text"
+	case CT_GEOMETRY:
+		r.warn("geometry column %s needs the PostGIS extension", c.Name)
+		if c.SRSID != nil {
+			return fmt.Sprintf("geometry(%s,%d)", c.getGeometryType(), *c.SRSID)
+		}
+		return fmt.Sprintf("geometry(%s)", c.getGeometryType())
+	}
+	r.warn("column %s of type %s is text", c.Name, c.ColumnTypeUTF8)
+	return "text"
+}
+
+/*
+* Get the COLLATE clause of a text column
+ */
+func (r *postgresRenderer) getCollate(c *Column) string {
+	if c.Collation == nil || !c.isText() {
+		return ""
+	}
+	switch c.Type {
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING, CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+	default:
+		return ""
+	}
+	if strings.HasSuffix(c.Collation.Name, "_bin") {
+		return ` COLLATE "C"`
+	}
+	if strings.HasSuffix(c.Collation.Name, "_ci") {
+		if r.opts.CaseInsensitiveCollation != "" {
+			return " COLLATE " + quotePostgresIdentifier(r.opts.CaseInsensitiveCollation)
+		}
+		r.warn("case-insensitive collation %s is compared case-sensitively", c.Collation.Name)
+	}
+	return ""
+}
+
+/*
+* Get the DEFAULT clause of a column, empty if it has none
+ */
+func (r *postgresRenderer) getDefault(c *Column) string {
+	if c.GenerationExpressionUTF8 != "" || c.DefaultValueUTF8Null || c.IsAutoIncrement {
+		return ""
+	}
+	value := c.DefaultValueUTF8
+	switch {
+	case strings.HasPrefix(c.DefaultOption, "("):
+		return " DEFAULT " + r.convertExpression(c.DefaultOption, "default of column "+c.Name)
+	case strings.HasPrefix(strings.ToUpper(c.DefaultOption), "CURRENT_TIMESTAMP"):
+		if c.Type == CT_DATETIME || c.Type == CT_DATETIME2 {
+			return " DEFAULT LOCALTIMESTAMP" + strings.TrimPrefix(strings.ToUpper(c.DefaultOption), "CURRENT_TIMESTAMP")
+		}
+		return " DEFAULT " + strings.ToUpper(c.DefaultOption)
+	case c.DefaultOption != "":
+		r.warn("default %s of column %s is not converted", c.DefaultOption, c.Name)
+		return ""
+	case strings.HasPrefix(value, "0000-00-00"):
+		r.warn("zero date default of column %s is dropped", c.Name)
+		return ""
+	case c.isBoolean():
+		if value == "0" {
+			return " DEFAULT false"
+		}
+		return " DEFAULT true"
+	case c.Type == CT_BIT:
+		return " DEFAULT B" + strings.TrimPrefix(value, "b")
+	}
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG, CT_DECIMAL, CT_NEWDECIMAL, CT_FLOAT,
+		CT_DOUBLE, CT_YEAR:
+		return " DEFAULT " + value
+	}
+	return " DEFAULT " + quotePostgresString(value)
+}
+
+/*
+* Get the column definition of CREATE TABLE
+ */
+func (r *postgresRenderer) getColumnDefinition(c *Column) string {
+	definition := r.quote(c.Name) + " " + r.getColumnType(c) + r.getCollate(c)
+	if c.GenerationExpressionUTF8 != "" {
+		if c.IsVirtual {
+			r.warn("virtual generated column %s is stored", c.Name)
+		}
+		definition += fmt.Sprintf(" GENERATED ALWAYS AS (%s) STORED",
+			r.convertExpression(c.GenerationExpressionUTF8, "expression of generated column "+c.Name))
+	}
+	if c.IsAutoIncrement {
+		switch c.Type {
+		case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG:
+			definition += " GENERATED BY DEFAULT AS IDENTITY"
+		default:
+			r.warn("auto increment of %s column %s is dropped", c.ColumnTypeUTF8, c.Name)
+		}
+	}
+	if !c.IsNullable {
+		definition += " NOT NULL"
+	}
+	definition += r.getDefault(c)
+	if c.UpdateOption != "" {
+		r.warn("ON UPDATE %s of column %s needs a trigger", c.UpdateOption, c.Name)
+	}
+	if c.isHiddenUser() && !c.isGipk() {
+		r.warn("invisible column %s is visible", c.Name)
+	}
+	return definition
+}
+
+/*
+* Get a key part of an index, prefix key parts become expressions
+@param[in]	keyPart		key part
+@return key part and true if it is an expression or descending
+*/
+func (r *postgresRenderer) getKeyPart(keyPart *IndexKeyPart) (string, bool) {
+	var part string
+	isExpression := true
+	switch {
+	case keyPart.GetExpression() != "":
+		part = fmt.Sprintf("(%s)", r.convertExpression(keyPart.GetExpression(),
+			"expression of functional key part"))
+	case keyPart.Length != 0 && keyPart.Column.isText():
+		part = fmt.Sprintf("(left(%s, %d))", r.quote(keyPart.Column.Name), keyPart.Length)
+	case keyPart.Length != 0:
+		part = fmt.Sprintf("(substring(%s from 1 for %d))", r.quote(keyPart.Column.Name), keyPart.Length)
+	default:
+		part = r.quote(keyPart.Column.Name)
+		isExpression = false
+	}
+	if keyPart.Descending {
+		part += " DESC"
+		isExpression = true
+	}
+	return part, isExpression
+}
+
+/*
+* Get the key parts of an index
+@return key parts and true if any of them is an expression or descending
+*/
+func (r *postgresRenderer) getKeyParts(index *Index) (string, bool) {
+	parts := make([]string, len(index.KeyParts))
+	hasExpression := false
+	for n, keyPart := range index.KeyParts {
+		var isExpression bool
+		parts[n], isExpression = r.getKeyPart(keyPart)
+		hasExpression = hasExpression || isExpression
+	}
+	return strings.Join(parts, ", "), hasExpression
+}
+
+/*
+* Get the tsvector expression of a FULLTEXT index
+ */
+func (r *postgresRenderer) getTsvector(index *Index) string {
+	parts := make([]string, len(index.KeyParts))
+	for n, keyPart := range index.KeyParts {
+		parts[n] = fmt.Sprintf("coalesce(%s, '')", r.quote(keyPart.Column.Name))
+	}
+	return fmt.Sprintf("to_tsvector(%s, %s)", quotePostgresString(r.opts.TextSearchConfig),
+		strings.Join(parts, " || ' ' || "))
+}
+
+/*
+* Render the indexes: the primary key and unique constraints of plain
+columns are table constraints, the others CREATE INDEX statements.
+@return table constraints
+*/
+func (r *postgresRenderer) renderIndexes() (constraints []string) {
+	for _, index := range r.table.GetUserIndexes() {
+		keyParts, hasExpression := r.getKeyParts(index)
+		name := r.objectName(index.Name)
+		if !index.IsVisible && index.Type != IT_PRIMARY {
+			r.warn("invisible index %s is visible", index.Name)
+		}
+		switch {
+		case index.Type == IT_PRIMARY && hasExpression:
+			r.warn("primary key prefixes and sort orders are dropped")
+			names := make([]string, len(index.KeyParts))
+			for n, keyPart := range index.KeyParts {
+				names[n] = r.quote(keyPart.Column.Name)
+			}
+			constraints = append(constraints, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(names, ", ")))
+			continue
+		case index.Type == IT_PRIMARY:
+			constraints = append(constraints, fmt.Sprintf("PRIMARY KEY (%s)", keyParts))
+			continue
+		case index.Type == IT_UNIQUE && !hasExpression:
+			constraints = append(constraints, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", r.quote(name), keyParts))
+		case index.Type == IT_UNIQUE:
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("CREATE UNIQUE INDEX %s%s ON %s (%s)",
+				r.opts.getIfNotExists(), r.quote(name), r.tableName(), keyParts))
+		case index.Type == IT_FULLTEXT:
+			if parser := index.Options["parser_name"]; parser != "" {
+				r.warn("fulltext index %s uses the %s parser, the tsvector uses the %s configuration",
+					index.Name, parser, r.opts.TextSearchConfig)
+			}
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("CREATE INDEX %s%s ON %s USING gin (%s)",
+				r.opts.getIfNotExists(), r.quote(name), r.tableName(), r.getTsvector(index)))
+		case index.Type == IT_SPATIAL:
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("CREATE INDEX %s%s ON %s USING gist (%s)",
+				r.opts.getIfNotExists(), r.quote(name), r.tableName(), keyParts))
+		default:
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("CREATE INDEX %s%s ON %s (%s)",
+				r.opts.getIfNotExists(), r.quote(name), r.tableName(), keyParts))
+		}
+		if index.Comment != "" {
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("COMMENT ON INDEX %s IS %s",
+				r.qualify(r.table.SchemaName, name), quotePostgresString(index.Comment)))
+		}
+	}
+	return constraints
+}
+
+func (r *postgresRenderer) renderForeignKeys() {
+	for _, fk := range r.table.ForeignKeys {
+		columns := make([]string, len(fk.ColumnNames))
+		for n, name := range fk.ColumnNames {
+			columns[n] = r.quote(name)
+		}
+		referenced := make([]string, len(fk.ReferenceNames))
+		for n, name := range fk.ReferenceNames {
+			referenced[n] = r.quote(name)
+		}
+		statement := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+			r.tableName(), r.quote(fk.Name), strings.Join(columns, ", "),
+			r.qualify(fk.ReferencedTableSchemaName, fk.ReferencedTableName), strings.Join(referenced, ", "))
+		if fk.DeleteRule != FK_RULE_NO_ACTION && fk.DeleteRule != 0 {
+			statement += " ON DELETE " + fk.DeleteRule.String()
+		}
+		if fk.UpdateRule != FK_RULE_NO_ACTION && fk.UpdateRule != 0 {
+			statement += " ON UPDATE " + fk.UpdateRule.String()
+		}
+		r.result.ForeignKeys = append(r.result.ForeignKeys, statement)
+	}
+}
+
+/*
+* Render a table as PostgreSQL statements. Types, defaults, identity
+columns, indexes, constraints and comments are translated, everything that
+is lost or needs a review is listed in the warnings.
+@param[in]	table	table schema
+@param[in]	opts	options, default NewPostgresOptions
+@return statements of the table
+*/
+func RenderPostgres(table *TableSchema, opts *PostgresOptions) *PostgresTable {
+	if opts == nil {
+		opts = NewPostgresOptions()
+	}
+	r := &postgresRenderer{
+		table: table,
+		opts:  opts,
+		result: &PostgresTable{
+			SchemaName: table.SchemaName,
+			Name:       table.Name,
+			Statements: make([]string, 0),
+		},
+		warned: make(map[string]bool),
+	}
+	columns := table.GetUserColumns()
+	definitions := make([]string, 0, len(columns))
+	for _, column := range columns {
+		definitions = append(definitions, r.getColumnDefinition(column))
+	}
+	definitions = append(definitions, r.checks...)
+	/* CREATE INDEX statements follow CREATE TABLE */
+	createTypes := r.result.Statements
+	r.result.Statements = make([]string, 0)
+	definitions = append(definitions, r.renderIndexes()...)
+	for _, check := range table.CheckConstraints {
+		if check.State != CC_ENFORCED {
+			r.warn("check constraint %s is not enforced and is dropped", check.Name)
+			continue
+		}
+		definitions = append(definitions, fmt.Sprintf("CONSTRAINT %s CHECK (%s)", r.quote(check.Name),
+			r.convertExpression(check.CheckClauseUTF8, "check constraint "+check.Name)))
+	}
+	if table.Partitioning != nil {
+		r.warn("partitioning is not translated, the table is not partitioned")
+	}
+	createTable := fmt.Sprintf("CREATE TABLE %s%s (\n  %s\n)", opts.getIfNotExists(), r.tableName(),
+		strings.Join(definitions, ",\n  "))
+	r.result.Statements = append(append(createTypes, createTable), r.result.Statements...)
+	if table.Comment != "" {
+		r.result.Statements = append(r.result.Statements, fmt.Sprintf("COMMENT ON TABLE %s IS %s",
+			r.tableName(), quotePostgresString(table.Comment)))
+	}
+	for _, column := range columns {
+		if column.Comment != "" {
+			r.result.Statements = append(r.result.Statements, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s",
+				r.tableName(), r.quote(column.Name), quotePostgresString(column.Comment)))
+		}
+	}
+	r.renderForeignKeys()
+	return r.result
+}
+
+/*
+* Get the statements of the table with its warnings as comments
+ */
+func (t *PostgresTable) String() string {
+	var b strings.Builder
+	for _, warning := range t.Warnings {
+		fmt.Fprintf(&b, "-- WARNING: %s\n", warning)
+	}
+	for _, statement := range append(t.Statements, t.ForeignKeys...) {
+		b.WriteString(statement + ";\n")
+	}
+	return b.String()
+}
+
+/*
+* Render tables as a PostgreSQL script: the schemas, the tables and then all
+foreign keys, so the tables can be created in any order.
+@param[in]	tables	table schemas
+@param[in]	opts	options, default NewPostgresOptions
+@return script
+*/
+func RenderPostgresScript(tables []*TableSchema, opts *PostgresOptions) string {
+	if opts == nil {
+		opts = NewPostgresOptions()
+	}
+	var b strings.Builder
+	if opts.CreateSchemas && opts.IncludeSchemaName {
+		for _, name := range getSchemaNames(tables) {
+			fmt.Fprintf(&b, "CREATE SCHEMA IF NOT EXISTS %s%s\n", quotePostgresIdentifier(name), opts.Terminator)
+		}
+		b.WriteString("\n")
+	}
+	foreignKeys := make([]string, 0)
+	for _, table := range tables {
+		pgTable := RenderPostgres(table, opts)
+		for _, warning := range pgTable.Warnings {
+			fmt.Fprintf(&b, "-- WARNING: %s\n", warning)
+		}
+		for _, statement := range pgTable.Statements {
+			b.WriteString(statement + opts.Terminator + "\n")
+		}
+		b.WriteString("\n")
+		foreignKeys = append(foreignKeys, pgTable.ForeignKeys...)
+	}
+	for _, statement := range foreignKeys {
+		b.WriteString(statement + opts.Terminator + "\n")
+	}
+	return b.String()
+}
+
+/*
+* Renderer of PostgreSQL statements, see RenderPostgresScript
+ */
+type PostgresRenderer struct {
+	/** PostgreSQL options, default NewPostgresOptions, the shared options are
+	replaced by those passed to Render */
+	Options *PostgresOptions
+}
+
+func (r *PostgresRenderer) Name() string {
+	return "postgres"
+}
+
+func (r *PostgresRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	pgOpts := NewPostgresOptions()
+	if r.Options != nil {
+		*pgOpts = *r.Options
+	}
+	if opts != nil {
+		pgOpts.RenderOptions = *opts
+	}
+	_, err := io.WriteString(w, RenderPostgresScript(tables, pgOpts))
+	return err
+}
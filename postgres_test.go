@@ -0,0 +1,30 @@
+package ibd2schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPostgresUnsignedBigintForeignKey(t *testing.T) {
+	tables, err := ParseDDLScript("CREATE TABLE `parent` (`id` bigint unsigned NOT NULL AUTO_INCREMENT, "+
+		"PRIMARY KEY (`id`));\n"+
+		"CREATE TABLE `child` (`id` int NOT NULL, `parent_id` bigint unsigned DEFAULT NULL, PRIMARY KEY (`id`), "+
+		"CONSTRAINT `fk_parent` FOREIGN KEY (`parent_id`) REFERENCES `parent` (`id`));", &DDLParseOptions{
+		SchemaName: "test", DefaultCollation: DDL_DEFAULT_COLLATION})
+	if err != nil {
+		t.Fatalf("parse failed, err:%v", err)
+	}
+	tests := []struct {
+		table *TableSchema
+		want  string
+	}{
+		{tables[0], `"id" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL`},
+		{tables[1], `"parent_id" bigint`},
+	}
+	for _, tt := range tests {
+		script := RenderPostgres(tt.table, nil).String()
+		if !strings.Contains(script, tt.want) {
+			t.Errorf("table %s: %q not found in\n%s", tt.table.Name, tt.want, script)
+		}
+	}
+}
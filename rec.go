@@ -1,5 +1,10 @@
 package ibd2schema
 
+import (
+	"encoding/binary"
+	"fmt"
+)
+
 const (
 
 	/* Number of extra bytes in a new-style record,
@@ -24,6 +29,19 @@ const (
 	been delete marked */
 	REC_INFO_DELETED_FLAG uint32 = 0x20
 	REC_INFO_BITS_SHIFT   uint32 = 0
+	/** The minimum record flag in info bits; it is set on the leftmost node
+	pointer record of each non-leaf level */
+	REC_INFO_MIN_REC_FLAG uint32 = 0x10
+	/** The instant flag in info bits; the record has the number of fields
+	stored, written by INSTANT ADD COLUMN before MySQL 8.0.29 */
+	REC_INFO_INSTANT_FLAG uint32 = 0x80
+	/** The version flag in info bits; the record has the row version stored,
+	written by INSTANT ADD/DROP COLUMN since MySQL 8.0.29 */
+	REC_INFO_VERSION_FLAG uint32 = 0x40
+	/** Mask of the info bits */
+	REC_INFO_BITS_MASK uint32 = 0xF0
+	/** Length of the child page number in a node pointer record */
+	REC_NODE_PTR_SIZE uint32 = 4
 	/* Number of extra bytes in an old-style record,
 	in addition to the data and the offsets */
 	REC_N_OLD_EXTRA_BYTES = 6
@@ -58,3 +76,131 @@ const (
 	REC_STATUS_INFIMUM
 	REC_STATUS_SUPREMUM
 )
+
+/*
+* Physical record of an index page in the new-style compact format
+ */
+type Record struct {
+	/** offset of the record origin on the page */
+	Offset   uint32
+	InfoBits uint32
+	Status   byte
+	Fields   []*IndexField
+	/** start offset of each field relative to the record origin */
+	FieldStarts []uint32
+	/** length of each field, the in-page length for externally stored fields */
+	FieldLens []uint32
+	/** true if the field is SQL NULL */
+	Nulls []bool
+	/** true if the field is stored externally */
+	Externs []bool
+	/** total length of the record data */
+	DataLen uint32
+	/** child page number, only for node pointer records */
+	ChildPageNum uint32
+}
+
+/*
+* Parse the field offsets of a record, like rec_init_offsets()
+@param[in]	recOffset	offset of the record origin
+@param[in]	fields		physical fields of the index
+@param[in]	nUniq		number of fields in node pointer records
+@return record
+*/
+func (p *Page) ParseRecord(recOffset uint32, fields []*IndexField, nUniq int) (rec *Record, err error) {
+	data := p.UncompressedData
+	if recOffset < PAGE_NEW_INFIMUM || recOffset >= uint32(len(data)) {
+		return nil, fmt.Errorf("record offset %d out of page", recOffset)
+	}
+	p.GetIsCompact()
+	if !p.IsCompact {
+		return nil, fmt.Errorf("page %d is in old-style record format", p.PageNum)
+	}
+	rec = &Record{
+		Offset:   recOffset,
+		InfoBits: uint32(data[recOffset-REC_NEW_INFO_BITS]) & REC_INFO_BITS_MASK,
+		Status:   p.GetRecType(recOffset),
+	}
+	if rec.InfoBits&(REC_INFO_INSTANT_FLAG|REC_INFO_VERSION_FLAG) != 0 {
+		return nil, fmt.Errorf("record of instantly added or dropped columns is not supported")
+	}
+	nFields := len(fields)
+	switch rec.Status {
+	case REC_STATUS_ORDINARY:
+	case REC_STATUS_NODE_PTR:
+		nFields = nUniq
+	default:
+		return nil, fmt.Errorf("record at %d is not a user record", recOffset)
+	}
+	rec.Fields = fields[:nFields]
+	rec.FieldStarts = make([]uint32, nFields)
+	rec.FieldLens = make([]uint32, nFields)
+	rec.Nulls = make([]bool, nFields)
+	rec.Externs = make([]bool, nFields)
+
+	var nNullable uint32
+	for _, field := range fields {
+		if field.Column.IsNullable {
+			nNullable++
+		}
+	}
+	nullsPos := recOffset - (REC_N_NEW_EXTRA_BYTES + 1)
+	lensPos := nullsPos - UTBitsInBytes(nNullable)
+	var nullMask byte = 1
+	var offs uint32
+	for n, field := range rec.Fields {
+		rec.FieldStarts[n] = offs
+		if field.Column.IsNullable {
+			if nullMask == 0 {
+				nullsPos--
+				nullMask = 1
+			}
+			isNull := data[nullsPos]&nullMask != 0
+			nullMask <<= 1
+			if isNull {
+				rec.Nulls[n] = true
+				continue
+			}
+		}
+		if field.FixedLen != 0 {
+			rec.FieldLens[n] = field.FixedLen
+			offs += field.FixedLen
+			continue
+		}
+		length := uint32(data[lensPos])
+		lensPos--
+		if field.Column.isBigCol() && length&0x80 != 0 {
+			length = (length << 8) | uint32(data[lensPos])
+			lensPos--
+			rec.Externs[n] = length&0x4000 != 0
+			length &= 0x3fff
+		}
+		rec.FieldLens[n] = length
+		offs += length
+	}
+	rec.DataLen = offs
+	if rec.Status == REC_STATUS_NODE_PTR {
+		childPos := recOffset + offs
+		if childPos+REC_NODE_PTR_SIZE > uint32(len(data)) {
+			return nil, fmt.Errorf("node pointer at %d exceeds page len", recOffset)
+		}
+		rec.ChildPageNum = binary.BigEndian.Uint32(data[childPos:])
+		rec.DataLen += REC_NODE_PTR_SIZE
+	}
+	if recOffset+rec.DataLen > uint32(len(data)) {
+		return nil, fmt.Errorf("record at %d with length %d exceeds page len",
+			recOffset, rec.DataLen)
+	}
+	return rec, nil
+}
+
+/*
+* Get the stored bytes of a field, nil if the field is SQL NULL.
+ */
+func (rec *Record) GetFieldData(p *Page, n int) []byte {
+	if rec.Nulls[n] {
+		return nil
+	}
+	start := rec.Offset + rec.FieldStarts[n]
+	return p.UncompressedData[start : start+rec.FieldLens[n]]
+}
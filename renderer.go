@@ -0,0 +1,317 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+* Options that all renderers share
+ */
+type RenderOptions struct {
+	/** CREATE TABLE IF NOT EXISTS, and the same for the other statements that
+	support it */
+	IfNotExists bool
+	/** qualify the table names with the schema name */
+	IncludeSchemaName bool
+	/** create the schemas of the tables first, only if IncludeSchemaName is
+	set */
+	CreateSchemas bool
+	/** terminator of the statements, empty for none */
+	Terminator string
+}
+
+func NewRenderOptions() *RenderOptions {
+	return &RenderOptions{
+		IncludeSchemaName: true,
+		CreateSchemas:     true,
+		Terminator:        ";",
+	}
+}
+
+func (opts *RenderOptions) getIfNotExists() string {
+	if opts.IfNotExists {
+		return "IF NOT EXISTS "
+	}
+	return ""
+}
+
+/*
+* Renderer of the typed table model, e.g. as the DDL of a SQL dialect
+ */
+type Renderer interface {
+	/** name of the renderer in the registry, e.g. "mysql" */
+	Name() string
+	/** write the tables to w */
+	Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error
+}
+
+var (
+	renderersLock sync.RWMutex
+	/** registered renderers by name */
+	renderers = map[string]Renderer{
+		"mysql":      &MySQLRenderer{},
+		"postgres":   &PostgresRenderer{},
+		"clickhouse": &ClickHouseRenderer{},
+		"bigquery":   &WarehouseRenderer{Options: NewWarehouseOptions(WAREHOUSE_BIGQUERY)},
+		"snowflake":  &WarehouseRenderer{Options: NewWarehouseOptions(WAREHOUSE_SNOWFLAKE)},
+		"jsonschema": &JSONSchemaRenderer{},
+		"avro":       &AvroRenderer{},
+		"go":         &GoStructRenderer{},
+		"proto":      &ProtoRenderer{},
+		"json":       &SchemaDocumentRenderer{},
+	}
+)
+
+/*
+* Register a renderer under its name
+@param[in]	renderer	renderer
+@return error if another renderer has the same name
+*/
+func RegisterRenderer(renderer Renderer) error {
+	renderersLock.Lock()
+	defer renderersLock.Unlock()
+	if _, ok := renderers[renderer.Name()]; ok {
+		return fmt.Errorf("renderer %s is already registered", renderer.Name())
+	}
+	renderers[renderer.Name()] = renderer
+	return nil
+}
+
+/*
+* Get a registered renderer by name
+ */
+func GetRenderer(name string) (Renderer, error) {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	renderer, ok := renderers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown renderer %s", name)
+	}
+	return renderer, nil
+}
+
+/*
+* Get the sorted names of the registered renderers
+ */
+func GetRendererNames() []string {
+	renderersLock.RLock()
+	defer renderersLock.RUnlock()
+	names := make([]string, 0, len(renderers))
+	for name := range renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+* Render tables with a registered renderer
+@param[in]	name	name of the renderer
+@param[in]	tables	table schemas
+@param[in]	opts	options, default NewRenderOptions
+@return rendered tables
+*/
+func RenderTables(name string, tables []*TableSchema, opts *RenderOptions) (string, error) {
+	renderer, err := GetRenderer(name)
+	if err != nil {
+		return "", err
+	}
+	if opts == nil {
+		opts = NewRenderOptions()
+	}
+	var b strings.Builder
+	if err = renderer.Render(&b, tables, opts); err != nil {
+		return "", fmt.Errorf("render %s failed, err:%v", name, err)
+	}
+	return b.String(), nil
+}
+
+/*
+* Quote a MySQL identifier, e.g. `order`
+ */
+func quoteMySQLIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+/*
+* Quote a MySQL string literal, line breaks are escaped so the literal fits
+on one line
+*/
+func quoteMySQLString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `''`, "\n", `\n`, "\r", `\r`)
+	return "'" + replacer.Replace(s) + "'"
+}
+
+/*
+* Quote MySQL identifiers and join them with the separator
+ */
+func quoteMySQLIdentifiers(names []string, separator string) string {
+	quoted := make([]string, len(names))
+	for n, name := range names {
+		quoted[n] = quoteMySQLIdentifier(name)
+	}
+	return strings.Join(quoted, separator)
+}
+
+/*
+* Renderer of MySQL CREATE TABLE statements
+ */
+type MySQLRenderer struct {
+	/** server version to render for, e.g. MySQL 5.7 or MariaDB 10.6; nil
+	for the version of the tables */
+	Target *TargetVersion
+	/** foreign keys to leave out, e.g. to add them after all tables */
+	skippedForeignKeys map[*ForeignKey]bool
+}
+
+func (r *MySQLRenderer) Name() string {
+	return "mysql"
+}
+
+/*
+* Render the CREATE TABLE statement of a table from its columns, indexes,
+foreign keys, check constraints, table options and partitioning, without
+terminator.
+@param[in]	table	table schema
+@param[in]	opts	options, default NewRenderOptions
+@return CREATE TABLE statement
+*/
+func (r *MySQLRenderer) RenderTable(table *TableSchema, opts *RenderOptions) string {
+	statement, _ := r.RenderTableWithWarnings(table, opts)
+	return statement
+}
+
+/*
+* Render the CREATE TABLE statement of a table like RenderTable, with the
+warnings about the constructs that were rewritten or removed for Target
+@param[in]	table	table schema
+@param[in]	opts	options, default NewRenderOptions
+@return CREATE TABLE statement, warnings
+*/
+func (r *MySQLRenderer) RenderTableWithWarnings(table *TableSchema, opts *RenderOptions) (string, []string) {
+	if opts == nil {
+		opts = NewRenderOptions()
+	}
+	statement, warnings := r.renderTableWithoutPartitioning(table, opts)
+	if table.Partitioning != nil {
+		statement += "\n" + table.Partitioning.GetDefinition()
+	}
+	return statement, warnings
+}
+
+/*
+* Render the CREATE TABLE statement of a table like RenderTable, without the
+partitioning
+*/
+func (r *MySQLRenderer) renderTableWithoutPartitioning(table *TableSchema, opts *RenderOptions) (
+	string, []string) {
+	name := quoteMySQLIdentifier(table.Name)
+	if opts.IncludeSchemaName && table.SchemaName != "" {
+		name = quoteMySQLIdentifier(table.SchemaName) + "." + name
+	}
+	var definitions, warnings []string
+	collation := table.Collation
+	if r.Target != nil {
+		converted := r.Target.convertTable(table, r.getForeignKeys(table))
+		definitions, collation, warnings = converted.definitions, converted.collation, converted.warnings
+	} else {
+		definitions = make([]string, 0)
+		for _, column := range table.GetUserColumns() {
+			definitions = append(definitions, column.GetDefinition())
+		}
+		for _, index := range table.GetUserIndexes() {
+			definitions = append(definitions, index.GetDefinition())
+		}
+		for _, fk := range r.getForeignKeys(table) {
+			definitions = append(definitions, fk.GetDefinition())
+		}
+		for _, cc := range table.CheckConstraints {
+			definitions = append(definitions, cc.GetDefinition())
+		}
+	}
+	statement := fmt.Sprintf("CREATE TABLE %s%s (\n  %s\n) ENGINE=%s", opts.getIfNotExists(), name,
+		strings.Join(definitions, ",\n  "), table.Engine)
+	if collation != nil {
+		statement += fmt.Sprintf(" DEFAULT CHARSET=%s COLLATE=%s", collation.CharsetName, collation.Name)
+	}
+	if table.Comment != "" {
+		statement += " COMMENT = " + quoteMySQLString(table.Comment)
+	}
+	return statement, warnings
+}
+
+/*
+* Get the foreign keys of a table that are rendered
+ */
+func (r *MySQLRenderer) getForeignKeys(table *TableSchema) []*ForeignKey {
+	foreignKeys := make([]*ForeignKey, 0, len(table.ForeignKeys))
+	for _, fk := range table.ForeignKeys {
+		if !r.skippedForeignKeys[fk] {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	return foreignKeys
+}
+
+/*
+* Get the warnings of the tables that do not belong to a single table, e.g.
+foreign keys that the target rejects, by table
+*/
+func (r *MySQLRenderer) getScriptWarnings(tables []*TableSchema) map[*TableSchema][]string {
+	if r.Target == nil {
+		return map[*TableSchema][]string{}
+	}
+	return r.Target.getForeignKeyWarnings(tables)
+}
+
+/*
+* Write warnings as SQL comments
+ */
+func writeWarnings(b *strings.Builder, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(b, "-- WARNING: %s\n", warning)
+	}
+}
+
+/*
+* Render the tables as a MySQL script, foreign key checks are disabled so
+the tables can be created in any order. With Target, the warnings precede
+the statement of their table as comments.
+*/
+func (r *MySQLRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	if opts == nil {
+		opts = NewRenderOptions()
+	}
+	var b strings.Builder
+	if r.Target != nil {
+		fmt.Fprintf(&b, "-- Target: %s\n\n", r.Target)
+	}
+	hasForeignKeys := false
+	for _, table := range tables {
+		hasForeignKeys = hasForeignKeys || len(table.ForeignKeys) != 0
+	}
+	if hasForeignKeys {
+		fmt.Fprintf(&b, "SET FOREIGN_KEY_CHECKS=0%s\n\n", opts.Terminator)
+	}
+	if opts.CreateSchemas && opts.IncludeSchemaName {
+		for _, name := range getSchemaNames(tables) {
+			fmt.Fprintf(&b, "CREATE DATABASE IF NOT EXISTS %s%s\n", quoteMySQLIdentifier(name), opts.Terminator)
+		}
+		b.WriteString("\n")
+	}
+	scriptWarnings := r.getScriptWarnings(tables)
+	for _, table := range tables {
+		statement, warnings := r.RenderTableWithWarnings(table, opts)
+		writeWarnings(&b, append(warnings, scriptWarnings[table]...))
+		fmt.Fprintf(&b, "%s%s\n\n", statement, opts.Terminator)
+	}
+	if hasForeignKeys {
+		fmt.Fprintf(&b, "SET FOREIGN_KEY_CHECKS=1%s\n", opts.Terminator)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
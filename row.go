@@ -0,0 +1,112 @@
+package ibd2schema
+
+import (
+	"fmt"
+)
+
+/*
+* Check if the record is delete marked.
+ */
+func (rec *Record) IsDeleteMarked() bool {
+	return rec.InfoBits&REC_INFO_DELETED_FLAG != 0
+}
+
+/*
+* Get the full stored bytes of a field, including the externally stored
+part.
+@param[in]	page	page of the record
+@param[in]	rec	record
+@param[in]	n	field number
+@return stored bytes, nil if the field is SQL NULL
+*/
+func (ts *TableSpace) GetFieldData(page *Page, rec *Record, n int) (data []byte, err error) {
+	data = rec.GetFieldData(page, n)
+	if data == nil || !rec.Externs[n] {
+		return data, nil
+	}
+	if len(data) < BTR_EXTERN_FIELD_REF_SIZE {
+		return nil, fmt.Errorf("extern field %s is shorter than field reference",
+			rec.Fields[n].Column.Name)
+	}
+	localLen := len(data) - BTR_EXTERN_FIELD_REF_SIZE
+	extern, err := ts.FetchExternData(data[localLen:])
+	if err != nil {
+		return nil, fmt.Errorf("read extern field %s failed, err:%v", rec.Fields[n].Column.Name, err)
+	}
+	full := make([]byte, 0, localLen+len(extern))
+	full = append(full, data[:localLen]...)
+	return append(full, extern...), nil
+}
+
+/*
+* Decode the values of all fields of a record, see DecodeColumnValue for the
+value types.
+@param[in]	page	page of the record
+@param[in]	rec	record
+@return values in the order of the record fields
+*/
+func (ts *TableSpace) DecodeRecord(page *Page, rec *Record) (values []interface{}, err error) {
+	values = make([]interface{}, len(rec.Fields))
+	for n, field := range rec.Fields {
+		data, err := ts.GetFieldData(page, rec, n)
+		if err != nil {
+			return nil, err
+		}
+		values[n], err = DecodeColumnValue(field.Column, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+/*
+* Walk all user records on the leaf level of an index from left to right,
+delete marked records included.
+@param[in]	index		index to walk
+@param[in]	columnCache	columns of the table
+@param[in]	fn		called for each record, stop walking if it returns false
+*/
+func (ts *TableSpace) WalkRecords(index *Index, columnCache ColumnCache,
+	fn func(page *Page, rec *Record) (bool, error)) (err error) {
+	fields, err := index.GetFields(columnCache)
+	if err != nil {
+		return err
+	}
+	nUniq, err := index.GetNUniqInTree(columnCache)
+	if err != nil {
+		return err
+	}
+	return ts.WalkLevel(index, columnCache, 0, func(page *Page) (bool, error) {
+		return ts.walkPageRecords(page, PAGE_NEW_INFIMUM, fields, nUniq, fn)
+	})
+}
+
+/*
+* Walk the user records of a page after the given record.
+@return false if fn stops walking
+*/
+func (ts *TableSpace) walkPageRecords(page *Page, recOffset uint32, fields []*IndexField, nUniq int,
+	fn func(page *Page, rec *Record) (bool, error)) (next bool, err error) {
+	for n := uint16(0); ; n++ {
+		recOffset, err = page.GetNextRec(recOffset)
+		if err != nil {
+			return false, err
+		}
+		if recOffset == PAGE_NEW_SUPREMUM {
+			return true, nil
+		}
+		if n > page.NRecs {
+			return false, fmt.Errorf("page %d has more records than PAGE_N_RECS %d",
+				page.PageNum, page.NRecs)
+		}
+		rec, err := page.ParseRecord(recOffset, fields, nUniq)
+		if err != nil {
+			return false, err
+		}
+		next, err = fn(page, rec)
+		if err != nil || !next {
+			return false, err
+		}
+	}
+}
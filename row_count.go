@@ -0,0 +1,160 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	/** Default number of leaf pages sampled to estimate the row count, same as
+	innodb_stats_persistent_sample_pages */
+	ROW_COUNT_SAMPLE_PAGES = 20
+)
+
+/*
+* Row count of a table, counted from the clustered index without decoding rows
+ */
+type RowCount struct {
+	IndexName string
+	/** number of rows summed from PAGE_N_RECS of all leaf pages, delete
+	marked rows which are not purged yet are included */
+	ExactRows     uint64
+	LeafPages     uint32
+	ExactDuration time.Duration
+	/** number of rows estimated from the sampled leaf pages */
+	EstimatedRows    uint64
+	SampledPages     uint32
+	EstimateDuration time.Duration
+}
+
+/*
+* Count the records of an index by walking its leaf level and adding up
+PAGE_N_RECS.
+@param[in]	index		index to count
+@param[in]	columnCache	columns of the table
+@return number of records and number of leaf pages
+*/
+func (ts *TableSpace) CountRows(index *Index, columnCache ColumnCache) (
+	rows uint64, leafPages uint32, err error) {
+	err = ts.WalkLevel(index, columnCache, 0, func(page *Page) (bool, error) {
+		rows += uint64(page.NRecs)
+		leafPages++
+		return true, nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return rows, leafPages, nil
+}
+
+/*
+* Estimate the records of an index from a sample of its leaf pages. The leaf
+pages are taken from the leaf segment, so the B-tree is not walked. The
+segment also holds the BLOB pages of externally stored columns, so pages
+are probed at evenly spread positions, doubling the density, until enough
+leaf pages of the index are found. The number of leaf pages is estimated
+from the share of probed pages that are leaf pages of the index.
+@param[in]	index		index to estimate
+@param[in]	samplePages	number of leaf pages to sample
+@return estimated number of records and number of sampled pages
+*/
+func (ts *TableSpace) EstimateRows(index *Index, samplePages uint32) (
+	rows uint64, sampledPages uint32, err error) {
+	if index.RootPageNum == FIL_NULL {
+		return 0, 0, fmt.Errorf("root page of index %s not found", index.Name)
+	}
+	if samplePages == 0 {
+		samplePages = ROW_COUNT_SAMPLE_PAGES
+	}
+	root, err := ts.FetchIndexPage(index.RootPageNum, index.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if root.PageLevel == 0 {
+		return uint64(root.NRecs), 1, nil
+	}
+	ss, err := ts.GetSegmentStats(root.GetFsegHeader(PAGE_BTR_SEG_LEAF))
+	if err != nil {
+		return 0, 0, fmt.Errorf("read leaf segment of index %s failed, err:%v", index.Name, err)
+	}
+	usedPages := uint64(len(ss.PageNums))
+	if usedPages == 0 {
+		return 0, 0, fmt.Errorf("leaf segment of index %s is empty", index.Name)
+	}
+	var sampledRecs, probedPages uint64
+	probed := make(map[uint64]bool)
+	for probes := uint64(samplePages); sampledPages < samplePages && probedPages < usedPages; probes *= 2 {
+		if probes > usedPages {
+			probes = usedPages
+		}
+		for n := uint64(0); n < probes && sampledPages < samplePages; n++ {
+			position := n * usedPages / probes
+			if probed[position] {
+				continue
+			}
+			probed[position] = true
+			probedPages++
+			page, err := ts.FetchPage(ss.PageNums[position])
+			if err != nil {
+				return 0, 0, fmt.Errorf("fetch page %d of index %s failed, err:%v",
+					ss.PageNums[position], index.Name, err)
+			}
+			page.GetPageType()
+			if page.PageType != FIL_PAGE_INDEX {
+				continue
+			}
+			page.GetIndexID()
+			page.GetPageLevel()
+			if page.IndexID != index.ID || page.PageLevel != 0 {
+				continue
+			}
+			page.GetNRecs()
+			sampledRecs += uint64(page.NRecs)
+			sampledPages++
+		}
+	}
+	if sampledPages == 0 {
+		return 0, 0, fmt.Errorf("no leaf page of index %s found in its leaf segment", index.Name)
+	}
+	leafPages := usedPages * uint64(sampledPages) / probedPages
+	rows = sampledRecs * leafPages / uint64(sampledPages)
+	return rows, sampledPages, nil
+}
+
+/*
+* Count the rows of the tables in the tablespace, both exactly and from a
+sample of leaf pages.
+@param[in]	samplePages	number of leaf pages to sample, 0 for default
+*/
+func (ts *TableSpace) DumpRowCounts(samplePages uint32) (err error) {
+	if ts.TableSchemas == nil {
+		err = ts.DumpSchemas()
+		if err != nil {
+			return err
+		}
+	}
+	for _, tableSchema := range ts.TableSchemas {
+		if tableSchema.Hidden != HT_VISIBLE {
+			continue
+		}
+		index, err := GetClusteredIndex(tableSchema.Indexes, tableSchema.Columns)
+		if err != nil {
+			return err
+		}
+		rc := &RowCount{IndexName: index.Name}
+		start := time.Now()
+		rc.ExactRows, rc.LeafPages, err = ts.CountRows(index, tableSchema.Columns)
+		if err != nil {
+			return err
+		}
+		rc.ExactDuration = time.Since(start)
+		start = time.Now()
+		rc.EstimatedRows, rc.SampledPages, err = ts.EstimateRows(index, samplePages)
+		if err != nil {
+			return err
+		}
+		rc.EstimateDuration = time.Since(start)
+		tableSchema.RowCount = rc
+	}
+	return nil
+}
@@ -0,0 +1,661 @@
+package ibd2schema
+
+import (
+	"context"
+	"fmt"
+)
+
+/* https://dev.mysql.com/doc/refman/8.0/en/comparison-operators.html */
+type PredicateOp int
+
+const (
+	PRED_EQ PredicateOp = iota + 1
+	PRED_NE
+	PRED_LT
+	PRED_LE
+	PRED_GT
+	PRED_GE
+	PRED_IN
+	PRED_IS_NULL
+	PRED_IS_NOT_NULL
+)
+
+func (op PredicateOp) String() string {
+	switch op {
+	case PRED_EQ:
+		return "="
+	case PRED_NE:
+		return "!="
+	case PRED_LT:
+		return "<"
+	case PRED_LE:
+		return "<="
+	case PRED_GT:
+		return ">"
+	case PRED_GE:
+		return ">="
+	case PRED_IN:
+		return "IN"
+	case PRED_IS_NULL:
+		return "IS NULL"
+	case PRED_IS_NOT_NULL:
+		return "IS NOT NULL"
+	}
+	return "unknown predicate operator"
+}
+
+/*
+* Condition on a column value, see CompareValues for the accepted values.
+Like in SQL, a NULL column value only matches IS NULL.
+*/
+type Predicate struct {
+	Column string
+	Op     PredicateOp
+	/** value to compare with, unused for IN, IS NULL and IS NOT NULL */
+	Value interface{}
+	/** values of IN */
+	Values []interface{}
+}
+
+/*
+* Bound of a primary key range. Values are given for a leading prefix of the
+primary key columns.
+*/
+type KeyBound struct {
+	Values    []interface{}
+	Inclusive bool
+}
+
+type ScanOptions struct {
+	/** lower bound of the primary key, nil to start from the first row */
+	Lower *KeyBound
+	/** upper bound of the primary key, nil to read up to the last row */
+	Upper *KeyBound
+	/** all predicates must match */
+	Predicates []*Predicate
+	/** names of the columns to return, nil for all stored columns */
+	Columns []string
+	/** return delete marked rows, which are purged or uncommitted deletes */
+	IncludeDeleted bool
+	/** maximum number of rows to return, 0 for no limit */
+	Limit uint64
+}
+
+/*
+* Get the options of a scan of the rows with the given primary key prefix.
+ */
+func NewKeyScanOptions(values ...interface{}) *ScanOptions {
+	return &ScanOptions{
+		Lower: &KeyBound{Values: values, Inclusive: true},
+		Upper: &KeyBound{Values: values, Inclusive: true},
+	}
+}
+
+type Row struct {
+	Columns      []*Column
+	Values       []interface{}
+	DeleteMarked bool
+}
+
+/*
+* Get the value of a column of the row.
+@param[in]	name	column name
+@return value and true if the row has the column
+*/
+func (r *Row) Get(name string) (value interface{}, ok bool) {
+	for n, column := range r.Columns {
+		if column.Name == name {
+			return r.Values[n], true
+		}
+	}
+	return nil, false
+}
+
+/*
+* Predicate bound to a field of the clustered index record.
+ */
+type scanPredicate struct {
+	*Predicate
+	column *Column
+	pos    int
+}
+
+/*
+* Iterator over the rows of a scan, used like database/sql.Rows:
+
+	for it.Next() {
+		row := it.Row()
+	}
+	if err := it.Err(); err != nil {
+	}
+*/
+type RowIterator struct {
+	ctx         context.Context
+	ts          *TableSpace
+	opts        *ScanOptions
+	index       *Index
+	fields      []*IndexField
+	nUniq       int
+	predicates  []*scanPredicate
+	columns     []*Column
+	columnPos   []int
+	lower       []interface{}
+	upper       []interface{}
+	nSeekFields int
+	page        *Page
+	recOffset   uint32
+	nPageRecs   uint16
+	row         *Row
+	nRows       uint64
+	done        bool
+	err         error
+	/** values of the current record, decoded on demand */
+	values  []interface{}
+	decoded []bool
+}
+
+/*
+* Scan the rows of a table in primary key order. The clustered index is
+descended to the lower bound of the primary key range instead of reading
+all leaf pages. Leading string key parts of non-binary collations are not
+used for the descent, they are only compared approximately with
+CompareValues like the predicates. Bounds on prefix key parts are compared
+on the prefix.
+@param[in]	ctx		context to cancel the scan
+@param[in]	tableSchema	table schema from the SDI
+@param[in]	opts		scan options, nil to read all rows
+@return row iterator
+*/
+func (ts *TableSpace) Scan(ctx context.Context, tableSchema *TableSchema, opts *ScanOptions) (
+	it *RowIterator, err error) {
+	if opts == nil {
+		opts = &ScanOptions{}
+	}
+	columnCache := tableSchema.Columns
+	index, err := GetClusteredIndex(tableSchema.Indexes, columnCache)
+	if err != nil {
+		return nil, err
+	}
+	it, err = ts.newIndexIterator(ctx, index, columnCache, opts)
+	if err != nil {
+		return nil, err
+	}
+	fields := it.fields
+	/* position of each column's full value in the record */
+	positions := make(map[string]int)
+	for pos, field := range fields {
+		if field.PrefixLen == 0 && !field.Column.isSystemColumn() {
+			positions[field.Column.Name] = pos
+		}
+	}
+	if opts.Columns == nil {
+		for opx := 0; opx < len(columnCache); opx++ {
+			column, ok := columnCache[opx]
+			if !ok {
+				return nil, fmt.Errorf("column %d not found in the column map", opx)
+			}
+			if pos, ok := positions[column.Name]; ok {
+				it.columns = append(it.columns, column)
+				it.columnPos = append(it.columnPos, pos)
+			}
+		}
+	}
+	for _, name := range opts.Columns {
+		pos, ok := positions[name]
+		if !ok {
+			return nil, fmt.Errorf("column %s is not stored in index %s", name, index.Name)
+		}
+		it.columns = append(it.columns, fields[pos].Column)
+		it.columnPos = append(it.columnPos, pos)
+	}
+	for _, predicate := range opts.Predicates {
+		pos, ok := positions[predicate.Column]
+		if !ok {
+			return nil, fmt.Errorf("column %s of predicate is not stored in index %s",
+				predicate.Column, index.Name)
+		}
+		if predicate.Op < PRED_EQ || predicate.Op > PRED_IS_NOT_NULL {
+			return nil, fmt.Errorf("unknown operator %d of predicate on column %s",
+				predicate.Op, predicate.Column)
+		}
+		it.predicates = append(it.predicates,
+			&scanPredicate{Predicate: predicate, column: fields[pos].Column, pos: pos})
+	}
+	return it, nil
+}
+
+/*
+* Get an iterator over the leaf records of any index in key order, without
+columns and predicates. The bounds are given in the order of the index
+fields, see nextRecord.
+@param[in]	ctx		context to cancel the scan
+@param[in]	index		index to read
+@param[in]	columnCache	columns of the table
+@param[in]	opts		scan options
+@return record iterator
+*/
+func (ts *TableSpace) newIndexIterator(ctx context.Context, index *Index, columnCache ColumnCache,
+	opts *ScanOptions) (it *RowIterator, err error) {
+	if index.RootPageNum == FIL_NULL {
+		return nil, fmt.Errorf("root page of index %s not found", index.Name)
+	}
+	fields, err := index.GetFields(columnCache)
+	if err != nil {
+		return nil, err
+	}
+	nUniq, err := index.GetNUniqInTree(columnCache)
+	if err != nil {
+		return nil, err
+	}
+	it = &RowIterator{
+		ctx:     ctx,
+		ts:      ts,
+		opts:    opts,
+		index:   index,
+		fields:  fields,
+		nUniq:   nUniq,
+		values:  make([]interface{}, len(fields)),
+		decoded: make([]bool, len(fields)),
+	}
+	if it.lower, err = it.getBoundValues(opts.Lower); err != nil {
+		return nil, err
+	}
+	if it.upper, err = it.getBoundValues(opts.Upper); err != nil {
+		return nil, err
+	}
+	for it.nSeekFields < nUniq && fields[it.nSeekFields].Column.hasExactOrder() {
+		it.nSeekFields++
+	}
+	return it, nil
+}
+
+/*
+* Check the values of a key bound and cut them to the key part prefixes.
+ */
+func (it *RowIterator) getBoundValues(bound *KeyBound) (values []interface{}, err error) {
+	if bound == nil {
+		return nil, nil
+	}
+	if len(bound.Values) > it.nUniq {
+		return nil, fmt.Errorf("key bound has %d values, primary key has %d columns",
+			len(bound.Values), it.nUniq)
+	}
+	values = make([]interface{}, len(bound.Values))
+	for n, value := range bound.Values {
+		field := it.fields[n]
+		if field.PrefixLen != 0 {
+			value = GetValuePrefix(field.Column, value, field.PrefixLen)
+		}
+		values[n] = value
+	}
+	return values, nil
+}
+
+/*
+* Advance to the next matching row.
+@return false when the scan is finished, cancelled or failed, see Err
+*/
+func (it *RowIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	it.row = nil
+	if it.opts.Limit != 0 && it.nRows >= it.opts.Limit {
+		it.done = true
+		return false
+	}
+	row, err := it.next()
+	if err != nil {
+		it.err = err
+	}
+	if row == nil {
+		it.done = true
+		return false
+	}
+	it.row = row
+	it.nRows++
+	return true
+}
+
+/*
+* Get the current row, valid until the next call of Next.
+ */
+func (it *RowIterator) Row() *Row {
+	return it.row
+}
+
+/*
+* Get the columns of the returned rows.
+ */
+func (it *RowIterator) Columns() []*Column {
+	return it.columns
+}
+
+/*
+* Get the error that stopped the scan, including context cancellation.
+ */
+func (it *RowIterator) Err() error {
+	return it.err
+}
+
+/*
+* Stop the scan.
+ */
+func (it *RowIterator) Close() error {
+	it.done = true
+	it.row = nil
+	return nil
+}
+
+func (it *RowIterator) next() (row *Row, err error) {
+	page, rec, err := it.nextRecord()
+	if err != nil || rec == nil {
+		return nil, err
+	}
+	row = &Row{
+		Columns:      it.columns,
+		Values:       make([]interface{}, len(it.columns)),
+		DeleteMarked: rec.IsDeleteMarked(),
+	}
+	for n, pos := range it.columnPos {
+		if row.Values[n], err = it.getValue(page, rec, pos); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+/*
+* Advance to the next record in the bounds that matches the predicates.
+@return page and record, nil record when the scan is finished
+*/
+func (it *RowIterator) nextRecord() (page *Page, rec *Record, err error) {
+	if it.page == nil {
+		if err = it.seek(); err != nil {
+			return nil, nil, err
+		}
+	}
+	for {
+		if err = it.ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		recOffset, err := it.page.GetNextRec(it.recOffset)
+		if err != nil {
+			return nil, nil, err
+		}
+		if recOffset == PAGE_NEW_SUPREMUM {
+			if it.page.NextPageNum == FIL_NULL {
+				return nil, nil, nil
+			}
+			prevPageNum := it.page.PageNum
+			it.page, err = it.ts.FetchIndexPage(it.page.NextPageNum, it.index.ID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if it.page.PageLevel != 0 || it.page.PrevPageNum != prevPageNum {
+				return nil, nil, fmt.Errorf("page %d is not linked to page %d on leaf level",
+					it.page.PageNum, prevPageNum)
+			}
+			it.recOffset, it.nPageRecs = PAGE_NEW_INFIMUM, 0
+			continue
+		}
+		it.recOffset = recOffset
+		it.nPageRecs++
+		if it.nPageRecs > it.page.NRecs {
+			return nil, nil, fmt.Errorf("page %d has more records than PAGE_N_RECS %d",
+				it.page.PageNum, it.page.NRecs)
+		}
+		rec, err := it.page.ParseRecord(recOffset, it.fields, it.nUniq)
+		if err != nil {
+			return nil, nil, err
+		}
+		for n := range it.decoded {
+			it.decoded[n] = false
+		}
+		/* key order decides on the bounds before delete marks */
+		cmp, err := it.compareKey(it.page, rec, it.upper, it.nSeekFields)
+		if err != nil {
+			return nil, nil, err
+		}
+		if it.upper != nil && (cmp > 0 || (cmp == 0 && !it.opts.Upper.Inclusive &&
+			len(it.upper) <= it.nSeekFields)) {
+			return nil, nil, nil
+		}
+		if rec.IsDeleteMarked() && !it.opts.IncludeDeleted {
+			continue
+		}
+		match, err := it.matchBounds(it.page, rec)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !match {
+			continue
+		}
+		match, err = it.matchPredicates(it.page, rec)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !match {
+			continue
+		}
+		return it.page, rec, nil
+	}
+}
+
+/*
+* Decode a field of the current record once.
+ */
+func (it *RowIterator) getValue(page *Page, rec *Record, n int) (value interface{}, err error) {
+	if it.decoded[n] {
+		return it.values[n], nil
+	}
+	var data []byte
+	if page.PageLevel == 0 {
+		data, err = it.ts.GetFieldData(page, rec, n)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		data = rec.GetFieldData(page, n)
+	}
+	value, err = DecodeColumnValue(it.fields[n].Column, data)
+	if err != nil {
+		return nil, err
+	}
+	it.values[n], it.decoded[n] = value, true
+	return value, nil
+}
+
+/*
+* Compare the key of a record with a bound in index order.
+@param[in]	page		page of the record
+@param[in]	rec		record
+@param[in]	bound		values of the key prefix
+@param[in]	nFields		maximum number of key parts to compare
+@return negative, zero or positive as the record key is less than, equal to
+or greater than the bound
+*/
+func (it *RowIterator) compareKey(page *Page, rec *Record, bound []interface{}, nFields int) (
+	cmp int, err error) {
+	if rec.InfoBits&REC_INFO_MIN_REC_FLAG != 0 {
+		return -1, nil
+	}
+	for n := 0; n < len(bound) && n < nFields; n++ {
+		value, err := it.getValue(page, rec, n)
+		if err != nil {
+			return 0, err
+		}
+		cmp, err = CompareValues(it.fields[n].Column, value, bound[n])
+		if err != nil {
+			return 0, err
+		}
+		if it.fields[n].Descending {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp, nil
+		}
+	}
+	return 0, nil
+}
+
+/*
+* Check that the full key of the current record is in the scan range.
+ */
+func (it *RowIterator) matchBounds(page *Page, rec *Record) (match bool, err error) {
+	if it.lower != nil {
+		cmp, err := it.compareKey(page, rec, it.lower, len(it.lower))
+		if err != nil {
+			return false, err
+		}
+		if cmp < 0 || (cmp == 0 && !it.opts.Lower.Inclusive) {
+			return false, nil
+		}
+	}
+	if it.upper != nil {
+		cmp, err := it.compareKey(page, rec, it.upper, len(it.upper))
+		if err != nil {
+			return false, err
+		}
+		if cmp > 0 || (cmp == 0 && !it.opts.Upper.Inclusive) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+/*
+* Check the predicates on the current record.
+ */
+func (it *RowIterator) matchPredicates(page *Page, rec *Record) (match bool, err error) {
+	for _, predicate := range it.predicates {
+		value, err := it.getValue(page, rec, predicate.pos)
+		if err != nil {
+			return false, err
+		}
+		match, err = predicate.match(value)
+		if err != nil {
+			return false, err
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (p *scanPredicate) match(value interface{}) (match bool, err error) {
+	switch p.Op {
+	case PRED_IS_NULL:
+		return value == nil, nil
+	case PRED_IS_NOT_NULL:
+		return value != nil, nil
+	}
+	if value == nil {
+		return false, nil
+	}
+	if p.Op == PRED_IN {
+		for _, v := range p.Values {
+			if v == nil {
+				continue
+			}
+			cmp, err := CompareValues(p.column, value, v)
+			if err != nil {
+				return false, err
+			}
+			if cmp == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	if p.Value == nil {
+		return false, nil
+	}
+	cmp, err := CompareValues(p.column, value, p.Value)
+	if err != nil {
+		return false, err
+	}
+	switch p.Op {
+	case PRED_EQ:
+		return cmp == 0, nil
+	case PRED_NE:
+		return cmp != 0, nil
+	case PRED_LT:
+		return cmp < 0, nil
+	case PRED_LE:
+		return cmp <= 0, nil
+	case PRED_GT:
+		return cmp > 0, nil
+	case PRED_GE:
+		return cmp >= 0, nil
+	}
+	return false, fmt.Errorf("unknown predicate operator %d", p.Op)
+}
+
+/*
+* Descend the index to the leaf page that holds the first record not less
+than the lower bound, and position before the first record of the page.
+*/
+func (it *RowIterator) seek() (err error) {
+	page, err := it.ts.FetchIndexPage(it.index.RootPageNum, it.index.ID)
+	if err != nil {
+		return err
+	}
+	for page.PageLevel > 0 {
+		if err = it.ctx.Err(); err != nil {
+			return err
+		}
+		childPageNum := uint32(FIL_NULL)
+		recOffset := PAGE_NEW_INFIMUM
+		for {
+			recOffset, err = page.GetNextRec(recOffset)
+			if err != nil {
+				return err
+			}
+			if recOffset == PAGE_NEW_SUPREMUM {
+				break
+			}
+			rec, err := page.ParseRecord(recOffset, it.fields, it.nUniq)
+			if err != nil {
+				return err
+			}
+			if rec.Status != REC_STATUS_NODE_PTR {
+				return fmt.Errorf("record at %d of non-leaf page %d is not a node pointer",
+					recOffset, page.PageNum)
+			}
+			for n := range it.decoded {
+				it.decoded[n] = false
+			}
+			/* the child of the last node pointer less than the lower
+			bound may hold rows equal to it */
+			if childPageNum != FIL_NULL && it.lower != nil {
+				cmp, err := it.compareKey(page, rec, it.lower, it.nSeekFields)
+				if err != nil {
+					return err
+				}
+				if cmp >= 0 {
+					break
+				}
+			}
+			childPageNum = rec.ChildPageNum
+			if it.lower == nil || it.nSeekFields == 0 {
+				break
+			}
+		}
+		if childPageNum == FIL_NULL {
+			return fmt.Errorf("non-leaf page %d of index %s is empty", page.PageNum, it.index.Name)
+		}
+		parentLevel := page.PageLevel
+		page, err = it.ts.FetchIndexPage(childPageNum, it.index.ID)
+		if err != nil {
+			return err
+		}
+		if page.PageLevel+1 != parentLevel {
+			return fmt.Errorf("page level not match, parentLevel:%d, childLevel:%d",
+				parentLevel, page.PageLevel)
+		}
+	}
+	it.page, it.recOffset, it.nPageRecs = page, PAGE_NEW_INFIMUM, 0
+	return nil
+}
@@ -0,0 +1,877 @@
+package ibd2schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DiffAction string
+
+const (
+	DIFF_ADDED   DiffAction = "added"
+	DIFF_REMOVED DiffAction = "removed"
+	DIFF_CHANGED DiffAction = "changed"
+	/** renamed, possibly with other changes */
+	DIFF_RENAMED DiffAction = "renamed"
+)
+
+type DiffObject string
+
+const (
+	DIFF_OBJECT_TABLE            DiffObject = "table"
+	DIFF_OBJECT_COLUMN           DiffObject = "column"
+	DIFF_OBJECT_INDEX            DiffObject = "index"
+	DIFF_OBJECT_FOREIGN_KEY      DiffObject = "foreign_key"
+	DIFF_OBJECT_CHECK_CONSTRAINT DiffObject = "check_constraint"
+	DIFF_OBJECT_PARTITION        DiffObject = "partition"
+)
+
+const (
+	/** matched by the InnoDB id, index id or column physical position */
+	DIFF_MATCHED_BY_SE_ID = "se_id"
+	DIFF_MATCHED_BY_NAME  = "name"
+	/** matched by the ordinal position of a column of the same InnoDB table */
+	DIFF_MATCHED_BY_POSITION = "position"
+	/** matched by an identical definition under another name */
+	DIFF_MATCHED_BY_DEFINITION = "definition"
+)
+
+const (
+	/** share of columns with the same name and type, of all columns of both
+	tables, for which a removed and an added table are a rename */
+	DIFF_TABLE_MIN_SIMILARITY = 0.8
+)
+
+/*
+* Attribute of a schema object, the old value is empty for added objects and
+the new value is empty for removed objects.
+*/
+type AttributeDiff struct {
+	Name string `json:"name"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+type ObjectDiff struct {
+	Object DiffObject `json:"object"`
+	Action DiffAction `json:"action"`
+	Name   string     `json:"name"`
+	/** old name of a renamed object */
+	OldName string `json:"old_name,omitempty"`
+	/** how the old and new object were matched, empty if added or removed */
+	MatchedBy  string           `json:"matched_by,omitempty"`
+	Attributes []*AttributeDiff `json:"attributes,omitempty"`
+}
+
+type TableDiff struct {
+	SchemaName string `json:"schema"`
+	Name       string `json:"table"`
+	/** old names of a renamed table or a table in another schema */
+	OldSchemaName string        `json:"old_schema,omitempty"`
+	OldName       string        `json:"old_table,omitempty"`
+	Diffs         []*ObjectDiff `json:"diffs"`
+}
+
+/*
+* Schema object prepared for matching and comparing
+ */
+type diffItem struct {
+	name string
+	/** stable id assigned by InnoDB, empty if unknown */
+	seID string
+	/** ordinal position key, used if the items have no other stable id */
+	positionKey string
+	attributes  []*AttributeDiff
+	position    int
+}
+
+/*
+* Get a comparable definition of the item, all attributes except the name.
+ */
+func (item *diffItem) definition() string {
+	values := make([]string, len(item.attributes))
+	for n, attribute := range item.attributes {
+		values[n] = attribute.Name + "=" + attribute.New
+	}
+	return strings.Join(values, "\x1f")
+}
+
+func newDiffItem(name, seID string, position int, attributes ...string) *diffItem {
+	item := &diffItem{name: name, seID: seID, position: position}
+	for n := 0; n+1 < len(attributes); n += 2 {
+		item.attributes = append(item.attributes,
+			&AttributeDiff{Name: attributes[n], New: attributes[n+1]})
+	}
+	return item
+}
+
+type diffPair struct {
+	old       *diffItem
+	new       *diffItem
+	matchedBy string
+}
+
+/*
+* Match old and new items by SE id, then by name, then by position key,
+then by definition.
+@return matched pairs in the new order, removed items in the old order and
+added items in the new order
+*/
+func matchDiffItems(oldItems, newItems []*diffItem) (pairs []*diffPair, removed, added []*diffItem) {
+	oldMatched := make([]bool, len(oldItems))
+	newMatched := make([]*diffPair, len(newItems))
+	match := func(matchedBy string, key func(item *diffItem) string) {
+		keys := make(map[string]int)
+		for n, item := range oldItems {
+			if k := key(item); !oldMatched[n] && k != "" {
+				if _, ok := keys[k]; !ok {
+					keys[k] = n
+				}
+			}
+		}
+		for n, item := range newItems {
+			if newMatched[n] != nil {
+				continue
+			}
+			k := key(item)
+			o, ok := keys[k]
+			if k == "" || !ok || oldMatched[o] {
+				continue
+			}
+			oldMatched[o] = true
+			newMatched[n] = &diffPair{old: oldItems[o], new: item, matchedBy: matchedBy}
+		}
+	}
+	match(DIFF_MATCHED_BY_SE_ID, func(item *diffItem) string { return item.seID })
+	match(DIFF_MATCHED_BY_NAME, func(item *diffItem) string { return strings.ToLower(item.name) })
+	match(DIFF_MATCHED_BY_POSITION, func(item *diffItem) string { return item.positionKey })
+	match(DIFF_MATCHED_BY_DEFINITION, (*diffItem).definition)
+	for n, item := range newItems {
+		if newMatched[n] != nil {
+			pairs = append(pairs, newMatched[n])
+		} else {
+			added = append(added, item)
+		}
+	}
+	for n, item := range oldItems {
+		if !oldMatched[n] {
+			removed = append(removed, item)
+		}
+	}
+	return pairs, removed, added
+}
+
+/*
+* Get the positions of the pairs that keep their relative order, the
+longest increasing subsequence of the old positions in the new order.
+*/
+func getUnmovedPairs(pairs []*diffPair) map[*diffPair]bool {
+	/* tails[k] is the index of the smallest tail of subsequences of length k+1 */
+	tails := make([]int, 0)
+	prev := make([]int, len(pairs))
+	for n, pair := range pairs {
+		k := sort.Search(len(tails), func(i int) bool {
+			return pairs[tails[i]].old.position >= pair.old.position
+		})
+		prev[n] = -1
+		if k > 0 {
+			prev[n] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, n)
+		} else {
+			tails[k] = n
+		}
+	}
+	unmoved := make(map[*diffPair]bool)
+	if len(tails) == 0 {
+		return unmoved
+	}
+	for n := tails[len(tails)-1]; n != -1; n = prev[n] {
+		unmoved[pairs[n]] = true
+	}
+	return unmoved
+}
+
+/*
+* Compare the items of one kind of schema object.
+@param[in]	object		kind of the objects
+@param[in]	oldItems	items of the old schema
+@param[in]	newItems	items of the new schema
+@param[in]	checkPosition	report objects that changed their relative order
+@return differences of the objects
+*/
+func diffItems(object DiffObject, oldItems, newItems []*diffItem, checkPosition bool) (
+	diffs []*ObjectDiff) {
+	pairs, removed, added := matchDiffItems(oldItems, newItems)
+	var unmoved map[*diffPair]bool
+	if checkPosition {
+		unmoved = getUnmovedPairs(pairs)
+	}
+	for _, item := range removed {
+		diff := &ObjectDiff{Object: object, Action: DIFF_REMOVED, Name: item.name}
+		for _, attribute := range item.attributes {
+			if attribute.New != "" {
+				diff.Attributes = append(diff.Attributes,
+					&AttributeDiff{Name: attribute.Name, Old: attribute.New})
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	for _, pair := range pairs {
+		diff := &ObjectDiff{Object: object, Action: DIFF_CHANGED, Name: pair.new.name,
+			MatchedBy: pair.matchedBy}
+		if pair.old.name != pair.new.name {
+			diff.Action = DIFF_RENAMED
+			diff.OldName = pair.old.name
+		}
+		for n, attribute := range pair.new.attributes {
+			old := pair.old.attributes[n].New
+			if old != attribute.New {
+				diff.Attributes = append(diff.Attributes,
+					&AttributeDiff{Name: attribute.Name, Old: old, New: attribute.New})
+			}
+		}
+		if checkPosition && !unmoved[pair] {
+			diff.Attributes = append(diff.Attributes, &AttributeDiff{Name: "position",
+				Old: strconv.Itoa(pair.old.position + 1), New: strconv.Itoa(pair.new.position + 1)})
+		}
+		if diff.Action == DIFF_RENAMED || len(diff.Attributes) != 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	for _, item := range added {
+		diff := &ObjectDiff{Object: object, Action: DIFF_ADDED, Name: item.name}
+		for _, attribute := range item.attributes {
+			if attribute.New != "" {
+				diff.Attributes = append(diff.Attributes,
+					&AttributeDiff{Name: attribute.Name, New: attribute.New})
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+/*
+* Get the DEFAULT clause value of a column, empty if it has none
+ */
+func (c *Column) getDefault() string {
+	if c.GenerationExpressionUTF8 != "" {
+		return ""
+	}
+	if c.DefaultValueNull && c.DefaultValueUTF8Null {
+		return "NULL"
+	}
+	if c.DefaultValueUTF8Null {
+		return ""
+	}
+	if strings.HasPrefix(c.DefaultOption, "(") {
+		return "(" + NormalizeExpression(c.DefaultOption) + ")"
+	}
+	if c.DefaultOption != "" {
+		return c.DefaultOption
+	}
+	return quoteMySQLString(c.DefaultValueUTF8)
+}
+
+/*
+* Get the column type without the display width of integer types, which is
+deprecated and no longer stored since 8.0.19 unless the column is ZEROFILL
+or tinyint(1).
+*/
+func (c *Column) getComparableType() string {
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG:
+	default:
+		return c.ColumnTypeUTF8
+	}
+	if c.IsZerofill || strings.HasPrefix(c.ColumnTypeUTF8, "tinyint(1)") {
+		return c.ColumnTypeUTF8
+	}
+	start := strings.IndexByte(c.ColumnTypeUTF8, '(')
+	end := strings.IndexByte(c.ColumnTypeUTF8, ')')
+	if start < 0 || end < start {
+		return c.ColumnTypeUTF8
+	}
+	return c.ColumnTypeUTF8[:start] + c.ColumnTypeUTF8[end+1:]
+}
+
+/*
+* Check if the column has a character set, collations of other columns are
+not compared.
+*/
+func (c *Column) hasCharset() bool {
+	return c.isString() || c.Type == CT_ENUM || c.Type == CT_SET
+}
+
+/*
+* Get the columns in ordinal order that are defined by the user, excluding
+system columns and hidden columns of functional indexes.
+*/
+func (ts *TableSchema) GetUserColumns() []*Column {
+	columns := make([]*Column, 0, len(ts.Columns))
+	for opx := 0; opx < len(ts.Columns); opx++ {
+		column, ok := ts.Columns[opx]
+		if !ok || (column.isHidden() && !column.isHiddenUser()) {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+/*
+* Get the indexes that are defined by the user, excluding hidden indexes.
+ */
+func (ts *TableSchema) GetUserIndexes() []*Index {
+	indexes := make([]*Index, 0, len(ts.Indexes))
+	for _, index := range ts.Indexes {
+		if !index.Hidden {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+/*
+* Check if the SE ids of two tables can be compared, which is the case when
+both come from the same InnoDB table that was not rebuilt in between.
+*/
+func haveSameSEIDs(oldTable, newTable *TableSchema) bool {
+	return oldTable.SEPrivateID != 0 && oldTable.SEPrivateID == newTable.SEPrivateID
+}
+
+func getColumnDiffItems(table *TableSchema, useSEID bool) []*diffItem {
+	items := make([]*diffItem, 0)
+	for n, c := range table.GetUserColumns() {
+		seID := ""
+		if useSEID {
+			seID = c.SEPrivateData["physical_pos"]
+		}
+		generated := ""
+		if c.GenerationExpressionUTF8 != "" {
+			expression := NormalizeExpression(c.GenerationExpressionUTF8)
+			generated = expression + " STORED"
+			if c.IsVirtual {
+				generated = expression + " VIRTUAL"
+			}
+		}
+		collation := ""
+		if c.hasCharset() {
+			collation = c.Collation.Name
+		}
+		srid := ""
+		if c.SRSID != nil {
+			srid = strconv.FormatUint(uint64(*c.SRSID), 10)
+		}
+		item := newDiffItem(c.Name, seID, n,
+			"type", c.getComparableType(),
+			"nullable", strconv.FormatBool(c.IsNullable),
+			"default", c.getDefault(),
+			"on_update", c.UpdateOption,
+			"auto_increment", strconv.FormatBool(c.IsAutoIncrement),
+			"generated", generated,
+			"collation", collation,
+			"srid", srid,
+			"invisible", strconv.FormatBool(c.isHiddenUser()),
+			"comment", c.Comment,
+		)
+		/*
+			* Before 8.0.29 columns have no physical position, but the columns of
+			an InnoDB table that was not rebuilt can only be renamed or added
+			at the end, so the ordinal position identifies them.
+		*/
+		if useSEID && seID == "" {
+			item.positionKey = strconv.Itoa(n)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func getIndexDiffItems(table *TableSchema, useSEID bool) []*diffItem {
+	items := make([]*diffItem, 0)
+	for n, index := range table.GetUserIndexes() {
+		seID := ""
+		if useSEID && index.ID != 0 {
+			seID = strconv.FormatUint(index.ID, 10)
+		}
+		keyParts := make([]string, len(index.KeyParts))
+		for i, keyPart := range index.KeyParts {
+			keyParts[i] = keyPart.String()
+			if expression := keyPart.GetExpression(); expression != "" {
+				keyParts[i] = strings.Replace(keyParts[i], expression,
+					NormalizeExpression(expression), 1)
+			}
+		}
+		algorithm := ""
+		if index.IsAlgorithmExplicit {
+			algorithm = strings.ToUpper(index.Algorithm.String())
+		}
+		items = append(items, newDiffItem(index.Name, seID, n,
+			"type", index.Type.String(),
+			"key_parts", strings.Join(keyParts, ","),
+			"algorithm", algorithm,
+			"visible", strconv.FormatBool(index.IsVisible),
+			"parser", index.Options["parser_name"],
+			"comment", index.Comment,
+		))
+	}
+	return items
+}
+
+func quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for n, name := range names {
+		quoted[n] = quoteMySQLIdentifier(name)
+	}
+	return strings.Join(quoted, ",")
+}
+
+func getForeignKeyDiffItems(table *TableSchema) []*diffItem {
+	items := make([]*diffItem, 0)
+	for n, fk := range table.ForeignKeys {
+		items = append(items, newDiffItem(fk.Name, "", n,
+			"columns", quoteNames(fk.ColumnNames),
+			"referenced_table", fmt.Sprintf("`%s`.`%s`",
+				fk.ReferencedTableSchemaName, fk.ReferencedTableName),
+			"referenced_columns", quoteNames(fk.ReferenceNames),
+			"on_update", fk.UpdateRule.String(),
+			"on_delete", fk.DeleteRule.String(),
+		))
+	}
+	return items
+}
+
+func getCheckConstraintDiffItems(table *TableSchema) []*diffItem {
+	items := make([]*diffItem, 0)
+	for n, cc := range table.CheckConstraints {
+		items = append(items, newDiffItem(cc.Name, "", n,
+			"clause", NormalizeExpression(cc.CheckClauseUTF8),
+			"enforced", strconv.FormatBool(cc.State != CC_NOT_ENFORCED),
+		))
+	}
+	return items
+}
+
+func getPartitionDiffItems(table *TableSchema) []*diffItem {
+	items := make([]*diffItem, 0)
+	if table.Partitioning == nil {
+		return items
+	}
+	for n, p := range table.Partitioning.Partitions {
+		subpartitions := make([]string, len(p.Subpartitions))
+		for i, sp := range p.Subpartitions {
+			subpartitions[i] = fmt.Sprintf("`%s`", sp.Name)
+		}
+		items = append(items, newDiffItem(p.Name, "", n,
+			"values", NormalizeExpression(p.DescriptionUTF8),
+			"subpartitions", strings.Join(subpartitions, ","),
+			"comment", p.Comment,
+		))
+	}
+	return items
+}
+
+/*
+* Table options that are derived by the server and not set by the user
+ */
+var diffIgnoredTableOptions = map[string]bool{
+	"pack_record":   true,
+	"keys_disabled": true,
+	"row_type":      true,
+}
+
+func getTableDiffItem(table *TableSchema) *diffItem {
+	collation, partitionBy, subpartitionBy := "", "", ""
+	if table.Collation != nil {
+		collation = table.Collation.Name
+	}
+	if p := table.Partitioning; p != nil {
+		partitionBy = fmt.Sprintf("%s (%s)", p.Type.String(), NormalizeExpression(p.ExpressionUTF8))
+		if p.SubpartitionType != ST_NONE {
+			subpartitionBy = fmt.Sprintf("%s (%s)", p.SubpartitionType.String(),
+				NormalizeExpression(p.SubpartitionExpressionUTF8))
+		}
+	}
+	rowFormat := ""
+	if table.RowFormat != 0 {
+		rowFormat = table.RowFormat.String()
+	}
+	attributes := []string{
+		"engine", table.Engine,
+		"collation", collation,
+		"row_format", rowFormat,
+		"comment", table.Comment,
+		"partition_by", partitionBy,
+		"subpartition_by", subpartitionBy,
+	}
+	keys := make([]string, 0, len(table.Options))
+	for key := range table.Options {
+		if !diffIgnoredTableOptions[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attributes = append(attributes, key, table.Options[key])
+	}
+	return newDiffItem(table.Name, "", 0, attributes...)
+}
+
+/*
+* Compare the table options. Options that exist on one side only are
+compared with an empty value.
+*/
+func diffTableOptions(oldTable, newTable *TableSchema) *ObjectDiff {
+	oldItem, newItem := getTableDiffItem(oldTable), getTableDiffItem(newTable)
+	oldValues := make(map[string]string)
+	names := make([]string, 0)
+	for _, attribute := range oldItem.attributes {
+		oldValues[attribute.Name] = attribute.New
+		names = append(names, attribute.Name)
+	}
+	newValues := make(map[string]string)
+	for _, attribute := range newItem.attributes {
+		if _, ok := oldValues[attribute.Name]; !ok {
+			names = append(names, attribute.Name)
+		}
+		newValues[attribute.Name] = attribute.New
+	}
+	diff := &ObjectDiff{Object: DIFF_OBJECT_TABLE, Action: DIFF_CHANGED, Name: newTable.Name}
+	for _, name := range names {
+		if oldValues[name] != newValues[name] {
+			diff.Attributes = append(diff.Attributes,
+				&AttributeDiff{Name: name, Old: oldValues[name], New: newValues[name]})
+		}
+	}
+	if len(diff.Attributes) == 0 {
+		return nil
+	}
+	return diff
+}
+
+/*
+* Compare two table schemas. Columns and indexes are matched by their InnoDB
+ids when both schemas come from the same InnoDB table, so renames are
+detected; otherwise objects are matched by name, and objects with an
+identical definition under another name are reported as renamed. Column
+position changes are only reported for columns that moved relative to the
+other columns.
+@param[in]	oldTable	old table schema
+@param[in]	newTable	new table schema
+@return differences of the tables
+*/
+func DiffTableSchemas(oldTable, newTable *TableSchema) *TableDiff {
+	diff := &TableDiff{
+		SchemaName: newTable.SchemaName,
+		Name:       newTable.Name,
+		Diffs:      make([]*ObjectDiff, 0),
+	}
+	if oldTable.SchemaName != newTable.SchemaName || oldTable.Name != newTable.Name {
+		diff.OldSchemaName, diff.OldName = oldTable.SchemaName, oldTable.Name
+	}
+	useSEID := haveSameSEIDs(oldTable, newTable)
+	if tableDiff := diffTableOptions(oldTable, newTable); tableDiff != nil {
+		diff.Diffs = append(diff.Diffs, tableDiff)
+	}
+	diff.Diffs = append(diff.Diffs, diffItems(DIFF_OBJECT_COLUMN,
+		getColumnDiffItems(oldTable, useSEID), getColumnDiffItems(newTable, useSEID), true)...)
+	diff.Diffs = append(diff.Diffs, diffItems(DIFF_OBJECT_INDEX,
+		getIndexDiffItems(oldTable, useSEID), getIndexDiffItems(newTable, useSEID), false)...)
+	diff.Diffs = append(diff.Diffs, diffItems(DIFF_OBJECT_FOREIGN_KEY,
+		getForeignKeyDiffItems(oldTable), getForeignKeyDiffItems(newTable), false)...)
+	diff.Diffs = append(diff.Diffs, diffItems(DIFF_OBJECT_CHECK_CONSTRAINT,
+		getCheckConstraintDiffItems(oldTable), getCheckConstraintDiffItems(newTable), false)...)
+	diff.Diffs = append(diff.Diffs, diffItems(DIFF_OBJECT_PARTITION,
+		getPartitionDiffItems(oldTable), getPartitionDiffItems(newTable), false)...)
+	return diff
+}
+
+/*
+* Compare two lists of table schemas, e.g. of two backups. Tables are paired
+by schema and table name first. The remaining tables are paired by InnoDB
+table id, which only finds renames within the same instance as each
+instance assigns its own ids, and an id of 0 is unknown. The remaining
+tables are paired by their columns if they are similar enough, see
+DIFF_TABLE_MIN_SIMILARITY, otherwise they are removed and added. Hidden
+tables are ignored.
+@param[in]	oldTables	old table schemas
+@param[in]	newTables	new table schemas
+@return differences of all tables, including tables without changes
+*/
+func DiffTableSchemaLists(oldTables, newTables []*TableSchema) (diffs []*TableDiff) {
+	pairs, removed, added := pairTableSchemas(oldTables, newTables)
+	for _, pair := range pairs {
+		diffs = append(diffs, DiffTableSchemas(pair[0], pair[1]))
+	}
+	for _, table := range removed {
+		diffs = append(diffs, &TableDiff{SchemaName: table.SchemaName, Name: table.Name,
+			Diffs: []*ObjectDiff{{Object: DIFF_OBJECT_TABLE, Action: DIFF_REMOVED, Name: table.Name}}})
+	}
+	for _, table := range added {
+		diffs = append(diffs, &TableDiff{SchemaName: table.SchemaName, Name: table.Name,
+			Diffs: []*ObjectDiff{{Object: DIFF_OBJECT_TABLE, Action: DIFF_ADDED, Name: table.Name}}})
+	}
+	return diffs
+}
+
+/*
+* Pair the visible tables of two lists, see DiffTableSchemaLists.
+@return pairs of old and new tables in the new order, removed tables in the
+old order and added tables in the new order
+*/
+func pairTableSchemas(oldTables, newTables []*TableSchema) (
+	pairs [][2]*TableSchema, removed, added []*TableSchema) {
+	visible := func(tables []*TableSchema) []*TableSchema {
+		result := make([]*TableSchema, 0, len(tables))
+		for _, table := range tables {
+			if table.Hidden == HT_VISIBLE {
+				result = append(result, table)
+			}
+		}
+		return result
+	}
+	oldTables, newTables = visible(oldTables), visible(newTables)
+	oldPaired := make([]bool, len(oldTables))
+	/* index of the old table of each new table, -1 if not paired */
+	newPaired := make([]int, len(newTables))
+	for n := range newPaired {
+		newPaired[n] = -1
+	}
+	pair := func(key func(table *TableSchema) string) {
+		keys := make(map[string]int)
+		for n, table := range oldTables {
+			if k := key(table); !oldPaired[n] && k != "" {
+				if _, ok := keys[k]; !ok {
+					keys[k] = n
+				}
+			}
+		}
+		for n, table := range newTables {
+			if newPaired[n] != -1 {
+				continue
+			}
+			k := key(table)
+			o, ok := keys[k]
+			if k == "" || !ok || oldPaired[o] {
+				continue
+			}
+			oldPaired[o], newPaired[n] = true, o
+		}
+	}
+	pair(func(table *TableSchema) string { return table.SchemaName + "." + table.Name })
+	pair(func(table *TableSchema) string {
+		if table.SEPrivateID == 0 {
+			return ""
+		}
+		return strconv.FormatUint(table.SEPrivateID, 10)
+	})
+	removedPositions, addedPositions := make([]int, 0), make([]int, 0)
+	for n := range oldTables {
+		if !oldPaired[n] {
+			removedPositions = append(removedPositions, n)
+		}
+	}
+	for n := range newTables {
+		if newPaired[n] == -1 {
+			addedPositions = append(addedPositions, n)
+		}
+	}
+	/* the most similar tables first, ties in the new and old order */
+	type candidate struct {
+		old, new   int
+		similarity float64
+	}
+	candidates := make([]candidate, 0)
+	for _, a := range addedPositions {
+		for _, r := range removedPositions {
+			similarity := getColumnSimilarity(oldTables[r], newTables[a])
+			if similarity >= DIFF_TABLE_MIN_SIMILARITY {
+				candidates = append(candidates, candidate{old: r, new: a, similarity: similarity})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	for _, c := range candidates {
+		if !oldPaired[c.old] && newPaired[c.new] == -1 {
+			oldPaired[c.old], newPaired[c.new] = true, c.old
+		}
+	}
+	for _, n := range removedPositions {
+		if !oldPaired[n] {
+			removed = append(removed, oldTables[n])
+		}
+	}
+	for _, n := range addedPositions {
+		if newPaired[n] == -1 {
+			added = append(added, newTables[n])
+		}
+	}
+	for n, table := range newTables {
+		if newPaired[n] != -1 {
+			pairs = append(pairs, [2]*TableSchema{oldTables[newPaired[n]], table})
+		}
+	}
+	return pairs, removed, added
+}
+
+/*
+* Get the share of the user columns of two tables with the same name and
+type, of all user columns of both tables.
+@return similarity from 0 to 1
+*/
+func getColumnSimilarity(oldTable, newTable *TableSchema) float64 {
+	columns := make(map[string]bool)
+	for _, column := range oldTable.GetUserColumns() {
+		columns[strings.ToLower(column.Name)+" "+column.ColumnTypeUTF8] = true
+	}
+	common, total := 0, len(columns)
+	for _, column := range newTable.GetUserColumns() {
+		if columns[strings.ToLower(column.Name)+" "+column.ColumnTypeUTF8] {
+			common++
+		} else {
+			total++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(common) / float64(total)
+}
+
+/*
+* Get the table schemas of a tablespace, see DumpSchemas.
+ */
+func (ts *TableSpace) GetTableSchemas() (tables []*TableSchema, err error) {
+	if ts.TableSchemas == nil {
+		err = ts.DumpSchemas()
+		if err != nil {
+			return nil, err
+		}
+	}
+	tables = make([]*TableSchema, 0, len(ts.TableSchemas))
+	for _, table := range ts.TableSchemas {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].SchemaName+"."+tables[i].Name < tables[j].SchemaName+"."+tables[j].Name
+	})
+	return tables, nil
+}
+
+/*
+* Compare the table schemas of two tablespaces.
+ */
+func DiffTableSpaces(oldTs, newTs *TableSpace) (diffs []*TableDiff, err error) {
+	oldTables, err := oldTs.GetTableSchemas()
+	if err != nil {
+		return nil, err
+	}
+	newTables, err := newTs.GetTableSchemas()
+	if err != nil {
+		return nil, err
+	}
+	return DiffTableSchemaLists(oldTables, newTables), nil
+}
+
+/*
+* Compare the table schemas of two SDI JSON documents, see ParseSDIDocument.
+ */
+func DiffSDIDocuments(oldData, newData []byte) (diffs []*TableDiff, err error) {
+	getTables := func(data []byte) ([]*TableSchema, error) {
+		sdis, err := ParseSDIDocument(data)
+		if err != nil {
+			return nil, err
+		}
+		tables := make([]*TableSchema, 0)
+		for _, sdi := range sdis {
+			if sdi.TableSchema != nil {
+				tables = append(tables, sdi.TableSchema)
+			}
+		}
+		return tables, nil
+	}
+	oldTables, err := getTables(oldData)
+	if err != nil {
+		return nil, err
+	}
+	newTables, err := getTables(newData)
+	if err != nil {
+		return nil, err
+	}
+	return DiffTableSchemaLists(oldTables, newTables), nil
+}
+
+/*
+* Check if the tables differ.
+ */
+func (d *TableDiff) HasChanges() bool {
+	return len(d.Diffs) != 0 || d.OldName != "" || d.OldSchemaName != ""
+}
+
+/*
+* Get the diff as text, one line per object and one indented line per
+changed attribute.
+*/
+func (d *TableDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Table `%s`.`%s`", d.SchemaName, d.Name)
+	if d.OldName != "" || d.OldSchemaName != "" {
+		fmt.Fprintf(&b, " (was `%s`.`%s`)", d.OldSchemaName, d.OldName)
+	}
+	if !d.HasChanges() {
+		b.WriteString(": no changes\n")
+		return b.String()
+	}
+	b.WriteString("\n")
+	signs := map[DiffAction]string{
+		DIFF_ADDED: "+", DIFF_REMOVED: "-", DIFF_CHANGED: "~", DIFF_RENAMED: ">",
+	}
+	for _, diff := range d.Diffs {
+		object := strings.ReplaceAll(string(diff.Object), "_", " ")
+		fmt.Fprintf(&b, "  %s %s `%s` %s", signs[diff.Action], object, diff.Name, diff.Action)
+		if diff.Action == DIFF_RENAMED {
+			fmt.Fprintf(&b, " from `%s`", diff.OldName)
+		}
+		if diff.MatchedBy != "" && diff.MatchedBy != DIFF_MATCHED_BY_NAME {
+			fmt.Fprintf(&b, " (matched by %s)", diff.MatchedBy)
+		}
+		b.WriteString("\n")
+		for _, attribute := range diff.Attributes {
+			switch diff.Action {
+			case DIFF_ADDED:
+				fmt.Fprintf(&b, "      %s: %s\n", attribute.Name, attribute.New)
+			case DIFF_REMOVED:
+				fmt.Fprintf(&b, "      %s: %s\n", attribute.Name, attribute.Old)
+			default:
+				fmt.Fprintf(&b, "      %s: %s -> %s\n", attribute.Name,
+					quoteEmpty(attribute.Old), quoteEmpty(attribute.New))
+			}
+		}
+	}
+	return b.String()
+}
+
+func quoteEmpty(value string) string {
+	if value == "" {
+		return `""`
+	}
+	return value
+}
+
+/*
+* Get the diffs as an indented JSON array.
+ */
+func DumpTableDiffsJson(diffs []*TableDiff) ([]byte, error) {
+	if diffs == nil {
+		diffs = make([]*TableDiff, 0)
+	}
+	return json.MarshalIndent(diffs, "", "  ")
+}
@@ -0,0 +1,129 @@
+package ibd2schema
+
+import (
+	"testing"
+)
+
+func parseTestTables(t *testing.T, script string) []*TableSchema {
+	t.Helper()
+	tables, err := ParseDDLScript(script,
+		&DDLParseOptions{SchemaName: "test", DefaultCollation: DDL_DEFAULT_COLLATION})
+	if err != nil {
+		t.Fatalf("parse failed, err:%v", err)
+	}
+	return tables
+}
+
+const (
+	testUsersDDL = "CREATE TABLE `users` (`id` int NOT NULL, `name` varchar(20), `email` varchar(50), " +
+		"`created` datetime, `score` int, PRIMARY KEY (`id`));\n"
+	testAccountsDDL = "CREATE TABLE `accounts` (`id` int NOT NULL, `name` varchar(20), `email` varchar(50), " +
+		"`created` datetime, `score` int, PRIMARY KEY (`id`));\n"
+	testOrdersDDL = "CREATE TABLE `orders` (`order_id` bigint NOT NULL, `amount` decimal(10,2), " +
+		"PRIMARY KEY (`order_id`));\n"
+)
+
+func TestDiffTableSchemaListsPairing(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldScript string
+		newScript string
+		/** expected "old -> new" of renamed tables, "-old" and "+new" of removed and added tables */
+		want []string
+	}{
+		{
+			name:      "rename",
+			oldScript: testUsersDDL,
+			newScript: testAccountsDDL,
+			want:      []string{"users -> accounts"},
+		},
+		{
+			name:      "drop and unrelated create",
+			oldScript: testUsersDDL,
+			newScript: testOrdersDDL,
+			want:      []string{"-users", "+orders"},
+		},
+		{
+			name:      "rename with another create",
+			oldScript: testUsersDDL,
+			newScript: testOrdersDDL + testAccountsDDL,
+			want:      []string{"users -> accounts", "+orders"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diffs := DiffTableSchemaLists(parseTestTables(t, tt.oldScript), parseTestTables(t, tt.newScript))
+			got := make([]string, 0)
+			for _, diff := range diffs {
+				switch {
+				case diff.OldName != "":
+					got = append(got, diff.OldName+" -> "+diff.Name)
+				case len(diff.Diffs) == 1 && diff.Diffs[0].Object == DIFF_OBJECT_TABLE &&
+					diff.Diffs[0].Action == DIFF_REMOVED:
+					got = append(got, "-"+diff.Name)
+				case len(diff.Diffs) == 1 && diff.Diffs[0].Object == DIFF_OBJECT_TABLE &&
+					diff.Diffs[0].Action == DIFF_ADDED:
+					got = append(got, "+"+diff.Name)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+			for n := range got {
+				if got[n] != tt.want[n] {
+					t.Errorf("got %q, want %q", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateMigrationsRenameDropCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldScript string
+		newScript string
+		want      []string
+	}{
+		{
+			name:      "rename",
+			oldScript: testUsersDDL,
+			newScript: testAccountsDDL,
+			want:      []string{"RENAME TABLE `test`.`users` TO `test`.`accounts`"},
+		},
+		{
+			name:      "drop and create",
+			oldScript: testUsersDDL,
+			newScript: testOrdersDDL,
+			want: []string{
+				"DROP TABLE `test`.`users`",
+				"CREATE TABLE `test`.`orders` (\n" +
+					"  `order_id` bigint NOT NULL,\n" +
+					"  `amount` decimal(10,2) DEFAULT NULL,\n" +
+					"  PRIMARY KEY (`order_id`)\n" +
+					") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations := GenerateMigrations(parseTestTables(t, tt.oldScript), parseTestTables(t, tt.newScript),
+				nil)
+			got := make([]string, 0)
+			for _, migration := range migrations {
+				for _, statement := range migration.Statements {
+					got = append(got, statement.SQL)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got statements %q, want %q", got, tt.want)
+			}
+			for n := range got {
+				if got[n] != tt.want[n] {
+					t.Errorf("statement %d\ngot:\n%s\nwant:\n%s", n, got[n], tt.want[n])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package ibd2schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+* Versioned JSON format of the normalized table schemas. Within a version,
+fields are only added: existing fields keep their name, type and meaning, and
+consumers must ignore fields they do not know. Removing, renaming or
+retyping a field increments SCHEMA_DOCUMENT_VERSION, and the JSON Schema of
+each version is kept in schema/.
+*/
+const (
+	SCHEMA_DOCUMENT_FORMAT  = "ibd2schema"
+	SCHEMA_DOCUMENT_VERSION = 1
+	/** $id of the JSON Schema of the current version */
+	SCHEMA_DOCUMENT_SCHEMA_ID = "https://github.com/zing22845/go-ibd2schema/schema/v1.schema.json"
+)
+
+/*
+* JSON Schema of the current version of the format
+ */
+//go:embed schema/v1.schema.json
+var SchemaDocumentJSONSchema []byte
+
+type SchemaDocument struct {
+	/** SCHEMA_DOCUMENT_SCHEMA_ID */
+	Schema string `json:"$schema"`
+	/** SCHEMA_DOCUMENT_FORMAT */
+	Format string `json:"format"`
+	/** SCHEMA_DOCUMENT_VERSION */
+	Version int `json:"version"`
+	/** tables ordered by schema and name */
+	Tables []*SchemaDocumentTable `json:"tables"`
+}
+
+type SchemaDocumentTable struct {
+	Schema string `json:"schema"`
+	Name   string `json:"name"`
+	Engine string `json:"engine"`
+	/** default character set and collation, omitted if unknown */
+	Charset   string `json:"charset,omitempty"`
+	Collation string `json:"collation,omitempty"`
+	RowFormat string `json:"row_format,omitempty"`
+	Comment   string `json:"comment"`
+	/** version of the server that wrote the table, e.g. 80036 */
+	MySQLVersionID   uint32                           `json:"mysql_version_id,omitempty"`
+	Columns          []*SchemaDocumentColumn          `json:"columns"`
+	Indexes          []*SchemaDocumentIndex           `json:"indexes"`
+	ForeignKeys      []*SchemaDocumentForeignKey      `json:"foreign_keys"`
+	CheckConstraints []*SchemaDocumentCheckConstraint `json:"check_constraints"`
+	/** partitioning clause as in CREATE TABLE, omitted if not partitioned */
+	Partitioning string `json:"partitioning,omitempty"`
+}
+
+/*
+* Default of a column
+ */
+type SchemaDocumentDefault struct {
+	/** "null", "literal" or "expression" */
+	Kind string `json:"kind"`
+	/** literal value or expression, omitted for null */
+	Value string `json:"value,omitempty"`
+}
+
+/*
+* Generation of a generated column
+ */
+type SchemaDocumentGenerated struct {
+	Expression string `json:"expression"`
+	Stored     bool   `json:"stored"`
+}
+
+type SchemaDocumentColumn struct {
+	Name string `json:"name"`
+	/** position in the user columns, from 1 */
+	Position int `json:"position"`
+	/** type name, e.g. "varchar" */
+	DataType string `json:"data_type"`
+	/** full type, e.g. "varchar(64)" or "int unsigned" */
+	ColumnType string `json:"column_type"`
+	Nullable   bool   `json:"nullable"`
+	Unsigned   bool   `json:"unsigned"`
+	Zerofill   bool   `json:"zerofill"`
+	/** omitted if the column has no default */
+	Default       *SchemaDocumentDefault `json:"default,omitempty"`
+	OnUpdate      string                 `json:"on_update,omitempty"`
+	AutoIncrement bool                   `json:"auto_increment"`
+	/** omitted if the column is not generated */
+	Generated *SchemaDocumentGenerated `json:"generated,omitempty"`
+	Invisible bool                     `json:"invisible"`
+	Comment   string                   `json:"comment"`
+	/** character set and collation of string, ENUM and SET columns */
+	Charset   string `json:"charset,omitempty"`
+	Collation string `json:"collation,omitempty"`
+	/** maximum length in characters of CHAR and VARCHAR, in bytes of BINARY
+	and VARBINARY */
+	MaxLength *uint64 `json:"max_length,omitempty"`
+	/** precision and scale of DECIMAL */
+	NumericPrecision *uint32 `json:"numeric_precision,omitempty"`
+	NumericScale     *uint32 `json:"numeric_scale,omitempty"`
+	/** fractional seconds precision of TIME, DATETIME and TIMESTAMP */
+	DatetimePrecision *uint32 `json:"datetime_precision,omitempty"`
+	/** values of ENUM and SET */
+	Elements []string `json:"elements,omitempty"`
+	/** SRID of a spatial column, omitted if none */
+	SRID *uint32 `json:"srid,omitempty"`
+}
+
+type SchemaDocumentKeyPart struct {
+	/** column name, omitted for a functional key part */
+	Column string `json:"column,omitempty"`
+	/** expression of a functional key part */
+	Expression string `json:"expression,omitempty"`
+	/** prefix length in characters, omitted if the whole column is indexed */
+	Length     uint32 `json:"length,omitempty"`
+	Descending bool   `json:"descending"`
+}
+
+type SchemaDocumentIndex struct {
+	Name string `json:"name"`
+	/** "primary", "unique", "index", "fulltext" or "spatial" */
+	Type     string                   `json:"type"`
+	KeyParts []*SchemaDocumentKeyPart `json:"key_parts"`
+	Visible  bool                     `json:"visible"`
+	/** algorithm given in the DDL, e.g. "btree", omitted if implicit */
+	Algorithm string `json:"algorithm,omitempty"`
+	Comment   string `json:"comment"`
+}
+
+type SchemaDocumentForeignKey struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	/** e.g. "CASCADE" or "NO ACTION" */
+	OnUpdate string `json:"on_update"`
+	OnDelete string `json:"on_delete"`
+}
+
+type SchemaDocumentCheckConstraint struct {
+	Name       string `json:"name"`
+	Expression string `json:"expression"`
+	Enforced   bool   `json:"enforced"`
+}
+
+/*
+* Get the column of the document
+@param[in]	position	position in the user columns, from 1
+*/
+func (c *Column) getSchemaDocumentColumn(position int) *SchemaDocumentColumn {
+	dc := &SchemaDocumentColumn{
+		Name:          c.Name,
+		Position:      position,
+		DataType:      c.getBaseType(),
+		ColumnType:    c.ColumnTypeUTF8,
+		Nullable:      c.IsNullable,
+		Unsigned:      c.IsUnsigned,
+		Zerofill:      c.IsZerofill,
+		OnUpdate:      c.UpdateOption,
+		AutoIncrement: c.IsAutoIncrement,
+		Invisible:     c.isHiddenUser(),
+		Comment:       c.Comment,
+		SRID:          c.SRSID,
+	}
+	switch {
+	case c.GenerationExpressionUTF8 != "":
+		dc.Generated = &SchemaDocumentGenerated{Expression: c.GenerationExpressionUTF8, Stored: !c.IsVirtual}
+	case c.DefaultValueNull && c.DefaultValueUTF8Null:
+		dc.Default = &SchemaDocumentDefault{Kind: "null"}
+	case c.DefaultValueUTF8Null:
+	case c.DefaultOption != "":
+		dc.Default = &SchemaDocumentDefault{Kind: "expression", Value: c.DefaultOption}
+	default:
+		dc.Default = &SchemaDocumentDefault{Kind: "literal", Value: c.DefaultValueUTF8}
+	}
+	switch c.Type {
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING:
+		maxLength := c.Size
+		if c.isText() {
+			maxLength = c.getCharLength()
+		}
+		dc.MaxLength = &maxLength
+		fallthrough
+	case CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB, CT_ENUM, CT_SET:
+		if c.Collation != nil {
+			dc.Charset, dc.Collation = c.Collation.CharsetName, c.Collation.Name
+		}
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		precision, scale := c.NumericPrecision, c.NumericScale
+		dc.NumericPrecision, dc.NumericScale = &precision, &scale
+	case CT_TIME, CT_TIME2, CT_DATETIME, CT_DATETIME2, CT_TIMESTAMP, CT_TIMESTAMP2:
+		precision := c.DatetimePrecision
+		dc.DatetimePrecision = &precision
+	}
+	if c.Type == CT_ENUM || c.Type == CT_SET {
+		dc.Elements = append([]string{}, c.Elements...)
+	}
+	return dc
+}
+
+/*
+* Get the index of the document
+ */
+func (i *Index) getSchemaDocumentIndex() *SchemaDocumentIndex {
+	di := &SchemaDocumentIndex{
+		Name:     i.Name,
+		Type:     i.Type.String(),
+		KeyParts: make([]*SchemaDocumentKeyPart, 0, len(i.KeyParts)),
+		Visible:  i.IsVisible || i.Type == IT_PRIMARY,
+		Comment:  i.Comment,
+	}
+	if i.Type == IT_MULTIPLE {
+		di.Type = "index"
+	}
+	if i.IsAlgorithmExplicit {
+		di.Algorithm = i.Algorithm.String()
+	}
+	for _, keyPart := range i.KeyParts {
+		dk := &SchemaDocumentKeyPart{Length: keyPart.Length, Descending: keyPart.Descending}
+		if dk.Expression = keyPart.GetExpression(); dk.Expression == "" {
+			dk.Column = keyPart.Column.Name
+		}
+		di.KeyParts = append(di.KeyParts, dk)
+	}
+	return di
+}
+
+/*
+* Get the table of the document
+ */
+func (ts *TableSchema) getSchemaDocumentTable() *SchemaDocumentTable {
+	dt := &SchemaDocumentTable{
+		Schema:           ts.SchemaName,
+		Name:             ts.Name,
+		Engine:           ts.Engine,
+		Comment:          ts.Comment,
+		MySQLVersionID:   ts.MysqlVersionID,
+		Columns:          make([]*SchemaDocumentColumn, 0),
+		Indexes:          make([]*SchemaDocumentIndex, 0),
+		ForeignKeys:      make([]*SchemaDocumentForeignKey, 0, len(ts.ForeignKeys)),
+		CheckConstraints: make([]*SchemaDocumentCheckConstraint, 0, len(ts.CheckConstraints)),
+	}
+	if ts.Collation != nil {
+		dt.Charset, dt.Collation = ts.Collation.CharsetName, ts.Collation.Name
+	}
+	if ts.RowFormat != 0 {
+		dt.RowFormat = strings.ToLower(ts.RowFormat.String())
+	}
+	for n, column := range ts.GetUserColumns() {
+		dt.Columns = append(dt.Columns, column.getSchemaDocumentColumn(n+1))
+	}
+	for _, index := range ts.GetUserIndexes() {
+		dt.Indexes = append(dt.Indexes, index.getSchemaDocumentIndex())
+	}
+	for _, fk := range ts.ForeignKeys {
+		dt.ForeignKeys = append(dt.ForeignKeys, &SchemaDocumentForeignKey{
+			Name:              fk.Name,
+			Columns:           append([]string{}, fk.ColumnNames...),
+			ReferencedSchema:  fk.ReferencedTableSchemaName,
+			ReferencedTable:   fk.ReferencedTableName,
+			ReferencedColumns: append([]string{}, fk.ReferenceNames...),
+			OnUpdate:          fk.UpdateRule.String(),
+			OnDelete:          fk.DeleteRule.String(),
+		})
+	}
+	for _, cc := range ts.CheckConstraints {
+		dt.CheckConstraints = append(dt.CheckConstraints, &SchemaDocumentCheckConstraint{
+			Name:       cc.Name,
+			Expression: cc.CheckClauseUTF8,
+			Enforced:   cc.State != CC_NOT_ENFORCED,
+		})
+	}
+	if ts.Partitioning != nil {
+		dt.Partitioning = ts.Partitioning.GetDefinition()
+	}
+	return dt
+}
+
+/*
+* Generate the versioned JSON document of tables, see SchemaDocumentJSONSchema
+@param[in]	tables	table schemas, hidden tables are ignored
+@return document, tables ordered by schema and name
+*/
+func GenerateSchemaDocument(tables []*TableSchema) *SchemaDocument {
+	doc := &SchemaDocument{
+		Schema:  SCHEMA_DOCUMENT_SCHEMA_ID,
+		Format:  SCHEMA_DOCUMENT_FORMAT,
+		Version: SCHEMA_DOCUMENT_VERSION,
+		Tables:  make([]*SchemaDocumentTable, 0, len(tables)),
+	}
+	for _, table := range sortTables(tables) {
+		if table.Hidden != HT_VISIBLE {
+			continue
+		}
+		doc.Tables = append(doc.Tables, table.getSchemaDocumentTable())
+	}
+	return doc
+}
+
+/*
+* Parse a JSON document of tables
+@param[in]	data	JSON document
+@return document, error if it is not of a supported version
+*/
+func ParseSchemaDocument(data []byte) (*SchemaDocument, error) {
+	doc := &SchemaDocument{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("parse schema document failed, err:%v", err)
+	}
+	if doc.Format != SCHEMA_DOCUMENT_FORMAT {
+		return nil, fmt.Errorf("unknown schema document format %q", doc.Format)
+	}
+	if doc.Version != SCHEMA_DOCUMENT_VERSION {
+		return nil, fmt.Errorf("unsupported schema document version %d, expected %d", doc.Version,
+			SCHEMA_DOCUMENT_VERSION)
+	}
+	return doc, nil
+}
+
+/*
+* Renderer of the JSON document of the tables
+ */
+type SchemaDocumentRenderer struct{}
+
+func (r *SchemaDocumentRenderer) Name() string {
+	return "json"
+}
+
+func (r *SchemaDocumentRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	data, err := json.MarshalIndent(GenerateSchemaDocument(tables), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
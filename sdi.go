@@ -44,54 +44,118 @@ func (sdi *SDI) DumpTableSchema() (err error) {
 	}
 	// hidden
 	sdi.TableSchema = &TableSchema{
-		Name:   name.String(),
-		Hidden: HiddenType(ddObject.Get(`hidden`).Int()),
+		SchemaName: sdi.DatabaseName,
+		Name:       name.String(),
+		Hidden:     HiddenType(ddObject.Get(`hidden`).Int()),
 	}
 	if sdi.TableSchema.Hidden != HT_VISIBLE {
 		return nil
 	}
-	sdi.TableSchema.DDL = fmt.Sprintf("CREATE TABLE `%s` (\n", sdi.TableSchema.Name)
-	// parse table collation
-	tableCollationDDL, err := ParseCollation(ddObject)
-	if err != nil {
-		return err
-	}
 	// table columns
-	columnDDL, columnCache, err := ParseColumns(ddObject)
+	sdi.TableSchema.Columns, err = ParseColumns(ddObject)
 	if err != nil {
 		return err
 	}
-	sdi.TableSchema.DDL += columnDDL
 	// table indexes
-	indexDDL, err := ParseIndexes(ddObject, columnCache)
+	sdi.TableSchema.Indexes, err = ParseIndexes(ddObject, sdi.TableSchema.Columns)
 	if err != nil {
 		return err
 	}
-	sdi.TableSchema.DDL += indexDDL
 	// foreign keys
-	fkDDL, err := ParseForeignKeys(ddObject, columnCache, tableSchema.String())
+	sdi.TableSchema.ForeignKeys, err = ParseForeignKeys(ddObject, sdi.TableSchema.Columns,
+		tableSchema.String())
+	if err != nil {
+		return err
+	}
+	// check constraints
+	sdi.TableSchema.CheckConstraints, err = ParseCheckConstraints(ddObject)
+	if err != nil {
+		return err
+	}
+	// partitions
+	sdi.TableSchema.Partitioning, err = ParsePartitioning(ddObject)
+	if err != nil {
+		return err
+	}
+	// table options
+	err = sdi.TableSchema.parseOptions(ddObject)
 	if err != nil {
 		return err
 	}
-	sdi.TableSchema.DDL += fkDDL
-	// enclose column and index
-	sdi.TableSchema.DDL = sdi.TableSchema.DDL[:len(sdi.TableSchema.DDL)-2]
-	sdi.TableSchema.DDL += "\n)"
-	// engine
-	engineDDL, err := ParseEngine(ddObject)
+	// fingerprints
+	err = sdi.TableSchema.parseFingerprints(ddObject)
 	if err != nil {
 		return err
 	}
-	sdi.TableSchema.DDL += engineDDL
-	// table collation
-	sdi.TableSchema.DDL += tableCollationDDL
 	// table comment
 	tableComment := ddObject.Get(`comment`)
 	if !tableComment.Exists() {
 		return fmt.Errorf(`table comment not found`)
 	}
-	if tableComment.String() != "" {
-		sdi.TableSchema.DDL += fmt.Sprintf(" COMMENT = '%s'", tableComment.String())
-	}
+	sdi.TableSchema.Comment = tableComment.String()
+	// DDL
+	sdi.TableSchema.DDL = (&MySQLRenderer{}).RenderTable(sdi.TableSchema, &RenderOptions{})
 	return nil
 }
+
+/*
+* Parse the table options of SDI JSON
+@param[in]	ddObject	Data Dictionary JSON object
+*/
+func (ts *TableSchema) parseOptions(ddObject gjson.Result) (err error) {
+	if !ddObject.Get(`engine`).Exists() {
+		return fmt.Errorf("engine not found")
+	}
+	collationID := ddObject.Get(`collation_id`)
+	if !collationID.Exists() {
+		return fmt.Errorf(`table collation id not found`)
+	}
+	ts.SEPrivateID = ddObject.Get(`se_private_id`).Uint()
+	ts.MysqlVersionID = uint32(ddObject.Get(`mysql_version_id`).Uint())
+	ts.Engine = ddObject.Get(`engine`).String()
+	ts.RowFormat = RowFormat(ddObject.Get(`row_format`).Int())
+	ts.Options = ParseKeyValueList(ddObject.Get(`options`).String())
+	ts.Collation, err = GetCollationByID(int(collationID.Int()))
+	return err
+}
+
+/*
+* Parse the SDIs of a JSON document and dump their table schemas. The
+document is the output of ibd2sdi or DumpSDIs, a JSON array of SDI objects,
+or a single SDI object.
+@param[in]	data	JSON document
+@return SDIs of the document
+*/
+func ParseSDIDocument(data []byte) (sdis []*SDI, err error) {
+	if !gjson.ValidBytes(data) {
+		return nil, fmt.Errorf("invalid SDI JSON document")
+	}
+	doc := gjson.ParseBytes(data)
+	items := []gjson.Result{doc}
+	if doc.IsArray() {
+		items = doc.Array()
+	}
+	sdis = make([]*SDI, 0)
+	for _, item := range items {
+		/* skip the "ibd2sdi" header */
+		if !item.IsObject() {
+			continue
+		}
+		object := item.Get(`object`)
+		if !object.Exists() {
+			return nil, fmt.Errorf("SDI object not found")
+		}
+		sdi := &SDI{
+			Type:             item.Get(`type`).Uint(),
+			ID:               item.Get(`id`).Uint(),
+			UncompressedData: []byte(object.Raw),
+		}
+		sdi.UncompressedDataLen = uint64(len(sdi.UncompressedData))
+		err = sdi.DumpTableSchema()
+		if err != nil {
+			return nil, err
+		}
+		sdis = append(sdis, sdi)
+	}
+	return sdis, nil
+}
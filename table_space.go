@@ -27,9 +27,31 @@ type TablespaceFile struct {
 }
 
 type TableSchema struct {
-	Hidden HiddenType
-	Name   string
-	DDL    string
+	Hidden     HiddenType
+	SchemaName string
+	Name       string
+	DDL        string
+	/** table id in InnoDB */
+	SEPrivateID      uint64
+	MysqlVersionID   uint32
+	Engine           string
+	Collation        *Collation
+	Comment          string
+	RowFormat        RowFormat
+	Options          map[string]string
+	Columns          ColumnCache
+	Indexes          []*Index
+	ForeignKeys      []*ForeignKey
+	CheckConstraints []*CheckConstraint
+	/** nil if the table is not partitioned */
+	Partitioning *Partitioning
+	/** fingerprint of the logical schema, see GetFingerprint */
+	Fingerprint string
+	/** fingerprint including the physical options */
+	PhysicalFingerprint string
+	IndexStats          []*IndexStats
+	RowCount            *RowCount
+	IndexChecks         []*IndexCheck
 }
 
 type TableSpace struct {
@@ -0,0 +1,288 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+* Server the DDL is rendered for
+ */
+type TargetFlavor string
+
+const (
+	TARGET_MYSQL   TargetFlavor = "mysql"
+	TARGET_MARIADB TargetFlavor = "mariadb"
+)
+
+/*
+* Version of the server the DDL is rendered for. A version without patch is
+the first release of the minor, so the DDL is accepted by all its releases.
+*/
+type TargetVersion struct {
+	Flavor TargetFlavor
+	Major  int
+	Minor  int
+	Patch  int
+}
+
+/*
+* Language codes of the utf8mb4 UCA 9.0.0 collations and the names of the
+corresponding older collations, e.g. utf8mb4_de_pb_0900_ai_ci and
+utf8mb4_german2_ci
+*/
+var targetCollationLanguages = map[string]string{
+	"cs":      "czech",
+	"da":      "danish",
+	"de_pb":   "german2",
+	"eo":      "esperanto",
+	"es":      "spanish",
+	"es_trad": "spanish2",
+	"et":      "estonian",
+	"hr":      "croatian",
+	"hu":      "hungarian",
+	"is":      "icelandic",
+	"la":      "roman",
+	"lt":      "lithuanian",
+	"lv":      "latvian",
+	"pl":      "polish",
+	"ro":      "romanian",
+	"sk":      "slovak",
+	"sl":      "slovenian",
+	"sv":      "swedish",
+	"tr":      "turkish",
+	"vi":      "vietnamese",
+}
+
+var targetVersionRegexp = regexp.MustCompile(`^(?:(mysql|mariadb)[-:]?)?(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+/*
+* Parse a target version, e.g. "5.7", "8.0.13", "8.4", "mariadb-10.6" or
+"mariadb-11.4". Versions without flavor are MySQL versions.
+@param[in]	s	version
+@return target version, error if the version is malformed or too old
+*/
+func ParseTargetVersion(s string) (*TargetVersion, error) {
+	matches := targetVersionRegexp.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if matches == nil {
+		return nil, fmt.Errorf("invalid target version %s", s)
+	}
+	v := &TargetVersion{Flavor: TARGET_MYSQL}
+	if matches[1] != "" {
+		v.Flavor = TargetFlavor(matches[1])
+	}
+	v.Major, _ = strconv.Atoi(matches[2])
+	v.Minor, _ = strconv.Atoi(matches[3])
+	if matches[4] != "" {
+		v.Patch, _ = strconv.Atoi(matches[4])
+	}
+	switch {
+	case v.Flavor == TARGET_MYSQL && !v.atLeast(5, 7, 0):
+		return nil, fmt.Errorf("unsupported target version %s, expected MySQL 5.7 or later", s)
+	case v.Flavor == TARGET_MARIADB && !v.atLeast(10, 2, 0):
+		return nil, fmt.Errorf("unsupported target version %s, expected MariaDB 10.2 or later", s)
+	}
+	return v, nil
+}
+
+func (v *TargetVersion) String() string {
+	name := "MySQL"
+	if v.Flavor == TARGET_MARIADB {
+		name = "MariaDB"
+	}
+	if v.Patch == 0 {
+		return fmt.Sprintf("%s %d.%d", name, v.Major, v.Minor)
+	}
+	return fmt.Sprintf("%s %d.%d.%d", name, v.Major, v.Minor, v.Patch)
+}
+
+func (v *TargetVersion) atLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+/*
+* Check if a feature is supported by the target
+@param[in]	mysql	first MySQL version, nil if MySQL does not support it
+@param[in]	mariadb	first MariaDB version, nil if MariaDB does not support it
+*/
+func (v *TargetVersion) supports(mysql, mariadb []int) bool {
+	since := mysql
+	if v.Flavor == TARGET_MARIADB {
+		since = mariadb
+	}
+	return since != nil && v.atLeast(since[0], since[1], since[2])
+}
+
+/*
+* Get the closest collation of the target
+@return character set and collation names
+*/
+func (v *TargetVersion) mapCollation(collation *Collation) (string, string) {
+	charsetName, name := collation.CharsetName, collation.Name
+	if charsetName == "utf8mb3" && !v.supports([]int{8, 0, 30}, []int{10, 6, 1}) {
+		return "utf8", "utf8_" + strings.TrimPrefix(name, "utf8mb3_")
+	}
+	prefix, suffix, ok := strings.Cut(name, "_0900_")
+	if !ok || v.supports([]int{8, 0, 1}, nil) {
+		return charsetName, name
+	}
+	if suffix == "bin" {
+		return charsetName, "utf8mb4_bin"
+	}
+	language := targetCollationLanguages[strings.TrimPrefix(prefix, "utf8mb4_")]
+	if v.supports(nil, []int{10, 10, 1}) {
+		if language != "" {
+			return charsetName, fmt.Sprintf("utf8mb4_uca1400_%s_%s", language, suffix)
+		}
+		return charsetName, "utf8mb4_uca1400_" + suffix
+	}
+	if language != "" {
+		return charsetName, fmt.Sprintf("utf8mb4_%s_ci", language)
+	}
+	return charsetName, "utf8mb4_unicode_520_ci"
+}
+
+/*
+* Result of converting a table for a target version
+ */
+type targetTable struct {
+	definitions []string
+	collation   *Collation
+	warnings    []string
+}
+
+func (t *targetTable) warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	for _, w := range t.warnings {
+		if w == warning {
+			return
+		}
+	}
+	t.warnings = append(t.warnings, warning)
+}
+
+/*
+* Map a collation for the target, with a warning if it changes, e.g.
+utf8mb4_0900_ai_ci to utf8mb4_unicode_520_ci. The utf8mb3 names of old
+servers are not a change.
+*/
+func (t *targetTable) mapCollation(v *TargetVersion, collation *Collation) *Collation {
+	charsetName, name := v.mapCollation(collation)
+	if name == collation.Name {
+		return collation
+	}
+	if charsetName == collation.CharsetName {
+		t.warn("collation %s is not supported by %s, mapped to %s", collation.Name, v, name)
+	}
+	mapped := *collation
+	mapped.CharsetName, mapped.Name = charsetName, name
+	return &mapped
+}
+
+/*
+* Convert the definitions of a table for a target version. Unsupported
+constructs are rewritten, or removed with a warning.
+*/
+func (v *TargetVersion) convertTable(table *TableSchema, foreignKeys []*ForeignKey) *targetTable {
+	t := &targetTable{definitions: make([]string, 0)}
+	if table.Collation != nil {
+		t.collation = t.mapCollation(v, table.Collation)
+	}
+	for _, column := range table.GetUserColumns() {
+		opts := &columnDefinitionOptions{invisible: " /*!80023 INVISIBLE */"}
+		if column.IsExplicitCollation && !column.skipCharset() {
+			opts.collation = t.mapCollation(v, column.Collation)
+		}
+		if column.isHiddenUser() {
+			switch {
+			case v.Flavor == TARGET_MARIADB && v.supports(nil, []int{10, 3, 3}):
+				opts.invisible = " INVISIBLE"
+			case !v.supports([]int{8, 0, 23}, nil):
+				opts.invisible = ""
+				t.warn("column `%s` is visible, invisible columns are not supported by %s", column.Name, v)
+			}
+		}
+		if column.hasExpressionDefault() && !v.supports([]int{8, 0, 13}, []int{10, 2, 1}) {
+			opts.noExpressionDefault = true
+			t.warn("default %s of column `%s` removed, expression defaults are not supported by %s",
+				column.DefaultOption, column.Name, v)
+		}
+		t.definitions = append(t.definitions, column.getDefinition(opts))
+	}
+	for _, index := range table.GetUserIndexes() {
+		functional, descending := false, false
+		for _, keyPart := range index.KeyParts {
+			functional = functional || keyPart.GetExpression() != ""
+			descending = descending || keyPart.Descending
+		}
+		if functional && !v.supports([]int{8, 0, 13}, nil) {
+			t.warn("index `%s` removed, functional key parts are not supported by %s: %s", index.Name, v,
+				index.GetDefinition())
+			continue
+		}
+		if descending && !v.supports([]int{8, 0, 1}, []int{10, 8, 1}) {
+			t.warn("index `%s` is ascending, descending indexes are not supported by %s", index.Name, v)
+		}
+		invisible := " /*!80000 INVISIBLE */"
+		if !index.IsVisible && index.Type != IT_PRIMARY {
+			switch {
+			case v.Flavor == TARGET_MARIADB && v.supports(nil, []int{10, 6, 0}):
+				invisible = " IGNORED"
+			case v.Flavor == TARGET_MARIADB || !v.supports([]int{8, 0, 0}, nil):
+				invisible = ""
+				t.warn("index `%s` is visible, invisible indexes are not supported by %s", index.Name, v)
+			}
+		}
+		t.definitions = append(t.definitions, index.getDefinition(invisible))
+	}
+	for _, fk := range foreignKeys {
+		t.definitions = append(t.definitions, fk.GetDefinition())
+	}
+	for _, cc := range table.CheckConstraints {
+		definition := cc.GetDefinition()
+		switch {
+		case !v.supports([]int{8, 0, 16}, []int{10, 2, 1}):
+			t.warn("check constraint `%s` removed, check constraints are not enforced by %s: %s", cc.Name, v,
+				definition)
+			continue
+		case v.Flavor == TARGET_MARIADB && cc.State == CC_NOT_ENFORCED:
+			t.warn("check constraint `%s` removed, NOT ENFORCED is not supported by %s: %s", cc.Name, v,
+				definition)
+			continue
+		}
+		t.definitions = append(t.definitions, definition)
+	}
+	return t
+}
+
+/*
+* Get the warnings about the foreign keys that reference columns that are
+not a primary key or unique key, which MySQL 8.4 rejects by default, see
+restrict_fk_on_non_standard_key
+@param[in]	tables	table schemas
+@return warnings by table
+*/
+func (v *TargetVersion) getForeignKeyWarnings(tables []*TableSchema) map[*TableSchema][]string {
+	warnings := make(map[*TableSchema][]string)
+	if v.Flavor != TARGET_MYSQL || !v.atLeast(8, 4, 0) {
+		return warnings
+	}
+	for _, edge := range NewFKGraph(tables).Edges {
+		referenced := &ForeignKey{ColumnNames: edge.ForeignKey.ReferenceNames}
+		if isForeignKeyUnique(edge.Referenced, referenced) && len(edge.ForeignKey.ReferenceNames) != 0 {
+			continue
+		}
+		warnings[edge.Table] = append(warnings[edge.Table], fmt.Sprintf(
+			"foreign key `%s` references columns that are not a primary or unique key, rejected by %s "+
+				"unless restrict_fk_on_non_standard_key is OFF", edge.ForeignKey.Name, v))
+	}
+	return warnings
+}
@@ -116,6 +116,22 @@ const (
 	FK_RULE_SET_DEFAULT
 )
 
+func (r FKRule) String() string {
+	switch r {
+	case FK_RULE_NO_ACTION:
+		return "NO ACTION"
+	case FK_RULE_RESTRICT:
+		return "RESTRICT"
+	case FK_RULE_CASCADE:
+		return "CASCADE"
+	case FK_RULE_SET_NULL:
+		return "SET NULL"
+	case FK_RULE_SET_DEFAULT:
+		return "SET DEFAULT"
+	}
+	return "unknown foreign key rule"
+}
+
 type FKMatchOption int64
 
 const (
@@ -123,3 +139,129 @@ const (
 	FK_OPTION_PARTIAL
 	FK_OPTION_FULL
 )
+
+/* https://github.com/mysql/mysql-server/blob/trunk/sql/dd/types/index_element.h */
+type IndexElementOrder int64
+
+const (
+	IEO_UNDEF IndexElementOrder = iota + 1
+	IEO_ASC
+	IEO_DESC
+)
+
+/* https://github.com/mysql/mysql-server/blob/trunk/sql/dd/types/check_constraint.h */
+type CheckConstraintState int64
+
+const (
+	CC_ENFORCED CheckConstraintState = iota + 1
+	CC_NOT_ENFORCED
+)
+
+/* https://github.com/mysql/mysql-server/blob/trunk/sql/dd/types/table.h */
+type RowFormat int64
+
+const (
+	RF_FIXED RowFormat = iota + 1
+	RF_DYNAMIC
+	RF_COMPRESSED
+	RF_REDUNDANT
+	RF_COMPACT
+	RF_PAGED
+)
+
+func (rf RowFormat) String() string {
+	switch rf {
+	case RF_FIXED:
+		return "FIXED"
+	case RF_DYNAMIC:
+		return "DYNAMIC"
+	case RF_COMPRESSED:
+		return "COMPRESSED"
+	case RF_REDUNDANT:
+		return "REDUNDANT"
+	case RF_COMPACT:
+		return "COMPACT"
+	case RF_PAGED:
+		return "PAGED"
+	}
+	return "unknown row format"
+}
+
+type PartitionType int64
+
+const (
+	PT_NONE PartitionType = iota
+	PT_HASH
+	PT_KEY_51
+	PT_KEY_55
+	PT_LINEAR_HASH
+	PT_LINEAR_KEY_51
+	PT_LINEAR_KEY_55
+	PT_RANGE
+	PT_LIST
+	PT_RANGE_COLUMNS
+	PT_LIST_COLUMNS
+	PT_AUTO
+	PT_AUTO_LINEAR
+)
+
+func (pt PartitionType) String() string {
+	switch pt {
+	case PT_NONE:
+		return "NONE"
+	case PT_HASH:
+		return "HASH"
+	case PT_KEY_51, PT_KEY_55, PT_AUTO:
+		return "KEY"
+	case PT_LINEAR_HASH:
+		return "LINEAR HASH"
+	case PT_LINEAR_KEY_51, PT_LINEAR_KEY_55, PT_AUTO_LINEAR:
+		return "LINEAR KEY"
+	case PT_RANGE:
+		return "RANGE"
+	case PT_LIST:
+		return "LIST"
+	case PT_RANGE_COLUMNS:
+		return "RANGE COLUMNS"
+	case PT_LIST_COLUMNS:
+		return "LIST COLUMNS"
+	}
+	return "unknown partition type"
+}
+
+type SubpartitionType int64
+
+const (
+	ST_NONE SubpartitionType = iota
+	ST_HASH
+	ST_KEY_51
+	ST_KEY_55
+	ST_LINEAR_HASH
+	ST_LINEAR_KEY_51
+	ST_LINEAR_KEY_55
+)
+
+func (st SubpartitionType) String() string {
+	switch st {
+	case ST_NONE:
+		return "NONE"
+	case ST_HASH:
+		return "HASH"
+	case ST_KEY_51, ST_KEY_55:
+		return "KEY"
+	case ST_LINEAR_HASH:
+		return "LINEAR HASH"
+	case ST_LINEAR_KEY_51, ST_LINEAR_KEY_55:
+		return "LINEAR KEY"
+	}
+	return "unknown subpartition type"
+}
+
+type DefaultPartitioning int64
+
+const (
+	DP_NONE DefaultPartitioning = iota
+	DP_NO
+	DP_YES
+	DP_NUMBER
+)
@@ -2,6 +2,7 @@ package ibd2schema
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/tidwall/gjson"
 )
@@ -17,3 +18,24 @@ func CheckMember(column gjson.Result, member string) error {
 	}
 	return nil
 }
+
+/*
+* Parse a key-value list like se_private_data or options of SDI JSON
+@param[in]	str	key-value list, e.g. "id=154;root=4;space_id=6;"
+@return key-value map
+*/
+func ParseKeyValueList(str string) map[string]string {
+	kv := make(map[string]string)
+	for _, item := range strings.Split(str, ";") {
+		if item == "" {
+			continue
+		}
+		pair := strings.SplitN(item, "=", 2)
+		if len(pair) != 2 {
+			kv[pair[0]] = ""
+			continue
+		}
+		kv[pair[0]] = pair[1]
+	}
+	return kv
+}
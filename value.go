@@ -0,0 +1,401 @@
+package ibd2schema
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	/** offset of the integer part of a binary DATETIME */
+	DATETIMEF_INT_OFS int64 = 0x8000000000
+	/** offset of the integer part of a binary TIME */
+	TIMEF_INT_OFS int64 = 0x800000
+	/** offset of a binary TIME with 5 or 6 fractional digits */
+	TIMEF_OFS int64 = 0x800000000000
+)
+
+/*
+* Value of a DATE column
+ */
+type Date struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, d.Month, d.Day)
+}
+
+/*
+* Value of a DATETIME column, or a TIMESTAMP column in UTC
+ */
+type Datetime struct {
+	Date
+	Hour        int
+	Minute      int
+	Second      int
+	Microsecond int
+	/** fractional seconds precision */
+	Fsp uint32
+}
+
+func (dt Datetime) String() string {
+	s := fmt.Sprintf("%s %02d:%02d:%02d", dt.Date.String(), dt.Hour, dt.Minute, dt.Second)
+	return s + formatFraction(dt.Microsecond, dt.Fsp)
+}
+
+/*
+* Value of a TIME column
+ */
+type Time struct {
+	Negative    bool
+	Hour        int
+	Minute      int
+	Second      int
+	Microsecond int
+	/** fractional seconds precision */
+	Fsp uint32
+}
+
+func (t Time) String() string {
+	sign := ""
+	if t.Negative {
+		sign = "-"
+	}
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, t.Hour, t.Minute, t.Second)
+	return s + formatFraction(t.Microsecond, t.Fsp)
+}
+
+/*
+* Duration of the TIME value
+ */
+func (t Time) Duration() time.Duration {
+	d := time.Duration(t.Hour)*time.Hour + time.Duration(t.Minute)*time.Minute +
+		time.Duration(t.Second)*time.Second + time.Duration(t.Microsecond)*time.Microsecond
+	if t.Negative {
+		return -d
+	}
+	return d
+}
+
+func formatFraction(microsecond int, fsp uint32) string {
+	if fsp == 0 {
+		return ""
+	}
+	frac := fmt.Sprintf("%06d", microsecond)
+	return "." + frac[:fsp]
+}
+
+/*
+* Unpack a datetime in the packed longlong format of MySQL.
+ */
+func unpackDatetime(packed int64, fsp uint32) Datetime {
+	if packed < 0 {
+		packed = -packed
+	}
+	ymdhms := packed >> 24
+	ymd := ymdhms >> 17
+	ym := ymd >> 5
+	hms := ymdhms % (1 << 17)
+	return Datetime{
+		Date: Date{
+			Year:  int(ym / 13),
+			Month: int(ym % 13),
+			Day:   int(ymd % (1 << 5)),
+		},
+		Hour:        int(hms >> 12),
+		Minute:      int((hms >> 6) % (1 << 6)),
+		Second:      int(hms % (1 << 6)),
+		Microsecond: int(packed % (1 << 24)),
+		Fsp:         fsp,
+	}
+}
+
+/*
+* Unpack a time in the packed longlong format of MySQL.
+ */
+func unpackTime(packed int64, fsp uint32) Time {
+	t := Time{Fsp: fsp}
+	if packed < 0 {
+		t.Negative = true
+		packed = -packed
+	}
+	hms := packed >> 24
+	t.Hour = int((hms >> 12) % (1 << 10))
+	t.Minute = int((hms >> 6) % (1 << 6))
+	t.Second = int(hms % (1 << 6))
+	t.Microsecond = int(packed % (1 << 24))
+	return t
+}
+
+/*
+* Read a big-endian unsigned integer of 1 to 8 bytes.
+ */
+func readUintBE(data []byte) (v uint64) {
+	for _, b := range data {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+/*
+* Read a big-endian integer of 1 to 8 bytes stored with its sign bit
+flipped, as InnoDB stores signed integers.
+*/
+func readIntBE(data []byte) int64 {
+	bits := uint(len(data) * 8)
+	v := readUintBE(data) ^ (1 << (bits - 1))
+	/* sign extend */
+	return int64(v<<(64-bits)) >> (64 - bits)
+}
+
+/*
+* Read the fractional seconds part of a binary temporal value.
+@return microseconds
+*/
+func readFraction(data []byte, fsp uint32) int {
+	switch (fsp + 1) / 2 {
+	case 1:
+		return int(data[0]) * 10000
+	case 2:
+		return int(binary.BigEndian.Uint16(data)) * 100
+	case 3:
+		return int(readUintBE(data[:3]))
+	}
+	return 0
+}
+
+/*
+* Decode a DECIMAL value in the binary format.
+@param[in]	data		binary value
+@param[in]	precision	total number of digits
+@param[in]	scale		number of digits after the decimal point
+@return decimal string like "-123.4500"
+*/
+func DecodeDecimal(data []byte, precision, scale uint32) (value string, err error) {
+	size := DecimalBinSize(precision, scale)
+	if uint32(len(data)) < size {
+		return "", fmt.Errorf("decimal(%d,%d) needs %d bytes, got %d",
+			precision, scale, size, len(data))
+	}
+	buf := make([]byte, size)
+	copy(buf, data)
+	negative := buf[0]&0x80 == 0
+	buf[0] ^= 0x80
+	if negative {
+		for i := range buf {
+			buf[i] = ^buf[i]
+		}
+	}
+	intg := precision - scale
+	var sb strings.Builder
+	if negative {
+		sb.WriteByte('-')
+	}
+	pos := uint32(0)
+	var intDigits strings.Builder
+	if leading := dig2bytes[intg%DIG_PER_DEC1]; leading > 0 {
+		fmt.Fprintf(&intDigits, "%d", readUintBE(buf[pos:pos+leading]))
+		pos += leading
+	}
+	for n := uint32(0); n < intg/DIG_PER_DEC1; n++ {
+		fmt.Fprintf(&intDigits, "%09d", binary.BigEndian.Uint32(buf[pos:]))
+		pos += 4
+	}
+	intPart := strings.TrimLeft(intDigits.String(), "0")
+	if intPart == "" {
+		intPart = "0"
+	}
+	sb.WriteString(intPart)
+	if scale > 0 {
+		sb.WriteByte('.')
+		for n := uint32(0); n < scale/DIG_PER_DEC1; n++ {
+			fmt.Fprintf(&sb, "%09d", binary.BigEndian.Uint32(buf[pos:]))
+			pos += 4
+		}
+		if trailing := dig2bytes[scale%DIG_PER_DEC1]; trailing > 0 {
+			fmt.Fprintf(&sb, "%0*d", scale%DIG_PER_DEC1, readUintBE(buf[pos:pos+trailing]))
+		}
+	}
+	return sb.String(), nil
+}
+
+/*
+* Decode the value of a column stored in an index record. The value types
+are:
+  - int64 for signed and uint64 for unsigned integers, YEAR and BIT
+  - float32 for FLOAT and float64 for DOUBLE
+  - string for DECIMAL, ENUM, SET, JSON and columns with non binary charset
+  - Date for DATE, Datetime for DATETIME, Time for TIME
+  - time.Time in UTC for TIMESTAMP
+  - []byte for columns with binary charset, BLOB and GEOMETRY
+  - nil for SQL NULL
+
+@param[in]	column	column definition
+@param[in]	data	stored bytes, including the externally stored part
+@return decoded value
+*/
+func DecodeColumnValue(column *Column, data []byte) (value interface{}, err error) {
+	if data == nil {
+		return nil, nil
+	}
+	fixedSize := column.GetFixedSize()
+	if fixedSize != 0 && uint32(len(data)) < fixedSize {
+		return nil, fmt.Errorf("column %s needs %d bytes, got %d", column.Name, fixedSize, len(data))
+	}
+	if column.isSystemColumn() {
+		return readUintBE(data), nil
+	}
+	switch column.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG:
+		if column.IsUnsigned {
+			return readUintBE(data[:fixedSize]), nil
+		}
+		return readIntBE(data[:fixedSize]), nil
+	case CT_FLOAT:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data)), nil
+	case CT_DOUBLE:
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+	case CT_NEWDECIMAL:
+		return DecodeDecimal(data, column.NumericPrecision, column.NumericScale)
+	case CT_YEAR:
+		if data[0] == 0 {
+			return int64(0), nil
+		}
+		return int64(data[0]) + 1900, nil
+	case CT_DATE, CT_NEWDATE:
+		v := readIntBE(data[:3])
+		return Date{Year: int(v >> 9), Month: int((v >> 5) & 15), Day: int(v & 31)}, nil
+	case CT_DATETIME2:
+		intPart := int64(readUintBE(data[:5])) - DATETIMEF_INT_OFS
+		frac := readFraction(data[5:], column.DatetimePrecision)
+		return unpackDatetime(intPart<<24+int64(frac), column.DatetimePrecision), nil
+	case CT_TIMESTAMP2:
+		seconds := int64(binary.BigEndian.Uint32(data))
+		frac := readFraction(data[4:], column.DatetimePrecision)
+		return time.Unix(seconds, int64(frac)*1000).UTC(), nil
+	case CT_TIME2:
+		return decodeTime2(data, column.DatetimePrecision), nil
+	case CT_DATETIME:
+		v := readIntBE(data[:8])
+		ymd, hms := v/1000000, v%1000000
+		return Datetime{
+			Date:   Date{Year: int(ymd / 10000), Month: int(ymd / 100 % 100), Day: int(ymd % 100)},
+			Hour:   int(hms / 10000),
+			Minute: int(hms / 100 % 100),
+			Second: int(hms % 100),
+		}, nil
+	case CT_TIMESTAMP:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case CT_TIME:
+		v := readIntBE(data[:3])
+		t := Time{}
+		if v < 0 {
+			t.Negative = true
+			v = -v
+		}
+		t.Hour, t.Minute, t.Second = int(v/10000), int(v/100%100), int(v%100)
+		return t, nil
+	case CT_BIT:
+		return readUintBE(data[:fixedSize]), nil
+	case CT_ENUM:
+		n := readUintBE(data[:fixedSize])
+		if n == 0 || n > uint64(len(column.Elements)) {
+			return "", nil
+		}
+		return column.Elements[n-1], nil
+	case CT_SET:
+		bits := readUintBE(data[:fixedSize])
+		names := make([]string, 0)
+		for n, name := range column.Elements {
+			if bits&(1<<uint(n)) != 0 {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ","), nil
+	case CT_JSON:
+		return JSONBinaryToText(data)
+	case CT_GEOMETRY:
+		return data, nil
+	case CT_STRING:
+		if column.Collation.CharsetName == "binary" {
+			return data, nil
+		}
+		return strings.TrimRight(string(data), " "), nil
+	case CT_VARCHAR, CT_VAR_STRING, CT_TINY_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB, CT_BLOB:
+		if column.Collation.CharsetName == "binary" {
+			return data, nil
+		}
+		return string(data), nil
+	}
+	return nil, fmt.Errorf("unsupported type %d of column %s", column.Type, column.Name)
+}
+
+/*
+* Decode a TIME value in the binary format.
+ */
+func decodeTime2(data []byte, fsp uint32) Time {
+	var packed int64
+	switch (fsp + 1) / 2 {
+	case 0:
+		packed = (int64(readUintBE(data[:3])) - TIMEF_INT_OFS) << 24
+	case 1:
+		intPart := int64(readUintBE(data[:3])) - TIMEF_INT_OFS
+		frac := int64(data[3])
+		if intPart < 0 && frac != 0 {
+			intPart++
+			frac -= 0x100
+		}
+		packed = intPart<<24 + frac*10000
+	case 2:
+		intPart := int64(readUintBE(data[:3])) - TIMEF_INT_OFS
+		frac := int64(binary.BigEndian.Uint16(data[3:]))
+		if intPart < 0 && frac != 0 {
+			intPart++
+			frac -= 0x10000
+		}
+		packed = intPart<<24 + frac*100
+	default:
+		packed = int64(readUintBE(data[:6])) - TIMEF_OFS
+	}
+	return unpackTime(packed, fsp)
+}
+
+/*
+* Get the first characters of a value, like the column prefix stored in
+a prefix index.
+@param[in]	column		column definition
+@param[in]	value		decoded value
+@param[in]	prefixLen	prefix length in bytes
+@return the prefix of the value
+*/
+func GetValuePrefix(column *Column, value interface{}, prefixLen uint32) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		if uint32(len(v)) > prefixLen {
+			return v[:prefixLen]
+		}
+		return v
+	case string:
+		if column.Collation.Maxlen <= 1 || !strings.HasPrefix(column.Collation.CharsetName, "utf8") {
+			if uint32(len(v)) > prefixLen {
+				return v[:prefixLen]
+			}
+			return v
+		}
+		nChars := prefixLen / uint32(column.Collation.Maxlen)
+		n, end := uint32(0), 0
+		for end < len(v) && n < nChars {
+			_, size := utf8.DecodeRuneInString(v[end:])
+			end += size
+			n++
+		}
+		return v[:end]
+	}
+	return value
+}
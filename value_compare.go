@@ -0,0 +1,382 @@
+package ibd2schema
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+* Check if the values of a column are stored in an order that CompareValues
+follows exactly. Strings of non-binary collations are only compared
+approximately, so B-tree navigation must not rely on them.
+@param[in]	column	column of the values
+@return true if CompareValues matches the index order
+*/
+func (c *Column) hasExactOrder() bool {
+	if !c.isString() {
+		return c.Type != CT_JSON
+	}
+	return c.Collation.CharsetName == "binary" || strings.HasSuffix(c.Collation.Name, "_bin")
+}
+
+/*
+* Check if the column stores character or binary strings
+ */
+func (c *Column) isString() bool {
+	switch c.Type {
+	case CT_STRING, CT_VARCHAR, CT_VAR_STRING, CT_TINY_BLOB, CT_MEDIUM_BLOB,
+		CT_LONG_BLOB, CT_BLOB:
+		return true
+	}
+	return false
+}
+
+/*
+* Compare a decoded column value with a value given by the caller.
+NULL sorts before any other value. Numbers of any Go numeric type or decimal
+strings are accepted for numeric columns; time.Time, Date, Datetime, Time,
+time.Duration or strings like '2024-01-02 03:04:05.123' for temporal
+columns; element names or numbers for ENUM and SET columns. Strings of
+non-binary collations are compared case-insensitively, and trailing spaces
+are ignored for PAD SPACE collations.
+@param[in]	column	column of the values
+@param[in]	stored	value decoded by DecodeColumnValue
+@param[in]	value	value to compare with
+@return negative, zero or positive as stored is less than, equal to or
+greater than value
+*/
+func CompareValues(column *Column, stored, value interface{}) (cmp int, err error) {
+	if stored == nil || value == nil {
+		switch {
+		case stored == nil && value == nil:
+			return 0, nil
+		case stored == nil:
+			return -1, nil
+		}
+		return 1, nil
+	}
+	switch column.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG, CT_FLOAT, CT_DOUBLE,
+		CT_DECIMAL, CT_NEWDECIMAL, CT_YEAR, CT_BIT:
+		a, err := toRat(stored)
+		if err != nil {
+			return 0, fmt.Errorf("column %s: %v", column.Name, err)
+		}
+		b, err := toRat(value)
+		if err != nil {
+			return 0, fmt.Errorf("column %s: %v", column.Name, err)
+		}
+		return a.Cmp(b), nil
+	case CT_DATE, CT_NEWDATE, CT_DATETIME, CT_DATETIME2, CT_TIMESTAMP, CT_TIMESTAMP2,
+		CT_TIME, CT_TIME2:
+		a, err := temporalKey(column, stored)
+		if err != nil {
+			return 0, err
+		}
+		b, err := temporalKey(column, value)
+		if err != nil {
+			return 0, err
+		}
+		return compareInt64(a, b), nil
+	case CT_ENUM, CT_SET:
+		a, err := elementsKey(column, stored)
+		if err != nil {
+			return 0, err
+		}
+		b, err := elementsKey(column, value)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case a < b:
+			return -1, nil
+		case a > b:
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if b, ok := stored.([]byte); ok {
+		switch v := value.(type) {
+		case []byte:
+			return bytes.Compare(b, v), nil
+		case string:
+			return bytes.Compare(b, []byte(v)), nil
+		}
+		return 0, fmt.Errorf("column %s: cannot compare binary string with %T", column.Name, value)
+	}
+	a, ok := stored.(string)
+	if !ok {
+		return 0, fmt.Errorf("column %s: cannot compare %T", column.Name, stored)
+	}
+	var b string
+	switch v := value.(type) {
+	case string:
+		b = v
+	case []byte:
+		b = string(v)
+	default:
+		return 0, fmt.Errorf("column %s: cannot compare string with %T", column.Name, value)
+	}
+	return compareStrings(column, a, b), nil
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+/*
+* Compare two strings with an approximation of the column collation.
+ */
+func compareStrings(column *Column, a, b string) int {
+	if column.Type == CT_JSON {
+		return strings.Compare(a, b)
+	}
+	/* collations of the UCA 9.0.0 family are NO PAD */
+	if !strings.Contains(column.Collation.Name, "_0900_") {
+		a, b = strings.TrimRight(a, " "), strings.TrimRight(b, " ")
+	}
+	if !column.hasExactOrder() {
+		a, b = strings.ToUpper(a), strings.ToUpper(b)
+	}
+	return strings.Compare(a, b)
+}
+
+func toRat(value interface{}) (*big.Rat, error) {
+	r := new(big.Rat)
+	switch v := value.(type) {
+	case int:
+		return r.SetInt64(int64(v)), nil
+	case int8:
+		return r.SetInt64(int64(v)), nil
+	case int16:
+		return r.SetInt64(int64(v)), nil
+	case int32:
+		return r.SetInt64(int64(v)), nil
+	case int64:
+		return r.SetInt64(v), nil
+	case uint:
+		return r.SetUint64(uint64(v)), nil
+	case uint8:
+		return r.SetUint64(uint64(v)), nil
+	case uint16:
+		return r.SetUint64(uint64(v)), nil
+	case uint32:
+		return r.SetUint64(uint64(v)), nil
+	case uint64:
+		return r.SetUint64(v), nil
+	case float32:
+		return floatToRat(float64(v))
+	case float64:
+		return floatToRat(v)
+	case string:
+		if _, ok := r.SetString(strings.TrimSpace(v)); !ok {
+			return nil, fmt.Errorf("invalid number '%s'", v)
+		}
+		return r, nil
+	case *big.Rat:
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot compare number with %T", value)
+}
+
+func floatToRat(f float64) (*big.Rat, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("cannot compare with %v", f)
+	}
+	return new(big.Rat).SetFloat64(f), nil
+}
+
+/*
+* Get the key of a DATE or DATETIME value that sorts like the stored value.
+ */
+func datetimeKey(year, month, day, hour, minute, second, microsecond int) int64 {
+	ymd := int64((year*13+month)*32 + day)
+	hms := int64(hour*3600 + minute*60 + second)
+	return (ymd*86400+hms)*1000000 + int64(microsecond)
+}
+
+/*
+* Convert a temporal value to an integer that sorts like the stored value.
+For TIMESTAMP columns it is microseconds since the epoch in UTC, for TIME
+columns signed microseconds, and for DATE and DATETIME columns a packed
+calendar value.
+@param[in]	column	column of the value
+@param[in]	value	temporal value
+@return comparable key
+*/
+func temporalKey(column *Column, value interface{}) (key int64, err error) {
+	isTimestamp := column.Type == CT_TIMESTAMP || column.Type == CT_TIMESTAMP2
+	isTime := column.Type == CT_TIME || column.Type == CT_TIME2
+	if s, ok := value.(string); ok {
+		if isTime {
+			value, err = parseTimeValue(s)
+		} else {
+			value, err = parseDatetimeValue(s)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("column %s: %v", column.Name, err)
+		}
+	}
+	switch v := value.(type) {
+	case Time:
+		if !isTime {
+			break
+		}
+		return int64(v.Duration() / time.Microsecond), nil
+	case time.Duration:
+		if !isTime {
+			break
+		}
+		return int64(v / time.Microsecond), nil
+	case Date:
+		if isTime {
+			break
+		}
+		if isTimestamp {
+			return time.Date(v.Year, time.Month(v.Month), v.Day, 0, 0, 0, 0, time.UTC).UnixMicro(), nil
+		}
+		return datetimeKey(v.Year, v.Month, v.Day, 0, 0, 0, 0), nil
+	case Datetime:
+		if isTime {
+			break
+		}
+		if isTimestamp {
+			return time.Date(v.Year, time.Month(v.Month), v.Day, v.Hour, v.Minute, v.Second,
+				v.Microsecond*1000, time.UTC).UnixMicro(), nil
+		}
+		return datetimeKey(v.Year, v.Month, v.Day, v.Hour, v.Minute, v.Second, v.Microsecond), nil
+	case time.Time:
+		if isTime {
+			break
+		}
+		if isTimestamp {
+			return v.UnixMicro(), nil
+		}
+		return datetimeKey(v.Year(), int(v.Month()), v.Day(), v.Hour(), v.Minute(), v.Second(),
+			v.Nanosecond()/1000), nil
+	}
+	return 0, fmt.Errorf("column %s: cannot compare %T with temporal value", column.Name, value)
+}
+
+/*
+* Parse a string like '2024-01-02', '2024-01-02 03:04:05' or
+'2024-01-02 03:04:05.123456' into a Datetime.
+*/
+func parseDatetimeValue(s string) (dt Datetime, err error) {
+	s = strings.TrimSpace(s)
+	datePart, timePart, hasTime := strings.Cut(strings.Replace(s, "T", " ", 1), " ")
+	fields := strings.Split(datePart, "-")
+	if len(fields) != 3 {
+		return dt, fmt.Errorf("invalid datetime '%s'", s)
+	}
+	parts := make([]int, 3)
+	for n, f := range fields {
+		if parts[n], err = strconv.Atoi(f); err != nil {
+			return dt, fmt.Errorf("invalid datetime '%s'", s)
+		}
+	}
+	dt.Year, dt.Month, dt.Day = parts[0], parts[1], parts[2]
+	if !hasTime {
+		return dt, nil
+	}
+	t, err := parseTimeValue(timePart)
+	if err != nil || t.Negative || t.Hour > 23 {
+		return dt, fmt.Errorf("invalid datetime '%s'", s)
+	}
+	dt.Hour, dt.Minute, dt.Second, dt.Microsecond, dt.Fsp = t.Hour, t.Minute, t.Second,
+		t.Microsecond, t.Fsp
+	return dt, nil
+}
+
+/*
+* Parse a string like '-838:59:59' or '12:34:56.789' into a Time.
+ */
+func parseTimeValue(s string) (t Time, err error) {
+	s = strings.TrimSpace(s)
+	str := s
+	if strings.HasPrefix(str, "-") {
+		t.Negative = true
+		str = str[1:]
+	}
+	str, frac, hasFrac := strings.Cut(str, ".")
+	fields := strings.Split(str, ":")
+	if len(fields) != 3 {
+		return t, fmt.Errorf("invalid time '%s'", s)
+	}
+	parts := make([]int, 3)
+	for n, f := range fields {
+		if parts[n], err = strconv.Atoi(f); err != nil || parts[n] < 0 {
+			return t, fmt.Errorf("invalid time '%s'", s)
+		}
+	}
+	t.Hour, t.Minute, t.Second = parts[0], parts[1], parts[2]
+	if t.Minute > 59 || t.Second > 59 {
+		return t, fmt.Errorf("invalid time '%s'", s)
+	}
+	if hasFrac {
+		if len(frac) == 0 || len(frac) > 6 {
+			return t, fmt.Errorf("invalid time '%s'", s)
+		}
+		t.Fsp = uint32(len(frac))
+		frac += strings.Repeat("0", 6-len(frac))
+		if t.Microsecond, err = strconv.Atoi(frac); err != nil {
+			return t, fmt.Errorf("invalid time '%s'", s)
+		}
+	}
+	return t, nil
+}
+
+/*
+* Convert an ENUM or SET value to its stored number. ENUM values are
+compared by element index and SET values by bit mask, as InnoDB does.
+@param[in]	column	ENUM or SET column
+@param[in]	value	element name(s) or number
+@return stored number
+*/
+func elementsKey(column *Column, value interface{}) (key uint64, err error) {
+	s, ok := value.(string)
+	if !ok {
+		r, err := toRat(value)
+		if err != nil || !r.IsInt() || r.Sign() < 0 {
+			return 0, fmt.Errorf("column %s: invalid element value %v", column.Name, value)
+		}
+		return r.Num().Uint64(), nil
+	}
+	findElement := func(name string) (int, error) {
+		for n, element := range column.Elements {
+			if strings.EqualFold(element, name) {
+				return n, nil
+			}
+		}
+		return 0, fmt.Errorf("column %s has no element '%s'", column.Name, name)
+	}
+	if column.Type == CT_ENUM {
+		if s == "" {
+			return 0, nil
+		}
+		n, err := findElement(s)
+		return uint64(n + 1), err
+	}
+	if s == "" {
+		return 0, nil
+	}
+	for _, name := range strings.Split(s, ",") {
+		n, err := findElement(name)
+		if err != nil {
+			return 0, err
+		}
+		key |= 1 << uint(n)
+	}
+	return key, nil
+}
@@ -0,0 +1,513 @@
+package ibd2schema
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+/*
+* SQL dialect of an analytical warehouse
+ */
+type WarehouseDialect string
+
+const (
+	WAREHOUSE_BIGQUERY  WarehouseDialect = "bigquery"
+	WAREHOUSE_SNOWFLAKE WarehouseDialect = "snowflake"
+)
+
+/*
+* Largest precision of NUMBER of Snowflake
+ */
+const SNOWFLAKE_MAX_PRECISION = 38
+
+type WarehouseOptions struct {
+	/** the tables are created in datasets or schemas named like the MySQL
+	schemas if IncludeSchemaName is set, else in the default one */
+	RenderOptions
+	Dialect WarehouseDialect
+	/** add the primary key, unique and foreign key constraints, which the
+	warehouses do not enforce */
+	Constraints bool
+}
+
+func NewWarehouseOptions(dialect WarehouseDialect) *WarehouseOptions {
+	return &WarehouseOptions{
+		RenderOptions: *NewRenderOptions(),
+		Dialect:       dialect,
+		Constraints:   true,
+	}
+}
+
+/*
+* Statements of a table for an analytical warehouse. Foreign keys are
+separate ALTER TABLE statements, so they can be added after all tables are
+created.
+*/
+type WarehouseTable struct {
+	SchemaName string   `json:"schema"`
+	Name       string   `json:"table"`
+	Statements []string `json:"statements"`
+	/** ALTER TABLE statements adding the foreign keys */
+	ForeignKeys []string `json:"foreign_keys,omitempty"`
+	/** lossy or incomplete translations */
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (t *WarehouseTable) warn(format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	for _, w := range t.Warnings {
+		if w == warning {
+			return
+		}
+	}
+	t.Warnings = append(t.Warnings, warning)
+}
+
+/*
+* Get the statements of the table with its warnings as comments
+ */
+func (t *WarehouseTable) String() string {
+	var b strings.Builder
+	for _, warning := range t.Warnings {
+		fmt.Fprintf(&b, "-- WARNING: %s\n", warning)
+	}
+	for _, statement := range append(t.Statements, t.ForeignKeys...) {
+		b.WriteString(statement + ";\n")
+	}
+	return b.String()
+}
+
+/*
+* Quote a string literal of a dialect with backslash escapes, which
+ClickHouse, BigQuery and Snowflake share
+*/
+func quoteBackslashString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`)
+	return "'" + replacer.Replace(s) + "'"
+}
+
+/*
+* Quote an identifier with backticks and backslash escapes, e.g. `order`
+ */
+func quoteBacktickIdentifier(name string) string {
+	return "`" + strings.NewReplacer(`\`, `\\`, "`", "\\`").Replace(name) + "`"
+}
+
+/*
+* Get the DEFAULT clause of a column for a warehouse, only literals and
+CURRENT_TIMESTAMP are translated.
+@param[in]	t		table collecting the warnings
+@param[in]	c		column
+@param[in]	now		CURRENT_TIMESTAMP of the dialect for a precision
+@return DEFAULT clause, empty if none
+*/
+func getWarehouseDefault(t *WarehouseTable, c *Column, now func(precision uint32) string) string {
+	if c.GenerationExpressionUTF8 != "" || c.DefaultValueUTF8Null || c.IsAutoIncrement {
+		return ""
+	}
+	value := c.DefaultValueUTF8
+	switch {
+	case strings.HasPrefix(strings.ToUpper(c.DefaultOption), "CURRENT_TIMESTAMP"):
+		return " DEFAULT " + now(c.DatetimePrecision)
+	case c.DefaultOption != "":
+		t.warn("default %s of column %s is dropped", c.DefaultOption, c.Name)
+		return ""
+	case strings.HasPrefix(value, "0000-00-00"):
+		t.warn("zero date default of column %s is dropped", c.Name)
+		return ""
+	case c.Type == CT_BIT:
+		bits, ok := parseBitLiteral(value)
+		if !ok {
+			t.warn("default %s of column %s is dropped", value, c.Name)
+			return ""
+		}
+		return fmt.Sprintf(" DEFAULT %d", bits)
+	}
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_LONGLONG, CT_DECIMAL, CT_NEWDECIMAL, CT_FLOAT,
+		CT_DOUBLE, CT_YEAR:
+		return " DEFAULT " + value
+	}
+	return " DEFAULT " + quoteBackslashString(value)
+}
+
+/*
+* State of rendering one table for BigQuery or Snowflake
+ */
+type warehouseRenderer struct {
+	table  *TableSchema
+	opts   *WarehouseOptions
+	result *WarehouseTable
+}
+
+func (r *warehouseRenderer) quote(name string) string {
+	if r.opts.Dialect == WAREHOUSE_SNOWFLAKE {
+		return quotePostgresIdentifier(name)
+	}
+	return quoteBacktickIdentifier(name)
+}
+
+func (r *warehouseRenderer) qualify(schemaName, name string) string {
+	if r.opts.IncludeSchemaName && schemaName != "" {
+		return r.quote(schemaName) + "." + r.quote(name)
+	}
+	return r.quote(name)
+}
+
+func (r *warehouseRenderer) quoteNames(names []string) string {
+	quoted := make([]string, len(names))
+	for n, name := range names {
+		quoted[n] = r.quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+/*
+* Get the BigQuery type of a column
+ */
+func (r *warehouseRenderer) getBigQueryType(c *Column) string {
+	switch c.Type {
+	case CT_TINY, CT_SHORT, CT_INT24, CT_LONG, CT_YEAR:
+		return "INT64"
+	case CT_LONGLONG:
+		if c.IsUnsigned {
+			return "NUMERIC(20, 0)"
+		}
+		return "INT64"
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		/* NUMERIC holds 29 integer and 9 fractional digits */
+		if c.NumericScale <= 9 && c.NumericPrecision-c.NumericScale <= 29 {
+			return fmt.Sprintf("NUMERIC(%d, %d)", c.NumericPrecision, c.NumericScale)
+		}
+		return fmt.Sprintf("BIGNUMERIC(%d, %d)", c.NumericPrecision, c.NumericScale)
+	case CT_FLOAT, CT_DOUBLE:
+		return "FLOAT64"
+	case CT_BIT:
+		if c.NumericPrecision == 64 {
+			return "NUMERIC(20, 0)"
+		}
+		return "INT64"
+	case CT_DATE, CT_NEWDATE:
+		return "DATE"
+	case CT_TIME, CT_TIME2:
+		r.result.warn("time column %s only holds times of day, MySQL TIME ranges from -838:59:59 to 838:59:59", c.Name)
+		return "TIME"
+	case CT_DATETIME, CT_DATETIME2:
+		return "DATETIME"
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		return "TIMESTAMP"
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING:
+		if !c.isText() {
+			return fmt.Sprintf("BYTES(%d)", c.Size)
+		}
+		return fmt.Sprintf("STRING(%d)", c.getCharLength())
+	case CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if !c.isText() {
+			return "BYTES"
+		}
+		return "STRING"
+	case CT_JSON:
+		return "JSON"
+	case CT_ENUM, CT_SET:
+		return "STRING"
+	case CT_GEOMETRY:
+		if c.SRSID == nil || *c.SRSID != 4326 {
+			r.result.warn("geometry column %s is GEOGRAPHY, which uses WGS 84 coordinates", c.Name)
+		}
+		return "GEOGRAPHY"
+	}
+	r.result.warn("column %s of type %s is STRING", c.Name, c.ColumnTypeUTF8)
+	return "STRING"
+}
+
+/*
+* Get the Snowflake type of a column
+ */
+func (r *warehouseRenderer) getSnowflakeType(c *Column) string {
+	integer := func(signedDigits, unsignedDigits int) string {
+		if c.IsUnsigned {
+			return fmt.Sprintf("NUMBER(%d,0)", unsignedDigits)
+		}
+		return fmt.Sprintf("NUMBER(%d,0)", signedDigits)
+	}
+	switch c.Type {
+	case CT_TINY:
+		return integer(3, 3)
+	case CT_SHORT:
+		return integer(5, 5)
+	case CT_INT24:
+		return integer(7, 8)
+	case CT_LONG:
+		return integer(10, 10)
+	case CT_LONGLONG:
+		return integer(19, 20)
+	case CT_DECIMAL, CT_NEWDECIMAL:
+		if c.NumericPrecision > SNOWFLAKE_MAX_PRECISION {
+			r.result.warn("decimal column %s has more than %d digits and is FLOAT", c.Name, SNOWFLAKE_MAX_PRECISION)
+			return "FLOAT"
+		}
+		return fmt.Sprintf("NUMBER(%d,%d)", c.NumericPrecision, c.NumericScale)
+	case CT_FLOAT, CT_DOUBLE:
+		return "FLOAT"
+	case CT_BIT:
+		return "NUMBER(20,0)"
+	case CT_YEAR:
+		return "NUMBER(4,0)"
+	case CT_DATE, CT_NEWDATE:
+		return "DATE"
+	case CT_TIME, CT_TIME2:
+		r.result.warn("time column %s only holds times of day, MySQL TIME ranges from -838:59:59 to 838:59:59", c.Name)
+		return fmt.Sprintf("TIME(%d)", c.DatetimePrecision)
+	case CT_DATETIME, CT_DATETIME2:
+		return fmt.Sprintf("TIMESTAMP_NTZ(%d)", c.DatetimePrecision)
+	case CT_TIMESTAMP, CT_TIMESTAMP2:
+		return fmt.Sprintf("TIMESTAMP_LTZ(%d)", c.DatetimePrecision)
+	case CT_VARCHAR, CT_VAR_STRING, CT_STRING:
+		if !c.isText() {
+			return fmt.Sprintf("BINARY(%d)", c.Size)
+		}
+		return fmt.Sprintf("VARCHAR(%d)", c.getCharLength())
+	case CT_TINY_BLOB, CT_BLOB, CT_MEDIUM_BLOB, CT_LONG_BLOB:
+		if c.Type == CT_LONG_BLOB {
+			r.result.warn("longtext or longblob column %s holds at most 16 MB", c.Name)
+		}
+		if !c.isText() {
+			return "BINARY"
+		}
+		return "VARCHAR"
+	case CT_JSON:
+		return "VARIANT"
+	case CT_ENUM, CT_SET:
+		return "VARCHAR"
+	case CT_GEOMETRY:
+		if c.SRSID != nil {
+			return fmt.Sprintf("GEOMETRY(%d)", *c.SRSID)
+		}
+		return "GEOMETRY"
+	}
+	r.result.warn("column %s of type %s is VARCHAR", c.Name, c.ColumnTypeUTF8)
+	return "VARCHAR"
+}
+
+/*
+* Get the column definition of CREATE TABLE
+ */
+func (r *warehouseRenderer) getColumnDefinition(c *Column) string {
+	var definition string
+	now := func(precision uint32) string {
+		return "CURRENT_TIMESTAMP()"
+	}
+	if r.opts.Dialect == WAREHOUSE_SNOWFLAKE {
+		definition = r.quote(c.Name) + " " + r.getSnowflakeType(c)
+		now = func(precision uint32) string {
+			return fmt.Sprintf("CURRENT_TIMESTAMP(%d)", precision)
+		}
+	} else {
+		definition = r.quote(c.Name) + " " + r.getBigQueryType(c)
+		if c.Type == CT_DATETIME || c.Type == CT_DATETIME2 {
+			now = func(precision uint32) string {
+				return "CURRENT_DATETIME()"
+			}
+		}
+	}
+	if c.GenerationExpressionUTF8 != "" {
+		r.result.warn("generated column %s is a plain column", c.Name)
+	}
+	if !c.IsNullable {
+		definition += " NOT NULL"
+	}
+	definition += getWarehouseDefault(r.result, c, now)
+	if c.Comment != "" {
+		if r.opts.Dialect == WAREHOUSE_SNOWFLAKE {
+			definition += " COMMENT " + quoteBackslashString(c.Comment)
+		} else {
+			definition += fmt.Sprintf(" OPTIONS(description=%s)", quoteBackslashString(c.Comment))
+		}
+	}
+	return definition
+}
+
+/*
+* Get the primary key and unique constraints of CREATE TABLE
+ */
+func (r *warehouseRenderer) getConstraints() []string {
+	constraints := make([]string, 0)
+	if !r.opts.Constraints {
+		return constraints
+	}
+	notEnforced := ""
+	if r.opts.Dialect == WAREHOUSE_BIGQUERY {
+		notEnforced = " NOT ENFORCED"
+	}
+	for _, index := range r.table.GetUserIndexes() {
+		if index.Type != IT_PRIMARY && index.Type != IT_UNIQUE {
+			continue
+		}
+		names := make([]string, 0, len(index.KeyParts))
+		functional, prefix := false, false
+		for _, keyPart := range index.KeyParts {
+			functional = functional || keyPart.GetExpression() != ""
+			prefix = prefix || keyPart.Length != 0
+			names = append(names, keyPart.Column.Name)
+		}
+		switch {
+		case functional:
+			r.result.warn("index %s with functional key parts is dropped", index.Name)
+		case prefix:
+			/* a constraint on the whole columns would be stronger than the index */
+			r.result.warn("index %s with prefix key parts is dropped", index.Name)
+		case index.Type == IT_PRIMARY:
+			constraints = append(constraints, fmt.Sprintf("PRIMARY KEY (%s)%s", r.quoteNames(names), notEnforced))
+		case r.opts.Dialect == WAREHOUSE_BIGQUERY:
+			r.result.warn("unique index %s is dropped", index.Name)
+		default:
+			constraints = append(constraints, fmt.Sprintf("CONSTRAINT %s UNIQUE (%s)", r.quote(index.Name),
+				r.quoteNames(names)))
+		}
+	}
+	return constraints
+}
+
+func (r *warehouseRenderer) renderForeignKeys() {
+	if !r.opts.Constraints {
+		return
+	}
+	notEnforced := ""
+	if r.opts.Dialect == WAREHOUSE_BIGQUERY {
+		notEnforced = " NOT ENFORCED"
+	}
+	for _, fk := range r.table.ForeignKeys {
+		r.result.ForeignKeys = append(r.result.ForeignKeys, fmt.Sprintf(
+			"ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)%s",
+			r.qualify(r.table.SchemaName, r.table.Name), r.quote(fk.Name), r.quoteNames(fk.ColumnNames),
+			r.qualify(fk.ReferencedTableSchemaName, fk.ReferencedTableName), r.quoteNames(fk.ReferenceNames),
+			notEnforced))
+	}
+}
+
+/*
+* Render a table for BigQuery or Snowflake. Types use the precision, scale
+and sign of the columns, constraints are informational and indexes, check
+constraints, partitioning and auto increment are dropped, the lossy
+translations are listed in the warnings.
+@param[in]	table	table schema
+@param[in]	opts	options, default NewWarehouseOptions(WAREHOUSE_BIGQUERY)
+@return statements of the table
+*/
+func RenderWarehouse(table *TableSchema, opts *WarehouseOptions) *WarehouseTable {
+	if opts == nil {
+		opts = NewWarehouseOptions(WAREHOUSE_BIGQUERY)
+	}
+	r := &warehouseRenderer{
+		table: table,
+		opts:  opts,
+		result: &WarehouseTable{
+			SchemaName: table.SchemaName,
+			Name:       table.Name,
+			Statements: make([]string, 0),
+		},
+	}
+	definitions := make([]string, 0)
+	for _, column := range table.GetUserColumns() {
+		definitions = append(definitions, r.getColumnDefinition(column))
+	}
+	definitions = append(definitions, r.getConstraints()...)
+	for _, check := range table.CheckConstraints {
+		r.result.warn("check constraint %s is dropped", check.Name)
+	}
+	if table.Partitioning != nil {
+		r.result.warn("partitioning is not translated, the table is not partitioned")
+	}
+	createTable := fmt.Sprintf("CREATE TABLE %s%s (\n  %s\n)", opts.getIfNotExists(),
+		r.qualify(table.SchemaName, table.Name), strings.Join(definitions, ",\n  "))
+	if table.Comment != "" {
+		if opts.Dialect == WAREHOUSE_SNOWFLAKE {
+			createTable += " COMMENT = " + quoteBackslashString(table.Comment)
+		} else {
+			createTable += fmt.Sprintf("\nOPTIONS(description=%s)", quoteBackslashString(table.Comment))
+		}
+	}
+	r.result.Statements = append(r.result.Statements, createTable)
+	r.renderForeignKeys()
+	return r.result
+}
+
+/*
+* Get the sorted names of the schemas of tables, without the empty name of
+tables parsed without a schema
+*/
+func getSchemaNames(tables []*TableSchema) []string {
+	schemas := make(map[string]bool)
+	for _, table := range tables {
+		if table.SchemaName != "" {
+			schemas[table.SchemaName] = true
+		}
+	}
+	names := make([]string, 0, len(schemas))
+	for name := range schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+/*
+* Render tables as a BigQuery or Snowflake script: the datasets or schemas,
+the tables and then all foreign keys.
+@param[in]	tables	table schemas
+@param[in]	opts	options, default NewWarehouseOptions(WAREHOUSE_BIGQUERY)
+@return script
+*/
+func RenderWarehouseScript(tables []*TableSchema, opts *WarehouseOptions) string {
+	if opts == nil {
+		opts = NewWarehouseOptions(WAREHOUSE_BIGQUERY)
+	}
+	var b strings.Builder
+	if opts.CreateSchemas && opts.IncludeSchemaName {
+		r := &warehouseRenderer{opts: opts}
+		for _, name := range getSchemaNames(tables) {
+			fmt.Fprintf(&b, "CREATE SCHEMA IF NOT EXISTS %s%s\n", r.quote(name), opts.Terminator)
+		}
+		b.WriteString("\n")
+	}
+	foreignKeys := make([]string, 0)
+	for _, table := range tables {
+		whTable := RenderWarehouse(table, opts)
+		for _, warning := range whTable.Warnings {
+			fmt.Fprintf(&b, "-- WARNING: %s\n", warning)
+		}
+		for _, statement := range whTable.Statements {
+			b.WriteString(statement + opts.Terminator + "\n")
+		}
+		b.WriteString("\n")
+		foreignKeys = append(foreignKeys, whTable.ForeignKeys...)
+	}
+	for _, statement := range foreignKeys {
+		b.WriteString(statement + opts.Terminator + "\n")
+	}
+	return b.String()
+}
+
+/*
+* Renderer of BigQuery or Snowflake statements, see RenderWarehouseScript
+ */
+type WarehouseRenderer struct {
+	/** warehouse options with the dialect, the shared options are replaced by
+	those passed to Render */
+	Options *WarehouseOptions
+}
+
+func (r *WarehouseRenderer) Name() string {
+	return string(r.Options.Dialect)
+}
+
+func (r *WarehouseRenderer) Render(w io.Writer, tables []*TableSchema, opts *RenderOptions) error {
+	whOpts := *r.Options
+	if opts != nil {
+		whOpts.RenderOptions = *opts
+	}
+	_, err := io.WriteString(w, RenderWarehouseScript(tables, &whOpts))
+	return err
+}
@@ -0,0 +1,182 @@
+package ibd2schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+/*
+* xbstream format of Percona XtraBackup, a sequence of chunks of the files
+of a backup. Chunks of different files may be interleaved.
+*/
+const (
+	XBSTREAM_CHUNK_MAGIC = "XBSTCK01"
+	/** unknown chunk types with this flag can be skipped */
+	XBSTREAM_FLAG_IGNORABLE = 0x01
+	/** data of a file at an offset */
+	XBSTREAM_CHUNK_TYPE_PAYLOAD = 'P'
+	/** data of a file with holes, see XbstreamSparseEntry */
+	XBSTREAM_CHUNK_TYPE_SPARSE = 'S'
+	/** end of a file */
+	XBSTREAM_CHUNK_TYPE_EOF = 'E'
+	/** maximum length of the path of a chunk */
+	XBSTREAM_MAX_PATH_LEN = 512
+	/** maximum length of the payload of a chunk, xtrabackup writes chunks of
+	--read-buffer-size, 10MB by default */
+	XBSTREAM_MAX_PAYLOAD_LEN = 256 << 20
+	/** maximum number of entries of the sparse map of a chunk */
+	XBSTREAM_MAX_SPARSE_MAP_LEN = 1 << 20
+)
+
+/*
+* Entry of the sparse map of a sparse chunk: skip a hole, then write the
+next bytes of the payload.
+*/
+type XbstreamSparseEntry struct {
+	Skip   uint32
+	Length uint32
+}
+
+type XbstreamChunk struct {
+	Flags uint8
+	Type  uint8
+	Path  string
+	/** offset of the payload in the file */
+	Offset    uint64
+	Checksum  uint32
+	SparseMap []XbstreamSparseEntry
+	Payload   []byte
+}
+
+type XbstreamReader struct {
+	Reader io.Reader
+	header []byte
+}
+
+func NewXbstreamReader(r io.Reader) *XbstreamReader {
+	return &XbstreamReader{Reader: r, header: make([]byte, 20)}
+}
+
+/*
+* Check if data starts with the magic of an xbstream chunk
+ */
+func IsXbstream(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(XBSTREAM_CHUNK_MAGIC))
+}
+
+/*
+* Read the next chunk of the stream.
+@return next chunk, io.EOF at the end of the stream
+*/
+func (x *XbstreamReader) Next() (chunk *XbstreamChunk, err error) {
+	header := x.header[:14]
+	n, err := io.ReadFull(x.Reader, header)
+	if err == io.EOF || (err == io.ErrUnexpectedEOF && n == 0) {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read chunk header failed, err:%v", err)
+	}
+	if !IsXbstream(header) {
+		return nil, fmt.Errorf("invalid chunk magic %q", header[:8])
+	}
+	chunk = &XbstreamChunk{Flags: header[8], Type: header[9]}
+	pathLen := binary.LittleEndian.Uint32(header[10:])
+	if pathLen > XBSTREAM_MAX_PATH_LEN {
+		return nil, fmt.Errorf("chunk path length %d exceeds %d", pathLen, XBSTREAM_MAX_PATH_LEN)
+	}
+	path := make([]byte, pathLen)
+	if _, err = io.ReadFull(x.Reader, path); err != nil {
+		return nil, fmt.Errorf("read chunk path failed, err:%v", err)
+	}
+	chunk.Path = string(path)
+	switch chunk.Type {
+	case XBSTREAM_CHUNK_TYPE_EOF:
+		return chunk, nil
+	case XBSTREAM_CHUNK_TYPE_PAYLOAD, XBSTREAM_CHUNK_TYPE_SPARSE:
+	default:
+		if chunk.Flags&XBSTREAM_FLAG_IGNORABLE == 0 {
+			return nil, fmt.Errorf("unknown chunk type %q of %s", chunk.Type, chunk.Path)
+		}
+	}
+	sparseMapSize := uint32(0)
+	if chunk.Type == XBSTREAM_CHUNK_TYPE_SPARSE {
+		if _, err = io.ReadFull(x.Reader, x.header[:4]); err != nil {
+			return nil, fmt.Errorf("read sparse map size failed, err:%v", err)
+		}
+		sparseMapSize = binary.LittleEndian.Uint32(x.header)
+		if sparseMapSize > XBSTREAM_MAX_SPARSE_MAP_LEN {
+			return nil, fmt.Errorf("sparse map size %d of %s exceeds %d", sparseMapSize, chunk.Path,
+				XBSTREAM_MAX_SPARSE_MAP_LEN)
+		}
+	}
+	header = x.header[:20]
+	if _, err = io.ReadFull(x.Reader, header); err != nil {
+		return nil, fmt.Errorf("read payload header of %s failed, err:%v", chunk.Path, err)
+	}
+	length := binary.LittleEndian.Uint64(header)
+	chunk.Offset = binary.LittleEndian.Uint64(header[8:])
+	chunk.Checksum = binary.LittleEndian.Uint32(header[16:])
+	if length > XBSTREAM_MAX_PAYLOAD_LEN {
+		return nil, fmt.Errorf("payload length %d of %s exceeds %d", length, chunk.Path,
+			XBSTREAM_MAX_PAYLOAD_LEN)
+	}
+	if sparseMapSize > 0 {
+		sparseMap := make([]byte, 8*int(sparseMapSize))
+		if _, err = io.ReadFull(x.Reader, sparseMap); err != nil {
+			return nil, fmt.Errorf("read sparse map of %s failed, err:%v", chunk.Path, err)
+		}
+		chunk.SparseMap = make([]XbstreamSparseEntry, sparseMapSize)
+		sparseLen := uint64(0)
+		for i := range chunk.SparseMap {
+			chunk.SparseMap[i].Skip = binary.LittleEndian.Uint32(sparseMap[8*i:])
+			chunk.SparseMap[i].Length = binary.LittleEndian.Uint32(sparseMap[8*i+4:])
+			sparseLen += uint64(chunk.SparseMap[i].Length)
+		}
+		if sparseLen > length {
+			return nil, fmt.Errorf("sparse map length %d of %s exceeds payload length %d", sparseLen,
+				chunk.Path, length)
+		}
+	}
+	chunk.Payload = make([]byte, length)
+	if _, err = io.ReadFull(x.Reader, chunk.Payload); err != nil {
+		return nil, fmt.Errorf("read payload of %s failed, err:%v", chunk.Path, err)
+	}
+	/* xtrabackup checksums the payload of sparse chunks too, without the
+	sparse map */
+	if (chunk.Type == XBSTREAM_CHUNK_TYPE_PAYLOAD || chunk.Type == XBSTREAM_CHUNK_TYPE_SPARSE) &&
+		crc32.ChecksumIEEE(chunk.Payload) != chunk.Checksum {
+		return nil, fmt.Errorf("checksum mismatch of %s at offset %d", chunk.Path, chunk.Offset)
+	}
+	return chunk, nil
+}
+
+/*
+* Write the payload of a chunk to its offset in a file
+@param[in]	w	file of the chunk
+*/
+func (chunk *XbstreamChunk) WriteTo(w io.WriterAt) (err error) {
+	switch chunk.Type {
+	case XBSTREAM_CHUNK_TYPE_PAYLOAD:
+		_, err = w.WriteAt(chunk.Payload, int64(chunk.Offset))
+		return err
+	case XBSTREAM_CHUNK_TYPE_SPARSE:
+		offset, payload := int64(chunk.Offset), chunk.Payload
+		for _, entry := range chunk.SparseMap {
+			offset += int64(entry.Skip)
+			if int(entry.Length) > len(payload) {
+				return fmt.Errorf("sparse map of %s exceeds the payload", chunk.Path)
+			}
+			if _, err = w.WriteAt(payload[:entry.Length], offset); err != nil {
+				return err
+			}
+			offset += int64(entry.Length)
+			payload = payload[entry.Length:]
+		}
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package ibd2schema
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+/*
+* Encode a sparse chunk like xtrabackup
+ */
+func encodeSparseChunk(path string, sparseMap []XbstreamSparseEntry, payload []byte, checksum uint32) []byte {
+	var b bytes.Buffer
+	b.WriteString(XBSTREAM_CHUNK_MAGIC)
+	b.WriteByte(0)
+	b.WriteByte(XBSTREAM_CHUNK_TYPE_SPARSE)
+	binary.Write(&b, binary.LittleEndian, uint32(len(path)))
+	b.WriteString(path)
+	binary.Write(&b, binary.LittleEndian, uint32(len(sparseMap)))
+	binary.Write(&b, binary.LittleEndian, uint64(len(payload)))
+	binary.Write(&b, binary.LittleEndian, uint64(0))
+	binary.Write(&b, binary.LittleEndian, checksum)
+	for _, entry := range sparseMap {
+		binary.Write(&b, binary.LittleEndian, entry.Skip)
+		binary.Write(&b, binary.LittleEndian, entry.Length)
+	}
+	b.Write(payload)
+	return b.Bytes()
+}
+
+func TestXbstreamReaderSparseChunk(t *testing.T) {
+	payload := []byte("abcdefgh")
+	checksum := crc32.ChecksumIEEE(payload)
+	tests := []struct {
+		name      string
+		sparseMap []XbstreamSparseEntry
+		checksum  uint32
+		/** expected error, empty if the chunk is valid */
+		wantErr string
+	}{
+		{"valid", []XbstreamSparseEntry{{Skip: 16, Length: 4}, {Skip: 16, Length: 4}}, checksum, ""},
+		{"corrupt payload", []XbstreamSparseEntry{{Skip: 16, Length: 8}}, checksum + 1, "checksum mismatch"},
+		{"sparse map exceeds payload", []XbstreamSparseEntry{{Skip: 16, Length: 8}, {Skip: 16, Length: 1}},
+			checksum, "exceeds payload length"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewXbstreamReader(bytes.NewReader(encodeSparseChunk("t.ibd", tt.sparseMap, payload,
+				tt.checksum)))
+			chunk, err := reader.Next()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				if len(chunk.SparseMap) != len(tt.sparseMap) || !bytes.Equal(chunk.Payload, payload) {
+					t.Fatalf("unexpected chunk %+v", chunk)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("got error %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
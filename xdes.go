@@ -1,5 +1,7 @@
 package ibd2schema
 
+import "encoding/binary"
+
 const (
 	/*                      EXTENT DESCRIPTOR
 	                        =================
@@ -33,3 +35,87 @@ func GetXdesSize(pageSize *PageSize) uint32 {
 func XdesArrSize(pageSize *PageSize) uint32 {
 	return pageSize.Physical / FspExtentSize(pageSize.Logical)
 }
+
+// extent states
+const (
+	/** extent descriptor is not initialized */
+	XDES_NOT_INITED = iota
+	/** extent is in free list of space */
+	XDES_FREE
+	/** extent is in free fragment list of space */
+	XDES_FREE_FRAG
+	/** extent is in full fragment list of space */
+	XDES_FULL_FRAG
+	/** extent belongs to a segment */
+	XDES_FSEG
+	/** fragment extent leased to segment */
+	XDES_FSEG_FRAG
+)
+
+const (
+	/** Index of the bit which tells if the page is free */
+	XDES_FREE_BIT = 0
+	/** NOTE: currently not used! Index of the bit which tells if there are old
+	versions of tuples on the page */
+	XDES_CLEAN_BIT = 1
+)
+
+/*
+* Extent descriptor
+ */
+type Xdes struct {
+	/** first page number of the extent */
+	FirstPageNum uint32
+	/** the identifier of the segment to which this extent belongs */
+	SegmentID uint64
+	Node      *FlstNode
+	State     uint32
+	Bitmap    []byte
+	/** number of pages in the extent */
+	Size uint32
+}
+
+func NewXdes(firstPageNum uint32, data []byte, pageSize *PageSize) *Xdes {
+	return &Xdes{
+		FirstPageNum: firstPageNum,
+		SegmentID:    binary.BigEndian.Uint64(data[XDES_ID:]),
+		Node:         NewFlstNode(data[XDES_FLST_NODE:]),
+		State:        binary.BigEndian.Uint32(data[XDES_STATE:]),
+		Bitmap:       data[XDES_BITMAP:GetXdesSize(pageSize)],
+		Size:         FspExtentSize(pageSize.Logical),
+	}
+}
+
+/*
+* Get a bit of the descriptor bitmap.
+@param[in]	bit	XDES_FREE_BIT or XDES_CLEAN_BIT
+@param[in]	offset	page offset within extent
+@return value of the bit
+*/
+func (x *Xdes) GetBit(bit uint32, offset uint32) bool {
+	index := XDES_BITS_PER_PAGE*offset + bit
+	return (x.Bitmap[index/8]>>(index%8))&1 != 0
+}
+
+/*
+* Check if a page of the extent is free.
+@param[in]	offset	page offset within extent
+@return true if the page is free
+*/
+func (x *Xdes) IsPageFree(offset uint32) bool {
+	return x.GetBit(XDES_FREE_BIT, offset)
+}
+
+/*
+* Get the page numbers of the used pages in the extent.
+@return used page numbers
+*/
+func (x *Xdes) UsedPages() (pageNums []uint32) {
+	pageNums = make([]uint32, 0, x.Size)
+	for n := uint32(0); n < x.Size; n++ {
+		if !x.IsPageFree(n) {
+			pageNums = append(pageNums, x.FirstPageNum+n)
+		}
+	}
+	return pageNums
+}